@@ -9,11 +9,19 @@
 
 // byte order helpers — bpf_endian.h가 없는 환경을 위한 fallback
 // __builtin_bswap*는 clang이 항상 인라인으로 처리하므로 BPF verifier 거부 없음
+// big-endian 타깃(bpfeb, 예: s390x)에서는 network order가 곧 host order이므로 변환하지 않는다.
 #ifndef bpf_ntohl
+#if __BYTE_ORDER__ == __ORDER_LITTLE_ENDIAN__
 #define bpf_ntohl(x) __builtin_bswap32(x)
 #define bpf_ntohs(x) __builtin_bswap16(x)
 #define bpf_htonl(x) __builtin_bswap32(x)
 #define bpf_htons(x) __builtin_bswap16(x)
+#else
+#define bpf_ntohl(x) (x)
+#define bpf_ntohs(x) (x)
+#define bpf_htonl(x) (x)
+#define bpf_htons(x) (x)
+#endif
 #endif
 
 typedef unsigned char  u8;
@@ -168,6 +176,16 @@ struct {
 	__type(value, struct conn_info_t);
 } conn_info SEC(".maps");
 
+// Per-port protocol hints: remote port → protocol_t (populated from userspace, --port-hints).
+// A hinted connection tries only the hinted parser first and skips the full
+// inference chain when it matches; unmatched payloads fall back to inference.
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1024);
+	__type(key, u16);  // remote port (host byte order)
+	__type(value, u8); // protocol_t
+} port_hints SEC(".maps");
+
 // Saves sockaddr pointer from accept4/accept enter for use in exit.
 struct {
 	__uint(type, BPF_MAP_TYPE_HASH);
@@ -667,6 +685,40 @@ static __always_inline struct infer_result_t infer_protocol(
 	return r;
 }
 
+// Runs only the parser for a hinted protocol (see port_hints).
+static __always_inline struct infer_result_t infer_hinted(
+	u8 hint, const char *buf, u32 count, struct conn_state_t *cs)
+{
+	struct infer_result_t r = {PROTO_UNKNOWN, MSG_UNKNOWN};
+	u8 t = MSG_UNKNOWN;
+
+	switch (hint) {
+	case PROTO_TLS:   t = infer_tls(buf, count); break;
+	case PROTO_HTTP:  t = infer_http(buf, count); break;
+	case PROTO_CQL:   t = infer_cql(buf, count); break;
+	case PROTO_MONGO: t = infer_mongo(buf, count); break;
+	case PROTO_PGSQL: t = infer_pgsql(buf, count); break;
+	case PROTO_MYSQL: t = infer_mysql(buf, count, cs); break;
+	case PROTO_MUX:   t = infer_mux(buf, count); break;
+	case PROTO_KAFKA: t = infer_kafka(buf, count, cs); break;
+	case PROTO_DNS:   t = infer_dns(buf, count); break;
+	case PROTO_AMQP:  t = infer_amqp(buf, count); break;
+	case PROTO_NATS:  t = infer_nats(buf, count); break;
+	case PROTO_REDIS:
+		if (infer_redis(buf, count) != MSG_UNKNOWN) {
+			r.protocol = PROTO_REDIS; // msg_type stays unknown, as in infer_protocol
+		}
+		return r;
+	default:
+		return r;
+	}
+	if (t != MSG_UNKNOWN) {
+		r.protocol = hint;
+		r.msg_type = t;
+	}
+	return r;
+}
+
 // ─── Emit helper ────────────────────────────────────────────────
 
 static __always_inline int emit_event(struct args_t *a, long bytes, u8 direction)
@@ -695,7 +747,16 @@ static __always_inline int emit_event(struct args_t *a, long bytes, u8 direction
 		proto = cs->protocol;
 		mtype = MSG_UNKNOWN;
 	} else {
-		struct infer_result_t r = infer_protocol(probe, probe_len, cs);
+		struct infer_result_t r = {PROTO_UNKNOWN, MSG_UNKNOWN};
+		struct conn_info_t *hci = bpf_map_lookup_elem(&conn_info, &conn_key);
+		if (hci) {
+			u16 port = hci->remote_port;
+			u8 *hint = bpf_map_lookup_elem(&port_hints, &port);
+			if (hint)
+				r = infer_hinted(*hint, probe, probe_len, cs);
+		}
+		if (r.protocol == PROTO_UNKNOWN)
+			r = infer_protocol(probe, probe_len, cs);
 		proto = r.protocol;
 		mtype = r.msg_type;
 
@@ -7,6 +7,7 @@
 // 흐름:
 //   1. Loader 초기화 (internal/agent/ebpf)
 //      → BPF 프로그램 로드 + syscall tracepoint attach + ringbuf 구독
+//      → 실패 시 /proc/net polling fallback (internal/agent/procnet, 연결 이벤트만)
 //
 //   2. SSLLoader + ProcScanner 초기화
 //      → ssl_trace.c BPF 로드 (loader의 ringbuf 공유)
@@ -15,6 +16,7 @@
 //
 //   3. 이벤트 루프 (for)
 //      → loader.Read()로 ringbuf에서 이벤트 블로킹 대기
+//      → enrich.Pipeline(--enrichers 순서)으로 K8s/external/DNS/GeoIP/webhook 메타데이터 보강
 //      → 이벤트 도착 시 방향/PID/FD/프로토콜/페이로드 출력
 //      → ringbuf.ErrClosed 수신 시 (Ctrl+C 등) 루프 종료
 //
@@ -31,64 +33,428 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/cilium/ebpf/ringbuf"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/credentials"
 
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/agent/admin"
 	agentebpf "github.com/gihongjo/nefi/internal/agent/ebpf"
+	"github.com/gihongjo/nefi/internal/agent/enrich"
+	"github.com/gihongjo/nefi/internal/agent/export"
 	agentgrpc "github.com/gihongjo/nefi/internal/agent/grpc"
 	agentk8s "github.com/gihongjo/nefi/internal/agent/k8s"
+	"github.com/gihongjo/nefi/internal/agent/memguard"
+	"github.com/gihongjo/nefi/internal/agent/ndjson"
+	"github.com/gihongjo/nefi/internal/agent/netclass"
+	"github.com/gihongjo/nefi/internal/agent/nodename"
+	"github.com/gihongjo/nefi/internal/agent/procnet"
+	"github.com/gihongjo/nefi/internal/agent/rdns"
+	"github.com/gihongjo/nefi/internal/agent/remotecfg"
+	"github.com/gihongjo/nefi/internal/agent/stats"
+	"github.com/gihongjo/nefi/internal/configz"
+	"github.com/gihongjo/nefi/internal/metrics"
 	"github.com/gihongjo/nefi/internal/model"
+	"github.com/gihongjo/nefi/internal/version"
 )
 
 func main() {
+	adminAddr := flag.String("admin-addr", ":9091", "agent admin HTTP address (/healthz, /configz); empty = disabled")
+	dryRun := flag.Bool("dry-run", false, "load BPF and enrich events but export nothing; log volume statistics instead")
+	statsInterval := flag.Duration("stats-interval", 30*time.Second, "dry-run statistics log interval")
+	exporterMode := flag.String("exporter", envOr("EXPORTER", export.SinkGRPC), "comma-separated event exporters, each event goes to all: grpc (to --server-addr), stdout or file (NDJSON); env EXPORTER")
+	exportPath := flag.String("exporter-file", envOr("EXPORTER_FILE", "nefi-events.ndjson"), "NDJSON output path for --exporter=file; env EXPORTER_FILE")
+	clusterName := flag.String("cluster-name", envOr("CLUSTER_NAME", ""), "cluster name stamped into every event so one server can ingest from several clusters; env CLUSTER_NAME")
+	tenant := flag.String("tenant", envOr("TENANT", ""), "tenant that owns this agent's events on a shared server; ignored when the server takes the tenant from the client certificate organization; env TENANT")
 	serverAddr := flag.String("server-addr", "", "nefi-server gRPC address (e.g. nefi-server:9090); empty = stdout only")
+	var classCfg netclass.Config
+	flag.StringVar(&classCfg.CIDRFile, "external-cidrs", "", "file of \"<cidr|ip|hostname> <name>\" lines naming external endpoints; hostname rules (e.g. *.rds.amazonaws.com) need --reverse-dns")
+	flag.StringVar(&classCfg.AWSRanges, "aws-ip-ranges", "", "path to AWS ip-ranges.json for cloud range detection")
+	flag.StringVar(&classCfg.GCPRanges, "gcp-ip-ranges", "", "path to GCP cloud.json for cloud range detection")
+	flag.StringVar(&classCfg.AzureRange, "azure-ip-ranges", "", "path to Azure ServiceTags JSON for cloud range detection")
+	meshDedup := flag.Bool("mesh-dedup", true, "drop events of Istio/Linkerd sidecar processes, which duplicate the app's own observation of each request (mesh stage)")
+	reverseDNS := flag.Bool("reverse-dns", false, "label unresolved external IPs by reverse DNS hostname")
+	rdnsRate := flag.Float64("reverse-dns-rate", 10, "maximum reverse DNS lookups per second")
+	rdnsTTL := flag.Duration("reverse-dns-ttl", 10*time.Minute, "reverse DNS cache TTL (positive and negative results)")
+	enrichers := flag.String("enrichers", enrich.DefaultStages, "ordered, comma-separated enrichment stages (k8s, mesh, external, dns, geoip, webhook); unconfigured stages are skipped")
+	geoIPFile := flag.String("geoip-cidrs", "", "file of \"<cidr> <country>\" lines for the geoip enricher (label "+enrich.GeoLabelCountry+" on external remotes)")
+	webhookURL := flag.String("enrich-webhook", "", "URL the webhook enricher POSTs remote addresses to for extra remote_name/remote_labels")
+	webhookRate := flag.Float64("enrich-webhook-rate", 10, "maximum webhook enricher calls per second")
+	webhookTTL := flag.Duration("enrich-webhook-ttl", 10*time.Minute, "webhook enricher cache TTL (successful and failed calls)")
+	procFallback := flag.Bool("proc-fallback", true, "if BPF cannot be loaded, poll /proc/net and conntrack for coarse connection events")
+	procPollInterval := flag.Duration("proc-poll-interval", 10*time.Second, "/proc/net polling interval for the fallback collector")
+	keepaliveTime := flag.Duration("grpc-keepalive-time", envDurationOr("GRPC_KEEPALIVE_TIME", 30*time.Second), "ping the server connection this often to detect half-open connections (e.g. through NLBs); 0 disables; env GRPC_KEEPALIVE_TIME")
+	keepaliveTimeout := flag.Duration("grpc-keepalive-timeout", envDurationOr("GRPC_KEEPALIVE_TIMEOUT", 10*time.Second), "reconnect when a keepalive ping is unanswered this long; env GRPC_KEEPALIVE_TIMEOUT")
+	keepaliveIdle := flag.Bool("grpc-keepalive-without-stream", envOr("GRPC_KEEPALIVE_WITHOUT_STREAM", "true") == "true", "send keepalive pings even when no stream is open; env GRPC_KEEPALIVE_WITHOUT_STREAM")
+	exportQueueSize := flag.Int("export-queue-size", envIntOr("EXPORT_QUEUE_SIZE", agentgrpc.DefaultQueueSize), "events buffered for the gRPC exporter before the lowest-priority ones are dropped; env EXPORT_QUEUE_SIZE")
+	exportTierLimits := flag.String("export-queue-tier-limits", envOr("EXPORT_QUEUE_TIER_LIMITS", ""), "per-tier queue limits below --export-queue-size, e.g. connection=256 (tiers: error, l7, connection); env EXPORT_QUEUE_TIER_LIMITS")
+	exportBatchSize := flag.Int("export-batch-size", envIntOr("EXPORT_BATCH_SIZE", agentgrpc.DefaultBatchSize), "maximum events per batch sent to the server (at most 10000); env EXPORT_BATCH_SIZE")
+	exportFlush := flag.Duration("export-flush-interval", envDurationOr("EXPORT_FLUSH_INTERVAL", agentgrpc.DefaultFlushInterval), "send a partial batch this long after its first event; env EXPORT_FLUSH_INTERVAL")
+	exportCompress := flag.Bool("export-compress", envOr("EXPORT_COMPRESS", "false") == "true", "gzip event batches when the server supports it (less egress, more agent CPU); env EXPORT_COMPRESS")
+	var serverTLS agentgrpc.TLS
+	flag.BoolVar(&serverTLS.Enabled, "server-tls", envOr("SERVER_TLS", "false") == "true", "connect to --server-addr over TLS; env SERVER_TLS")
+	flag.StringVar(&serverTLS.CAFile, "server-tls-ca", envOr("SERVER_TLS_CA", ""), "PEM CA bundle to verify the server certificate with --server-tls (default: system roots); env SERVER_TLS_CA")
+	flag.StringVar(&serverTLS.ServerName, "server-tls-server-name", envOr("SERVER_TLS_SERVER_NAME", ""), "TLS server name (SNI) to send and verify instead of the --server-addr host, e.g. behind a load balancer with a shared certificate; env SERVER_TLS_SERVER_NAME")
+	flag.BoolVar(&serverTLS.InsecureSkipVerify, "server-tls-insecure-skip-verify", envOr("SERVER_TLS_INSECURE_SKIP_VERIFY", "false") == "true", "do not verify the server certificate (labs only); env SERVER_TLS_INSECURE_SKIP_VERIFY")
+	flag.StringVar(&serverTLS.CertFile, "server-tls-cert", envOr("SERVER_TLS_CERT", ""), "PEM client certificate to present with --server-tls when the server requires mTLS; env SERVER_TLS_CERT")
+	flag.StringVar(&serverTLS.KeyFile, "server-tls-key", envOr("SERVER_TLS_KEY", ""), "PEM private key for --server-tls-cert; env SERVER_TLS_KEY")
+	drainTimeout := flag.Duration("drain-timeout", 10*time.Second, "on shutdown, keep streaming queued events for up to this long")
+	podLabels := flag.String("pod-labels", "", "comma-separated pod label keys to attach to events (e.g. app.kubernetes.io/version,team)")
+	podAnnotations := flag.String("pod-annotations", "", "comma-separated pod annotation keys to attach to events")
+	nodeNameFile := flag.String("node-name-file", nodename.DefaultFile, "downward API file holding spec.nodeName, used when NODE_NAME is unset")
+	var k8sCfg agentk8s.Config
+	flag.StringVar(&k8sCfg.Kubeconfig, "kubeconfig", "", "kubeconfig path for running outside the cluster; empty = in-cluster service account")
+	flag.StringVar(&k8sCfg.Context, "kube-context", "", "kubeconfig context to use (default: current context)")
+	kubeQPS := flag.Float64("kube-api-qps", 0, "client-side K8s API QPS limit; 0 = client-go default (5)")
+	flag.IntVar(&k8sCfg.Burst, "kube-api-burst", 0, "client-side K8s API burst; 0 = client-go default (10)")
+	flag.DurationVar(&k8sCfg.Resync, "kube-resync", 30*time.Second, "pod/service cache refresh period")
+	kubeNamespaces := flag.String("kube-namespaces", "", "comma-separated namespaces to list/watch pods, EndpointSlices and Services in; empty = cluster-wide")
+	ownerResources := flag.String("kube-owner-resources", "", "comma-separated group/version/resource owners to follow past ReplicaSet/Job when naming workloads (e.g. Knative: apps/v1/deployments,serving.knative.dev/v1/revisions,serving.knative.dev/v1/configurations); needs list/watch RBAC")
+	flag.IntVar(&k8sCfg.MaxEntries, "kube-cache-max-entries", 0, "maximum entries in each of the pod IP, hostPort and PID caches; 0 = unlimited")
+	flag.DurationVar(&k8sCfg.StaleTTL, "kube-cache-stale-ttl", 5*time.Minute, "keep deleted pods and previous owners of reused pod IPs resolvable for late events this long")
+	flag.BoolVar(&k8sCfg.NetworkPolicies, "kube-network-policies", false, "watch NetworkPolicies and mark each flow as allowed, denied or not covered (policy field); keeps full pod labels in memory")
+	flag.StringVar(&k8sCfg.PodFieldSelector, "kube-pod-field-selector", "", "field selector for the remote pod list (e.g. spec.nodeName=$(NODE_NAME) for this node's pods only)")
+	memGuardOn := flag.Bool("mem-guard", true, "shrink the export queue, sample, then pause capture as memory approaches the limits")
+	memSoftMB := flag.Int("mem-soft-limit-mb", 0, "memory guard soft limit in MiB; 0 = 70% of the container memory limit")
+	memHardMB := flag.Int("mem-hard-limit-mb", 0, "memory guard hard limit in MiB (capture pauses above it); 0 = 90% of the container memory limit")
+	configPoll := flag.Duration("config-poll-interval", 30*time.Second, "how often to poll nefi-server for fleet runtime config (sampling, namespace filters); 0 disables")
+	portHintsFlag := flag.String("port-hints", "", "comma-separated port=protocol parser hints for remote ports (e.g. 6379=redis,5432=postgres,9092=kafka,443=tls)")
 	flag.Parse()
 
+	portHints, err := agentebpf.ParsePortHints(*portHintsFlag)
+	if err != nil {
+		log.Fatalf("Invalid --port-hints: %v", err)
+	}
+	stageNames, err := enrich.ParseStages(*enrichers)
+	if err != nil {
+		log.Fatalf("Invalid --enrichers: %v", err)
+	}
+
+	// NDJSON을 stdout으로 내보낼 때는 사람이 읽는 출력을 stderr로 돌려 스트림을 깨끗하게 유지한다.
+	sinkNames := export.Names(*exporterMode)
+	stdoutSink := slices.Contains(sinkNames, export.SinkStdout)
+	eventOut := os.Stdout
+	if stdoutSink {
+		os.Stdout = os.Stderr
+	}
+
 	fmt.Println("============================================================")
 	fmt.Println("  Nefi Agent — eBPF Socket Data Capture (libbpf/CO-RE)")
 	fmt.Println("============================================================")
+	vi := version.Get()
+	fmt.Printf("[*] version=%s commit=%s built=%s schema=%d\n", vi.Version, vi.GitCommit, vi.BuildDate, vi.SchemaVersion)
 
-	loader, err := agentebpf.New()
-	if err != nil {
-		log.Fatalf("Failed to start BPF: %v", err)
+	// 이벤트량/보강 통계 — dry-run 로그와 admin /stats에 사용
+	eventStats := stats.New()
+
+	// agent 자체 메트릭 (admin /metrics)
+	agentMetrics := metrics.NewRegistry()
+
+	// Admin HTTP — /healthz, /readyz, /configz, /stats, /metrics, /debug/cache (--admin-addr 지정 시 활성화)
+	var adminSrv *admin.Server
+	if *adminAddr != "" {
+		srv, err := admin.New(*adminAddr)
+		if err != nil {
+			log.Fatalf("Failed to start admin server: %v", err)
+		}
+		adminSrv = srv
+		entries := append(configz.FromFlags(flag.CommandLine), configz.Env("NODE_NAME"))
+		adminSrv.Handle("GET /configz", configz.Handler("nefi-agent", entries))
+		adminSrv.Handle("GET /stats", eventStats)
+		adminSrv.Handle("GET /metrics", agentMetrics)
+		adminSrv.Start()
+		defer adminSrv.Close()
+		fmt.Printf("[+] Admin HTTP listening on %s\n", adminSrv.Addr())
 	}
-	defer loader.Close()
 
-	fmt.Println("[+] BPF loaded and tracepoints attached!")
+	// 이벤트 소스: eBPF ringbuf, 또는 BPF 로드 실패 시 /proc/net polling (연결 이벤트만)
+	var (
+		source   eventSource
+		connOnly bool // source가 payload 없는 연결 이벤트만 생성함
+		sslErr   error
+	)
+	loader, bpfErr := agentebpf.New()
+	if bpfErr != nil {
+		if !*procFallback {
+			log.Fatalf("Failed to start BPF: %v", bpfErr)
+		}
+		log.Printf("[WARN] BPF unavailable: %v", bpfErr)
+		source = procnet.New(*procPollInterval)
+		connOnly = true
+		fmt.Printf("[+] Fallback collector active: polling /proc/net every %v (connections only)\n", *procPollInterval)
+	} else {
+		source = loader
+		fmt.Println("[+] BPF loaded and tracepoints attached!")
+		if len(portHints) > 0 {
+			if err := loader.SetPortHints(portHints); err != nil {
+				log.Printf("[WARN] Port hints not applied: %v", err)
+			} else {
+				fmt.Printf("[+] Protocol port hints: %d port(s)\n", len(portHints))
+			}
+		}
+
+		// SSL/TLS uprobe — graceful degradation if unavailable (e.g. non-Linux).
+		var sslLoader *agentebpf.SSLLoader
+		sslLoader, sslErr = agentebpf.NewSSLLoader(loader.EventsMap())
+		if sslErr != nil {
+			log.Printf("[WARN] SSL/TLS tracing disabled: %v", sslErr)
+		} else {
+			defer sslLoader.Close()
+			scanner := agentebpf.NewProcScanner(sslLoader, 5*time.Second)
+			scanner.Start()
+			defer scanner.Stop()
+			fmt.Println("[+] SSL/TLS uprobe active (5 s scan interval)")
+		}
+	}
+	defer source.Close()
 	fmt.Printf("[*] PID=%d\n", os.Getpid())
 
-	// SSL/TLS uprobe — graceful degradation if unavailable (e.g. non-Linux).
-	sslLoader, err := agentebpf.NewSSLLoader(loader.EventsMap())
-	if err != nil {
-		log.Printf("[WARN] SSL/TLS tracing disabled: %v", err)
-	} else {
-		defer sslLoader.Close()
-		scanner := agentebpf.NewProcScanner(sslLoader, 5*time.Second)
-		scanner.Start()
-		defer scanner.Stop()
-		fmt.Println("[+] SSL/TLS uprobe active (5 s scan interval)")
+	// 노드 이름 — NODE_NAME이 없으면 downward API 파일, kubelet, hostname 순으로 탐지한다.
+	nodeName, nodeSource := nodename.Detect(*nodeNameFile)
+	switch nodeSource {
+	case "":
+		log.Printf("[WARN] Node name could not be determined; events will carry no node name")
+	case nodename.SourceHostname:
+		// hostname은 노드 이름과 다를 수 있으므로 K8s pod 필터링에는 쓰지 않는다.
+		fmt.Printf("[*] Node name: %s (source: %s, not used for K8s pod filtering)\n", nodeName, nodeSource)
+	default:
+		fmt.Printf("[*] Node name: %s (source: %s)\n", nodeName, nodeSource)
+		k8sCfg.NodeName = nodeName
 	}
 
 	// K8s pod resolver — graceful degradation if not running in-cluster.
-	resolver, err := agentk8s.NewResolver()
+	k8sCfg.LabelKeys = splitList(*podLabels)
+	k8sCfg.AnnotationKeys = splitList(*podAnnotations)
+	k8sCfg.Namespaces = splitList(*kubeNamespaces)
+	k8sCfg.OwnerResources = splitList(*ownerResources)
+	k8sCfg.QPS = float32(*kubeQPS)
+	resolver, err := agentk8s.NewResolver(k8sCfg)
 	if err != nil {
 		log.Printf("[WARN] K8s resolver disabled: %v", err)
 		resolver = nil
 	} else {
 		fmt.Println("[+] K8s pod resolver active")
+		resolver.RegisterMetrics(agentMetrics)
+		if n := resolver.Node(); n.Zone != "" || n.Region != "" {
+			fmt.Printf("[+] Node topology: zone=%s region=%s instance-type=%s\n", n.Zone, n.Region, n.InstanceType)
+		}
 	}
 
-	// gRPC sender — nefi-server로 이벤트 전송 (--server-addr 지정 시 활성화)
-	var sender *agentgrpc.Sender
-	nodeName := os.Getenv("NODE_NAME")
-	if *serverAddr != "" {
-		sender = agentgrpc.New(*serverAddr, nodeName)
-		defer sender.Close()
-		fmt.Printf("[+] gRPC sender active → %s\n", *serverAddr)
+	// External endpoint classifier — K8s에서 해석되지 않은 remote IP에 논리 이름 부여.
+	classifier, err := netclass.New(classCfg)
+	if err != nil {
+		log.Fatalf("Failed to load external endpoint classification: %v", err)
+	}
+	if userN, cloudN := classifier.Sizes(); userN+cloudN > 0 {
+		fmt.Printf("[+] External endpoint classifier: %d user mappings, %d cloud ranges\n", userN, cloudN)
+	}
+
+	// Reverse DNS — external IP를 hostname으로 표시 (--reverse-dns 지정 시 활성화)
+	var rdnsResolver *rdns.Resolver
+	if *reverseDNS {
+		rdnsResolver = rdns.New(*rdnsRate, *rdnsTTL)
+		defer rdnsResolver.Close()
+		fmt.Printf("[+] Reverse DNS active (%.0f lookups/s, TTL %v)\n", *rdnsRate, *rdnsTTL)
+	}
+
+	// Exporter — --exporter의 sink를 모두 열어 이벤트마다 fan-out한다.
+	// grpc: nefi-server로 전송 (--server-addr 지정 시), stdout/file: 로컬 NDJSON
+	var (
+		exp         export.Sink
+		sender      *agentgrpc.Sender
+		serverCreds credentials.TransportCredentials // sender와 remote config poller가 같이 쓴다
+	)
+	exports := export.NewRegistry()
+	exports.Register(export.SinkGRPC, func() (export.Sink, error) {
+		if *serverAddr == "" {
+			return nil, nil
+		}
+		tierLimits, err := agentgrpc.ParseTierLimits(*exportTierLimits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --export-queue-tier-limits: %w", err)
+		}
+		if serverCreds, err = serverTLS.Credentials(); err != nil {
+			return nil, fmt.Errorf("invalid --server-tls settings: %w", err)
+		}
+		if serverTLS.Enabled && serverTLS.InsecureSkipVerify {
+			log.Printf("[WARN] Server certificate verification disabled (--server-tls-insecure-skip-verify)")
+		}
+		sender = agentgrpc.New(agentgrpc.Config{
+			ServerAddr:   *serverAddr,
+			NodeName:     nodeName,
+			DrainTimeout: *drainTimeout,
+			Handshake:    handshake(bpfErr, sslErr, resolver, *clusterName, *tenant),
+			Keepalive: agentgrpc.Keepalive{
+				Time:                *keepaliveTime,
+				Timeout:             *keepaliveTimeout,
+				PermitWithoutStream: *keepaliveIdle,
+			},
+			Compress:      *exportCompress,
+			Credentials:   serverCreds,
+			QueueSize:     *exportQueueSize,
+			TierLimits:    tierLimits,
+			BatchSize:     *exportBatchSize,
+			FlushInterval: *exportFlush,
+			Capture: func() agentgrpc.CaptureLoad {
+				snap := eventStats.Snapshot()
+				return agentgrpc.CaptureLoad{
+					Captured:   snap.Captured,
+					SampledOut: snap.SampledOut,
+					Paused:     time.Duration(snap.PausedSec * float64(time.Second)),
+				}
+			},
+		})
+		sender.RegisterMetrics(agentMetrics)
+		transport := "plaintext"
+		if serverTLS.Enabled {
+			transport = "TLS"
+		}
+		fmt.Printf("[+] gRPC sender active → %s (%s)\n", *serverAddr, transport)
+		return sender, nil
+	})
+	exports.Register(export.SinkStdout, func() (export.Sink, error) {
+		fmt.Println("[+] NDJSON exporter active → stdout")
+		return ndjson.New(eventOut), nil
+	})
+	exports.Register(export.SinkFile, func() (export.Sink, error) {
+		fileExp, err := ndjson.Open(*exportPath)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("[+] NDJSON exporter active → %s\n", *exportPath)
+		return fileExp, nil
+	})
+	if *dryRun {
+		fmt.Printf("[+] Dry-run: export disabled, logging statistics every %v\n", *statsInterval)
+		go logStats(eventStats, *statsInterval)
+	} else if exp, err = exports.Open(sinkNames); err != nil {
+		log.Fatalf("Failed to open exporter: %v", err)
+	}
+
+	// 서버 관리 런타임 설정 (샘플링, namespace 제외) — gRPC export일 때만 poll한다.
+	var remote *remotecfg.Poller
+	if sender != nil && *configPoll > 0 {
+		remote, err = remotecfg.New(*serverAddr, nodeName, *configPoll, serverCreds)
+		if err != nil {
+			log.Printf("[WARN] Remote config disabled: %v", err)
+		} else {
+			defer remote.Close()
+			fmt.Printf("[+] Remote config polling every %v\n", *configPoll)
+		}
+	}
+	if exp != nil {
+		defer exp.Close()
+	}
+
+	// 보강 pipeline — --enrichers 순서대로 실행한다.
+	var stages []enrich.Enricher
+	for _, name := range stageNames {
+		switch name {
+		case enrich.StageK8s:
+			stages = append(stages, enrich.K8s(resolver))
+		case enrich.StageMesh:
+			stages = append(stages, enrich.Mesh(*meshDedup))
+		case enrich.StageExternal:
+			stages = append(stages, enrich.External(classifier))
+		case enrich.StageDNS:
+			stages = append(stages, enrich.DNS(rdnsResolver, classifier))
+		case enrich.StageGeoIP:
+			if *geoIPFile == "" {
+				continue
+			}
+			geo, err := netclass.New(netclass.Config{CIDRFile: *geoIPFile})
+			if err != nil {
+				log.Fatalf("Failed to load GeoIP mapping: %v", err)
+			}
+			stages = append(stages, enrich.GeoIP(geo))
+		case enrich.StageWebhook:
+			if *webhookURL == "" {
+				continue
+			}
+			wh := enrich.NewWebhook(*webhookURL, *webhookRate, *webhookTTL)
+			defer wh.Close()
+			stages = append(stages, wh)
+		}
+		// 서버에서 내려준 namespace 제외/샘플링 — 로컬 pod 해석 직후에 걸러
+		// 뒤 stage(remote 해석, DNS, webhook) 비용을 줄인다.
+		if name == enrich.StageK8s && remote != nil {
+			stages = append(stages, remoteFilter(remote))
+		}
+	}
+	if remote != nil && !slices.Contains(stageNames, enrich.StageK8s) {
+		stages = append([]enrich.Enricher{remoteFilter(remote)}, stages...)
+	}
+	pipeline := enrich.New(stages...)
+	fmt.Printf("[+] Enrichment pipeline: %s\n", strings.Join(pipeline.Names(), " → "))
+
+	// 메모리 보호 — 노드의 워크로드와 함께 OOM kill되지 않도록 단계적으로 부하를 줄인다.
+	var guard *memguard.Guard
+	if *memGuardOn {
+		soft, hard := uint64(*memSoftMB)<<20, uint64(*memHardMB)<<20
+		if autoSoft, autoHard, ok := memguard.AutoLimits(); ok {
+			if soft == 0 {
+				soft = autoSoft
+			}
+			if hard == 0 {
+				hard = autoHard
+			}
+		}
+		switch {
+		case soft == 0 || hard == 0:
+			fmt.Println("[*] Memory guard inactive: no container memory limit and no --mem-*-limit-mb")
+		case soft >= hard:
+			log.Fatalf("Invalid memory guard limits: soft %d MiB must be below hard %d MiB", soft>>20, hard>>20)
+		default:
+			var queueCap int
+			if sender != nil {
+				queueCap = sender.State().QueueCap
+			}
+			guard = memguard.New(memguard.Config{
+				SoftLimit: soft,
+				HardLimit: hard,
+				Interval:  time.Second,
+				OnLevel: func(l memguard.Level) {
+					if sender == nil {
+						return
+					}
+					if l >= memguard.LevelShrinkQueue {
+						sender.SetQueueLimit(queueCap / 4)
+					} else {
+						sender.SetQueueLimit(0)
+					}
+				},
+			})
+			defer guard.Close()
+			guard.RegisterMetrics(agentMetrics)
+			if adminSrv != nil {
+				adminSrv.AddCheck("memory", func() admin.Component {
+					// 보호 단계에서도 agent는 동작 중이므로 ready는 유지한다.
+					return admin.Component{State: guard.Level().String(), Ready: true,
+						Detail: map[string]any{"usage_bytes": guard.Usage(), "soft_limit_bytes": soft, "hard_limit_bytes": hard}}
+				})
+			}
+			fmt.Printf("[+] Memory guard: soft %d MiB, hard %d MiB\n", soft>>20, hard>>20)
+		}
+	}
+
+	if adminSrv != nil {
+		registerHealthChecks(adminSrv, bpfErr, sslErr, resolver, sender, sinkNames, *dryRun)
+		if resolver != nil {
+			adminSrv.Handle("GET /debug/cache", resolver)
+		} else {
+			adminSrv.Handle("GET /debug/cache", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "k8s resolver disabled", http.StatusServiceUnavailable)
+			}))
+		}
 	}
 
 	fmt.Println("[*] Tracing socket I/O... Press Ctrl+C to stop.")
@@ -98,10 +464,12 @@ func main() {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
+	stopping := make(chan struct{})
 	go func() {
 		<-sig
 		fmt.Println("\n[*] Shutting down...")
-		loader.Close()
+		close(stopping)
+		source.Close()
 	}()
 
 	// nefi-agent 자신의 트래픽(gRPC 등)이 PROTO_HTTP로 오분류되어
@@ -109,63 +477,65 @@ func main() {
 	selfPID := uint32(os.Getpid())
 
 	for {
-		event, err := loader.Read()
+		// hard limit 초과 시 읽기를 멈춘다 — 그동안 커널 ringbuf가 넘치면 BPF 쪽에서 drop된다.
+		waitWhilePaused(guard, eventStats, stopping)
+
+		event, err := source.Read()
 
 		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
+			if errors.Is(err, ringbuf.ErrClosed) || errors.Is(err, procnet.ErrClosed) {
 				break
 			}
 			log.Printf("Error reading event: %v", err)
 			continue
 		}
 
+		eventStats.Captured()
 		comm := event.CommString()
 
 		if event.PID == selfPID {
 			continue
 		}
 
-		if event.Protocol != model.ProtoHTTP {
+		if event.Protocol != model.ProtoHTTP && !connOnly {
 			continue
 		}
 
-		// Resolve local pod (by PID → cgroup → UID).
-		podLabel := comm
-		if resolver != nil {
-			if pod := resolver.Resolve(event.PID); pod != nil {
-				podLabel = pod.Namespace + "/" + pod.PodName + " | " + comm
-			}
+		if guard != nil && !guard.Allow() {
+			eventStats.SampledOut()
+			continue
 		}
 
-		// Resolve remote pod (by remote IP → cluster-wide podsByIP).
+		te := agentgrpc.NewTraceEvent(event, nodeName)
+		te.Cluster = *clusterName
+		te.Connection = connOnly
+		if !pipeline.Enrich(event, te) {
+			agentgrpc.ReleaseTraceEvent(te)
+			continue
+		}
+
+		podLabel := comm
+		if te.PodName != "" {
+			podLabel = te.Namespace + "/" + te.PodName + " | " + comm
+		}
 		remoteLabel := event.RemoteIPString()
-		remoteNs, remotePodName := "", ""
-		if resolver != nil && event.RemoteIP != 0 {
-			if remotePod := resolver.ResolveIP(event.RemoteIP); remotePod != nil {
-				remoteNs = remotePod.Namespace
-				remotePodName = remotePod.PodName
-				remoteLabel = remoteNs + "/" + remotePodName
-			} else if svc := resolver.ResolveServiceIP(event.RemoteIP); svc != nil {
-				// ClusterIP DNAT 전 주소인 경우 서비스 이름으로 레이블 설정
-				remoteNs = svc.Namespace
-				remotePodName = svc.Name
-				remoteLabel = remoteNs + "/" + remotePodName
-			}
+		if te.RemotePod != "" {
+			remoteLabel = te.RemoteNs + "/" + te.RemotePod
+		} else if te.RemoteName != "" {
+			remoteLabel = te.RemoteName + " (" + remoteLabel + ")"
 		}
 		if event.RemotePort != 0 && remoteLabel != "" {
 			remoteLabel = fmt.Sprintf("%s:%d", remoteLabel, event.RemotePort)
 		}
 
-		// Forward to nefi-server if sender is active.
-		if sender != nil {
-			namespace, podName := "", ""
-			if resolver != nil {
-				if pod := resolver.Resolve(event.PID); pod != nil {
-					namespace = pod.Namespace
-					podName = pod.PodName
-				}
-			}
-			sender.Send(event, namespace, podName, remoteNs, remotePodName)
+		eventStats.Observe(te)
+
+		// Forward to the exporter (nefi-server or NDJSON) if active.
+		if exp != nil {
+			exp.Send(te)
+		}
+		if *dryRun || stdoutSink {
+			continue
 		}
 
 		// Print event with protocol, message type, and remote endpoint.
@@ -195,5 +565,192 @@ func main() {
 
 	}
 
+	if *dryRun {
+		printStats(eventStats.Snapshot())
+	}
 	fmt.Println("[*] Done.")
 }
+
+// backlogRatio 이상 전송 큐가 차 있으면 exporter를 backlogged로 보고한다.
+const backlogRatio = 0.8
+
+// envOr는 환경변수 key가 설정돼 있으면 그 값을, 아니면 def를 반환한다 (flag 기본값용).
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envDurationOr는 환경변수 key를 time.Duration으로 읽는다 (flag 기본값용).
+// 설정돼 있지 않으면 def를 반환하고, 형식이 잘못되면 종료한다.
+func envDurationOr(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return d
+}
+
+// envIntOr는 환경변수 key를 정수로 읽는다 (flag 기본값용).
+// 설정돼 있지 않으면 def를 반환하고, 형식이 잘못되면 종료한다.
+func envIntOr(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return n
+}
+
+// eventSource는 캡처 이벤트 공급원이다 (eBPF Loader 또는 procnet Poller).
+type eventSource interface {
+	Read() (*model.DataEvent, error)
+	Close()
+}
+
+// handshake는 server에 보고할 커널 버전, 활성 수집 방식, 노드 topology label, 클러스터 이름과 tenant를 모은다.
+func handshake(bpfErr, sslErr error, resolver *agentk8s.Resolver, cluster, tenant string) agentgrpc.Handshake {
+	hs := agentgrpc.Handshake{Cluster: cluster, Tenant: tenant}
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		hs.KernelVersion = unix.ByteSliceToString(uts.Release[:])
+	}
+	if bpfErr != nil {
+		hs.Probes = append(hs.Probes, "proc_fallback")
+	} else {
+		hs.Probes = append(hs.Probes, "tracepoints")
+		if sslErr == nil {
+			hs.Probes = append(hs.Probes, "ssl_uprobes")
+		}
+	}
+	if resolver != nil {
+		n := resolver.Node()
+		hs.NodeLabels = make(map[string]string)
+		for k, v := range map[string]string{"zone": n.Zone, "region": n.Region, "instance_type": n.InstanceType} {
+			if v != "" {
+				hs.NodeLabels[k] = v
+			}
+		}
+	}
+	return hs
+}
+
+// waitWhilePaused는 memory guard가 읽기를 멈춘 동안 블로킹하고 멈춘 시간을 st에 더한다
+// (종료 신호 시 즉시 반환).
+func waitWhilePaused(g *memguard.Guard, st *stats.Stats, stopping <-chan struct{}) {
+	if g == nil || !g.Paused() {
+		return
+	}
+	start := time.Now()
+	defer func() { st.Paused(time.Since(start)) }()
+	for g.Paused() {
+		select {
+		case <-stopping:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// registerHealthChecks는 /readyz에 ebpf, k8s, exporter 구성요소 상태를 등록한다.
+func registerHealthChecks(srv *admin.Server, bpfErr, sslErr error, resolver *agentk8s.Resolver, sender *agentgrpc.Sender, sinks []string, dryRun bool) {
+	srv.AddCheck("ebpf", func() admin.Component {
+		if bpfErr != nil {
+			// /proc/net fallback으로 연결 정보만 수집 중 — 동작은 하므로 ready는 유지한다.
+			return admin.Component{State: "fallback", Ready: true, Detail: map[string]any{"error": bpfErr.Error()}}
+		}
+		if sslErr != nil {
+			return admin.Component{State: "degraded", Ready: true, Detail: map[string]any{"ssl": sslErr.Error()}}
+		}
+		return admin.Component{State: "attached", Ready: true}
+	})
+	srv.AddCheck("k8s_cache", func() admin.Component {
+		if resolver == nil {
+			return admin.Component{State: "disabled", Ready: true}
+		}
+		lastSync, lastErr := resolver.SyncState()
+		detail := map[string]any{"last_sync": lastSync}
+		if !lastSync.IsZero() {
+			detail["age_sec"] = int(time.Since(lastSync).Seconds())
+		}
+		if lastErr != nil {
+			// 이전 캐시로 계속 해석하므로 ready는 유지한다.
+			detail["error"] = lastErr.Error()
+			detail["outage_since"] = resolver.OutageSince()
+			if lastSync.IsZero() {
+				return admin.Component{State: "syncing", Ready: true, Detail: detail} // 아직 한 번도 동기화 못 함
+			}
+			return admin.Component{State: "stale", Ready: true, Detail: detail}
+		}
+		return admin.Component{State: "synced", Ready: true, Detail: detail}
+	})
+	srv.AddCheck("exporter", func() admin.Component {
+		switch {
+		case dryRun:
+			return admin.Component{State: "dry-run", Ready: true}
+		case !slices.Contains(sinks, export.SinkGRPC):
+			return admin.Component{State: strings.Join(sinks, ","), Ready: true} // 로컬 NDJSON
+		case sender == nil:
+			return admin.Component{State: "disabled", Ready: true}
+		}
+		st := sender.State()
+		detail := map[string]any{"queue_depth": st.QueueDepth, "queue_capacity": st.QueueCap}
+		switch {
+		case !st.Connected:
+			return admin.Component{State: "disconnected", Ready: false, Detail: detail}
+		case float64(st.QueueDepth) >= backlogRatio*float64(st.QueueCap):
+			return admin.Component{State: "backlogged", Ready: true, Detail: detail}
+		}
+		return admin.Component{State: "connected", Ready: true, Detail: detail}
+	})
+}
+
+// logStats는 dry-run 중 주기적으로 누적 통계를 로그로 남긴다.
+func logStats(st *stats.Stats, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s := st.Snapshot()
+		log.Printf("[dry-run] captured=%d events=%d (%.1f/s) bytes=%d (%.0f B/s, avg %.0f B) pod=%d remote=%d external=%d",
+			s.Captured, s.Events, s.EventsPerSec, s.Bytes, s.BytesPerSec, s.AvgEventBytes,
+			s.PodResolved, s.RemoteResolved, s.RemoteExternal)
+	}
+}
+
+// printStats는 dry-run 종료 시 최종 통계를 출력한다.
+func printStats(s stats.Snapshot) {
+	fmt.Println("[*] Dry-run summary:")
+	fmt.Printf("    uptime        %.0fs\n", s.UptimeSec)
+	fmt.Printf("    captured      %d\n", s.Captured)
+	fmt.Printf("    exportable    %d events (%.1f/s), %d bytes (%.0f B/s)\n", s.Events, s.EventsPerSec, s.Bytes, s.BytesPerSec)
+	fmt.Printf("    enrichment    pod=%d remote=%d external=%d\n", s.PodResolved, s.RemoteResolved, s.RemoteExternal)
+	for _, p := range s.Protocols {
+		fmt.Printf("    %-13s %d events, %d bytes\n", p.Protocol, p.Events, p.Bytes)
+	}
+}
+
+// remoteFilter는 server 관리 런타임 설정의 namespace 제외/샘플링을 pipeline stage로 만든다.
+func remoteFilter(p *remotecfg.Poller) enrich.Enricher {
+	return enrich.Func("remote-config", func(_ *model.DataEvent, te *nefiv1.TraceEvent) bool {
+		return p.Allow(te.Namespace)
+	})
+}
+
+// splitList는 콤마로 구분된 flag 값을 공백을 제거한 목록으로 변환한다.
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	agentgrpc "github.com/gihongjo/nefi/internal/agent/grpc"
+	"github.com/gihongjo/nefi/internal/server/backfill"
+)
+
+// backfillMain은 "nefi-server backfill [flags] <source>..."를 실행한다: 이벤트 덤프를 실행 중인
+// nefi-server의 수집 경로로 다시 보낸다 (internal/server/backfill).
+func backfillMain(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: nefi-server backfill [flags] <file|dir|-|http(s)://...|s3://bucket/key|s3://bucket/prefix/>...\n\n"+
+			"Replays NDJSON (agent --exporter=file, /api/v1/ingest) or length-delimited EventBatch (.pb) dumps\n"+
+			"through a running server's normal ingestion path. S3 uses AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,\n"+
+			"AWS_SESSION_TOKEN, AWS_REGION and AWS_ENDPOINT_URL.\n\nflags:\n")
+		fs.PrintDefaults()
+	}
+	var cfg backfill.Config
+	var serverTLS agentgrpc.TLS
+	addr := fs.String("server-addr", envOr("SERVER_ADDR", "localhost:9090"), "nefi-server gRPC address to send events to; env SERVER_ADDR")
+	fs.BoolVar(&serverTLS.Enabled, "server-tls", envBoolOr("SERVER_TLS", false), "connect to --server-addr over TLS; env SERVER_TLS")
+	fs.StringVar(&serverTLS.CAFile, "server-tls-ca", envOr("SERVER_TLS_CA", ""), "PEM CA bundle to verify the server certificate with --server-tls (default: system roots); env SERVER_TLS_CA")
+	fs.StringVar(&serverTLS.ServerName, "server-tls-server-name", envOr("SERVER_TLS_SERVER_NAME", ""), "TLS server name (SNI) to verify instead of the --server-addr host; env SERVER_TLS_SERVER_NAME")
+	fs.StringVar(&serverTLS.CertFile, "server-tls-cert", envOr("SERVER_TLS_CERT", ""), "PEM client certificate to present when the server requires mTLS; env SERVER_TLS_CERT")
+	fs.StringVar(&serverTLS.KeyFile, "server-tls-key", envOr("SERVER_TLS_KEY", ""), "PEM private key for --server-tls-cert; env SERVER_TLS_KEY")
+	fs.StringVar(&cfg.Format, "format", backfill.FormatAuto, "dump format: auto (by extension: .pb/.binpb = proto, else NDJSON), ndjson or proto")
+	fs.IntVar(&cfg.BatchSize, "batch-size", backfill.DefaultBatchSize, "events per batch sent to the server (at most 10000)")
+	fs.Float64Var(&cfg.Rate, "rate", 0, "events per second to send (0 = as fast as the server accepts; throttled batches are retried after the server's hint)")
+	fs.IntVar(&cfg.Parallel, "parallel", 1, "sources sent concurrently")
+	fs.IntVar(&cfg.Repeat, "repeat", 1, "send every source this many times (load testing; each pass is stored again)")
+	fs.StringVar(&cfg.RunID, "run-id", backfill.DefaultRunID, "backfill run name; rerunning with the same name skips batches the server already stored")
+	fs.StringVar(&cfg.Node, "node-name", backfill.DefaultNode, "node name reported to the server; the server's per-node event rate limit applies to it")
+	fs.StringVar(&cfg.Cluster, "cluster-name", "", "cluster name for events that carry none")
+	fs.StringVar(&cfg.Tenant, "tenant", "", "tenant that owns the events (ignored when the server takes it from the client certificate)")
+	fs.Parse(args) //nolint:errcheck // ExitOnError
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := backfill.ValidFormat(cfg.Format); err != nil {
+		log.Fatalf("Invalid --format: %v", err)
+	}
+	creds, err := serverTLS.Credentials()
+	if err != nil {
+		log.Fatalf("Invalid --server-tls settings: %v", err)
+	}
+	cfg.Addr = *addr
+	cfg.Creds = creds
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	st, err := backfill.Run(ctx, cfg, fs.Args())
+	fmt.Printf("[*] backfill: %d sources, %d batches, %d events sent to %s\n", st.Sources, st.Batches, st.Events, cfg.Addr)
+	if err != nil {
+		log.Fatalf("backfill: %v", err)
+	}
+}
@@ -2,8 +2,10 @@
 //
 // 흐름:
 //
-//	agent -[gRPC stream]-> CollectorService -> Store -> Hub -[WebSocket]-> browser/mobile
-//	                                                 -> Aggregator -[WebSocket stats]-> browser/mobile
+//	agent -[gRPC stream]-> CollectorService -> enrich.Pipeline -> Store -> Hub -[WebSocket]-> browser/mobile
+//	                                                                    -> Aggregator -[WebSocket stats]-> browser/mobile
+//
+// "nefi-server backfill"은 이벤트 덤프(파일, S3)를 실행 중인 server의 수집 경로로 다시 보낸다 (backfill.go).
 package main
 
 import (
@@ -11,23 +13,165 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/gihongjo/nefi/internal/agent/netclass"
+	"github.com/gihongjo/nefi/internal/configz"
 	"github.com/gihongjo/nefi/internal/server/app"
+	"github.com/gihongjo/nefi/internal/server/archive"
+	"github.com/gihongjo/nefi/internal/server/audit"
+	"github.com/gihongjo/nefi/internal/server/collector"
+	"github.com/gihongjo/nefi/internal/server/enrich"
+	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/version"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		backfillMain(os.Args[2:])
+		return
+	}
 	cfg := app.Config{}
+	flag.StringVar(&cfg.Mode, "mode", app.ModeAll, "server mode: \"all\" (ingest + query) or \"query\" (API only, no ingestion or background workers; needs a storage backend shared between servers, which none of the current backends are)")
 	flag.StringVar(&cfg.GRPCAddr, "grpc-addr", ":9090", "gRPC listen address (agent → server)")
 	flag.StringVar(&cfg.HTTPAddr, "http-addr", ":8080", "HTTP listen address (WebSocket /ws, API /api/...)")
 	flag.IntVar(&cfg.Capacity, "capacity", 10000, "in-memory ring buffer capacity")
+	flag.StringVar(&cfg.Storage, "storage", envOr("STORAGE", store.BackendMemory), "event storage: \"memory\" (lost on restart) or \"embedded\" (also appended to a file in --data-dir and restored on restart; single server only); env STORAGE")
+	flag.StringVar(&cfg.DataDir, "data-dir", envOr("DATA_DIR", "nefi-data"), "directory for --storage=embedded; env DATA_DIR")
+	flag.DurationVar(&cfg.Retention, "retention", envDurationOr("RETENTION", 0), "drop stored events older than this, whichever of --capacity and --retention is reached first (0 = keep until the ring buffer wraps); env RETENTION")
+	flag.StringVar(&cfg.Archive.Dest, "archive", envOr("ARCHIVE", ""), "archive stored events as Parquet files partitioned by day and cluster to s3://bucket/prefix (S3-compatible stores via AWS_ENDPOINT_URL) or a local directory; empty = off; env ARCHIVE")
+	flag.DurationVar(&cfg.Archive.Interval, "archive-interval", envDurationOr("ARCHIVE_INTERVAL", archive.DefaultInterval), "how often buffered events are written to archive files; env ARCHIVE_INTERVAL")
+	flag.DurationVar(&cfg.Archive.Retention, "archive-retention", envDurationOr("ARCHIVE_RETENTION", 0), "delete archive day partitions older than this, checked hourly (0 = keep forever; on S3 a bucket lifecycle rule is cheaper); env ARCHIVE_RETENTION")
+	flag.IntVar(&cfg.Archive.MaxRows, "archive-max-rows", envIntOr("ARCHIVE_MAX_ROWS", archive.DefaultMaxRows), "largest archive file in events; a partition reaching it is written before the interval ends; env ARCHIVE_MAX_ROWS")
+	flag.Float64Var(&cfg.Collector.AdmitRate, "stream-admit-rate", 20, "new agent streams accepted per second (0 = unlimited)")
+	flag.IntVar(&cfg.Collector.AdmitBurst, "stream-admit-burst", 50, "new agent streams accepted in a burst before pacing applies")
+	flag.Float64Var(&cfg.Collector.NodeEventRate, "node-event-rate", envFloatOr("NODE_EVENT_RATE", 0), "events per second accepted from one node; excess batches are rejected with a retry hint and resent by the agent (0 = unlimited); env NODE_EVENT_RATE")
+	flag.IntVar(&cfg.Collector.NodeEventBurst, "node-event-burst", envIntOr("NODE_EVENT_BURST", 20000), "events accepted from one node in a burst above --node-event-rate; env NODE_EVENT_BURST")
+	flag.Float64Var(&cfg.Collector.GlobalEventRate, "global-event-rate", envFloatOr("GLOBAL_EVENT_RATE", 0), "events per second accepted from all agents together (0 = unlimited); env GLOBAL_EVENT_RATE")
+	flag.IntVar(&cfg.Collector.GlobalEventBurst, "global-event-burst", envIntOr("GLOBAL_EVENT_BURST", 200000), "events accepted from all agents in a burst above --global-event-rate; env GLOBAL_EVENT_BURST")
+	flag.Float64Var(&cfg.Collector.ConnSampleRate, "conn-sample-rate", envFloatOr("CONN_SAMPLE_RATE", 0), "connection events per second stored per --conn-sample-by key; excess events are sampled out before storage but still counted by live stats (0 = store all); env CONN_SAMPLE_RATE")
+	flag.StringVar(&cfg.Collector.ConnSampleBy, "conn-sample-by", envOr("CONN_SAMPLE_BY", collector.SampleByService), "key for --conn-sample-rate: \"service\" (namespace/workload) or \"node\"; env CONN_SAMPLE_BY")
+	flag.IntVar(&cfg.Collector.IngestWorkers, "ingest-workers", envIntOr("INGEST_WORKERS", 0), "workers storing received batches; batches from one node always go to the same worker (0 = GOMAXPROCS); env INGEST_WORKERS")
+	flag.IntVar(&cfg.Collector.IngestQueueBatches, "ingest-queue-batches", envIntOr("INGEST_QUEUE_BATCHES", 256), "batches queued per ingest worker before new batches are rejected with a retry hint; env INGEST_QUEUE_BATCHES")
+	flag.DurationVar(&cfg.Keepalive.Time, "grpc-keepalive-time", envDurationOr("GRPC_KEEPALIVE_TIME", time.Minute), "ping idle agent connections this often to detect half-open connections; env GRPC_KEEPALIVE_TIME")
+	flag.DurationVar(&cfg.Keepalive.Timeout, "grpc-keepalive-timeout", envDurationOr("GRPC_KEEPALIVE_TIMEOUT", 20*time.Second), "close an agent connection whose keepalive ping is unanswered this long; env GRPC_KEEPALIVE_TIMEOUT")
+	flag.DurationVar(&cfg.Keepalive.MinClientTime, "grpc-keepalive-min-time", envDurationOr("GRPC_KEEPALIVE_MIN_TIME", 15*time.Second), "minimum agent keepalive ping interval; agents pinging more often are disconnected; env GRPC_KEEPALIVE_MIN_TIME")
+	flag.DurationVar(&cfg.Keepalive.MaxConnAge, "grpc-max-connection-age", envDurationOr("GRPC_MAX_CONNECTION_AGE", 0), "close agent connections after this long so agents reconnect and rebalance across replicas; 0 = never; env GRPC_MAX_CONNECTION_AGE")
+	flag.DurationVar(&cfg.Keepalive.MaxConnAgeGrace, "grpc-max-connection-age-grace", envDurationOr("GRPC_MAX_CONNECTION_AGE_GRACE", 30*time.Second), "time an agent stream gets to finish after --grpc-max-connection-age; env GRPC_MAX_CONNECTION_AGE_GRACE")
+	flag.StringVar(&cfg.TLS.CertFile, "grpc-tls-cert", envOr("GRPC_TLS_CERT", ""), "PEM server certificate; serves gRPC over TLS when set; env GRPC_TLS_CERT")
+	flag.StringVar(&cfg.TLS.KeyFile, "grpc-tls-key", envOr("GRPC_TLS_KEY", ""), "PEM private key for --grpc-tls-cert; env GRPC_TLS_KEY")
+	flag.StringVar(&cfg.TLS.ClientCAFile, "grpc-tls-client-ca", envOr("GRPC_TLS_CLIENT_CA", ""), "PEM CA bundle that agent client certificates must chain to (mTLS); the certificate's URI/DNS SAN or CN is recorded as agent_identity on every event; env GRPC_TLS_CLIENT_CA")
+	flag.IntVar(&cfg.GRPCMaxRecvMsgSize, "grpc-max-recv-bytes", envIntOr("GRPC_MAX_RECV_BYTES", 16<<20), "largest gRPC message (one event batch) accepted from an agent, in bytes; env GRPC_MAX_RECV_BYTES")
+	flag.BoolVar(&cfg.GRPCReflection, "grpc-reflection", envBoolOr("GRPC_REFLECTION", false), "register gRPC server reflection so tools like grpcurl can list and call NefiCollector; env GRPC_REFLECTION")
+	flag.BoolVar(&cfg.Tenants.Isolation, "tenant-isolation", envBoolOr("TENANT_ISOLATION", false), "require an X-Nefi-Tenant header (set by an authenticating proxy) on API and WebSocket requests and return only that tenant's data; env TENANT_ISOLATION")
+	flag.StringVar(&cfg.Tenants.Operator, "operator-tenant", envOr("OPERATOR_TENANT", ""), "tenant that sees every tenant's data and may use fleet-wide admin endpoints under --tenant-isolation; env OPERATOR_TENANT")
+	flag.DurationVar(&cfg.Collector.StreamIdleTimeout, "stream-idle-timeout", envDurationOr("STREAM_IDLE_TIMEOUT", 0), "close agent streams that send no batch for this long; live agents reconnect and resend unacknowledged batches, so quiet nodes reconnect once per period (0 = never close); env STREAM_IDLE_TIMEOUT")
+	flag.DurationVar(&cfg.AgentStaleAfter, "agent-stale-after", envDurationOr("AGENT_STALE_AFTER", 2*time.Minute), "mark a connected agent stale when no batch arrives for this long; env AGENT_STALE_AFTER")
+	enrichers := flag.String("enrichers", enrich.DefaultStages, "ordered, comma-separated server enrichment stages (cluster, external, geoip, route); unconfigured stages are skipped")
+	defaultCluster := flag.String("default-cluster-name", envOr("DEFAULT_CLUSTER_NAME", ""), "cluster name the cluster enricher stamps on events whose agent sent none; env DEFAULT_CLUSTER_NAME")
+	var classCfg netclass.Config
+	flag.StringVar(&classCfg.CIDRFile, "external-cidrs", "", "file of \"<cidr|ip> <name>\" lines naming external endpoints for the external enricher")
+	flag.StringVar(&classCfg.AWSRanges, "aws-ip-ranges", "", "path to AWS ip-ranges.json; the external enricher names remotes in AWS ranges")
+	flag.StringVar(&classCfg.GCPRanges, "gcp-ip-ranges", "", "path to GCP cloud.json; the external enricher names remotes in GCP ranges")
+	flag.StringVar(&classCfg.AzureRange, "azure-ip-ranges", "", "path to Azure ServiceTags JSON; the external enricher names remotes in Azure ranges")
+	geoIPFile := flag.String("geoip-cidrs", "", "file of \"<cidr> <country>\" lines for the geoip enricher (label "+enrich.GeoLabelCountry+" on external remotes)")
+	routeRulesFile := flag.String("route-rules", envOr("ROUTE_RULES", ""), "file of \"<regexp> <replacement>\" lines the route enricher applies to HTTP paths before templating numeric and UUID segments (e.g. \"^/files/.* /files/{path}\"); env ROUTE_RULES")
+	auditLog := flag.String("audit-log", envOr("AUDIT_LOG", ""), "append ingestion audit records (agent sessions, batches, rejections, TLS auth failures) as JSON lines to this file, \"-\" for stdout (default: kept in memory only); env AUDIT_LOG")
+	flag.IntVar(&cfg.AuditCapacity, "audit-capacity", envIntOr("AUDIT_CAPACITY", audit.DefaultCapacity), "audit records kept in memory for /api/v1/admin/audit; env AUDIT_CAPACITY")
+	flag.BoolVar(&cfg.HTTPIngest, "http-ingest", envBoolOr("HTTP_INGEST", false), "accept EventBatch JSON/NDJSON on POST /api/v1/ingest on the HTTP port; the endpoint is unauthenticated and takes the tenant from the x-nefi-tenant header, so it is refused with --grpc-tls-client-ca; env HTTP_INGEST")
+	flag.BoolVar(&cfg.Demo, "demo", false, "generate synthetic demo traffic (fake services, errors, incidents) through the normal ingestion pipeline")
+	flag.Float64Var(&cfg.DemoRate, "demo-rate", 5, "demo entry-point requests per second")
 	flag.Parse()
+	cfg.Configz = configz.FromFlags(flag.CommandLine)
+
+	if err := collector.ValidSampleBy(cfg.Collector.ConnSampleBy); err != nil {
+		log.Fatalf("Invalid --conn-sample-by: %v", err)
+	}
+	switch *auditLog {
+	case "":
+	case "-":
+		cfg.AuditOutput = os.Stdout
+	default:
+		f, err := os.OpenFile(*auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			log.Fatalf("Failed to open --audit-log: %v", err)
+		}
+		defer f.Close()
+		cfg.AuditOutput = f
+	}
+	stageNames, err := enrich.ParseStages(*enrichers)
+	if err != nil {
+		log.Fatalf("Invalid --enrichers: %v", err)
+	}
+	for _, name := range stageNames {
+		switch name {
+		case enrich.StageCluster:
+			cfg.Collector.Enrichers = append(cfg.Collector.Enrichers, enrich.Cluster(*defaultCluster))
+		case enrich.StageExternal:
+			classifier, err := netclass.New(classCfg)
+			if err != nil {
+				log.Fatalf("Failed to load external classification: %v", err)
+			}
+			cfg.Collector.Enrichers = append(cfg.Collector.Enrichers, enrich.External(classifier))
+		case enrich.StageGeoIP:
+			if *geoIPFile == "" {
+				continue
+			}
+			geo, err := netclass.New(netclass.Config{CIDRFile: *geoIPFile})
+			if err != nil {
+				log.Fatalf("Failed to load GeoIP mapping: %v", err)
+			}
+			cfg.Collector.Enrichers = append(cfg.Collector.Enrichers, enrich.GeoIP(geo))
+		case enrich.StageRoute:
+			var rules []enrich.RouteRule
+			if *routeRulesFile != "" {
+				if rules, err = enrich.LoadRouteRules(*routeRulesFile); err != nil {
+					log.Fatalf("Failed to load --route-rules: %v", err)
+				}
+			}
+			cfg.Collector.Enrichers = append(cfg.Collector.Enrichers, enrich.Route(rules))
+		}
+	}
 
 	fmt.Println("============================================================")
 	fmt.Println("  Nefi Server — gRPC Collector + WebSocket Hub")
 	fmt.Println("============================================================")
-	fmt.Printf("[+] gRPC: %s  HTTP: %s  capacity: %d\n", cfg.GRPCAddr, cfg.HTTPAddr, cfg.Capacity)
+	vi := version.Get()
+	fmt.Printf("[*] version=%s commit=%s built=%s schema=%d\n", vi.Version, vi.GitCommit, vi.BuildDate, vi.SchemaVersion)
+	fmt.Printf("[+] mode: %s  gRPC: %s  HTTP: %s  capacity: %d\n", cfg.Mode, cfg.GRPCAddr, cfg.HTTPAddr, cfg.Capacity)
+	if cfg.Storage == store.BackendEmbedded {
+		fmt.Printf("[+] storage: embedded (%s)\n", cfg.DataDir)
+	}
+	if cfg.Archive.Dest != "" {
+		fmt.Printf("[+] archive: %s every %s\n", cfg.Archive.Dest, cfg.Archive.Interval)
+		if cfg.Archive.Retention > 0 {
+			fmt.Printf("[+] archive retention: %s\n", cfg.Archive.Retention)
+		}
+	}
+	if cfg.Retention > 0 {
+		fmt.Printf("[+] retention: %s\n", cfg.Retention)
+	}
+	fmt.Printf("[+] enrichers: %s\n", strings.Join(enrich.New(cfg.Collector.Enrichers...).Names(), ","))
+	if cfg.Collector.ConnSampleRate > 0 {
+		fmt.Printf("[+] connection event sampling: %.0f events/s per %s\n", cfg.Collector.ConnSampleRate, cfg.Collector.ConnSampleBy)
+	}
+	if *auditLog != "" {
+		fmt.Printf("[+] audit log: %s\n", *auditLog)
+	}
+	if cfg.Tenants.Isolation {
+		fmt.Printf("[+] tenant isolation: on (operator tenant %q)\n", cfg.Tenants.Operator)
+	}
+	if cfg.HTTPIngest {
+		fmt.Printf("[+] HTTP ingest: POST %s/api/v1/ingest (unauthenticated)\n", cfg.HTTPAddr)
+	}
+	if cfg.Demo {
+		fmt.Printf("[+] demo traffic: %.1f req/s (synthetic, no cluster required)\n", cfg.DemoRate)
+	}
 
 	srv, err := app.New(cfg)
 	if err != nil {
@@ -42,3 +186,67 @@ func main() {
 	}
 	fmt.Println("[*] Done.")
 }
+
+// envOr는 환경변수 key가 설정돼 있으면 그 값을, 아니면 def를 반환한다 (flag 기본값용).
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envIntOr는 환경변수 key를 정수로 읽는다 (flag 기본값용).
+// 설정돼 있지 않으면 def를 반환하고, 형식이 잘못되면 종료한다.
+func envIntOr(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return n
+}
+
+// envFloatOr는 환경변수 key를 실수로 읽는다 (flag 기본값용).
+// 설정돼 있지 않으면 def를 반환하고, 형식이 잘못되면 종료한다.
+func envFloatOr(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return f
+}
+
+// envBoolOr는 환경변수 key를 bool로 읽는다 (flag 기본값용).
+// 설정돼 있지 않으면 def를 반환하고, 형식이 잘못되면 종료한다.
+func envBoolOr(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return b
+}
+
+// envDurationOr는 환경변수 key를 time.Duration으로 읽는다 (flag 기본값용).
+// 설정돼 있지 않으면 def를 반환하고, 형식이 잘못되면 종료한다.
+func envDurationOr(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return d
+}
@@ -66,16 +66,553 @@ func (x *CollectSummary) GetReceived() uint64 {
 	return 0
 }
 
+// EventBatch는 SendBatch로 한 번에 전송하는 이벤트 묶음이다.
+type EventBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*TraceEvent          `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`                                     // 최대 10000개
+	Seq           uint64                 `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`                                          // agent가 붙이는 번호 (1부터 증가, 외부 producer의 SendBatch는 0)
+	SchemaVersion uint32                 `protobuf:"varint,3,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"` // events의 인코딩 스키마 (0 = 보내지 않음, 메타데이터의 버전을 따른다)
+	Strings       []string               `protobuf:"bytes,4,rep,name=strings,proto3" json:"strings,omitempty"`                                   // 문자열 사전 ("dict" 기능): events의 dict_refs가 가리키는 값
+	Load          *LoadReport            `protobuf:"bytes,5,opt,name=load,proto3" json:"load,omitempty"`                                         // agent 부하 보고 (몇 초마다 batch 하나에만 싣는다)
+	// agent 프로세스마다 새로 만드는 UUID. (producer_id, seq)가 batch ID이며, server는
+	// producer별로 최근 1024개 seq 안에서 이미 저장한 batch를 다시 저장하지 않고 ack만 한다
+	// (재전송 중복 제거). 그보다 오래된 seq의 batch는 중복으로 본다.
+	// 비어 있으면(외부 producer, 구버전 agent) 중복을 제거하지 않는다.
+	ProducerId    string `protobuf:"bytes,6,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventBatch) Reset() {
+	*x = EventBatch{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventBatch) ProtoMessage() {}
+
+func (x *EventBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventBatch.ProtoReflect.Descriptor instead.
+func (*EventBatch) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EventBatch) GetEvents() []*TraceEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *EventBatch) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *EventBatch) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *EventBatch) GetStrings() []string {
+	if x != nil {
+		return x.Strings
+	}
+	return nil
+}
+
+func (x *EventBatch) GetLoad() *LoadReport {
+	if x != nil {
+		return x.Load
+	}
+	return nil
+}
+
+func (x *EventBatch) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+// LoadReport는 agent의 수집/전송 부하다. server는 노드별 마지막 보고를 모아
+// 데이터 유실이 생기는 노드를 보여준다. 카운터는 agent 시작 이후 누적이다.
+type LoadReport struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	EventsPerSec     float64                `protobuf:"fixed64,1,opt,name=events_per_sec,json=eventsPerSec,proto3" json:"events_per_sec,omitempty"`             // 직전 보고 이후 전송 큐에 들어온 이벤트/초
+	Captured         uint64                 `protobuf:"varint,2,opt,name=captured,proto3" json:"captured,omitempty"`                                            // eBPF에서 읽은 이벤트 (필터 전)
+	Enqueued         uint64                 `protobuf:"varint,3,opt,name=enqueued,proto3" json:"enqueued,omitempty"`                                            // 전송 큐에 들어온 이벤트
+	QueueDropped     uint64                 `protobuf:"varint,4,opt,name=queue_dropped,json=queueDropped,proto3" json:"queue_dropped,omitempty"`                // 전송 큐가 가득 차 버린 이벤트
+	QueueDepth       uint32                 `protobuf:"varint,5,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`                      // 현재 전송 큐 깊이
+	QueueCapacity    uint32                 `protobuf:"varint,6,opt,name=queue_capacity,json=queueCapacity,proto3" json:"queue_capacity,omitempty"`             // 현재 전송 큐 상한 (메모리 보호로 줄어들 수 있다)
+	UnackedEvents    uint64                 `protobuf:"varint,7,opt,name=unacked_events,json=unackedEvents,proto3" json:"unacked_events,omitempty"`             // 보냈지만 ack받지 못한 이벤트
+	SampledOut       uint64                 `protobuf:"varint,8,opt,name=sampled_out,json=sampledOut,proto3" json:"sampled_out,omitempty"`                      // 메모리 보호가 export 전에 버린 이벤트
+	CapturePausedSec float64                `protobuf:"fixed64,9,opt,name=capture_paused_sec,json=capturePausedSec,proto3" json:"capture_paused_sec,omitempty"` // 메모리 보호로 캡처를 멈춘 시간 (그동안 커널 ringbuf가 넘친다)
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LoadReport) Reset() {
+	*x = LoadReport{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadReport) ProtoMessage() {}
+
+func (x *LoadReport) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadReport.ProtoReflect.Descriptor instead.
+func (*LoadReport) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LoadReport) GetEventsPerSec() float64 {
+	if x != nil {
+		return x.EventsPerSec
+	}
+	return 0
+}
+
+func (x *LoadReport) GetCaptured() uint64 {
+	if x != nil {
+		return x.Captured
+	}
+	return 0
+}
+
+func (x *LoadReport) GetEnqueued() uint64 {
+	if x != nil {
+		return x.Enqueued
+	}
+	return 0
+}
+
+func (x *LoadReport) GetQueueDropped() uint64 {
+	if x != nil {
+		return x.QueueDropped
+	}
+	return 0
+}
+
+func (x *LoadReport) GetQueueDepth() uint32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *LoadReport) GetQueueCapacity() uint32 {
+	if x != nil {
+		return x.QueueCapacity
+	}
+	return 0
+}
+
+func (x *LoadReport) GetUnackedEvents() uint64 {
+	if x != nil {
+		return x.UnackedEvents
+	}
+	return 0
+}
+
+func (x *LoadReport) GetSampledOut() uint64 {
+	if x != nil {
+		return x.SampledOut
+	}
+	return 0
+}
+
+func (x *LoadReport) GetCapturePausedSec() float64 {
+	if x != nil {
+		return x.CapturePausedSec
+	}
+	return 0
+}
+
+// BatchAck는 StreamBatches에서 server가 batch를 저장했음을 알린다.
+// batch는 순서대로 처리되므로 seq 이하의 모든 batch가 저장된 것이다 (누적 ack).
+type BatchAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchAck) Reset() {
+	*x = BatchAck{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchAck) ProtoMessage() {}
+
+func (x *BatchAck) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchAck.ProtoReflect.Descriptor instead.
+func (*BatchAck) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchAck) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+// NegotiateRequest는 agent가 지원하는 스키마 버전과 기능이다.
+type NegotiateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeName      string                 `protobuf:"bytes,1,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	SchemaVersion uint32                 `protobuf:"varint,2,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"` // agent의 최신 스키마 버전
+	Features      []string               `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`                                 // agent가 쓸 수 있는 기능 (예: "batch_ack", "gzip")
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateRequest) Reset() {
+	*x = NegotiateRequest{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateRequest) ProtoMessage() {}
+
+func (x *NegotiateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateRequest.ProtoReflect.Descriptor instead.
+func (*NegotiateRequest) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *NegotiateRequest) GetNodeName() string {
+	if x != nil {
+		return x.NodeName
+	}
+	return ""
+}
+
+func (x *NegotiateRequest) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *NegotiateRequest) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+// NegotiateResponse는 이 연결에서 쓸 스키마 버전과 기능이다.
+type NegotiateResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	SchemaVersion       uint32                 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"` // 합의된 스키마 (agent와 server 중 낮은 쪽)
+	ServerSchemaVersion uint32                 `protobuf:"varint,2,opt,name=server_schema_version,json=serverSchemaVersion,proto3" json:"server_schema_version,omitempty"`
+	MinSchemaVersion    uint32                 `protobuf:"varint,3,opt,name=min_schema_version,json=minSchemaVersion,proto3" json:"min_schema_version,omitempty"` // server가 받는 가장 오래된 스키마
+	Features            []string               `protobuf:"bytes,4,rep,name=features,proto3" json:"features,omitempty"`                                            // 요청한 기능 중 server도 지원하는 것
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *NegotiateResponse) Reset() {
+	*x = NegotiateResponse{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateResponse) ProtoMessage() {}
+
+func (x *NegotiateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateResponse.ProtoReflect.Descriptor instead.
+func (*NegotiateResponse) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NegotiateResponse) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *NegotiateResponse) GetServerSchemaVersion() uint32 {
+	if x != nil {
+		return x.ServerSchemaVersion
+	}
+	return 0
+}
+
+func (x *NegotiateResponse) GetMinSchemaVersion() uint32 {
+	if x != nil {
+		return x.MinSchemaVersion
+	}
+	return 0
+}
+
+func (x *NegotiateResponse) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+// AgentConfigRequest는 agent가 현재 적용 중인 설정 revision을 알린다.
+type AgentConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeName      string                 `protobuf:"bytes,1,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"` // 이 agent의 노드 이름
+	Revision      uint64                 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`                // 현재 적용된 AgentConfig.revision (0 = 아직 없음)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentConfigRequest) Reset() {
+	*x = AgentConfigRequest{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentConfigRequest) ProtoMessage() {}
+
+func (x *AgentConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentConfigRequest.ProtoReflect.Descriptor instead.
+func (*AgentConfigRequest) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AgentConfigRequest) GetNodeName() string {
+	if x != nil {
+		return x.NodeName
+	}
+	return ""
+}
+
+func (x *AgentConfigRequest) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+// AgentConfig는 server가 agent에 내려주는 런타임 설정이다.
+// revision이 요청의 revision과 같으면 agent는 적용을 건너뛴다.
+type AgentConfig struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Revision uint64                 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"` // 설정이 바뀔 때마다 증가 (0 = server 기본값, 미설정)
+	// sample_rate는 export할 이벤트 비율이다 (0 < rate ≤ 1). 0은 agent 기본값(1)을 뜻한다.
+	SampleRate float64 `protobuf:"fixed64,2,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	// exclude_namespaces의 pod에서 발생한 이벤트는 export하지 않는다.
+	ExcludeNamespaces []string `protobuf:"bytes,3,rep,name=exclude_namespaces,json=excludeNamespaces,proto3" json:"exclude_namespaces,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AgentConfig) Reset() {
+	*x = AgentConfig{}
+	mi := &file_nefi_v1_collector_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentConfig) ProtoMessage() {}
+
+func (x *AgentConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_nefi_v1_collector_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentConfig.ProtoReflect.Descriptor instead.
+func (*AgentConfig) Descriptor() ([]byte, []int) {
+	return file_nefi_v1_collector_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AgentConfig) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *AgentConfig) GetSampleRate() float64 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *AgentConfig) GetExcludeNamespaces() []string {
+	if x != nil {
+		return x.ExcludeNamespaces
+	}
+	return nil
+}
+
 var File_nefi_v1_collector_proto protoreflect.FileDescriptor
 
 const file_nefi_v1_collector_proto_rawDesc = "" +
 	"\n" +
 	"\x17nefi/v1/collector.proto\x12\anefi.v1\x1a\x14nefi/v1/events.proto\",\n" +
 	"\x0eCollectSummary\x12\x1a\n" +
-	"\breceived\x18\x01 \x01(\x04R\breceived2M\n" +
+	"\breceived\x18\x01 \x01(\x04R\breceived\"\xd6\x01\n" +
+	"\n" +
+	"EventBatch\x12+\n" +
+	"\x06events\x18\x01 \x03(\v2\x13.nefi.v1.TraceEventR\x06events\x12\x10\n" +
+	"\x03seq\x18\x02 \x01(\x04R\x03seq\x12%\n" +
+	"\x0eschema_version\x18\x03 \x01(\rR\rschemaVersion\x12\x18\n" +
+	"\astrings\x18\x04 \x03(\tR\astrings\x12'\n" +
+	"\x04load\x18\x05 \x01(\v2\x13.nefi.v1.LoadReportR\x04load\x12\x1f\n" +
+	"\vproducer_id\x18\x06 \x01(\tR\n" +
+	"producerId\"\xcd\x02\n" +
+	"\n" +
+	"LoadReport\x12$\n" +
+	"\x0eevents_per_sec\x18\x01 \x01(\x01R\feventsPerSec\x12\x1a\n" +
+	"\bcaptured\x18\x02 \x01(\x04R\bcaptured\x12\x1a\n" +
+	"\benqueued\x18\x03 \x01(\x04R\benqueued\x12#\n" +
+	"\rqueue_dropped\x18\x04 \x01(\x04R\fqueueDropped\x12\x1f\n" +
+	"\vqueue_depth\x18\x05 \x01(\rR\n" +
+	"queueDepth\x12%\n" +
+	"\x0equeue_capacity\x18\x06 \x01(\rR\rqueueCapacity\x12%\n" +
+	"\x0eunacked_events\x18\a \x01(\x04R\runackedEvents\x12\x1f\n" +
+	"\vsampled_out\x18\b \x01(\x04R\n" +
+	"sampledOut\x12,\n" +
+	"\x12capture_paused_sec\x18\t \x01(\x01R\x10capturePausedSec\"\x1c\n" +
+	"\bBatchAck\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\"r\n" +
+	"\x10NegotiateRequest\x12\x1b\n" +
+	"\tnode_name\x18\x01 \x01(\tR\bnodeName\x12%\n" +
+	"\x0eschema_version\x18\x02 \x01(\rR\rschemaVersion\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x03(\tR\bfeatures\"\xb8\x01\n" +
+	"\x11NegotiateResponse\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\rR\rschemaVersion\x122\n" +
+	"\x15server_schema_version\x18\x02 \x01(\rR\x13serverSchemaVersion\x12,\n" +
+	"\x12min_schema_version\x18\x03 \x01(\rR\x10minSchemaVersion\x12\x1a\n" +
+	"\bfeatures\x18\x04 \x03(\tR\bfeatures\"M\n" +
+	"\x12AgentConfigRequest\x12\x1b\n" +
+	"\tnode_name\x18\x01 \x01(\tR\bnodeName\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x04R\brevision\"y\n" +
+	"\vAgentConfig\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12\x1f\n" +
+	"\vsample_rate\x18\x02 \x01(\x01R\n" +
+	"sampleRate\x12-\n" +
+	"\x12exclude_namespaces\x18\x03 \x03(\tR\x11excludeNamespaces2\xce\x02\n" +
 	"\rNefiCollector\x12<\n" +
 	"\n" +
-	"SendEvents\x12\x13.nefi.v1.TraceEvent\x1a\x17.nefi.v1.CollectSummary(\x01B/Z-github.com/gihongjo/nefi/proto/nefi/v1;nefiv1b\x06proto3"
+	"SendEvents\x12\x13.nefi.v1.TraceEvent\x1a\x17.nefi.v1.CollectSummary(\x01\x12C\n" +
+	"\x0eGetAgentConfig\x12\x1b.nefi.v1.AgentConfigRequest\x1a\x14.nefi.v1.AgentConfig\x129\n" +
+	"\tSendBatch\x12\x13.nefi.v1.EventBatch\x1a\x17.nefi.v1.CollectSummary\x12;\n" +
+	"\rStreamBatches\x12\x13.nefi.v1.EventBatch\x1a\x11.nefi.v1.BatchAck(\x010\x01\x12B\n" +
+	"\tNegotiate\x12\x19.nefi.v1.NegotiateRequest\x1a\x1a.nefi.v1.NegotiateResponseB/Z-github.com/gihongjo/nefi/proto/nefi/v1;nefiv1b\x06proto3"
 
 var (
 	file_nefi_v1_collector_proto_rawDescOnce sync.Once
@@ -89,19 +626,36 @@ func file_nefi_v1_collector_proto_rawDescGZIP() []byte {
 	return file_nefi_v1_collector_proto_rawDescData
 }
 
-var file_nefi_v1_collector_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_nefi_v1_collector_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_nefi_v1_collector_proto_goTypes = []any{
-	(*CollectSummary)(nil), // 0: nefi.v1.CollectSummary
-	(*TraceEvent)(nil),     // 1: nefi.v1.TraceEvent
+	(*CollectSummary)(nil),     // 0: nefi.v1.CollectSummary
+	(*EventBatch)(nil),         // 1: nefi.v1.EventBatch
+	(*LoadReport)(nil),         // 2: nefi.v1.LoadReport
+	(*BatchAck)(nil),           // 3: nefi.v1.BatchAck
+	(*NegotiateRequest)(nil),   // 4: nefi.v1.NegotiateRequest
+	(*NegotiateResponse)(nil),  // 5: nefi.v1.NegotiateResponse
+	(*AgentConfigRequest)(nil), // 6: nefi.v1.AgentConfigRequest
+	(*AgentConfig)(nil),        // 7: nefi.v1.AgentConfig
+	(*TraceEvent)(nil),         // 8: nefi.v1.TraceEvent
 }
 var file_nefi_v1_collector_proto_depIdxs = []int32{
-	1, // 0: nefi.v1.NefiCollector.SendEvents:input_type -> nefi.v1.TraceEvent
-	0, // 1: nefi.v1.NefiCollector.SendEvents:output_type -> nefi.v1.CollectSummary
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	8, // 0: nefi.v1.EventBatch.events:type_name -> nefi.v1.TraceEvent
+	2, // 1: nefi.v1.EventBatch.load:type_name -> nefi.v1.LoadReport
+	8, // 2: nefi.v1.NefiCollector.SendEvents:input_type -> nefi.v1.TraceEvent
+	6, // 3: nefi.v1.NefiCollector.GetAgentConfig:input_type -> nefi.v1.AgentConfigRequest
+	1, // 4: nefi.v1.NefiCollector.SendBatch:input_type -> nefi.v1.EventBatch
+	1, // 5: nefi.v1.NefiCollector.StreamBatches:input_type -> nefi.v1.EventBatch
+	4, // 6: nefi.v1.NefiCollector.Negotiate:input_type -> nefi.v1.NegotiateRequest
+	0, // 7: nefi.v1.NefiCollector.SendEvents:output_type -> nefi.v1.CollectSummary
+	7, // 8: nefi.v1.NefiCollector.GetAgentConfig:output_type -> nefi.v1.AgentConfig
+	0, // 9: nefi.v1.NefiCollector.SendBatch:output_type -> nefi.v1.CollectSummary
+	3, // 10: nefi.v1.NefiCollector.StreamBatches:output_type -> nefi.v1.BatchAck
+	5, // 11: nefi.v1.NefiCollector.Negotiate:output_type -> nefi.v1.NegotiateResponse
+	7, // [7:12] is the sub-list for method output_type
+	2, // [2:7] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_nefi_v1_collector_proto_init() }
@@ -116,7 +670,7 @@ func file_nefi_v1_collector_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nefi_v1_collector_proto_rawDesc), len(file_nefi_v1_collector_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,7 +19,11 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	NefiCollector_SendEvents_FullMethodName = "/nefi.v1.NefiCollector/SendEvents"
+	NefiCollector_SendEvents_FullMethodName     = "/nefi.v1.NefiCollector/SendEvents"
+	NefiCollector_GetAgentConfig_FullMethodName = "/nefi.v1.NefiCollector/GetAgentConfig"
+	NefiCollector_SendBatch_FullMethodName      = "/nefi.v1.NefiCollector/SendBatch"
+	NefiCollector_StreamBatches_FullMethodName  = "/nefi.v1.NefiCollector/StreamBatches"
+	NefiCollector_Negotiate_FullMethodName      = "/nefi.v1.NefiCollector/Negotiate"
 )
 
 // NefiCollectorClient is the client API for NefiCollector service.
@@ -33,6 +37,20 @@ type NefiCollectorClient interface {
 	// SendEvents: agent → server 단방향 클라이언트 스트리밍.
 	// agent가 이벤트를 스트림으로 push하고, 완료 시 CollectSummary를 받는다.
 	SendEvents(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[TraceEvent, CollectSummary], error)
+	// GetAgentConfig: agent가 주기적으로 호출해 server에서 관리하는 런타임 설정을 받는다.
+	// 운영자는 server의 REST API로 설정을 바꾸고, 모든 agent가 다음 poll에서 적용한다.
+	GetAgentConfig(ctx context.Context, in *AgentConfigRequest, opts ...grpc.CallOption) (*AgentConfig, error)
+	// SendBatch: 클라이언트 스트리밍을 구현하기 어려운 외부 producer용 unary 전송.
+	// 메타데이터, 버전 검사, 수락 속도 제한과 저장 경로는 SendEvents와 같다.
+	SendBatch(ctx context.Context, in *EventBatch, opts ...grpc.CallOption) (*CollectSummary, error)
+	// StreamBatches: agent ↔ server 양방향 스트리밍 (SendEvents 대체).
+	// agent가 seq를 붙인 EventBatch를 보내면 server는 저장 후 BatchAck로 확인한다.
+	// agent는 ack 전까지 batch를 보관하고 재연결 시 다시 보낸다 (at-least-once).
+	StreamBatches(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[EventBatch, BatchAck], error)
+	// Negotiate: agent가 스트림을 열기 전에 호출해 쓸 스키마 버전과 기능을 정한다.
+	// server는 지원하는 버전 범위와 기능(ack batch 전송, 압축)을 알려주고,
+	// agent는 양쪽이 지원하는 것만 써서 전송한다 (구버전 server면 낮춘다).
+	Negotiate(ctx context.Context, in *NegotiateRequest, opts ...grpc.CallOption) (*NegotiateResponse, error)
 }
 
 type nefiCollectorClient struct {
@@ -56,6 +74,49 @@ func (c *nefiCollectorClient) SendEvents(ctx context.Context, opts ...grpc.CallO
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type NefiCollector_SendEventsClient = grpc.ClientStreamingClient[TraceEvent, CollectSummary]
 
+func (c *nefiCollectorClient) GetAgentConfig(ctx context.Context, in *AgentConfigRequest, opts ...grpc.CallOption) (*AgentConfig, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentConfig)
+	err := c.cc.Invoke(ctx, NefiCollector_GetAgentConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nefiCollectorClient) SendBatch(ctx context.Context, in *EventBatch, opts ...grpc.CallOption) (*CollectSummary, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CollectSummary)
+	err := c.cc.Invoke(ctx, NefiCollector_SendBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nefiCollectorClient) StreamBatches(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[EventBatch, BatchAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NefiCollector_ServiceDesc.Streams[1], NefiCollector_StreamBatches_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[EventBatch, BatchAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NefiCollector_StreamBatchesClient = grpc.BidiStreamingClient[EventBatch, BatchAck]
+
+func (c *nefiCollectorClient) Negotiate(ctx context.Context, in *NegotiateRequest, opts ...grpc.CallOption) (*NegotiateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NegotiateResponse)
+	err := c.cc.Invoke(ctx, NefiCollector_Negotiate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NefiCollectorServer is the server API for NefiCollector service.
 // All implementations must embed UnimplementedNefiCollectorServer
 // for forward compatibility.
@@ -67,6 +128,20 @@ type NefiCollectorServer interface {
 	// SendEvents: agent → server 단방향 클라이언트 스트리밍.
 	// agent가 이벤트를 스트림으로 push하고, 완료 시 CollectSummary를 받는다.
 	SendEvents(grpc.ClientStreamingServer[TraceEvent, CollectSummary]) error
+	// GetAgentConfig: agent가 주기적으로 호출해 server에서 관리하는 런타임 설정을 받는다.
+	// 운영자는 server의 REST API로 설정을 바꾸고, 모든 agent가 다음 poll에서 적용한다.
+	GetAgentConfig(context.Context, *AgentConfigRequest) (*AgentConfig, error)
+	// SendBatch: 클라이언트 스트리밍을 구현하기 어려운 외부 producer용 unary 전송.
+	// 메타데이터, 버전 검사, 수락 속도 제한과 저장 경로는 SendEvents와 같다.
+	SendBatch(context.Context, *EventBatch) (*CollectSummary, error)
+	// StreamBatches: agent ↔ server 양방향 스트리밍 (SendEvents 대체).
+	// agent가 seq를 붙인 EventBatch를 보내면 server는 저장 후 BatchAck로 확인한다.
+	// agent는 ack 전까지 batch를 보관하고 재연결 시 다시 보낸다 (at-least-once).
+	StreamBatches(grpc.BidiStreamingServer[EventBatch, BatchAck]) error
+	// Negotiate: agent가 스트림을 열기 전에 호출해 쓸 스키마 버전과 기능을 정한다.
+	// server는 지원하는 버전 범위와 기능(ack batch 전송, 압축)을 알려주고,
+	// agent는 양쪽이 지원하는 것만 써서 전송한다 (구버전 server면 낮춘다).
+	Negotiate(context.Context, *NegotiateRequest) (*NegotiateResponse, error)
 	mustEmbedUnimplementedNefiCollectorServer()
 }
 
@@ -80,6 +155,18 @@ type UnimplementedNefiCollectorServer struct{}
 func (UnimplementedNefiCollectorServer) SendEvents(grpc.ClientStreamingServer[TraceEvent, CollectSummary]) error {
 	return status.Error(codes.Unimplemented, "method SendEvents not implemented")
 }
+func (UnimplementedNefiCollectorServer) GetAgentConfig(context.Context, *AgentConfigRequest) (*AgentConfig, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAgentConfig not implemented")
+}
+func (UnimplementedNefiCollectorServer) SendBatch(context.Context, *EventBatch) (*CollectSummary, error) {
+	return nil, status.Error(codes.Unimplemented, "method SendBatch not implemented")
+}
+func (UnimplementedNefiCollectorServer) StreamBatches(grpc.BidiStreamingServer[EventBatch, BatchAck]) error {
+	return status.Error(codes.Unimplemented, "method StreamBatches not implemented")
+}
+func (UnimplementedNefiCollectorServer) Negotiate(context.Context, *NegotiateRequest) (*NegotiateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Negotiate not implemented")
+}
 func (UnimplementedNefiCollectorServer) mustEmbedUnimplementedNefiCollectorServer() {}
 func (UnimplementedNefiCollectorServer) testEmbeddedByValue()                       {}
 
@@ -108,19 +195,99 @@ func _NefiCollector_SendEvents_Handler(srv interface{}, stream grpc.ServerStream
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type NefiCollector_SendEventsServer = grpc.ClientStreamingServer[TraceEvent, CollectSummary]
 
+func _NefiCollector_GetAgentConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NefiCollectorServer).GetAgentConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NefiCollector_GetAgentConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NefiCollectorServer).GetAgentConfig(ctx, req.(*AgentConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NefiCollector_SendBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventBatch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NefiCollectorServer).SendBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NefiCollector_SendBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NefiCollectorServer).SendBatch(ctx, req.(*EventBatch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NefiCollector_StreamBatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NefiCollectorServer).StreamBatches(&grpc.GenericServerStream[EventBatch, BatchAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NefiCollector_StreamBatchesServer = grpc.BidiStreamingServer[EventBatch, BatchAck]
+
+func _NefiCollector_Negotiate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NegotiateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NefiCollectorServer).Negotiate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NefiCollector_Negotiate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NefiCollectorServer).Negotiate(ctx, req.(*NegotiateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NefiCollector_ServiceDesc is the grpc.ServiceDesc for NefiCollector service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var NefiCollector_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "nefi.v1.NefiCollector",
 	HandlerType: (*NefiCollectorServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAgentConfig",
+			Handler:    _NefiCollector_GetAgentConfig_Handler,
+		},
+		{
+			MethodName: "SendBatch",
+			Handler:    _NefiCollector_SendBatch_Handler,
+		},
+		{
+			MethodName: "Negotiate",
+			Handler:    _NefiCollector_Negotiate_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "SendEvents",
 			Handler:       _NefiCollector_SendEvents_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "StreamBatches",
+			Handler:       _NefiCollector_StreamBatches_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "nefi/v1/collector.proto",
 }
@@ -47,7 +47,73 @@ type TraceEvent struct {
 	HttpStatus      int32  `protobuf:"varint,19,opt,name=http_status,json=httpStatus,proto3" json:"http_status,omitempty"`                 // 200, 404, 500, ... (0 = request or unknown)
 	HttpContentType string `protobuf:"bytes,20,opt,name=http_content_type,json=httpContentType,proto3" json:"http_content_type,omitempty"` // application/json, text/html, ...
 	// Latency (populated by server collector for HTTP response events)
-	LatencyNs     uint64 `protobuf:"varint,21,opt,name=latency_ns,json=latencyNs,proto3" json:"latency_ns,omitempty"` // request → response latency in nanoseconds (0 = unknown)
+	LatencyNs uint64 `protobuf:"varint,21,opt,name=latency_ns,json=latencyNs,proto3" json:"latency_ns,omitempty"` // request → response latency in nanoseconds (0 = unknown)
+	// External endpoint classification (populated by agent when remote is not a pod/service)
+	RemoteExternal bool   `protobuf:"varint,22,opt,name=remote_external,json=remoteExternal,proto3" json:"remote_external,omitempty"` // true if the remote IP did not resolve to a cluster pod/service
+	RemoteName     string `protobuf:"bytes,23,opt,name=remote_name,json=remoteName,proto3" json:"remote_name,omitempty"`              // logical name for external remotes (CIDR mapping, cloud range, "internet"), or "node/<name>" for node IPs
+	// Node topology (populated by agent from its Node object labels)
+	NodeZone         string `protobuf:"bytes,24,opt,name=node_zone,json=nodeZone,proto3" json:"node_zone,omitempty"`                           // topology.kubernetes.io/zone (empty if unknown)
+	NodeRegion       string `protobuf:"bytes,25,opt,name=node_region,json=nodeRegion,proto3" json:"node_region,omitempty"`                     // topology.kubernetes.io/region (empty if unknown)
+	NodeInstanceType string `protobuf:"bytes,26,opt,name=node_instance_type,json=nodeInstanceType,proto3" json:"node_instance_type,omitempty"` // node.kubernetes.io/instance-type (empty if unknown)
+	// Allowlisted pod labels/annotations (populated by agent; keys configured by --pod-labels/--pod-annotations)
+	Labels       map[string]string `protobuf:"bytes,27,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`                                 // local pod
+	RemoteLabels map[string]string `protobuf:"bytes,28,rep,name=remote_labels,json=remoteLabels,proto3" json:"remote_labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // remote pod; external remotes may carry enricher labels (geoip, webhook)
+	// Coarse connection observation without payload (agent /proc/net fallback when eBPF is unavailable).
+	// direction follows the same convention: 0 = local side is the server, 1 = local side is the client.
+	Connection bool `protobuf:"varint,29,opt,name=connection,proto3" json:"connection,omitempty"`
+	// Socket identity "<node>/<pid>/<fd>" (populated by agent). Connection events and the HTTP
+	// events read/written on the same socket share it; fd numbers are reused after close,
+	// so pair events by conn_id within the connection's lifetime (timestamps).
+	ConnId string `protobuf:"bytes,30,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	// Kind of the remote endpoint (populated by agent): "Pod", "Service", "Node" or "External".
+	// Empty when the remote could not be classified (e.g. enrichment disabled).
+	RemoteKind string `protobuf:"bytes,31,opt,name=remote_kind,json=remoteKind,proto3" json:"remote_kind,omitempty"`
+	// Owning workload names resolved from ownerReferences (populated by agent), e.g. the Deployment
+	// behind a ReplicaSet pod. Empty when unknown; the server then derives it from the pod name.
+	Workload       string `protobuf:"bytes,32,opt,name=workload,proto3" json:"workload,omitempty"`
+	RemoteWorkload string `protobuf:"bytes,33,opt,name=remote_workload,json=remoteWorkload,proto3" json:"remote_workload,omitempty"`
+	// Service (in remote_ns) whose EndpointSlice lists remote_ip:remote_port, or the Service
+	// reached through its VIP/NodePort (populated by agent). A pod backing several Services on
+	// different ports gets the one matching the port.
+	RemoteService string `protobuf:"bytes,34,opt,name=remote_service,json=remoteService,proto3" json:"remote_service,omitempty"`
+	// How the local pod's NetworkPolicies treat this flow (populated by agent with
+	// --kube-network-policies): "none" (no policy selects the pod in this direction),
+	// "allowed" or "denied" (isolated but no rule admits the peer, i.e. unexpected traffic).
+	// Empty when not evaluated, e.g. the remote is a Service VIP that was not DNAT-resolved.
+	Policy string `protobuf:"bytes,35,opt,name=policy,proto3" json:"policy,omitempty"`
+	// Cluster the reporting agent runs in (agent --cluster-name / CLUSTER_NAME), so one
+	// nefi-server can ingest from several clusters. Empty for single-cluster deployments.
+	Cluster string `protobuf:"bytes,36,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	// Which leg of a service-mesh request this event is, when the local pod has an Istio or
+	// Linkerd sidecar (populated by agent): "app" (app process ↔ real peer), "local" (app
+	// process ↔ its own sidecar; the remote is not the peer) or "proxy" (the sidecar process;
+	// a copy of an app leg, dropped by the agent unless --mesh-dedup=false). Empty outside a mesh.
+	MeshHop string `protobuf:"bytes,37,opt,name=mesh_hop,json=meshHop,proto3" json:"mesh_hop,omitempty"`
+	// Stable DNS identity of the remote pod behind a headless Service, "<hostname>.<service>"
+	// (populated by agent from EndpointSlices). For a StatefulSet this names the ordinal pod,
+	// e.g. "kafka-2.kafka-headless", and remote_service is the governing Service.
+	RemoteHostname string `protobuf:"bytes,38,opt,name=remote_hostname,json=remoteHostname,proto3" json:"remote_hostname,omitempty"`
+	// String fields moved into the batch string table (EventBatch.strings) when the agent and
+	// server negotiated the "dict" feature: pairs of (field number, index into strings). The
+	// referenced fields are left empty on the wire; the server restores them before ingest.
+	// Never set outside an EventBatch.
+	DictRefs []uint32 `protobuf:"varint,39,rep,packed,name=dict_refs,json=dictRefs,proto3" json:"dict_refs,omitempty"`
+	// Identity of the agent that sent this event, taken from its verified mTLS client
+	// certificate: the URI SAN (e.g. a SPIFFE ID), else the first DNS SAN, else the subject
+	// CN. Set by the server on ingest, replacing whatever the sender put there, so a writer
+	// cannot claim another agent's identity. Empty when the server does not verify client
+	// certificates.
+	AgentIdentity string `protobuf:"bytes,40,opt,name=agent_identity,json=agentIdentity,proto3" json:"agent_identity,omitempty"`
+	// Tenant that owns this event. Set by the server on ingest from the sender: the
+	// organization (O) of its verified mTLS client certificate, else the x-nefi-tenant
+	// metadata/header (agent --tenant). The sender's own value is replaced. Readers only
+	// return a tenant's events to requests scoped to that tenant. Empty = no tenant.
+	Tenant string `protobuf:"bytes,41,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	// http_path with the query string removed and IDs templated (set by the server's route
+	// enricher), e.g. "/api/users/{id}" for "/api/users/123?full=1": numeric, UUID and hex ID path
+	// segments plus the server's --route-rules. Endpoint statistics group by this instead of
+	// the raw path. Empty when the event has no path or the route stage is disabled.
+	HttpRoute     string `protobuf:"bytes,42,opt,name=http_route,json=httpRoute,proto3" json:"http_route,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -229,11 +295,158 @@ func (x *TraceEvent) GetLatencyNs() uint64 {
 	return 0
 }
 
+func (x *TraceEvent) GetRemoteExternal() bool {
+	if x != nil {
+		return x.RemoteExternal
+	}
+	return false
+}
+
+func (x *TraceEvent) GetRemoteName() string {
+	if x != nil {
+		return x.RemoteName
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetNodeZone() string {
+	if x != nil {
+		return x.NodeZone
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetNodeRegion() string {
+	if x != nil {
+		return x.NodeRegion
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetNodeInstanceType() string {
+	if x != nil {
+		return x.NodeInstanceType
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *TraceEvent) GetRemoteLabels() map[string]string {
+	if x != nil {
+		return x.RemoteLabels
+	}
+	return nil
+}
+
+func (x *TraceEvent) GetConnection() bool {
+	if x != nil {
+		return x.Connection
+	}
+	return false
+}
+
+func (x *TraceEvent) GetConnId() string {
+	if x != nil {
+		return x.ConnId
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetRemoteKind() string {
+	if x != nil {
+		return x.RemoteKind
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetWorkload() string {
+	if x != nil {
+		return x.Workload
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetRemoteWorkload() string {
+	if x != nil {
+		return x.RemoteWorkload
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetRemoteService() string {
+	if x != nil {
+		return x.RemoteService
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetMeshHop() string {
+	if x != nil {
+		return x.MeshHop
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetRemoteHostname() string {
+	if x != nil {
+		return x.RemoteHostname
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetDictRefs() []uint32 {
+	if x != nil {
+		return x.DictRefs
+	}
+	return nil
+}
+
+func (x *TraceEvent) GetAgentIdentity() string {
+	if x != nil {
+		return x.AgentIdentity
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetHttpRoute() string {
+	if x != nil {
+		return x.HttpRoute
+	}
+	return ""
+}
+
 var File_nefi_v1_events_proto protoreflect.FileDescriptor
 
 const file_nefi_v1_events_proto_rawDesc = "" +
 	"\n" +
-	"\x14nefi/v1/events.proto\x12\anefi.v1\"\xe9\x04\n" +
+	"\x14nefi/v1/events.proto\x12\anefi.v1\"\xd7\v\n" +
 	"\n" +
 	"TraceEvent\x12!\n" +
 	"\ftimestamp_ns\x18\x01 \x01(\x04R\vtimestampNs\x12\x10\n" +
@@ -262,7 +475,40 @@ const file_nefi_v1_events_proto_rawDesc = "" +
 	"httpStatus\x12*\n" +
 	"\x11http_content_type\x18\x14 \x01(\tR\x0fhttpContentType\x12\x1d\n" +
 	"\n" +
-	"latency_ns\x18\x15 \x01(\x04R\tlatencyNsB/Z-github.com/gihongjo/nefi/proto/nefi/v1;nefiv1b\x06proto3"
+	"latency_ns\x18\x15 \x01(\x04R\tlatencyNs\x12'\n" +
+	"\x0fremote_external\x18\x16 \x01(\bR\x0eremoteExternal\x12\x1f\n" +
+	"\vremote_name\x18\x17 \x01(\tR\n" +
+	"remoteName\x12\x1b\n" +
+	"\tnode_zone\x18\x18 \x01(\tR\bnodeZone\x12\x1f\n" +
+	"\vnode_region\x18\x19 \x01(\tR\n" +
+	"nodeRegion\x12,\n" +
+	"\x12node_instance_type\x18\x1a \x01(\tR\x10nodeInstanceType\x127\n" +
+	"\x06labels\x18\x1b \x03(\v2\x1f.nefi.v1.TraceEvent.LabelsEntryR\x06labels\x12J\n" +
+	"\rremote_labels\x18\x1c \x03(\v2%.nefi.v1.TraceEvent.RemoteLabelsEntryR\fremoteLabels\x12\x1e\n" +
+	"\n" +
+	"connection\x18\x1d \x01(\bR\n" +
+	"connection\x12\x17\n" +
+	"\aconn_id\x18\x1e \x01(\tR\x06connId\x12\x1f\n" +
+	"\vremote_kind\x18\x1f \x01(\tR\n" +
+	"remoteKind\x12\x1a\n" +
+	"\bworkload\x18  \x01(\tR\bworkload\x12'\n" +
+	"\x0fremote_workload\x18! \x01(\tR\x0eremoteWorkload\x12%\n" +
+	"\x0eremote_service\x18\" \x01(\tR\rremoteService\x12\x16\n" +
+	"\x06policy\x18# \x01(\tR\x06policy\x12\x18\n" +
+	"\acluster\x18$ \x01(\tR\acluster\x12\x19\n" +
+	"\bmesh_hop\x18% \x01(\tR\ameshHop\x12'\n" +
+	"\x0fremote_hostname\x18& \x01(\tR\x0eremoteHostname\x12\x1b\n" +
+	"\tdict_refs\x18' \x03(\rR\bdictRefs\x12%\n" +
+	"\x0eagent_identity\x18( \x01(\tR\ragentIdentity\x12\x16\n" +
+	"\x06tenant\x18) \x01(\tR\x06tenant\x12\x1d\n" +
+	"\n" +
+	"http_route\x18* \x01(\tR\thttpRoute\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a?\n" +
+	"\x11RemoteLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B/Z-github.com/gihongjo/nefi/proto/nefi/v1;nefiv1b\x06proto3"
 
 var (
 	file_nefi_v1_events_proto_rawDescOnce sync.Once
@@ -276,16 +522,20 @@ func file_nefi_v1_events_proto_rawDescGZIP() []byte {
 	return file_nefi_v1_events_proto_rawDescData
 }
 
-var file_nefi_v1_events_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_nefi_v1_events_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_nefi_v1_events_proto_goTypes = []any{
 	(*TraceEvent)(nil), // 0: nefi.v1.TraceEvent
+	nil,                // 1: nefi.v1.TraceEvent.LabelsEntry
+	nil,                // 2: nefi.v1.TraceEvent.RemoteLabelsEntry
 }
 var file_nefi_v1_events_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: nefi.v1.TraceEvent.labels:type_name -> nefi.v1.TraceEvent.LabelsEntry
+	2, // 1: nefi.v1.TraceEvent.remote_labels:type_name -> nefi.v1.TraceEvent.RemoteLabelsEntry
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_nefi_v1_events_proto_init() }
@@ -299,7 +549,7 @@ func file_nefi_v1_events_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nefi_v1_events_proto_rawDesc), len(file_nefi_v1_events_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
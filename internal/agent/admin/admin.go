@@ -0,0 +1,70 @@
+// Package admin은 nefi-agent의 운영용 HTTP 엔드포인트를 제공한다.
+//
+// 엔드포인트:
+//
+//	GET /healthz — 프로세스 생존 확인 (항상 ok)
+//	GET /readyz  — 구성요소별 상태 JSON (하나라도 not ready면 503)
+//	GET /configz — effective 설정값 (비밀 값은 redact)
+//
+// 다른 컴포넌트는 Handle로 엔드포인트를 추가한다.
+package admin
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server는 agent admin HTTP 서버다.
+type Server struct {
+	mux    *http.ServeMux
+	srv    *http.Server
+	lis    net.Listener
+	health health
+}
+
+// New는 addr에 바인딩된 admin 서버를 생성한다. 요청 처리는 Start 이후 시작된다.
+func New(addr string) (*Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok")) //nolint:errcheck
+	})
+	s := &Server{
+		mux:    mux,
+		srv:    &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second},
+		lis:    lis,
+		health: health{checks: make(map[string]func() Component)},
+	}
+	mux.HandleFunc("GET /readyz", s.readyz)
+	return s, nil
+}
+
+// Handle은 pattern에 handler를 등록한다. (예: "GET /configz")
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Addr는 실제 바인딩된 주소다.
+func (s *Server) Addr() string {
+	return s.lis.Addr().String()
+}
+
+// Start는 백그라운드에서 요청 처리를 시작한다.
+func (s *Server) Start() {
+	go func() {
+		if err := s.srv.Serve(s.lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[admin] serve error: %v", err)
+		}
+	}()
+}
+
+// Close는 서버를 종료한다.
+func (s *Server) Close() {
+	s.srv.Close() //nolint:errcheck
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Component는 agent 구성요소 하나의 상태다.
+type Component struct {
+	State  string         `json:"state"`            // 예: attached, degraded, synced, connected, backlogged, disabled
+	Ready  bool           `json:"ready"`            // false면 /readyz가 503을 반환한다
+	Detail map[string]any `json:"detail,omitempty"` // 큐 깊이, 마지막 동기화 시각 등
+}
+
+// ReadyReport는 GET /readyz 응답이다.
+type ReadyReport struct {
+	Ready      bool                 `json:"ready"`
+	Components map[string]Component `json:"components"`
+}
+
+// health는 이름별 상태 확인 함수 목록이다.
+type health struct {
+	mu     sync.Mutex
+	checks map[string]func() Component
+}
+
+// AddCheck는 /readyz에 포함할 구성요소 상태 확인 함수를 등록한다.
+// check는 요청마다 호출되므로 블로킹 없이 빠르게 반환해야 한다.
+func (s *Server) AddCheck(name string, check func() Component) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.health.checks[name] = check
+}
+
+// Ready는 등록된 모든 구성요소의 현재 상태를 반환한다.
+func (s *Server) Ready() ReadyReport {
+	s.health.mu.Lock()
+	names := make([]string, 0, len(s.health.checks))
+	for name := range s.health.checks {
+		names = append(names, name)
+	}
+	checks := s.health.checks
+	s.health.mu.Unlock()
+	sort.Strings(names)
+
+	report := ReadyReport{Ready: true, Components: make(map[string]Component, len(names))}
+	for _, name := range names {
+		c := checks[name]()
+		report.Components[name] = c
+		if !c.Ready {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// GET /readyz
+func (s *Server) readyz(w http.ResponseWriter, _ *http.Request) {
+	report := s.Ready()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report) //nolint:errcheck
+}
@@ -27,10 +27,11 @@
 package ebpf
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	ciliumebpf "github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -130,11 +131,11 @@ func (l *Loader) Read() (*model.DataEvent, error) {
 		return nil, fmt.Errorf("reading ring buffer: %w", err)
 	}
 
-	var event model.DataEvent
-	if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &event); err != nil {
+	event, err := model.DecodeDataEvent(record.RawSample, binary.NativeEndian)
+	if err != nil {
 		return nil, fmt.Errorf("parsing event: %w", err)
 	}
-	return &event, nil
+	return event, nil
 }
 
 // EventsMap returns the shared ring buffer map so that SSLLoader can route
@@ -153,3 +154,58 @@ func (l *Loader) Close() {
 	}
 	l.objs.Close()
 }
+
+// SetPortHints replaces the port_hints map contents. Connections whose remote
+// port has a hint run only that protocol's parser first, falling back to the
+// full inference chain when the payload does not match.
+func (l *Loader) SetPortHints(hints map[uint16]model.Protocol) error {
+	var key uint16
+	var stale []uint16
+	iter := l.objs.PortHints.Iterate()
+	var val uint8
+	for iter.Next(&key, &val) {
+		if _, ok := hints[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("iterating port hints: %w", err)
+	}
+	for _, k := range stale {
+		if err := l.objs.PortHints.Delete(k); err != nil && !errors.Is(err, ciliumebpf.ErrKeyNotExist) {
+			return fmt.Errorf("deleting port hint %d: %w", k, err)
+		}
+	}
+	for port, proto := range hints {
+		if err := l.objs.PortHints.Put(port, uint8(proto)); err != nil {
+			return fmt.Errorf("setting port hint %d=%s: %w", port, proto, err)
+		}
+	}
+	return nil
+}
+
+// ParsePortHints parses "port=protocol" pairs separated by commas,
+// e.g. "6379=redis,5432=postgres,9092=kafka,443=tls".
+func ParsePortHints(s string) (map[uint16]model.Protocol, error) {
+	hints := make(map[uint16]model.Protocol)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		portStr, name, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("port hint %q: want port=protocol", item)
+		}
+		port, err := strconv.ParseUint(strings.TrimSpace(portStr), 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("port hint %q: invalid port", item)
+		}
+		proto, ok := model.ParseProtocol(name)
+		if !ok || proto == model.ProtoHTTP2 {
+			return nil, fmt.Errorf("port hint %q: unsupported protocol %q", item, name)
+		}
+		hints[uint16(port)] = proto
+	}
+	return hints, nil
+}
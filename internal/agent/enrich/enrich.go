@@ -0,0 +1,152 @@
+// Package enrich attaches metadata to captured events through an ordered
+// chain of stages, so that new metadata sources plug in without touching the
+// agent's event loop.
+//
+// 각 stage(Enricher)는 앞 stage가 채운 필드를 보고 자기 필드를 채운다. 순서는
+// --enrichers flag로 정하며 기본값은 "k8s,mesh,external,dns,geoip,webhook"이다:
+//
+//	k8s      — 로컬 pod(PID→cgroup), 노드 topology, remote pod/service/node 해석
+//	mesh     — Istio/Linkerd sidecar 프로세스가 관측한 요청 사본 제거 (구간 표시는 k8s stage)
+//	external — 클러스터에서 해석되지 않은 remote를 external로 표시하고 CIDR/클라우드 대역 이름 부여
+//	dns      — external remote의 이름을 reverse DNS hostname으로 교체 (사용자 CIDR 매핑이 우선)
+//	geoip    — external remote에 국가/지역 label 부여 (CIDR→국가 파일)
+//	webhook  — 사용자 HTTP 서비스가 반환한 remote 이름/label 병합 (비동기, 캐시)
+//
+// 설정되지 않은 stage(예: --reverse-dns 없이 dns)는 chain에서 빠진다.
+// stage가 false를 반환하면 이벤트는 버려지고 뒤 stage는 실행되지 않는다.
+package enrich
+
+import (
+	"fmt"
+	"strings"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// Stage 이름 (--enrichers 값).
+const (
+	StageK8s      = "k8s"
+	StageMesh     = "mesh"
+	StageExternal = "external"
+	StageDNS      = "dns"
+	StageGeoIP    = "geoip"
+	StageWebhook  = "webhook"
+)
+
+// DefaultStages는 --enrichers 기본값이다.
+const DefaultStages = StageK8s + "," + StageMesh + "," + StageExternal + "," + StageDNS + "," + StageGeoIP + "," + StageWebhook
+
+var knownStages = []string{StageK8s, StageMesh, StageExternal, StageDNS, StageGeoIP, StageWebhook}
+
+// Enricher는 이벤트 보강 stage 하나다.
+type Enricher interface {
+	// Name은 로그와 /configz에 표시되는 stage 이름이다.
+	Name() string
+	// Enrich는 te를 보강한다. ev는 원본 캡처 이벤트다 (읽기 전용).
+	// false를 반환하면 이벤트를 버린다.
+	Enrich(ev *model.DataEvent, te *nefiv1.TraceEvent) bool
+}
+
+// Func는 함수를 Enricher로 쓰기 위한 어댑터다.
+func Func(name string, fn func(ev *model.DataEvent, te *nefiv1.TraceEvent) bool) Enricher {
+	return funcEnricher{name: name, fn: fn}
+}
+
+type funcEnricher struct {
+	name string
+	fn   func(ev *model.DataEvent, te *nefiv1.TraceEvent) bool
+}
+
+func (f funcEnricher) Name() string { return f.name }
+
+func (f funcEnricher) Enrich(ev *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	return f.fn(ev, te)
+}
+
+// Pipeline은 stage를 순서대로 실행한다.
+type Pipeline struct {
+	stages []Enricher
+}
+
+// New는 stages를 주어진 순서로 실행하는 Pipeline을 만든다. nil stage는 무시한다.
+func New(stages ...Enricher) *Pipeline {
+	p := &Pipeline{}
+	for _, s := range stages {
+		if s != nil {
+			p.stages = append(p.stages, s)
+		}
+	}
+	return p
+}
+
+// Enrich는 모든 stage를 실행한다. 어떤 stage가 이벤트를 버리면 false다.
+func (p *Pipeline) Enrich(ev *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	for _, s := range p.stages {
+		if !s.Enrich(ev, te) {
+			return false
+		}
+	}
+	return true
+}
+
+// Names는 활성 stage 이름을 실행 순서대로 반환한다.
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// ParseStages는 콤마로 구분된 stage 목록을 검증한다. 중복과 알 수 없는 이름은 에러다.
+func ParseStages(s string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(f))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, k := range knownStages {
+			if name == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown enricher %q (want one of %s)", name, strings.Join(knownStages, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("enricher %q listed twice", name)
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// unresolved는 remote IP가 있지만 어떤 stage도 아직 이름을 붙이지 않은 이벤트다.
+// mesh의 app↔sidecar 구간은 remote가 자기 pod이므로 해석 대상이 아니다.
+func unresolved(te *nefiv1.TraceEvent) bool {
+	return te.RemoteIp != 0 && te.RemotePod == "" && te.RemoteName == "" && te.MeshHop != model.MeshHopLocal
+}
+
+// addRemoteLabels는 remote label을 추가한다. 앞 stage가 채운 값은 덮어쓰지 않는다.
+// te.RemoteLabels는 resolver 캐시의 map을 공유할 수 있으므로 복사본에 쓴다.
+func addRemoteLabels(te *nefiv1.TraceEvent, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	merged := make(map[string]string, len(te.RemoteLabels)+len(labels))
+	for k, v := range labels {
+		if v != "" {
+			merged[k] = v
+		}
+	}
+	for k, v := range te.RemoteLabels {
+		merged[k] = v
+	}
+	te.RemoteLabels = merged
+}
@@ -0,0 +1,197 @@
+package enrich
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	agentk8s "github.com/gihongjo/nefi/internal/agent/k8s"
+	"github.com/gihongjo/nefi/internal/agent/netclass"
+	"github.com/gihongjo/nefi/internal/agent/rdns"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// GeoLabelCountry는 geoip stage가 external remote에 붙이는 label 키다.
+const GeoLabelCountry = "nefi.io/geo-country"
+
+// K8s는 resolver 캐시로 로컬 pod, 노드 topology, remote pod/service/node를 채운다.
+func K8s(r *agentk8s.Resolver) Enricher {
+	if r == nil {
+		return nil
+	}
+	return k8sEnricher{r: r}
+}
+
+type k8sEnricher struct{ r *agentk8s.Resolver }
+
+func (k8sEnricher) Name() string { return StageK8s }
+
+func (k k8sEnricher) Enrich(ev *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	node := k.r.Node()
+	te.NodeZone = node.Zone
+	te.NodeRegion = node.Region
+	te.NodeInstanceType = node.InstanceType
+
+	// 로컬 pod (PID → cgroup → UID)
+	pod := k.r.Resolve(ev.PID)
+	if pod != nil {
+		te.Namespace = pod.Namespace
+		te.PodName = pod.PodName
+		te.Labels = pod.Labels
+		te.Workload = pod.Workload
+		te.MeshHop = pod.MeshHop(te.Comm, ev.RemoteIP, ev.RemotePort)
+	}
+
+	// remote (IP → cluster-wide podsByIP)
+	// app↔sidecar 구간의 remote는 자기 pod(loopback)이므로 해석하지 않는다.
+	if ev.RemoteIP == 0 || te.MeshHop == model.MeshHopLocal {
+		return true
+	}
+	// pod IP가 재사용된 경우 이벤트 시각에 IP를 갖고 있던 pod로 귀속한다.
+	remotePod := k.r.ResolveAddrAt(ev.RemoteIP, ev.RemotePort, eventTime(ev.TimestampNs))
+	if remotePod != nil {
+		te.RemoteNs = remotePod.Namespace
+		te.RemotePod = remotePod.PodName
+		te.RemoteLabels = remotePod.Labels
+		te.RemoteWorkload = remotePod.Workload
+		te.RemoteKind = model.RemoteKindPod
+		if ep := k.r.ResolveEndpoint(ev.RemoteIP, ev.RemotePort); ep != nil {
+			te.RemoteService = ep.Service.Name
+			te.RemoteHostname = ep.Hostname
+		}
+	} else if svc := k.r.ResolveServiceAddr(ev.RemoteIP, ev.RemotePort); svc != nil {
+		// Service VIP/NodePort로 DNAT 전 주소인 경우 서비스 이름으로 레이블 설정
+		te.RemoteNs = svc.Namespace
+		te.RemotePod = svc.Name
+		te.RemoteService = svc.Name
+		te.RemoteKind = model.RemoteKindService
+	} else if node := k.r.ResolveNodeIP(ev.RemoteIP); node != "" {
+		// node IP (kubelet, hostNetwork daemon, NodePort ingress 등) → node로 귀속
+		te.RemoteName = "node/" + node
+		te.RemoteKind = model.RemoteKindNode
+	}
+	// Service VIP는 DNAT 후 실제 pod를 알 수 없으므로 정책을 평가하지 않는다.
+	if te.RemoteKind != model.RemoteKindService {
+		te.Policy = k.r.PolicyVerdict(pod, remotePod, ev.RemoteIP, ev.RemotePort, ev.Direction == 0)
+	}
+	return true
+}
+
+// eventTime은 CLOCK_MONOTONIC 기준 이벤트 타임스탬프(bpf_ktime_get_ns)를
+// wall-clock 시각으로 바꾼다. 변환할 수 없으면 zero(= 현재)다.
+func eventTime(tsNs uint64) time.Time {
+	var now unix.Timespec
+	if tsNs == 0 || unix.ClockGettime(unix.CLOCK_MONOTONIC, &now) != nil {
+		return time.Time{}
+	}
+	age := now.Nano() - int64(tsNs)
+	if age < 0 {
+		age = 0
+	}
+	return time.Now().Add(-time.Duration(age))
+}
+
+// Mesh는 k8s stage가 sidecar 프로세스로 표시한 이벤트(te.MeshHop == proxy)를 버린다.
+// 같은 요청을 app 프로세스도 관측하므로, 남기면 토폴로지 엣지와 호출 수가 두 배가
+// 된다. mTLS 구간은 암호화돼 있어 HTTP로 파싱되는 것은 어차피 app↔sidecar 평문
+// 구간뿐이다. dedup이 false면 표시만 하고 모두 보낸다.
+func Mesh(dedup bool) Enricher {
+	if !dedup {
+		return nil
+	}
+	return meshEnricher{}
+}
+
+type meshEnricher struct{}
+
+func (meshEnricher) Name() string { return StageMesh }
+
+func (meshEnricher) Enrich(_ *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	return te.MeshHop != model.MeshHopProxy
+}
+
+// External은 앞 stage에서 해석되지 않은 remote를 external로 표시하고
+// CIDR 매핑 > 클라우드 대역 > private-network/internet 순으로 이름을 붙인다.
+func External(c *netclass.Classifier) Enricher {
+	if c == nil {
+		return nil
+	}
+	return externalEnricher{c: c}
+}
+
+type externalEnricher struct{ c *netclass.Classifier }
+
+func (externalEnricher) Name() string { return StageExternal }
+
+func (e externalEnricher) Enrich(_ *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	if unresolved(te) {
+		te.RemoteExternal = true
+		te.RemoteKind = model.RemoteKindExternal
+		te.RemoteName = e.c.Classify(te.RemoteIp)
+	}
+	return true
+}
+
+// DNS는 external remote의 이름을 reverse DNS hostname으로 바꾼다.
+// 사용자 CIDR 매핑(c.Mapped)은 hostname보다 우선하고, hostname이 매핑 파일의
+// hostname 규칙(c.MapHost)과 맞으면 그 이름을 쓴다. 조회는 비동기이므로
+// 첫 이벤트는 앞 stage의 이름으로 남을 수 있다.
+func DNS(r *rdns.Resolver, c *netclass.Classifier) Enricher {
+	if r == nil {
+		return nil
+	}
+	return dnsEnricher{r: r, c: c}
+}
+
+type dnsEnricher struct {
+	r *rdns.Resolver
+	c *netclass.Classifier
+}
+
+func (dnsEnricher) Name() string { return StageDNS }
+
+func (d dnsEnricher) Enrich(_ *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	if !te.RemoteExternal && !unresolved(te) {
+		return true
+	}
+	if d.c != nil {
+		if _, ok := d.c.Mapped(te.RemoteIp); ok {
+			return true
+		}
+	}
+	if host := d.r.Lookup(te.RemoteIp); host != "" {
+		if d.c != nil {
+			if name, ok := d.c.MapHost(host); ok {
+				host = name
+			}
+		}
+		te.RemoteExternal = true
+		te.RemoteKind = model.RemoteKindExternal
+		te.RemoteName = host
+	}
+	return true
+}
+
+// GeoIP는 external remote에 국가 label(GeoLabelCountry)을 붙인다.
+// geo는 "<cidr> <country>" 형식 파일로 만든 Classifier다 (--geoip-cidrs).
+func GeoIP(geo *netclass.Classifier) Enricher {
+	if geo == nil {
+		return nil
+	}
+	return geoEnricher{geo: geo}
+}
+
+type geoEnricher struct{ geo *netclass.Classifier }
+
+func (geoEnricher) Name() string { return StageGeoIP }
+
+func (g geoEnricher) Enrich(_ *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	if !te.RemoteExternal {
+		return true
+	}
+	if country, ok := g.geo.Mapped(te.RemoteIp); ok {
+		addRemoteLabels(te, map[string]string{GeoLabelCountry: country})
+	}
+	return true
+}
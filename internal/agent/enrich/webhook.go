@@ -0,0 +1,198 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+const (
+	webhookQueueSize  = 256
+	webhookTimeout    = 2 * time.Second
+	webhookMaxEntries = 65536
+)
+
+// WebhookRequest는 webhook에 POST하는 본문이다. remote IP 하나당 한 번 호출하고
+// 결과를 TTL 동안 캐시하므로, 응답은 remote 주소에만 의존해야 한다.
+type WebhookRequest struct {
+	RemoteIP   string `json:"remote_ip"`
+	RemotePort uint32 `json:"remote_port"` // 처음 관측된 포트 (참고용)
+	RemoteName string `json:"remote_name,omitempty"`
+	External   bool   `json:"external"`
+	NodeName   string `json:"node_name,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+}
+
+// WebhookResponse는 webhook 응답이다. 빈 필드는 무시한다.
+type WebhookResponse struct {
+	// RemoteName은 pod/service로 해석되지 않은 remote의 이름을 바꾼다.
+	RemoteName   string            `json:"remote_name,omitempty"`
+	RemoteLabels map[string]string `json:"remote_labels,omitempty"`
+}
+
+type webhookEntry struct {
+	resp      *WebhookResponse // nil = 호출 실패 (negative cache)
+	expiresAt time.Time
+}
+
+// Webhook은 사용자 HTTP 서비스로 remote 메타데이터를 받아오는 stage다.
+// rdns와 같은 방식으로 이벤트 루프를 블로킹하지 않는다: 캐시 miss면 이번
+// 이벤트는 그대로 통과시키고 백그라운드 호출을 예약한다.
+type Webhook struct {
+	url     string
+	ttl     time.Duration
+	client  *http.Client
+	limiter *rate.Limiter
+	queue   chan WebhookRequest
+	done    chan struct{}
+
+	mu      sync.Mutex
+	cache   map[string]webhookEntry
+	pending map[string]struct{}
+}
+
+// NewWebhook은 url로 초당 최대 perSec번 호출하고 결과를 ttl 동안 캐시하는 stage를 만든다.
+func NewWebhook(url string, perSec float64, ttl time.Duration) *Webhook {
+	w := &Webhook{
+		url:     url,
+		ttl:     ttl,
+		client:  &http.Client{Timeout: webhookTimeout},
+		limiter: rate.NewLimiter(rate.Limit(perSec), 1),
+		queue:   make(chan WebhookRequest, webhookQueueSize),
+		done:    make(chan struct{}),
+		cache:   make(map[string]webhookEntry),
+		pending: make(map[string]struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Name implements Enricher.
+func (w *Webhook) Name() string { return StageWebhook }
+
+// Enrich implements Enricher.
+func (w *Webhook) Enrich(ev *model.DataEvent, te *nefiv1.TraceEvent) bool {
+	if te.RemoteIp == 0 {
+		return true
+	}
+	resp := w.lookup(ev.RemoteIPString(), te)
+	if resp == nil {
+		return true
+	}
+	if resp.RemoteName != "" && te.RemotePod == "" {
+		te.RemoteName = resp.RemoteName
+	}
+	addRemoteLabels(te, resp.RemoteLabels)
+	return true
+}
+
+// Close는 백그라운드 호출을 멈춘다.
+func (w *Webhook) Close() {
+	close(w.done)
+}
+
+func (w *Webhook) lookup(ip string, te *nefiv1.TraceEvent) *WebhookResponse {
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if e, ok := w.cache[ip]; ok && now.Before(e.expiresAt) {
+		return e.resp
+	}
+	if _, ok := w.pending[ip]; ok {
+		return nil
+	}
+	req := WebhookRequest{
+		RemoteIP:   ip,
+		RemotePort: te.RemotePort,
+		RemoteName: te.RemoteName,
+		External:   te.RemoteExternal,
+		NodeName:   te.NodeName,
+		Cluster:    te.Cluster,
+	}
+	select {
+	case w.queue <- req:
+		w.pending[ip] = struct{}{}
+	default:
+		// 큐가 가득 차면 다음 이벤트에서 다시 시도한다.
+	}
+	return nil
+}
+
+func (w *Webhook) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-w.done
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case req := <-w.queue:
+			if err := w.limiter.Wait(ctx); err != nil {
+				return
+			}
+			resp, _ := w.call(ctx, req) // 실패도 negative cache로 TTL 동안 재호출하지 않는다
+			w.store(req.RemoteIP, resp)
+		}
+	}
+}
+
+func (w *Webhook) call(ctx context.Context, req WebhookRequest) (*WebhookResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	res, err := w.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook: %s", res.Status)
+	}
+	var resp WebhookResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (w *Webhook) store(ip string, resp *WebhookResponse) {
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.pending, ip)
+	if len(w.cache) >= webhookMaxEntries {
+		for k, e := range w.cache {
+			if now.After(e.expiresAt) {
+				delete(w.cache, k)
+			}
+		}
+		// 모두 살아 있으면 임의의 항목(map 순회 순서)을 1/16 비운다. 결과를 버리면 그 IP가
+		// 조회마다 다시 miss나고, 한 개만 비우면 다음 저장이 다시 캐시 전체를 훑는다.
+		for k := range w.cache {
+			if len(w.cache) < webhookMaxEntries-webhookMaxEntries/16 {
+				break
+			}
+			delete(w.cache, k)
+		}
+	}
+	w.cache[ip] = webhookEntry{resp: resp, expiresAt: now.Add(w.ttl)}
+}
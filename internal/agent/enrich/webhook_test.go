@@ -0,0 +1,25 @@
+package enrich
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWebhookStoreEvictsWhenFull(t *testing.T) {
+	w := &Webhook{ttl: time.Hour, cache: make(map[string]webhookEntry), pending: make(map[string]struct{})}
+	for i := 0; i < webhookMaxEntries; i++ {
+		w.store(fmt.Sprintf("ip-%d", i), &WebhookResponse{})
+	}
+	w.pending["203.0.113.1"] = struct{}{}
+	w.store("203.0.113.1", &WebhookResponse{RemoteName: "partner-api"})
+	if e, ok := w.cache["203.0.113.1"]; !ok || e.resp.RemoteName != "partner-api" {
+		t.Fatal("result dropped when the cache was full of live entries")
+	}
+	if _, ok := w.pending["203.0.113.1"]; ok {
+		t.Error("IP still pending after store")
+	}
+	if n := len(w.cache); n >= webhookMaxEntries {
+		t.Errorf("%d entries after eviction, want fewer than %d", n, webhookMaxEntries)
+	}
+}
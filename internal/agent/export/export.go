@@ -0,0 +1,129 @@
+// Package export는 보강된 이벤트를 내보내는 sink와 그 조합이다.
+//
+// sink는 이름으로 Registry에 등록하고 --exporter 값(콤마 구분)으로 골라 조합한다:
+//
+//	grpc   — nefi-server로 batch 전송 (internal/agent/grpc)
+//	stdout — NDJSON을 stdout으로 기록 (internal/agent/ndjson)
+//	file   — NDJSON을 파일로 기록
+//
+// 여러 sink를 고르면 모든 이벤트를 각 sink로 보낸다 (fan-out). OTLP, Kafka 같은 새
+// 대상은 Sink를 구현해 Register하면 agent의 이벤트 루프를 고치지 않고 붙일 수 있다.
+package export
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// 기본 sink 이름 (--exporter 값).
+const (
+	SinkGRPC   = "grpc"
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+)
+
+// Sink는 이벤트 전송 대상 하나다.
+type Sink interface {
+	// Send는 ev를 sink의 큐에 넣는다. 캡처 루프에서 호출되므로 블로킹하지 않아야 한다
+	// (가득 차면 drop). ev는 sink 소유가 되므로 전송 시 수정해도 된다 (grpc의 사전 인코딩).
+	Send(ev *nefiv1.TraceEvent)
+	// Close는 큐에 남은 이벤트를 flush하고 자원을 정리한다.
+	Close()
+}
+
+// Opener는 sink 하나를 만든다. 설정은 등록하는 쪽이 closure로 넘긴다.
+// 필요한 설정이 없어 sink를 만들 수 없으면 nil, nil을 반환하고 그 sink는 빠진다.
+type Opener func() (Sink, error)
+
+// Registry는 이름 → Opener 색인이다.
+type Registry struct {
+	openers map[string]Opener
+	names   []string // 등록 순서 (에러 메시지용)
+}
+
+// NewRegistry는 빈 Registry를 반환한다.
+func NewRegistry() *Registry {
+	return &Registry{openers: make(map[string]Opener)}
+}
+
+// Register는 name으로 open을 등록한다. 같은 이름을 두 번 등록하면 panic한다.
+func (r *Registry) Register(name string, open Opener) {
+	if _, ok := r.openers[name]; ok {
+		panic("export: duplicate sink " + name)
+	}
+	r.openers[name] = open
+	r.names = append(r.names, name)
+}
+
+// Names는 콤마로 구분된 sink 목록을 정규화한다 (공백 제거, 소문자, 빈 항목 제외).
+func Names(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if name := strings.ToLower(strings.TrimSpace(f)); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Open opens the named sinks in order and returns them as one Sink that
+// fans each event out to all of them. Unknown and duplicate names are
+// errors; if any sink fails to open, the ones already opened are closed.
+// It returns nil when no sink was opened.
+func (r *Registry) Open(names []string) (Sink, error) {
+	var sinks fanout
+	for i, name := range names {
+		open, ok := r.openers[name]
+		if !ok || slices.Contains(names[:i], name) {
+			sinks.Close()
+			if ok {
+				return nil, fmt.Errorf("duplicate exporter %q", name)
+			}
+			return nil, fmt.Errorf("unknown exporter %q (want one of %s)", name, strings.Join(r.names, ", "))
+		}
+		s, err := open()
+		if err != nil {
+			sinks.Close()
+			return nil, fmt.Errorf("exporter %s: %w", name, err)
+		}
+		if s != nil {
+			sinks = append(sinks, s)
+		}
+	}
+	switch len(sinks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sinks[0], nil
+	}
+	return sinks, nil
+}
+
+// fanout은 모든 이벤트를 각 sink로 보낸다. sink가 ev를 수정할 수 있으므로 첫 sink를
+// 뺀 나머지에는 복사본을 준다.
+type fanout []Sink
+
+func (f fanout) Send(ev *nefiv1.TraceEvent) {
+	for _, s := range f[1:] {
+		s.Send(proto.Clone(ev).(*nefiv1.TraceEvent))
+	}
+	f[0].Send(ev) // 복사가 끝난 뒤에 넘긴다
+}
+
+// Close는 모든 sink를 동시에 닫는다. 각 sink의 drain 시간이 더해지지 않게 한다.
+func (f fanout) Close() {
+	var wg sync.WaitGroup
+	for _, s := range f {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Close()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// loadReportInterval은 batch에 부하 보고(EventBatch.load)를 싣는 간격이다.
+const loadReportInterval = 10 * time.Second
+
+// CaptureLoad는 Sender 밖(캡처 루프, 메모리 보호)에서 세는 부하 지표다. 모두 누적 값이다.
+type CaptureLoad struct {
+	Captured   uint64        // eBPF에서 읽은 이벤트 (필터 전)
+	SampledOut uint64        // 메모리 보호가 export 전에 버린 이벤트
+	Paused     time.Duration // 메모리 보호로 캡처를 멈춘 시간
+}
+
+// loadReporter는 직전 부하 보고 시점이다. 전송 고루틴에서만 쓴다.
+type loadReporter struct {
+	capture  func() CaptureLoad // nil = 캡처 지표 없음
+	last     time.Time
+	enqueued uint64 // last 시점의 큐 enqueue 누적 수
+}
+
+// attachLoad는 직전 보고 후 loadReportInterval이 지났으면 b에 부하 보고를 싣는다.
+// 보고는 batch 하나에만 실리므로 전송량은 거의 늘지 않는다.
+func (s *Sender) attachLoad(b *nefiv1.EventBatch) {
+	now := time.Now()
+	elapsed := now.Sub(s.load.last)
+	if elapsed < loadReportInterval {
+		return
+	}
+	enqueued, dropped := s.queue.totals()
+	r := &nefiv1.LoadReport{
+		EventsPerSec:  float64(enqueued-s.load.enqueued) / elapsed.Seconds(),
+		Enqueued:      enqueued,
+		QueueDropped:  dropped,
+		QueueDepth:    uint32(s.queue.len()),
+		QueueCapacity: uint32(s.queueCap()),
+		UnackedEvents: uint64(s.unacked.events()),
+	}
+	if s.load.capture != nil {
+		c := s.load.capture()
+		r.Captured = c.Captured
+		r.SampledOut = c.SampledOut
+		r.CapturePausedSec = c.Paused.Seconds()
+	}
+	s.load.last, s.load.enqueued = now, enqueued
+	b.Load = r
+}
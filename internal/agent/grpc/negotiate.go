@@ -0,0 +1,185 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/batchdict"
+	"github.com/gihongjo/nefi/internal/version"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// negotiateTimeout은 Negotiate 호출 대기 상한이다.
+const negotiateTimeout = 10 * time.Second
+
+// protocol은 Negotiate로 server와 합의한 전송 방식이다.
+type protocol struct {
+	schema   int  // events 인코딩 스키마 버전
+	batchAck bool // StreamBatches (false = SendEvents 스트림, ack 없음)
+	gzip     bool // gzip 메시지 압축
+	dict     bool // batch 문자열 사전 인코딩
+
+	backend string // Negotiate에 응답한 server 주소 (DNS 해석 결과, 로그용)
+}
+
+func (p protocol) String() string {
+	parts := []string{"schema " + strconv.Itoa(p.schema)}
+	if p.batchAck {
+		parts = append(parts, version.FeatureBatchAck)
+	}
+	if p.gzip {
+		parts = append(parts, version.FeatureGzip)
+	}
+	if p.dict {
+		parts = append(parts, version.FeatureDict)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// encode는 b를 p의 스키마와 사전 인코딩 여부에 맞춘다. 재전송하는 batch는 이전 연결에서
+// 다른 server와 합의한 방식으로 인코딩돼 있을 수 있다.
+func (p protocol) encode(b *nefiv1.EventBatch) {
+	b.SchemaVersion = uint32(p.schema)
+	if p.dict {
+		batchdict.Encode(b)
+	} else {
+		batchdict.Decode(b) //nolint:errcheck // agent가 인코딩한 batch라 실패하지 않는다
+	}
+}
+
+// negotiate asks the server which schema version and features to use. A
+// server that predates Negotiate answers Unimplemented; the agent then
+// downgrades to version.PreNegotiationSchemaVersion over SendEvents.
+func (s *Sender) negotiate(ctx context.Context, client nefiv1.NefiCollectorClient) (protocol, error) {
+	want := []string{version.FeatureBatchAck, version.FeatureDict}
+	if s.compress {
+		want = append(want, version.FeatureGzip)
+	}
+	ctx, cancel := context.WithTimeout(ctx, negotiateTimeout)
+	defer cancel()
+	var server peer.Peer
+	resp, err := client.Negotiate(ctx, &nefiv1.NegotiateRequest{
+		NodeName:      s.nodeName,
+		SchemaVersion: version.SchemaVersion,
+		Features:      want,
+	}, grpc.Peer(&server))
+	var backend string
+	if server.Addr != nil {
+		backend = server.Addr.String()
+	}
+	if status.Code(err) == codes.Unimplemented {
+		return protocol{schema: version.PreNegotiationSchemaVersion, backend: backend}, nil
+	}
+	if err != nil {
+		return protocol{}, err
+	}
+	p := protocol{schema: int(resp.GetSchemaVersion()), backend: backend}
+	for _, f := range resp.GetFeatures() {
+		switch f {
+		case version.FeatureBatchAck:
+			p.batchAck = true
+		case version.FeatureGzip:
+			p.gzip = true
+		case version.FeatureDict:
+			p.dict = true
+		}
+	}
+	return p, nil
+}
+
+// streamEvents는 batch ack를 지원하지 않는 server에 SendEvents로 이벤트를 하나씩 보낸다.
+// 이 경로에는 ack가 없으므로 끊기는 순간 전송 중이던 이벤트는 잃을 수 있다.
+func (s *Sender) streamEvents(ctx context.Context, cancel context.CancelFunc, client nefiv1.NefiCollectorClient, p protocol, callOpts []grpc.CallOption) (connected bool, err error) {
+	st, streamErr := client.SendEvents(ctx, callOpts...)
+	if streamErr != nil {
+		return false, streamErr
+	}
+
+	log.Printf("[sender] connected to server %s → %s (%s) — server does not acknowledge batches", s.serverAddr, p.backend, p)
+	connected = true
+	s.connected.Store(true)
+	defer s.connected.Store(false)
+
+	// 이전 연결에서 ack받지 못한 batch는 이 스트림으로 넘기고 window에서 비운다.
+	sent := 0 // 이 스트림으로 보낸 이벤트 수 (종료 시 server의 received와 비교)
+	for _, b := range s.unacked.pending() {
+		p.encode(b)
+		for _, ev := range b.Events {
+			if err := st.Send(ev); err != nil {
+				return connected, closeEvents(st, err)
+			}
+		}
+		sent += len(b.Events)
+		s.stats.sent(len(b.Events), true)
+		s.unacked.ack(b.Seq)
+	}
+
+	for {
+		ev, ok := s.queue.pop(s.done, nil)
+		if !ok {
+			return connected, s.drainEvents(st, cancel, sent)
+		}
+		if err := st.Send(ev); err != nil {
+			return connected, closeEvents(st, err)
+		}
+		sent++
+		s.stats.sentEvents.Add(1)
+		releaseEvent(ev) // ack가 없으므로 Send가 직렬화한 뒤 바로 돌려보낸다
+	}
+}
+
+// closeEvents는 SendEvents 스트림의 Send 실패 원인을 반환한다. server가 스트림을
+// 닫으면 Send는 io.EOF만 반환하므로 실제 status는 CloseAndRecv로 받는다.
+func closeEvents(st grpc.ClientStreamingClient[nefiv1.TraceEvent, nefiv1.CollectSummary], err error) error {
+	if err == io.EOF {
+		_, err = st.CloseAndRecv()
+	}
+	return err
+}
+
+// drainEvents는 streamEvents의 drain이다. 큐에 남은 이벤트를 drainTimeout 동안 보내고
+// 스트림을 닫은 뒤 server의 CollectSummary(받은 이벤트 수)를 기다린다. server 응답이
+// 없으면 drainTimeout + drainCloseWait 후 cancel한다. sent는 drain 전까지 보낸 이벤트 수다.
+func (s *Sender) drainEvents(st grpc.ClientStreamingClient[nefiv1.TraceEvent, nefiv1.CollectSummary], cancel context.CancelFunc, sent int) error {
+	deadline := time.NewTimer(s.drainTimeout)
+	defer deadline.Stop()
+	force := time.AfterFunc(s.drainTimeout+drainCloseWait, cancel)
+	defer force.Stop()
+
+	flushed := 0
+	var sendErr error
+loop:
+	for s.drainTimeout > 0 {
+		select {
+		case <-deadline.C:
+			break loop
+		default:
+		}
+		ev, ok := s.queue.tryPop()
+		if !ok {
+			break loop
+		}
+		if sendErr = st.Send(ev); sendErr != nil {
+			break loop
+		}
+		s.stats.sentEvents.Add(1)
+		releaseEvent(ev)
+		flushed++
+	}
+
+	summary, err := st.CloseAndRecv()
+	reportClose(int(summary.GetReceived()), sent+flushed, err)
+	if sendErr != nil && sendErr != io.EOF {
+		err = sendErr
+	}
+	s.reportDrain(flushed)
+	return err
+}
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/version"
+)
+
+// negotiateServer는 Negotiate만 구현하는 collector다. fn이 nil이면 Negotiate가 없는
+// (PreNegotiationSchemaVersion) server처럼 Unimplemented를 반환한다.
+type negotiateServer struct {
+	nefiv1.UnimplementedNefiCollectorServer
+	fn func(*nefiv1.NegotiateRequest) (*nefiv1.NegotiateResponse, error)
+}
+
+func (s *negotiateServer) Negotiate(ctx context.Context, req *nefiv1.NegotiateRequest) (*nefiv1.NegotiateResponse, error) {
+	if s.fn == nil {
+		return s.UnimplementedNefiCollectorServer.Negotiate(ctx, req)
+	}
+	return s.fn(req)
+}
+
+// dialCollector는 srv를 메모리 안의 gRPC server로 띄우고 그 client를 반환한다.
+func dialCollector(t *testing.T, srv nefiv1.NefiCollectorServer) nefiv1.NefiCollectorClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	nefiv1.RegisterNefiCollectorServer(gs, srv)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return nefiv1.NewNefiCollectorClient(conn)
+}
+
+func TestNegotiate(t *testing.T) {
+	var req *nefiv1.NegotiateRequest
+	client := dialCollector(t, &negotiateServer{fn: func(r *nefiv1.NegotiateRequest) (*nefiv1.NegotiateResponse, error) {
+		req = r
+		schema, features, _ := version.Negotiate(int(r.GetSchemaVersion()), r.GetFeatures())
+		return &nefiv1.NegotiateResponse{SchemaVersion: uint32(schema), Features: features}, nil
+	}})
+
+	tests := []struct {
+		compress bool
+		want     protocol
+	}{
+		{false, protocol{schema: version.SchemaVersion, batchAck: true, dict: true}},
+		{true, protocol{schema: version.SchemaVersion, batchAck: true, dict: true, gzip: true}},
+	}
+	for _, tt := range tests {
+		s := &Sender{nodeName: "node-1", compress: tt.compress}
+		p, err := s.negotiate(context.Background(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.backend = ""
+		if p != tt.want {
+			t.Errorf("compress=%v: negotiated %+v, want %+v", tt.compress, p, tt.want)
+		}
+		if req.GetNodeName() != "node-1" || slices.Contains(req.GetFeatures(), version.FeatureGzip) != tt.compress {
+			t.Errorf("compress=%v: sent %v", tt.compress, req)
+		}
+	}
+}
+
+func TestNegotiateFallback(t *testing.T) {
+	s := &Sender{nodeName: "node-1"}
+	p, err := s.negotiate(context.Background(), dialCollector(t, &negotiateServer{}))
+	if err != nil {
+		t.Fatalf("server without Negotiate: %v", err)
+	}
+	if p.schema != version.PreNegotiationSchemaVersion || p.batchAck || p.gzip || p.dict {
+		t.Errorf("negotiated %+v with a server without Negotiate, want schema %d over SendEvents without features",
+			p, version.PreNegotiationSchemaVersion)
+	}
+
+	rejected := dialCollector(t, &negotiateServer{fn: func(*nefiv1.NegotiateRequest) (*nefiv1.NegotiateResponse, error) {
+		return nil, status.Error(codes.FailedPrecondition, "agent schema is no longer supported")
+	}})
+	if _, err := s.negotiate(context.Background(), rejected); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("rejection returned %v, want FailedPrecondition", err)
+	}
+}
@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"sync"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// 초당 수만 건의 이벤트를 보내면 이벤트마다 TraceEvent, batch마다 events 슬라이스와
+// EventBatch를 새로 할당하는 것이 GC 부담의 대부분이다. server가 ack한 batch와
+// 큐에서 버린 이벤트는 더 참조되지 않으므로 pool로 돌려보내 다시 쓴다.
+//
+// 소유권: Send로 넘긴 이벤트는 Sender 소유이고 (export.Sink 계약), Sender는
+// ack받거나 버린 뒤에만 pool에 넣는다. pool 밖에서 만든 이벤트도 넣을 수 있다.
+var (
+	eventPool = sync.Pool{New: func() any { return new(nefiv1.TraceEvent) }}
+	batchPool = sync.Pool{New: func() any { return new(nefiv1.EventBatch) }}
+	slicePool sync.Pool // *[]*nefiv1.TraceEvent
+)
+
+// newEvent는 pool에서 빈 TraceEvent를 꺼낸다.
+func newEvent() *nefiv1.TraceEvent {
+	return eventPool.Get().(*nefiv1.TraceEvent)
+}
+
+// releaseEvent는 ev를 비우고 pool에 돌려보낸다. dict_refs 버퍼는 다음 사전 인코딩에
+// 다시 쓰고, payload는 DataEvent의 버퍼를 가리키므로 재사용하지 않는다.
+func releaseEvent(ev *nefiv1.TraceEvent) {
+	refs := ev.DictRefs[:0]
+	ev.Reset()
+	ev.DictRefs = refs
+	eventPool.Put(ev)
+}
+
+// ReleaseTraceEvent returns an event made by NewTraceEvent that was dropped
+// before reaching a sink to the pool. ev must not be used afterwards.
+func ReleaseTraceEvent(ev *nefiv1.TraceEvent) {
+	releaseEvent(ev)
+}
+
+// newEvents는 용량이 size 이상인 빈 events 슬라이스를 꺼낸다. batch를 채우는 동안
+// append가 다시 할당하지 않는다.
+func newEvents(size int) []*nefiv1.TraceEvent {
+	if p, ok := slicePool.Get().(*[]*nefiv1.TraceEvent); ok && cap(*p) >= size {
+		return (*p)[:0]
+	}
+	return make([]*nefiv1.TraceEvent, 0, size)
+}
+
+// releaseBatch는 ack된 b의 이벤트, events 슬라이스, b 자신을 pool에 돌려보낸다.
+func releaseBatch(b *nefiv1.EventBatch) {
+	for i, ev := range b.Events {
+		releaseEvent(ev)
+		b.Events[i] = nil
+	}
+	events := b.Events[:0]
+	slicePool.Put(&events)
+	b.Reset()
+	batchPool.Put(b)
+}
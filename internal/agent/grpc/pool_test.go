@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"runtime"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/batchdict"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// benchRate는 GC 지표의 기준 처리량이다 (이벤트/초). gc/50k-events는 이 처리량에서
+// 1초 동안 일어나는 GC 횟수다.
+const benchRate = 50000
+
+// benchSink는 기준선 벤치마크의 이벤트가 스택에 할당되지 않도록 붙잡아 둔다.
+var benchSink *nefiv1.TraceEvent
+
+func benchDataEvent() *model.DataEvent {
+	ev := &model.DataEvent{
+		TimestampNs: 1,
+		PID:         4242,
+		FD:          7,
+		Protocol:    model.ProtoHTTP,
+		MsgType:     model.MsgRequest,
+		RemoteIP:    0x0a600a0a,
+		RemotePort:  8080,
+	}
+	copy(ev.Comm[:], "checkout")
+	ev.MsgSize = uint32(copy(ev.Msg[:], "GET /api/cart HTTP/1.1\r\nHost: cart\r\n\r\n"))
+	return ev
+}
+
+// enrich는 agent 파이프라인이 채우는 K8s 메타데이터를 흉내 낸다.
+func enrich(te *nefiv1.TraceEvent) {
+	te.Namespace = "shop"
+	te.PodName = "checkout-7d9f8-abcde"
+	te.RemoteNs = "shop"
+	te.RemotePod = "cart-5c6b7-fghij"
+	te.RemoteKind = "Pod"
+	te.Workload = "checkout"
+	te.RemoteWorkload = "cart"
+	te.RemoteService = "cart"
+	te.Cluster = "prod"
+}
+
+// reportGC는 b.N개 이벤트 동안의 GC 횟수를 benchRate 이벤트당 횟수로 보고한다.
+func reportGC(b *testing.B, before *runtime.MemStats) {
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N)*benchRate, "gc/50k-events")
+}
+
+func BenchmarkNewTraceEvent(b *testing.B) {
+	de := benchDataEvent()
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			te := NewTraceEvent(de, "node-a")
+			enrich(te)
+			releaseEvent(te)
+		}
+	})
+	b.Run("alloc", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			te := &nefiv1.TraceEvent{
+				TimestampNs: de.TimestampNs,
+				Pid:         de.PID,
+				Fd:          de.FD,
+				Protocol:    uint32(de.Protocol),
+				MsgType:     uint32(de.MsgType),
+				Comm:        de.CommString(),
+				NodeName:    "node-a",
+				RemoteIp:    de.RemoteIP,
+				RemotePort:  uint32(de.RemotePort),
+				Payload:     de.Payload(),
+				ConnId:      ConnID("node-a", de.PID, de.FD),
+			}
+			enrich(te)
+			benchSink = te
+		}
+	})
+}
+
+// BenchmarkExportPath는 이벤트 하나가 큐 → batch → 사전 인코딩 → 직렬화 → ack를
+// 거치는 비용이다. unpooled는 pool 도입 전처럼 매번 새로 할당하는 기준선이다.
+func BenchmarkExportPath(b *testing.B) {
+	de := benchDataEvent()
+	p := protocol{schema: 3, batchAck: true, dict: true}
+
+	b.Run("pooled", func(b *testing.B) {
+		q := newPriorityQueue([numTiers]int{DefaultQueueSize, DefaultQueueSize, DefaultQueueSize})
+		w := newWindow("bench")
+		events := newEvents(DefaultBatchSize)
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+		b.ReportAllocs()
+		for b.Loop() {
+			te := NewTraceEvent(de, "node-a")
+			enrich(te)
+			q.push(te, DefaultQueueSize)
+			ev, _ := q.tryPop()
+			events = append(events, ev)
+			if len(events) == DefaultBatchSize {
+				batch := w.add(events, p)
+				if _, err := proto.Marshal(batch); err != nil {
+					b.Fatal(err)
+				}
+				w.ack(batch.Seq)
+				events = newEvents(DefaultBatchSize)
+			}
+		}
+		reportGC(b, &before)
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		q := newPriorityQueue([numTiers]int{DefaultQueueSize, DefaultQueueSize, DefaultQueueSize})
+		var events []*nefiv1.TraceEvent
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+		b.ReportAllocs()
+		for b.Loop() {
+			te := &nefiv1.TraceEvent{
+				TimestampNs: de.TimestampNs,
+				Pid:         de.PID,
+				Fd:          de.FD,
+				Protocol:    uint32(de.Protocol),
+				MsgType:     uint32(de.MsgType),
+				Comm:        de.CommString(),
+				NodeName:    "node-a",
+				RemoteIp:    de.RemoteIP,
+				RemotePort:  uint32(de.RemotePort),
+				Payload:     de.Payload(),
+				ConnId:      ConnID("node-a", de.PID, de.FD),
+			}
+			enrich(te)
+			q.push(te, DefaultQueueSize)
+			ev, _ := q.tryPop()
+			events = append(events, ev)
+			if len(events) == DefaultBatchSize {
+				batch := &nefiv1.EventBatch{Events: events, SchemaVersion: 3}
+				batchdict.Encode(batch)
+				if _, err := proto.Marshal(batch); err != nil {
+					b.Fatal(err)
+				}
+				events = nil
+			}
+		}
+		reportGC(b, &before)
+	})
+}
+
+// 재전송할 수 있도록 ack 전에는 batch가 pool로 돌아가지 않아야 한다.
+func TestWindowReleasesOnAck(t *testing.T) {
+	w := newWindow("bench")
+	p := protocol{schema: 3, batchAck: true}
+	first := w.add([]*nefiv1.TraceEvent{{Namespace: "a"}}, p)
+	second := w.add([]*nefiv1.TraceEvent{{Namespace: "b"}}, p)
+
+	w.ack(first.Seq)
+	if len(first.Events) != 0 || first.Seq != 0 {
+		t.Errorf("acked batch was not released: %v", first)
+	}
+	pending := w.pending()
+	if len(pending) != 1 || pending[0] != second || second.Events[0].Namespace != "b" {
+		t.Fatalf("pending = %v, want the unacknowledged batch intact", pending)
+	}
+}
@@ -0,0 +1,239 @@
+package grpc
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/metrics"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// tier는 전송 큐 우선순위다. 값이 작을수록 먼저 보내고 늦게 버린다.
+type tier int
+
+const (
+	tierError      tier = iota // 5xx 응답 — 장애 분석에 가장 중요
+	tierL7                     // 그 밖의 L7(HTTP) 요청/응답
+	tierConnection             // payload 없는 연결 관측 — 대량, 주기적으로 재관측됨
+	numTiers
+)
+
+var tierNames = [numTiers]string{"error", "l7", "connection"}
+
+func (t tier) String() string { return tierNames[t] }
+
+// tierOf는 이벤트의 우선순위를 정한다. HTTP status는 server에서 파싱하므로
+// agent는 응답 payload의 status line 첫 숫자만 본다.
+func tierOf(ev *nefiv1.TraceEvent) tier {
+	switch {
+	case ev.Connection:
+		return tierConnection
+	case model.MsgType(ev.MsgType) == model.MsgResponse && isServerError(ev.Payload):
+		return tierError
+	}
+	return tierL7
+}
+
+// isServerError는 payload가 "HTTP/1.x 5xx" status line으로 시작하는지 본다.
+func isServerError(p []byte) bool {
+	return len(p) > 9 && bytes.HasPrefix(p, []byte("HTTP/1.")) && p[8] == ' ' && p[9] == '5'
+}
+
+// priorityQueue는 tier별 채널로 구성된 전송 큐다.
+//
+// 전체 깊이가 상한에 닿으면 들어온 이벤트보다 낮은 tier의 이벤트를 하나 버리고
+// 자리를 만든다. 버릴 낮은 tier 이벤트가 없으면 들어온 이벤트를 버린다.
+// 꺼낼 때는 항상 높은 tier부터 꺼낸다.
+type priorityQueue struct {
+	ch       [numTiers]chan *nefiv1.TraceEvent
+	enqueued [numTiers]atomic.Uint64
+	dropped  [numTiers]atomic.Uint64
+}
+
+// newPriorityQueue는 tier별로 limits[t]개까지 쌓는 큐를 만든다.
+func newPriorityQueue(limits [numTiers]int) *priorityQueue {
+	q := &priorityQueue{}
+	for t := range q.ch {
+		q.ch[t] = make(chan *nefiv1.TraceEvent, limits[t])
+	}
+	return q
+}
+
+// ParseTierLimits parses "tier=n" pairs such as "connection=256,l7=1024"
+// into Config.TierLimits. Tiers are error, l7 and connection.
+func ParseTierLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		name, v, ok := strings.Cut(kv, "=")
+		if !ok || !slices.Contains(tierNames[:], name) {
+			return nil, fmt.Errorf("tier limit %q: want <error|l7|connection>=<events>", kv)
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("tier limit %q: want a positive event count", kv)
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
+
+// len은 모든 tier에 쌓인 이벤트 수다.
+func (q *priorityQueue) len() int {
+	n := 0
+	for _, ch := range q.ch {
+		n += len(ch)
+	}
+	return n
+}
+
+// push는 ev를 넣는다. limit은 전체 깊이 상한이다. tier의 상한에 닿으면
+// 다른 tier를 버리지 않고 ev를 버린다.
+// push는 이벤트 루프 한 곳에서만 호출된다 (소비자는 전송 고루틴 하나).
+func (q *priorityQueue) push(ev *nefiv1.TraceEvent, limit int) {
+	t := tierOf(ev)
+	if q.len() >= limit && !q.evictBelow(t) {
+		q.dropped[t].Add(1)
+		releaseEvent(ev)
+		return
+	}
+	select {
+	case q.ch[t] <- ev:
+		q.enqueued[t].Add(1)
+	default:
+		q.dropped[t].Add(1)
+		releaseEvent(ev)
+	}
+}
+
+// evictBelow는 t보다 낮은 tier에서 가장 낮은 것부터 이벤트 하나를 버린다.
+func (q *priorityQueue) evictBelow(t tier) bool {
+	for low := numTiers - 1; low > t; low-- {
+		select {
+		case ev := <-q.ch[low]:
+			q.dropped[low].Add(1)
+			releaseEvent(ev)
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// totals는 모든 tier의 enqueue, drop 누적 수다.
+func (q *priorityQueue) totals() (enqueued, dropped uint64) {
+	for t := range q.ch {
+		enqueued += q.enqueued[t].Load()
+		dropped += q.dropped[t].Load()
+	}
+	return enqueued, dropped
+}
+
+// tryPop은 가장 높은 tier의 이벤트를 블로킹 없이 꺼낸다.
+func (q *priorityQueue) tryPop() (*nefiv1.TraceEvent, bool) {
+	for _, ch := range q.ch {
+		select {
+		case ev := <-ch:
+			return ev, true
+		default:
+		}
+	}
+	return nil, false
+}
+
+// pop은 이벤트가 올 때까지 기다린다. done이 닫히면 남은 이벤트와 관계없이
+// ok=false다 (남은 이벤트는 drain이 deadline 안에서 보낸다). stop이 닫혀도 기다림을
+// 멈추고 ok=false를 반환한다 — 유휴 중에 server가 스트림을 닫은 것을 바로 알아챈다.
+// 여러 tier가 동시에 준비돼 있으면 높은 tier가 우선한다.
+func (q *priorityQueue) pop(done, stop <-chan struct{}) (*nefiv1.TraceEvent, bool) {
+	return q.wait(done, stop, nil)
+}
+
+// popUntil은 pop과 같되 deadline이 오면 기다림을 멈추고 ok=false를 반환한다.
+// batch를 채울 때 linger 시간 동안만 다음 이벤트를 기다리는 데 쓴다.
+func (q *priorityQueue) popUntil(done <-chan struct{}, deadline <-chan time.Time) (*nefiv1.TraceEvent, bool) {
+	return q.wait(done, nil, deadline)
+}
+
+func (q *priorityQueue) wait(done, stop <-chan struct{}, deadline <-chan time.Time) (*nefiv1.TraceEvent, bool) {
+	select {
+	case <-done:
+		return nil, false
+	default:
+	}
+	if ev, ok := q.tryPop(); ok {
+		return ev, true
+	}
+	select {
+	case <-done:
+		return nil, false
+	case <-stop:
+		return nil, false
+	case <-deadline:
+		return nil, false
+	case ev := <-q.ch[tierError]:
+		return ev, true
+	case ev := <-q.ch[tierL7]:
+		return ev, true
+	case ev := <-q.ch[tierConnection]:
+		return ev, true
+	}
+}
+
+// registerMetrics는 tier별 큐 깊이와 enqueue/drop 건수를 reg에 등록한다.
+func (q *priorityQueue) registerMetrics(reg *metrics.Registry) {
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_export_queue_depth",
+		Help: "Events waiting in the exporter queue, by priority tier.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, numTiers)
+			for t := range q.ch {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"tier": tier(t).String()},
+					Value:  float64(len(q.ch[t])),
+				})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_export_enqueued_total",
+		Help: "Events accepted into the exporter queue, by priority tier.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, numTiers)
+			for t := range q.enqueued {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"tier": tier(t).String()},
+					Value:  float64(q.enqueued[t].Load()),
+				})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_export_dropped_total",
+		Help: "Events dropped or evicted from the full exporter queue, by priority tier.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, numTiers)
+			for t := range q.dropped {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"tier": tier(t).String()},
+					Value:  float64(q.dropped[t].Load()),
+				})
+			}
+			return samples
+		},
+	})
+}
@@ -2,93 +2,282 @@
 //
 // 역할:
 //   agent의 이벤트 루프에서 DataEvent를 받아 TraceEvent proto로 변환한 뒤,
-//   nefi-server의 NefiCollector.SendEvents 스트림에 전송한다.
+//   batch로 묶어 nefi-server의 NefiCollector.StreamBatches 스트림에 전송한다.
+//
+// 버전 협상:
+//   연결마다 먼저 NefiCollector.Negotiate로 스키마 버전과 기능(batch ack, gzip, 문자열
+//   사전)을 정한다. 사전에 합의하면 batch의 반복되는 이름(node, namespace, pod, 서비스 등)을
+//   batch 문자열 테이블의 index로 바꿔 보낸다 (internal/batchdict).
+//   server가 이 agent보다 오래됐으면 server의 스키마로 낮춰 보내고, Negotiate가 없는
+//   server에는 ack 없는 SendEvents 스트림으로 이벤트를 하나씩 보낸다.
+//
+// 전송 확인 (ack):
+//   batch마다 seq를 붙여 보내고, server가 저장 후 돌려주는 BatchAck로 확인한다.
+//   ack되지 않은 batch는 window에 보관했다가 재연결한 스트림에 먼저 다시 보낸다.
+//   batch에는 프로세스마다 새로 만든 producer_id가 붙어, ack 직전에 끊겨 다시 보낸 batch는
+//   server가 (producer_id, seq)로 알아보고 한 번만 저장한다. server가 바뀌면(다른 replica)
+//   중복 제거 상태가 없으므로 두 번 저장될 수 있다 (at-least-once).
+//   batch는 BatchSize개가 차거나 첫 이벤트 후 FlushInterval이 지나면 보낸다.
+//   window가 maxUnacked개로 차면 ack가 올 때까지 새 batch를 보내지 않으며,
+//   그동안 들어온 이벤트는 우선순위 큐에 쌓인다.
 //
 // 재연결 전략:
 //   연결이 끊기면 exponential backoff(최대 30초)로 재연결을 시도한다.
 //   server가 잠시 내려가도 agent는 계속 캡처를 유지한다.
+//   대기 시간에는 jitter를 섞어, server 재시작 후 모든 agent가 같은 순간에
+//   재연결하지 않도록 분산시킨다. server가 RetryInfo로 대기 시간을 알려주면
+//   (연결 ramp-up pacing, 노드별 수집 속도 제한) 그 값을 하한으로 사용한다.
+//   재연결마다 server 주소를 DNS로 다시 해석하고 응답한 backend 주소를 로그로 남기므로,
+//   server rollout 후 agent는 사라진 pod IP에 머물지 않는다.
+//
+// 우선순위 큐:
+//   전송 큐는 tier(5xx 응답 > 그 밖의 L7 > 연결 관측)별로 나뉜다. 큐가 가득 차면
+//   낮은 tier 이벤트를 먼저 버려 자리를 만들고, 전송도 높은 tier부터 한다.
+//   대량의 연결 이벤트 때문에 장애 시점의 에러 응답을 잃지 않기 위함이다.
+//
+// unary fallback:
+//   일부 proxy(Istio 설정 등)는 오래 열린 client stream을 끊는다. 스트림이
+//   streamHealthyAfter도 못 버티고 unaryFallbackAfter번 연속 끊기면 unaryRetryStream 동안
+//   batch마다 unary SendBatch를 호출하고(응답이 ack다), 그 뒤 스트림을 다시 시도한다.
+//
+// 종료 (drain):
+//   Close()는 큐에 남은 이벤트를 DrainTimeout 동안 계속 전송한 뒤 스트림을 닫고
+//   server가 스트림을 끝낼 때까지(최대 drainCloseWait 더) 남은 ack를 기다린다.
+//   SendEvents 스트림은 CloseAndRecv로 server가 받은 이벤트 수를 받는다. 어느 쪽이든
+//   server가 확인한 이벤트 수를 그 스트림으로 보낸 수와 비교해 로그로 남긴다.
+//   deadline 안에 보내지 못했거나 ack받지 못한 이벤트는 버리고, flush/abandon 건수를
+//   로그로 남긴다.
 package grpc
 
 import (
 	"context"
+	"io"
 	"log"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
 	"github.com/gihongjo/nefi/internal/model"
+	"github.com/gihongjo/nefi/internal/version"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	initialBackoff = 1 * time.Second
 	maxBackoff     = 30 * time.Second
-	sendChanSize   = 512
+	drainCloseWait = 2 * time.Second // drain 후 server의 남은 ack 대기 상한
+	maxUnacked     = 16              // ack를 기다리는 최대 batch 수
+	maxBatchSize   = 10000           // server가 batch 하나에 받는 최대 이벤트 수
+)
+
+// Config의 0 값에 적용되는 기본값.
+const (
+	DefaultQueueSize     = 512
+	DefaultBatchSize     = 256
+	DefaultFlushInterval = 200 * time.Millisecond
 )
 
+// Config는 Sender 설정이다.
+type Config struct {
+	ServerAddr   string                           // nefi-server gRPC 주소 (예: "nefi-server:9090")
+	NodeName     string                           // 스트림 메타데이터로 server에 보고되는 노드 이름
+	DrainTimeout time.Duration                    // 종료 시 남은 이벤트를 전송하는 최대 시간 (0 = drain 안 함)
+	Handshake    Handshake                        // 스트림 시작 시 버전 정보와 함께 보고하는 실행 환경
+	Keepalive    Keepalive                        // 연결 상태 확인 (gRPC keepalive ping)
+	Compress     bool                             // server가 지원하면 gzip으로 압축해 보낸다
+	Credentials  credentials.TransportCredentials // 연결 보안 (TLS.Credentials, nil = 평문)
+	Capture      func() CaptureLoad               // 부하 보고에 싣는 캡처 지표 (nil = 보내지 않음)
+
+	QueueSize     int            // 전송 큐 전체 상한 (이벤트 수)
+	TierLimits    map[string]int // tier 이름 → 그 tier에 쌓을 수 있는 이벤트 수 (없으면 QueueSize)
+	BatchSize     int            // batch 하나의 최대 이벤트 수 (최대 10000)
+	FlushInterval time.Duration  // 첫 이벤트 이후 batch를 채우며 기다리는 최대 시간
+}
+
+// Keepalive는 server 연결의 상태 확인 설정이다. NLB/ELB가 idle 연결을 조용히
+// 끊으면 Send는 에러 없이 막힐 수 있으므로, ping 응답이 없으면 연결을 닫고
+// 재연결한다 (ack되지 않은 batch는 다시 보낸다).
+type Keepalive struct {
+	Time                time.Duration // ping 간격 (0 = keepalive 끔). server의 최소 허용 간격 이상이어야 한다
+	Timeout             time.Duration // ping 응답 대기 시간. 넘으면 연결이 끊긴 것으로 본다
+	PermitWithoutStream bool          // 스트림이 없을 때도 ping한다
+}
+
+// Handshake는 agent가 스트림을 열 때 server에 보고하는 실행 환경/수집 능력이다.
+// server는 이를 agent registry에 기록해 fleet coverage와 버전 skew를 보여준다.
+type Handshake struct {
+	KernelVersion string            // uname release (예: "6.1.0-18-amd64")
+	Probes        []string          // 활성 수집 방식 (예: "tracepoints", "ssl_uprobes", "proc_fallback")
+	NodeLabels    map[string]string // 노드 topology label (zone, region, instance type)
+	Cluster       string            // agent가 속한 클러스터 이름 (--cluster-name)
+	Tenant        string            // agent 이벤트를 소유하는 tenant (--tenant)
+}
+
 // Sender는 nefi-server로 이벤트를 스트리밍하는 gRPC 클라이언트다.
 type Sender struct {
-	serverAddr string
-	nodeName   string
-	ch         chan *nefiv1.TraceEvent
-	done       chan struct{}
+	serverAddr   string
+	nodeName     string
+	drainTimeout time.Duration
+	handshake    Handshake
+	keepalive    Keepalive
+	compress     bool
+	creds        credentials.TransportCredentials
+	queueSize    int
+	batchSize    int
+	linger       time.Duration
+	queue        *priorityQueue
+	unacked      *window
+	load         loadReporter
+	stats        exportStats
+	done         chan struct{}
+	finished     chan struct{} // run 종료 (drain 완료) 시 닫힘
+	connected    atomic.Bool   // 현재 스트림이 열려 있는지
+	unary        atomic.Bool   // unary SendBatch로 전송 중인지 (스트림 fallback)
+	queueLimit   atomic.Int32  // 0보다 크면 큐 용량 대신 적용되는 상한 (메모리 보호)
+
+	// run 고루틴 전용 — 스트림이 연속으로 끊기면 unaryUntil까지 unary로 보낸다.
+	streamFailures int
+	unaryUntil     time.Time
+}
+
+// State는 Sender의 현재 상태다.
+type State struct {
+	Connected  bool
+	QueueDepth int
+	QueueCap   int
+}
+
+// State는 연결 여부와 전송 큐 깊이를 반환한다.
+func (s *Sender) State() State {
+	return State{Connected: s.connected.Load(), QueueDepth: s.queue.len(), QueueCap: s.queueCap()}
+}
+
+// SetQueueLimit은 전송 큐에 쌓을 수 있는 이벤트 수를 n으로 줄인다.
+// n ≤ 0 또는 n ≥ 큐 용량이면 원래 용량으로 되돌린다. 이미 쌓인 이벤트는 버리지 않는다.
+func (s *Sender) SetQueueLimit(n int) {
+	if n >= s.queueSize {
+		n = 0
+	}
+	s.queueLimit.Store(int32(max(n, 0)))
+}
+
+func (s *Sender) queueCap() int {
+	if n := int(s.queueLimit.Load()); n > 0 {
+		return n
+	}
+	return s.queueSize
 }
 
 // New는 Sender를 생성하고 백그라운드 전송 고루틴을 시작한다.
-// serverAddr: nefi-server gRPC 주소 (예: "nefi-server:9090")
-// nodeName: 이 agent가 실행 중인 노드 이름
-func New(serverAddr, nodeName string) *Sender {
+// 큐/batch 설정의 0 값에는 기본값을 적용한다.
+func New(cfg Config) *Sender {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = insecure.NewCredentials()
+	}
+	var limits [numTiers]int
+	for t := range limits {
+		limits[t] = cfg.QueueSize
+		if n, ok := cfg.TierLimits[tier(t).String()]; ok && n < cfg.QueueSize {
+			limits[t] = n
+		}
+	}
 	s := &Sender{
-		serverAddr: serverAddr,
-		nodeName:   nodeName,
-		ch:         make(chan *nefiv1.TraceEvent, sendChanSize),
-		done:       make(chan struct{}),
+		serverAddr:   cfg.ServerAddr,
+		nodeName:     cfg.NodeName,
+		drainTimeout: cfg.DrainTimeout,
+		handshake:    cfg.Handshake,
+		keepalive:    cfg.Keepalive,
+		compress:     cfg.Compress,
+		creds:        cfg.Credentials,
+		queueSize:    cfg.QueueSize,
+		batchSize:    min(cfg.BatchSize, maxBatchSize),
+		linger:       cfg.FlushInterval,
+		queue:        newPriorityQueue(limits),
+		unacked:      newWindow(uuid.NewString()),
+		load:         loadReporter{capture: cfg.Capture, last: time.Now()},
+		done:         make(chan struct{}),
+		finished:     make(chan struct{}),
 	}
 	go s.run()
 	return s
 }
 
-// Send는 DataEvent를 TraceEvent로 변환해 전송 큐에 넣는다.
-// 큐가 가득 차면 이벤트를 drop한다 (캡처 루프 블로킹 방지).
-func (s *Sender) Send(ev *model.DataEvent, namespace, podName, remoteNs, remotePod string) {
-	proto := &nefiv1.TraceEvent{
-		TimestampNs: ev.TimestampNs,
-		Pid:         ev.PID,
-		Fd:          ev.FD,
-		MsgSize:     ev.MsgSize,
-		Direction:   uint32(ev.Direction),
-		Protocol:    uint32(ev.Protocol),
-		MsgType:     uint32(ev.MsgType),
-		Comm:        ev.CommString(),
-		Namespace:   namespace,
-		PodName:     podName,
-		NodeName:    s.nodeName,
-		RemoteIp:    ev.RemoteIP,
-		RemotePort:  uint32(ev.RemotePort),
-		RemoteNs:    remoteNs,
-		RemotePod:   remotePod,
-		Payload:     ev.Payload(),
-	}
-
-	select {
-	case s.ch <- proto:
-	default:
-		// 큐 가득 참 → drop
+// NewTraceEvent는 DataEvent를 TraceEvent로 변환한다. TraceEvent는 pool에서 꺼내며,
+// Sender가 ack받은 뒤 pool로 돌려보낸다. sink로 보내지 않고 버릴 때는 ReleaseTraceEvent를 쓴다.
+// K8s 메타데이터(namespace, pod, remote 등)는 비어 있으며 호출자가 보강한다.
+// nodeName: 이 agent가 실행 중인 노드 이름
+func NewTraceEvent(ev *model.DataEvent, nodeName string) *nefiv1.TraceEvent {
+	te := newEvent()
+	te.TimestampNs = ev.TimestampNs
+	te.Pid = ev.PID
+	te.Fd = ev.FD
+	te.MsgSize = ev.MsgSize
+	te.Direction = uint32(ev.Direction)
+	te.Protocol = uint32(ev.Protocol)
+	te.MsgType = uint32(ev.MsgType)
+	te.Comm = ev.CommString()
+	te.NodeName = nodeName
+	te.RemoteIp = ev.RemoteIP
+	te.RemotePort = uint32(ev.RemotePort)
+	te.Payload = ev.Payload()
+	te.ConnId = ConnID(nodeName, ev.PID, ev.FD)
+	return te
+}
+
+// ConnID는 소켓 식별자 "<node>/<pid>/<fd>"를 만든다. 같은 소켓에서 관측된
+// 연결 이벤트(/proc/net fallback)와 HTTP 이벤트(eBPF)가 같은 값을 갖는다.
+// fd를 모르면(0) 빈 문자열이다.
+func ConnID(nodeName string, pid, fd uint32) string {
+	if fd == 0 {
+		return ""
 	}
+	return nodeName + "/" + strconv.FormatUint(uint64(pid), 10) + "/" + strconv.FormatUint(uint64(fd), 10)
 }
 
-// Close는 Sender를 종료하고 gRPC 연결을 닫는다.
+// Send는 보강이 끝난 TraceEvent를 전송 큐에 넣는다.
+// 큐가 가득 차면(메모리 보호로 줄어든 상한 포함) 낮은 tier부터 drop한다 (캡처 루프 블로킹 방지).
+func (s *Sender) Send(ev *nefiv1.TraceEvent) {
+	s.queue.push(ev, s.queueCap())
+}
+
+// Close는 큐에 남은 이벤트를 drain한 뒤 gRPC 연결을 닫는다.
+// 최대 DrainTimeout(+ 스트림 종료 대기)만큼 블로킹한다.
 func (s *Sender) Close() {
 	close(s.done)
+	<-s.finished
 }
 
 // run은 server에 연결하고 이벤트를 스트리밍한다.
 // 연결이 끊기면 exponential backoff로 재연결한다.
 func (s *Sender) run() {
+	defer close(s.finished)
 	backoff := initialBackoff
 	for {
 		select {
 		case <-s.done:
+			s.reportDrain(0)
 			return
 		default:
 		}
@@ -98,15 +287,29 @@ func (s *Sender) run() {
 			// 연결에 성공했다가 끊어진 경우 backoff 초기화
 			backoff = initialBackoff
 		}
-		if err != nil {
-			log.Printf("[sender] stream error: %v — retrying in %v", err, backoff)
+		s.stats.reconnects.Add(1)
+		wait := jitter(backoff)
+		if hint, ok := retryDelay(err); ok {
+			// server가 연결 수락 속도나 수집 속도를 조절 중 — 안내받은 시간 이후로 분산해 재시도한다.
+			wait = hint + jitter(hint)
+			log.Printf("[sender] server is throttling: %s — retrying in %v", status.Convert(err).Message(), wait.Round(time.Millisecond))
+		} else if guidance, ok := incompatible(err); ok {
+			// server가 이 agent 버전을 거부함 — 재시도는 최대 간격으로만 한다.
+			backoff = maxBackoff
+			wait = jitter(backoff)
+			log.Printf("[sender] server rejected this agent: %s — retrying in %v", guidance, wait.Round(time.Millisecond))
+		} else if err != nil {
+			log.Printf("[sender] stream error: %v — retrying in %v", err, wait.Round(time.Millisecond))
 		}
 
+		s.stats.backoff.Store(int64(wait))
 		select {
 		case <-s.done:
+			s.reportDrain(0)
 			return
-		case <-time.After(backoff):
+		case <-time.After(wait):
 		}
+		s.stats.backoff.Store(0)
 
 		backoff *= 2
 		if backoff > maxBackoff {
@@ -115,13 +318,59 @@ func (s *Sender) run() {
 	}
 }
 
-// stream은 서버에 연결하고 이벤트를 스트리밍한다.
+// metadata는 스트림 시작 시 server에 보고할 agent 식별/버전 정보와 handshake다.
+// schema는 Negotiate로 합의한 스키마 버전이다.
+func (s *Sender) metadata(schema int) metadata.MD {
+	info := version.Get()
+	labels := make([]string, 0, len(s.handshake.NodeLabels))
+	for k, v := range s.handshake.NodeLabels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	return metadata.Pairs(
+		version.MDNodeName, s.nodeName,
+		version.MDVersion, info.Version,
+		version.MDGitCommit, info.GitCommit,
+		version.MDBuildDate, info.BuildDate,
+		version.MDSchemaVersion, strconv.Itoa(schema),
+		version.MDKernelVersion, s.handshake.KernelVersion,
+		version.MDProbes, strings.Join(s.handshake.Probes, ","),
+		version.MDNodeLabels, strings.Join(labels, ","),
+		version.MDCluster, s.handshake.Cluster,
+		version.MDTenant, s.handshake.Tenant,
+	)
+}
+
+// serviceConfig는 server 주소가 여러 IP(headless Service, 여러 A 레코드)로 해석될 때
+// agent마다 무작위 순서로 골라 연결을 server pod 사이에 고르게 나눈다.
+const serviceConfig = `{"loadBalancingConfig":[{"pick_first":{"shuffleAddressList":true}}]}`
+
+// dialTarget은 scheme이 없는 server 주소에 dns resolver를 명시한다. stream은 재연결마다
+// 새 ClientConn을 만들므로 DNS를 다시 해석한다 — server pod가 옮겨 가도 죽은 IP에
+// 머물지 않고 rollout 직후 새 pod로 수렴한다.
+func dialTarget(addr string) string {
+	if strings.Contains(addr, "://") || strings.HasPrefix(addr, "unix:") {
+		return addr
+	}
+	return "dns:///" + addr
+}
+
+// stream은 서버에 연결해 전송 방식을 협상하고 이벤트를 스트리밍한다.
 // connected=true는 한 번이라도 스트림 전송에 성공했음을 의미하며,
 // 호출자가 backoff를 리셋하는 데 사용된다.
 func (s *Sender) stream() (connected bool, err error) {
-	conn, dialErr := grpc.NewClient(s.serverAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(s.creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	}
+	if s.keepalive.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                s.keepalive.Time,
+			Timeout:             s.keepalive.Timeout,
+			PermitWithoutStream: s.keepalive.PermitWithoutStream,
+		}))
+	}
+	conn, dialErr := grpc.NewClient(dialTarget(s.serverAddr), opts...)
 	if dialErr != nil {
 		return false, dialErr
 	}
@@ -130,27 +379,264 @@ func (s *Sender) stream() (connected bool, err error) {
 	client := nefiv1.NewNefiCollectorClient(conn)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	p, err := s.negotiate(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	ctx = metadata.NewOutgoingContext(ctx, s.metadata(p.schema))
+	var callOpts []grpc.CallOption
+	if p.gzip {
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	}
+	if !p.batchAck {
+		return s.streamEvents(ctx, cancel, client, p, callOpts)
+	}
+	if time.Now().Before(s.unaryUntil) {
+		return s.sendUnary(ctx, client, p, callOpts)
+	}
+	start := time.Now()
+	connected, err = s.streamBatches(ctx, cancel, client, p, callOpts)
+	s.noteStream(err, time.Since(start))
+	return connected, err
+}
 
-	st, streamErr := client.SendEvents(ctx)
+// streamBatches는 StreamBatches로 batch를 보내고 ack를 받는다.
+func (s *Sender) streamBatches(ctx context.Context, cancel context.CancelFunc, client nefiv1.NefiCollectorClient, p protocol, callOpts []grpc.CallOption) (connected bool, err error) {
+	st, streamErr := client.StreamBatches(ctx, callOpts...)
 	if streamErr != nil {
 		return false, streamErr
 	}
 
-	log.Printf("[sender] connected to server %s", s.serverAddr)
+	log.Printf("[sender] connected to server %s → %s (%s)", s.serverAddr, p.backend, p)
 	connected = true
+	s.connected.Store(true)
+	defer s.connected.Store(false)
+
+	// acks는 ack 수신 고루틴의 종료 원인이다 (server가 스트림을 정상 종료하면 nil).
+	acks := make(chan error, 1)
+	recvDone := make(chan struct{})
+	go func() {
+		defer close(recvDone)
+		acks <- s.recvAcks(st)
+	}()
+	// ack는 batch를 pool로 돌려보내므로, 다음 연결이 같은 batch를 재전송하기 전에
+	// 이 스트림의 ack 수신을 끝낸다.
+	defer func() {
+		cancel()
+		<-recvDone
+	}()
+
+	str := streamed{acked: s.unacked.acked.Load()}
+	if pending := s.unacked.pending(); len(pending) > 0 {
+		log.Printf("[sender] resending %d unacknowledged batches", len(pending))
+		for _, b := range pending {
+			p.encode(b)
+			n := len(b.Events) // Send 후에는 ack로 b가 pool로 돌아갔을 수 있다
+			if err := st.Send(b); err != nil {
+				return connected, sendError(err, acks)
+			}
+			str.sent += n
+			s.stats.sent(n, true)
+		}
+	}
+
+	for {
+		for s.unacked.len() >= maxUnacked {
+			select {
+			case <-s.unacked.freed:
+			case err := <-acks:
+				return connected, err
+			case <-s.done:
+				return connected, s.drain(st, acks, cancel, p, str)
+			}
+		}
+		events, ok := s.nextBatch(s.linger, recvDone)
+		if !ok {
+			select {
+			case <-s.done:
+				return connected, s.drain(st, acks, cancel, p, str)
+			default:
+				return connected, <-acks // 유휴 중에 server가 스트림을 닫았다
+			}
+		}
+		b := s.newBatch(events, p)
+		if err := st.Send(b); err != nil {
+			return connected, sendError(err, acks)
+		}
+		str.sent += len(events)
+		s.stats.sent(len(events), false)
+	}
+}
+
+// streamed는 한 스트림의 전송량이다. 종료 시 server가 ack한 이벤트 수와 비교한다.
+type streamed struct {
+	acked uint64 // 스트림 시작 시점의 window.acked
+	sent  int    // 이 스트림으로 보낸 이벤트 수 (재전송 포함)
+}
 
+// newBatch는 events로 window에 보관할 batch를 만들고, 때가 되면 부하 보고를 싣는다.
+func (s *Sender) newBatch(events []*nefiv1.TraceEvent, p protocol) *nefiv1.EventBatch {
+	b := s.unacked.add(events, p)
+	s.attachLoad(b)
+	return b
+}
+
+// nextBatch는 이벤트가 올 때까지 기다린 뒤, linger 동안 BatchSize까지 더 모은다.
+// done이 닫히거나, 첫 이벤트를 기다리는 동안 stop(스트림의 ack 수신 종료)이 닫히면 ok=false다.
+func (s *Sender) nextBatch(linger time.Duration, stop <-chan struct{}) ([]*nefiv1.TraceEvent, bool) {
+	ev, ok := s.queue.pop(s.done, stop)
+	if !ok {
+		return nil, false
+	}
+	events := append(newEvents(s.batchSize), ev)
+	timer := time.NewTimer(linger)
+	defer timer.Stop()
+	for len(events) < s.batchSize {
+		ev, ok := s.queue.popUntil(s.done, timer.C)
+		if !ok {
+			break
+		}
+		events = append(events, ev)
+	}
+	return events, true
+}
+
+// recvAcks는 server의 ack를 받아 window에서 확인된 batch를 버린다.
+// 스트림이 끝날 때까지 블로킹하며, 정상 종료(io.EOF)면 nil을 반환한다.
+func (s *Sender) recvAcks(st grpc.BidiStreamingClient[nefiv1.EventBatch, nefiv1.BatchAck]) error {
 	for {
+		ack, err := st.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		n, latency := s.unacked.ack(ack.GetSeq())
+		s.stats.ackedBatches.Add(uint64(n))
+		s.stats.ackLatency.Add(int64(latency))
+	}
+}
+
+// sendError는 Send 실패 원인을 반환한다. server가 스트림을 닫으면 Send는 io.EOF만
+// 반환하므로 실제 status는 ack 수신 고루틴(Recv)에서 받는다.
+func sendError(err error, acks <-chan error) error {
+	if err == io.EOF {
+		return <-acks
+	}
+	return err
+}
+
+// drain은 종료 시 큐에 남은 이벤트를 drainTimeout 동안 batch로 전송하고 스트림을 닫은 뒤
+// server가 스트림을 끝낼 때까지 남은 ack를 기다린다. server 응답이 없으면
+// drainTimeout + drainCloseWait 후 cancel로 스트림을 강제 종료한다. flush 건수는
+// drain 중 ack된 이벤트 수다.
+func (s *Sender) drain(st grpc.BidiStreamingClient[nefiv1.EventBatch, nefiv1.BatchAck], acks <-chan error, cancel context.CancelFunc, p protocol, str streamed) error {
+	deadline := time.NewTimer(s.drainTimeout)
+	defer deadline.Stop()
+	force := time.AfterFunc(s.drainTimeout+drainCloseWait, cancel)
+	defer force.Stop()
+
+	acked := s.unacked.acked.Load()
+	var sendErr error
+loop:
+	for s.drainTimeout > 0 {
 		select {
-		case <-s.done:
-			_, err := st.CloseAndRecv()
-			return connected, err
-		case ev, ok := <-s.ch:
+		case <-deadline.C:
+			break loop
+		default:
+		}
+		events := newEvents(s.batchSize)
+		for len(events) < s.batchSize {
+			ev, ok := s.queue.tryPop()
 			if !ok {
-				return connected, nil
+				break
 			}
-			if err := st.Send(ev); err != nil {
-				return connected, err
+			events = append(events, ev)
+		}
+		if len(events) == 0 {
+			break loop
+		}
+		b := s.newBatch(events, p)
+		if sendErr = st.Send(b); sendErr != nil {
+			break loop
+		}
+		str.sent += len(events)
+		s.stats.sent(len(events), false)
+	}
+
+	st.CloseSend() //nolint:errcheck
+	err := <-acks
+	reportClose(int(s.unacked.acked.Load()-str.acked), str.sent, err)
+	if sendErr != nil && sendErr != io.EOF {
+		err = sendErr
+	}
+	s.reportDrain(int(s.unacked.acked.Load() - acked))
+	return err
+}
+
+// reportDrain은 종료 시점의 flush/abandon 건수를 기록한다.
+func (s *Sender) reportDrain(flushed int) {
+	log.Printf("[sender] shutdown drain: flushed %d events, abandoned %d (%d unacknowledged)",
+		flushed, s.queue.len()+s.unacked.events(), s.unacked.events())
+}
+
+// reportClose는 종료 시 마지막 스트림에서 server가 받았다고 확인한 이벤트 수를 그 스트림으로
+// 보낸 수와 비교해 남긴다. err는 스트림 종료 결과다 (server 응답 없이 끊겼으면 nil이 아니다).
+func reportClose(accepted, sent int, err error) {
+	switch {
+	case err != nil:
+		log.Printf("[sender] final flush: server accepted %d of %d events sent on this stream, %d unconfirmed — stream closed with %v",
+			accepted, sent, sent-accepted, err)
+	case accepted < sent:
+		log.Printf("[sender] final flush: server accepted %d of %d events sent on this stream, %d unconfirmed",
+			accepted, sent, sent-accepted)
+	default:
+		log.Printf("[sender] final flush: server accepted all %d events sent on this stream", accepted)
+	}
+}
+
+// jitter는 d를 [d/2, d) 범위의 임의 값으로 바꾼다 (equal jitter).
+// 절반은 보장해 과도한 재시도를 막고, 나머지 절반으로 agent 간 재연결 시점을 흩는다.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// retryDelay는 server가 RetryInfo로 안내한 재시도 대기 시간을 반환한다. 연결 수락 속도
+// 제한은 Unavailable, 수집 속도 제한은 ResourceExhausted로 온다.
+func retryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || (st.Code() != codes.Unavailable && st.Code() != codes.ResourceExhausted) {
+		return 0, false
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// incompatible은 err가 server의 버전 호환성 거부인지 확인하고 안내 문구를 반환한다.
+// 호환성 거부는 version.ViolationAgentSchema violation으로 알아본다. 다른 이유의
+// FailedPrecondition까지 버전 문제로 보면 재시도를 최대 간격으로 늦추고 엉뚱한 안내를 남긴다.
+func incompatible(err error) (string, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.FailedPrecondition {
+		return "", false
+	}
+	for _, d := range st.Details() {
+		if pf, ok := d.(*errdetails.PreconditionFailure); ok {
+			for _, v := range pf.GetViolations() {
+				if v.GetType() == version.ViolationAgentSchema {
+					return v.GetDescription(), true
+				}
 			}
 		}
 	}
+	return "", false
 }
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/version"
+)
+
+func withViolation(code codes.Code, typ, desc string) error {
+	st, err := status.New(code, "rejected").WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{Type: typ, Description: desc}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+func TestIncompatible(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		guidance string
+		ok       bool
+	}{
+		{"schema violation", withViolation(codes.FailedPrecondition, version.ViolationAgentSchema, "upgrade the agent"), "upgrade the agent", true},
+		{"other violation", withViolation(codes.FailedPrecondition, "TENANT_DISABLED", "tenant is disabled"), "", false},
+		{"no detail", status.Error(codes.FailedPrecondition, "quota not configured"), "", false},
+		{"other code", withViolation(codes.InvalidArgument, version.ViolationAgentSchema, "x"), "", false},
+		{"not a status", errors.New("EOF"), "", false},
+		{"nil", nil, "", false},
+	}
+	for _, tt := range tests {
+		guidance, ok := incompatible(tt.err)
+		if guidance != tt.guidance || ok != tt.ok {
+			t.Errorf("%s: incompatible = %q, %v, want %q, %v", tt.name, guidance, ok, tt.guidance, tt.ok)
+		}
+	}
+}
+
+// closingServer는 StreamBatches를 열자마자 err로 닫는 collector다.
+type closingServer struct {
+	nefiv1.UnimplementedNefiCollectorServer
+	err error
+}
+
+func (s *closingServer) StreamBatches(nefiv1.NefiCollector_StreamBatchesServer) error {
+	return s.err
+}
+
+func TestStreamBatchesIdleClose(t *testing.T) {
+	client := dialCollector(t, &closingServer{err: status.Error(codes.Unavailable, "collector shutting down")})
+	s := &Sender{
+		batchSize: 10,
+		linger:    time.Millisecond,
+		queue:     newPriorityQueue([numTiers]int{10, 10, 10}),
+		unacked:   newWindow("producer-1"),
+		done:      make(chan struct{}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		connected bool
+		err       error
+	}
+	res := make(chan result, 1)
+	go func() {
+		connected, err := s.streamBatches(ctx, cancel, client, protocol{schema: version.SchemaVersion, batchAck: true}, nil)
+		res <- result{connected, err}
+	}()
+	select {
+	case r := <-res:
+		if !r.connected || status.Code(r.err) != codes.Unavailable {
+			t.Errorf("streamBatches = %v, %v, want true, Unavailable", r.connected, r.err)
+		}
+	case <-time.After(5 * time.Second): // 큐가 비어 있어도 닫힌 스트림을 알아채야 한다
+		close(s.done)
+		t.Fatal("idle sender did not notice the closed stream")
+	}
+	if s.connected.Load() {
+		t.Error("sender still reports connected")
+	}
+}
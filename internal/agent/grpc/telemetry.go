@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/metrics"
+)
+
+// exportStats는 전송 고루틴과 ack 수신 고루틴이 갱신하는 exporter 지표다.
+// 데이터 유실을 추측하지 않고 확인할 수 있도록, 큐에 들어온 이벤트부터 server의
+// ack까지 각 단계의 건수를 센다.
+type exportStats struct {
+	sentEvents    atomic.Uint64 // 스트림에 쓴 이벤트 (재전송 포함)
+	sentBatches   atomic.Uint64
+	resentBatches atomic.Uint64 // 재연결 후 다시 보낸 batch
+	ackedBatches  atomic.Uint64
+	ackLatency    atomic.Int64  // batch 전송부터 ack까지 걸린 시간 합 (ns)
+	reconnects    atomic.Uint64 // 스트림이 끊기거나 연결에 실패해 재연결을 기다린 횟수
+	backoff       atomic.Int64  // 현재 재연결 대기 시간 (ns, 연결 중이면 0)
+}
+
+// sent는 events개 이벤트를 담은 batch 하나의 전송을 센다.
+func (st *exportStats) sent(events int, resend bool) {
+	st.sentEvents.Add(uint64(events))
+	if resend {
+		st.resentBatches.Add(1)
+	} else {
+		st.sentBatches.Add(1)
+	}
+}
+
+// RegisterMetrics는 전송 큐, batch 전송/ack, 재연결 지표를 reg에 등록한다.
+func (s *Sender) RegisterMetrics(reg *metrics.Registry) {
+	s.queue.registerMetrics(reg)
+	counter := func(name, help string, v func() float64) {
+		reg.Register(metrics.Family{
+			Name:    name,
+			Help:    help,
+			Kind:    metrics.Counter,
+			Collect: func() []metrics.Sample { return []metrics.Sample{{Value: v()}} },
+		})
+	}
+	gauge := func(name, help string, v func() float64) {
+		reg.Register(metrics.Family{
+			Name:    name,
+			Help:    help,
+			Kind:    metrics.Gauge,
+			Collect: func() []metrics.Sample { return []metrics.Sample{{Value: v()}} },
+		})
+	}
+	counter("nefi_agent_export_sent_events_total",
+		"Events written to the server stream, including resent batches.",
+		func() float64 { return float64(s.stats.sentEvents.Load()) })
+	counter("nefi_agent_export_acked_events_total",
+		"Events the server acknowledged as stored.",
+		func() float64 { return float64(s.unacked.acked.Load()) })
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_export_batches_total",
+		Help: "Event batches by state: sent (first send), resent (after a reconnect) and acked.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{
+				{Labels: metrics.Labels{"state": "sent"}, Value: float64(s.stats.sentBatches.Load())},
+				{Labels: metrics.Labels{"state": "resent"}, Value: float64(s.stats.resentBatches.Load())},
+				{Labels: metrics.Labels{"state": "acked"}, Value: float64(s.stats.ackedBatches.Load())},
+			}
+		},
+	})
+	counter("nefi_agent_export_ack_latency_seconds_total",
+		"Sum of the time from sending a batch to its acknowledgement; divide by nefi_agent_export_batches_total{state=\"acked\"} for the mean.",
+		func() float64 { return time.Duration(s.stats.ackLatency.Load()).Seconds() })
+	counter("nefi_agent_export_reconnects_total",
+		"Times the exporter lost or failed to open the server stream and backed off before retrying.",
+		func() float64 { return float64(s.stats.reconnects.Load()) })
+	gauge("nefi_agent_export_backoff_seconds",
+		"Current reconnect backoff; 0 while connected or connecting.",
+		func() float64 { return time.Duration(s.stats.backoff.Load()).Seconds() })
+	gauge("nefi_agent_export_connected",
+		"1 while the server stream is open.",
+		func() float64 {
+			if s.connected.Load() {
+				return 1
+			}
+			return 0
+		})
+	gauge("nefi_agent_export_unary_fallback",
+		"1 while batches are sent with unary SendBatch calls because the stream kept failing.",
+		func() float64 {
+			if s.unary.Load() {
+				return 1
+			}
+			return 0
+		})
+	gauge("nefi_agent_export_unacked_batches",
+		"Batches sent to the server and not yet acknowledged; resent after a reconnect.",
+		func() float64 { return float64(s.unacked.len()) })
+}
@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"context"
+	"log"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"google.golang.org/grpc"
+)
+
+const (
+	unaryFallbackAfter = 3                // 연속으로 이만큼 스트림이 끊기면 unary로 전환
+	streamHealthyAfter = time.Minute      // 이보다 오래 버틴 스트림은 실패로 세지 않는다
+	unaryRetryStream   = 10 * time.Minute // unary로 보낸 뒤 스트림을 다시 시도하기까지
+	unaryTimeout       = 30 * time.Second // SendBatch 호출 하나의 대기 상한
+	// unaryMinLinger는 unary 모드의 최소 batch 대기 시간이다. server는 SendBatch 호출마다
+	// 수락 속도 제한 토큰을 쓰므로 호출 수를 줄이고 batch를 키운다.
+	unaryMinLinger = time.Second
+)
+
+// noteStream은 스트림 결과로 unary fallback 전환 여부를 정한다.
+// 수락 속도 제한이나 버전 거부는 스트림 경로 문제가 아니므로 세지 않는다.
+func (s *Sender) noteStream(err error, lived time.Duration) {
+	if _, ok := retryDelay(err); ok {
+		return
+	}
+	if _, ok := incompatible(err); ok {
+		return
+	}
+	if err == nil || lived >= streamHealthyAfter {
+		s.streamFailures = 0
+		return
+	}
+	s.streamFailures++
+	if s.streamFailures >= unaryFallbackAfter {
+		s.streamFailures = 0
+		s.unaryUntil = time.Now().Add(unaryRetryStream)
+		log.Printf("[sender] stream failed %d times in a row — sending unary batches for %v", unaryFallbackAfter, unaryRetryStream)
+	}
+}
+
+// sendUnary는 스트림 대신 batch마다 SendBatch를 호출한다. 응답이 곧 ack다.
+// unaryUntil이 지나면 nil을 반환해 run이 스트림을 다시 열게 한다.
+func (s *Sender) sendUnary(ctx context.Context, client nefiv1.NefiCollectorClient, p protocol, callOpts []grpc.CallOption) (connected bool, err error) {
+	log.Printf("[sender] sending to server %s → %s with unary batches (%s)", s.serverAddr, p.backend, p)
+	s.unary.Store(true)
+	defer s.unary.Store(false)
+
+	for _, b := range s.unacked.pending() {
+		p.encode(b)
+		if err := s.sendBatch(ctx, client, b, true, callOpts); err != nil {
+			return connected, err
+		}
+		connected = true
+	}
+	for time.Now().Before(s.unaryUntil) {
+		events, ok := s.nextBatch(max(s.linger, unaryMinLinger), nil)
+		if !ok {
+			return connected, s.drainUnary(ctx, client, p, callOpts)
+		}
+		if err := s.sendBatch(ctx, client, s.newBatch(events, p), false, callOpts); err != nil {
+			return connected, err
+		}
+		connected = true
+	}
+	return connected, nil
+}
+
+// sendBatch는 b를 SendBatch로 보내고 성공하면 window에서 버린다.
+// server가 RetryInfo로 throttle하면 안내받은 시간만큼 기다려 같은 batch를 다시 보낸다.
+func (s *Sender) sendBatch(ctx context.Context, client nefiv1.NefiCollectorClient, b *nefiv1.EventBatch, resend bool, callOpts []grpc.CallOption) error {
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, unaryTimeout)
+		_, err := client.SendBatch(callCtx, b, callOpts...)
+		cancel()
+		s.stats.sent(len(b.Events), resend)
+		if err == nil {
+			n, latency := s.unacked.ack(b.Seq)
+			s.stats.ackedBatches.Add(uint64(n))
+			s.stats.ackLatency.Add(int64(latency))
+			return nil
+		}
+		hint, ok := retryDelay(err)
+		if !ok {
+			return err
+		}
+		resend = true
+		select {
+		case <-time.After(hint + jitter(hint)):
+		case <-s.done:
+			return err
+		}
+	}
+}
+
+// drainUnary는 sendUnary의 drain이다. 큐에 남은 이벤트를 drainTimeout 동안 batch로 보낸다.
+func (s *Sender) drainUnary(ctx context.Context, client nefiv1.NefiCollectorClient, p protocol, callOpts []grpc.CallOption) error {
+	ctx, cancel := context.WithTimeout(ctx, s.drainTimeout)
+	defer cancel()
+
+	acked := s.unacked.acked.Load()
+	var err error
+	for s.drainTimeout > 0 && ctx.Err() == nil {
+		events := newEvents(s.batchSize)
+		for len(events) < s.batchSize {
+			ev, ok := s.queue.tryPop()
+			if !ok {
+				break
+			}
+			events = append(events, ev)
+		}
+		if len(events) == 0 {
+			break
+		}
+		if err = s.sendBatch(ctx, client, s.newBatch(events, p), false, callOpts); err != nil {
+			break
+		}
+	}
+	s.reportDrain(int(s.unacked.acked.Load() - acked))
+	return err
+}
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// window는 server로 보냈지만 아직 ack받지 못한 batch다.
+// 스트림이 끊기면 남은 batch를 재연결한 스트림에 seq 순서대로 다시 보낸다.
+// 전송 고루틴이 batch를 넣고, ack 수신 고루틴이 꺼내므로 mu로 보호한다.
+type window struct {
+	producer string // EventBatch.producer_id — (producer, seq)가 server의 중복 제거 키다
+	mu       sync.Mutex
+	batches  []unackedBatch // seq 오름차순
+	nextSeq  uint64
+	freed    chan struct{} // ack로 자리가 나면 신호 (buffer 1)
+	acked    atomic.Uint64 // ack된 이벤트 누적 수
+}
+
+type unackedBatch struct {
+	batch *nefiv1.EventBatch
+	sent  time.Time // 마지막 전송 시각 (ack 지연 측정용)
+}
+
+// newWindow는 빈 window를 만든다. producer는 이 window가 만드는 batch의 producer_id다.
+func newWindow(producer string) *window {
+	return &window{producer: producer, nextSeq: 1, freed: make(chan struct{}, 1)}
+}
+
+// add는 events를 p에 맞게 인코딩하고 다음 seq를 붙인 batch를 만들어 보관한 뒤 반환한다.
+func (w *window) add(events []*nefiv1.TraceEvent, p protocol) *nefiv1.EventBatch {
+	b := batchPool.Get().(*nefiv1.EventBatch)
+	b.Events = events
+	p.encode(b)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b.Seq = w.nextSeq
+	b.ProducerId = w.producer
+	w.nextSeq++
+	w.batches = append(w.batches, unackedBatch{batch: b, sent: time.Now()})
+	return b
+}
+
+// ack는 seq 이하의 batch를 모두 버리고 pool로 돌려보낸다 (server의 ack는 누적이다).
+// 버린 batch 수와 각 batch의 전송부터 ack까지 걸린 시간의 합을 반환한다.
+func (w *window) ack(seq uint64) (n int, latency time.Duration) {
+	now := time.Now()
+	w.mu.Lock()
+	for n < len(w.batches) && w.batches[n].batch.Seq <= seq {
+		w.acked.Add(uint64(len(w.batches[n].batch.Events)))
+		latency += now.Sub(w.batches[n].sent)
+		releaseBatch(w.batches[n].batch)
+		w.batches[n] = unackedBatch{}
+		n++
+	}
+	w.batches = w.batches[n:]
+	w.mu.Unlock()
+	if n > 0 {
+		select {
+		case w.freed <- struct{}{}:
+		default:
+		}
+	}
+	return n, latency
+}
+
+// pending은 ack되지 않은 batch를 seq 순서대로 반환한다. 곧 다시 보낼 batch이므로
+// 전송 시각을 지금으로 갱신한다.
+func (w *window) pending() []*nefiv1.EventBatch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	out := make([]*nefiv1.EventBatch, len(w.batches))
+	for i := range w.batches {
+		w.batches[i].sent = now
+		out[i] = w.batches[i].batch
+	}
+	return out
+}
+
+// len은 ack되지 않은 batch 수다.
+func (w *window) len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.batches)
+}
+
+// events는 ack되지 않은 batch에 담긴 이벤트 수다.
+func (w *window) events() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := 0
+	for _, b := range w.batches {
+		n += len(b.batch.Events)
+	}
+	return n
+}
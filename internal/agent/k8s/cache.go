@@ -0,0 +1,140 @@
+package k8s
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/gihongjo/nefi/internal/metrics"
+)
+
+// lookup은 캐시 통계를 집계하는 조회 종류다.
+type lookup int
+
+const (
+	lookupPID      lookup = iota // Resolve: pid → 로컬 pod (pidCache)
+	lookupPodIP                  // ResolveAddr: remote IP/hostPort → pod
+	lookupService                // ResolveServiceAddr: VIP/NodePort → Service
+	lookupEndpoint               // ResolveEndpoint: ip:port → Service
+	lookupNode                   // ResolveNodeIP: IP → node
+	numLookups
+)
+
+var lookupNames = [numLookups]string{"pid", "pod_ip", "service", "endpoint", "node"}
+
+// 상한(Config.MaxEntries)을 적용하는 캐시 이름. dropped 메트릭 label로 쓴다.
+const (
+	cachePodsByIP  = "pods_by_ip"
+	cacheHostPorts = "host_ports"
+	cachePIDs      = "pids"
+)
+
+// cacheStats는 조회 hit/miss와 상한 초과로 버린 항목 수다.
+// 이벤트 루프에서 호출되므로 lock 없이 atomic으로 센다.
+type cacheStats struct {
+	hits    [numLookups]atomic.Uint64
+	misses  [numLookups]atomic.Uint64
+	dropped map[string]*atomic.Uint64 // cache 이름 → 버린 항목 수 (키 집합은 고정)
+}
+
+func newCacheStats() *cacheStats {
+	return &cacheStats{dropped: map[string]*atomic.Uint64{
+		cachePodsByIP:  new(atomic.Uint64),
+		cacheHostPorts: new(atomic.Uint64),
+		cachePIDs:      new(atomic.Uint64),
+	}}
+}
+
+func (s *cacheStats) observe(l lookup, found bool) {
+	if found {
+		s.hits[l].Add(1)
+	} else {
+		s.misses[l].Add(1)
+	}
+}
+
+// full reports whether a cache of size n is at the limit max (0 = unlimited)
+// and, if so, counts the entry that will not be added.
+func (s *cacheStats) full(cache string, n, max int) bool {
+	if max <= 0 || n < max {
+		return false
+	}
+	s.dropped[cache].Add(1)
+	return true
+}
+
+// cacheSizes returns the current number of entries per cache.
+func (r *Resolver) cacheSizes() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	history := 0
+	for _, h := range r.ipHistory {
+		history += len(h)
+	}
+	r.owners.mu.RLock()
+	owners := len(r.owners.refs)
+	r.owners.mu.RUnlock()
+	return map[string]int{
+		cachePodsByIP:  len(r.podsByIP),
+		cacheHostPorts: len(r.hostPorts),
+		cachePIDs:      len(r.pidCache),
+		"local_pods":   len(r.podsByUID),
+		"ip_history":   history,
+		"services":     len(r.servicesByIP) + len(r.nodePorts),
+		"endpoints":    len(r.endpoints),
+		"nodes":        len(r.nodeIPs) + len(r.nodesByIP),
+		"owners":       owners,
+	}
+}
+
+// RegisterMetrics는 캐시 크기, 조회 hit/miss, 상한 초과로 버린 항목 수를 reg에 등록한다.
+func (r *Resolver) RegisterMetrics(reg *metrics.Registry) {
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_k8s_cache_entries",
+		Help: "Entries in the K8s metadata caches, by cache.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			sizes := r.cacheSizes()
+			samples := make([]metrics.Sample, 0, len(sizes))
+			for _, name := range sortedKeys(sizes) {
+				samples = append(samples, metrics.Sample{Labels: metrics.Labels{"cache": name}, Value: float64(sizes[name])})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_k8s_cache_lookups_total",
+		Help: "K8s metadata lookups, by lookup and result (hit = resolved from the cache).",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, 2*numLookups)
+			for l := range r.stats.hits {
+				samples = append(samples,
+					metrics.Sample{Labels: metrics.Labels{"lookup": lookupNames[l], "result": "hit"}, Value: float64(r.stats.hits[l].Load())},
+					metrics.Sample{Labels: metrics.Labels{"lookup": lookupNames[l], "result": "miss"}, Value: float64(r.stats.misses[l].Load())},
+				)
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_k8s_cache_dropped_total",
+		Help: "Entries not cached because the cache reached its size limit, by cache.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, len(r.stats.dropped))
+			for _, name := range sortedKeys(r.stats.dropped) {
+				samples = append(samples, metrics.Sample{Labels: metrics.Labels{"cache": name}, Value: float64(r.stats.dropped[name].Load())})
+			}
+			return samples
+		},
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
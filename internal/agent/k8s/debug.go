@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CacheDump is a point-in-time copy of the resolver caches, served on the
+// agent admin endpoint GET /debug/cache to diagnose enrichment misses.
+type CacheDump struct {
+	NodeName     string                  `json:"node_name"`
+	Node         NodeInfo                `json:"node"`
+	LastSync     time.Time               `json:"last_sync"`
+	LastError    string                  `json:"last_error,omitempty"`
+	PodsByIP     map[string]PodInfo      `json:"pods_by_ip"`     // pod IP → pod (hostNetwork 제외)
+	HostPorts    map[string]PodInfo      `json:"host_ports"`     // "nodeIP:port" → hostNetwork pod
+	NodesByIP    map[string]string       `json:"nodes_by_ip"`    // hostNetwork pod IP → node name
+	NodeIPs      map[string]string       `json:"node_ips"`       // node address → node name (informer)
+	ServicesByIP map[string]ServiceInfo  `json:"services_by_ip"` // Service VIP → service
+	NodePorts    map[int32]ServiceInfo   `json:"node_ports"`     // NodePort → service
+	Endpoints    map[string]ServiceInfo  `json:"endpoints"`      // "ip:port" → service (EndpointSlice)
+	LocalPods    map[string]PodInfo      `json:"local_pods"`     // pod UID → pod (이 노드)
+	PIDs         map[uint32]*PodInfo     `json:"pids"`           // pid → pod (null = pod 아님)
+	Lookup       map[string]*CacheLookup `json:"lookup,omitempty"`
+}
+
+// CacheLookup shows how a single IP would be resolved, in the same order as
+// the agent's remote enrichment (pod/hostPort → service → node).
+type CacheLookup struct {
+	Pod      *PodInfo     `json:"pod,omitempty"`
+	HostPort []string     `json:"host_ports,omitempty"` // 이 IP의 hostPort 항목
+	Service  *ServiceInfo `json:"service,omitempty"`
+	Node     string       `json:"node,omitempty"`
+}
+
+// Dump returns a copy of the resolver caches.
+func (r *Resolver) Dump() CacheDump {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d := CacheDump{
+		NodeName:     r.nodeName,
+		Node:         r.node,
+		LastSync:     r.lastSync,
+		PodsByIP:     make(map[string]PodInfo, len(r.podsByIP)),
+		HostPorts:    make(map[string]PodInfo, len(r.hostPorts)),
+		NodesByIP:    make(map[string]string, len(r.nodesByIP)),
+		NodeIPs:      make(map[string]string, len(r.nodeIPs)),
+		ServicesByIP: make(map[string]ServiceInfo, len(r.servicesByIP)),
+		NodePorts:    make(map[int32]ServiceInfo, len(r.nodePorts)),
+		Endpoints:    make(map[string]ServiceInfo, len(r.endpoints)),
+		LocalPods:    make(map[string]PodInfo, len(r.podsByUID)),
+		PIDs:         make(map[uint32]*PodInfo, len(r.pidCache)),
+	}
+	if r.lastErr != nil {
+		d.LastError = r.lastErr.Error()
+	}
+	for k, v := range r.podsByIP {
+		d.PodsByIP[k] = *v
+	}
+	for k, v := range r.hostPorts {
+		d.HostPorts[k] = *v
+	}
+	for k, v := range r.nodesByIP {
+		d.NodesByIP[k] = v
+	}
+	for k, v := range r.nodeIPs {
+		d.NodeIPs[k] = v
+	}
+	for k, v := range r.servicesByIP {
+		d.ServicesByIP[k] = *v
+	}
+	for k, v := range r.nodePorts {
+		d.NodePorts[k] = *v
+	}
+	for k, refs := range r.endpoints {
+		d.Endpoints[k] = *refs[0].svc
+	}
+	for k, v := range r.podsByUID {
+		d.LocalPods[k] = *v
+	}
+	for k, v := range r.pidCache {
+		if v != nil {
+			c := *v
+			v = &c
+		}
+		d.PIDs[k] = v
+	}
+	return d
+}
+
+// lookup explains how ip (dotted string) resolves against the caches.
+// r.mu must be held by the caller.
+func (r *Resolver) lookup(ip string) *CacheLookup {
+	l := &CacheLookup{Node: r.nodeNameLocked(ip)}
+	if p := r.podsByIP[ip]; p != nil {
+		c := *p
+		l.Pod = &c
+	}
+	if s := r.servicesByIP[ip]; s != nil {
+		c := *s
+		l.Service = &c
+	}
+	prefix := addrKey(ip, 0)
+	prefix = prefix[:len(prefix)-1] // "ip:" (IPv6면 "[ip]:")
+	for k := range r.hostPorts {
+		if strings.HasPrefix(k, prefix) {
+			l.HostPort = append(l.HostPort, k)
+		}
+	}
+	sort.Strings(l.HostPort)
+	return l
+}
+
+// ServeHTTP is the GET /debug/cache handler. With one or more ?ip= query
+// parameters it adds a per-IP lookup explanation to the dump.
+func (r *Resolver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	d := r.Dump()
+	if ips := req.URL.Query()["ip"]; len(ips) > 0 {
+		d.Lookup = make(map[string]*CacheLookup, len(ips))
+		r.mu.RLock()
+		for _, ip := range ips {
+			key := canonicalIP(ip)
+			if key == "" {
+				key = ip
+			}
+			d.Lookup[ip] = r.lookup(key)
+		}
+		r.mu.RUnlock()
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(d) //nolint:errcheck
+}
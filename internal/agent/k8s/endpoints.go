@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"sort"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// endpointRef는 "ip:port" 하나를 endpoint로 가진 EndpointSlice 항목이다.
+// 한 pod가 포트별로 여러 Service를 backing할 수 있으므로 색인은 IP가 아니라
+// ip:port 단위다.
+type endpointRef struct {
+	slice    string // EndpointSlice "ns/name" (update/delete 시 제거 단위)
+	svc      *ServiceInfo
+	pod      string // targetRef "ns/name" ("" = pod가 아닌 endpoint)
+	hostname string // headless Service endpoint의 hostname (StatefulSet이면 "<sts>-<ordinal>")
+}
+
+// labelHeadless는 endpointslice controller가 headless Service의 slice에 붙이는 label이다.
+const labelHeadless = "service.kubernetes.io/headless"
+
+// anyPort는 headless Service endpoint의 IP 단위 색인 키에 쓰는 포트다.
+// pod DNS 이름(<hostname>.<service>)으로 접속하는 client는 Service에 선언되지 않은
+// 포트(peer gossip 등)도 쓰고, 포트가 없는 headless Service도 흔하다.
+const anyPort = 0
+
+// registerEndpointSliceInformer는 factory의 namespace에서 EndpointSlice를 watch해
+// "ip:port" → Service 색인(r.endpoints)을 실시간으로 유지하도록 등록한다.
+func (r *Resolver) registerEndpointSliceInformer(factory informers.SharedInformerFactory) cache.InformerSynced {
+	informer := factory.Discovery().V1().EndpointSlices().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ //nolint:errcheck
+		AddFunc: func(obj any) {
+			if s, ok := obj.(*discoveryv1.EndpointSlice); ok {
+				r.indexEndpointSlice(s)
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if s, ok := obj.(*discoveryv1.EndpointSlice); ok {
+				r.indexEndpointSlice(s)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if s, ok := obj.(*discoveryv1.EndpointSlice); ok {
+				r.mu.Lock()
+				r.unindexEndpointSliceLocked(s.Namespace + "/" + s.Name)
+				r.mu.Unlock()
+			}
+		},
+	})
+	return informer.HasSynced
+}
+
+// indexEndpointSlice replaces slice's "ip:port" entries in r.endpoints.
+// Slices not owned by a Service (no service-name label) are skipped, as are
+// FQDN slices, whose addresses are hostnames. A dual-stack Service has one
+// slice per family; both are indexed.
+func (r *Resolver) indexEndpointSlice(slice *discoveryv1.EndpointSlice) {
+	key := slice.Namespace + "/" + slice.Name
+	svcName := slice.Labels[discoveryv1.LabelServiceName]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unindexEndpointSliceLocked(key)
+	if svcName == "" {
+		return
+	}
+	if slice.AddressType != discoveryv1.AddressTypeIPv4 && slice.AddressType != discoveryv1.AddressTypeIPv6 {
+		return
+	}
+	svc := &ServiceInfo{Namespace: slice.Namespace, Name: svcName}
+	_, headless := slice.Labels[labelHeadless]
+	var addrs []string
+	add := func(k string, ref endpointRef) {
+		refs := append(r.endpoints[k], ref)
+		// 같은 ip:port를 여러 Service가 선택하면 이름 순으로 첫 번째를 쓴다.
+		sort.Slice(refs, func(i, j int) bool { return refs[i].svc.Name < refs[j].svc.Name })
+		r.endpoints[k] = refs
+		addrs = append(addrs, k)
+	}
+	for _, ep := range slice.Endpoints {
+		ref := endpointRef{slice: key, svc: svc}
+		if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+			ref.pod = ep.TargetRef.Namespace + "/" + ep.TargetRef.Name
+		}
+		if headless && ep.Hostname != nil {
+			ref.hostname = *ep.Hostname
+		}
+		for _, a := range ep.Addresses {
+			addr := canonicalIP(a)
+			if addr == "" {
+				continue
+			}
+			for _, p := range slice.Ports {
+				if p.Port != nil && *p.Port != anyPort {
+					add(addrKey(addr, *p.Port), ref)
+				}
+			}
+			if headless {
+				add(addrKey(addr, anyPort), ref)
+			}
+		}
+	}
+	r.sliceAddrs[key] = addrs
+}
+
+func (r *Resolver) unindexEndpointSliceLocked(key string) {
+	for _, k := range r.sliceAddrs[key] {
+		refs := r.endpoints[k][:0]
+		for _, e := range r.endpoints[k] {
+			if e.slice != key {
+				refs = append(refs, e)
+			}
+		}
+		if len(refs) == 0 {
+			delete(r.endpoints, k)
+		} else {
+			r.endpoints[k] = refs
+		}
+	}
+	delete(r.sliceAddrs, key)
+}
+
+// EndpointInfo is the Service an endpoint address belongs to.
+type EndpointInfo struct {
+	Service *ServiceInfo
+	// Hostname is the pod's stable DNS identity "<hostname>.<service>" when
+	// the Service is headless and the pod sets a hostname, as StatefulSet
+	// pods do ("web-2.web"); "" otherwise.
+	Hostname string
+}
+
+// ResolveEndpoint returns the Service whose EndpointSlice lists ip:port
+// (host byte order) as an endpoint, or nil. Unlike a pod-IP lookup it tells
+// apart the Services a multi-port pod backs on each port. Pods behind a
+// headless Service also match on ports the Service does not declare, so
+// traffic to a StatefulSet pod's DNS name keeps its governing Service.
+func (r *Resolver) ResolveEndpoint(ip uint32, port uint16) *EndpointInfo {
+	if ip == 0 || port == 0 {
+		return nil
+	}
+	return r.resolveEndpoint(ipString(ip), port)
+}
+
+func (r *Resolver) resolveEndpoint(ip string, port uint16) *EndpointInfo {
+	var info *EndpointInfo
+	r.mu.RLock()
+	refs := r.endpoints[addrKey(ip, int32(port))]
+	if len(refs) == 0 {
+		refs = r.endpoints[addrKey(ip, anyPort)]
+	}
+	if len(refs) > 0 {
+		info = &EndpointInfo{Service: refs[0].svc}
+		if refs[0].hostname != "" {
+			info.Hostname = refs[0].hostname + "." + refs[0].svc.Name
+		}
+	}
+	r.mu.RUnlock()
+	r.stats.observe(lookupEndpoint, info != nil)
+	return info
+}
@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testSlice(name, svc string, typ discoveryv1.AddressType, port int32, addrs ...string) *discoveryv1.EndpointSlice {
+	s := &discoveryv1.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Namespace: "shop", Name: name, Labels: map[string]string{}},
+		AddressType: typ,
+		Ports:       []discoveryv1.EndpointPort{{Port: &port}},
+	}
+	if svc != "" {
+		s.Labels[discoveryv1.LabelServiceName] = svc
+	}
+	for _, a := range addrs {
+		s.Endpoints = append(s.Endpoints, discoveryv1.Endpoint{
+			Addresses: []string{a},
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "pod-" + a},
+		})
+	}
+	return s
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	r := newTestResolver(Config{})
+	r.indexEndpointSlice(testSlice("api-v4", "api", discoveryv1.AddressTypeIPv4, 8080, "10.1.0.5"))
+	r.indexEndpointSlice(testSlice("api-v6", "api", discoveryv1.AddressTypeIPv6, 8080, "fd00:0::05"))
+	r.indexEndpointSlice(testSlice("admin", "admin", discoveryv1.AddressTypeIPv4, 9090, "10.1.0.5"))
+	r.indexEndpointSlice(testSlice("orphan", "", discoveryv1.AddressTypeIPv4, 7000, "10.1.0.5"))
+	r.indexEndpointSlice(testSlice("fqdn", "ext", discoveryv1.AddressTypeFQDN, 443, "example.com"))
+
+	tests := []struct {
+		ip   string
+		port uint16
+		want string // "" = nil
+	}{
+		{"10.1.0.5", 8080, "api"},
+		{"10.1.0.5", 9090, "admin"}, // 같은 pod, 포트별로 다른 Service
+		{"10.1.0.5", 7000, ""},      // service-name label이 없는 slice
+		{"10.1.0.6", 8080, ""},
+		{"10.1.0.5", 0, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if info := r.ResolveEndpoint(ip4(tt.ip), tt.port); info != nil {
+			got = info.Service.Name
+		}
+		if got != tt.want {
+			t.Errorf("ResolveEndpoint(%s:%d) = %q, want %q", tt.ip, tt.port, got, tt.want)
+		}
+	}
+	if refs := r.endpoints[addrKey("fd00::5", 8080)]; len(refs) != 1 || refs[0].svc.Name != "api" {
+		t.Errorf("IPv6 endpoint not indexed in canonical form: %+v", refs)
+	}
+	if len(r.endpoints) != 3 {
+		t.Errorf("%d endpoint keys, want 3 (FQDN and unowned slices skipped)", len(r.endpoints))
+	}
+
+	r.indexEndpointSlice(testSlice("api-v4", "api", discoveryv1.AddressTypeIPv4, 8080, "10.1.0.6"))
+	if info := r.ResolveEndpoint(ip4("10.1.0.5"), 8080); info != nil {
+		t.Errorf("old endpoint still resolves to %s after update", info.Service.Name)
+	}
+	if info := r.ResolveEndpoint(ip4("10.1.0.6"), 8080); info == nil || info.Service.Name != "api" {
+		t.Errorf("new endpoint resolved to %+v, want api", info)
+	}
+	r.mu.Lock()
+	r.unindexEndpointSliceLocked("shop/api-v4")
+	r.mu.Unlock()
+	if info := r.ResolveEndpoint(ip4("10.1.0.6"), 8080); info != nil {
+		t.Errorf("deleted slice still resolves to %s", info.Service.Name)
+	}
+}
+
+func TestResolveEndpointHeadless(t *testing.T) {
+	r := newTestResolver(Config{})
+	slice := testSlice("db-abc", "db", discoveryv1.AddressTypeIPv4, 5432, "10.1.0.9")
+	slice.Labels[labelHeadless] = ""
+	hostname := "db-0"
+	slice.Endpoints[0].Hostname = &hostname
+	r.indexEndpointSlice(slice)
+
+	for _, port := range []uint16{5432, 7000} { // 선언하지 않은 포트(peer gossip)도 Service로 귀속한다
+		info := r.ResolveEndpoint(ip4("10.1.0.9"), port)
+		if info == nil || info.Service.Name != "db" || info.Hostname != "db-0.db" {
+			t.Errorf("port %d resolved to %+v, want db with hostname db-0.db", port, info)
+		}
+	}
+}
+
+func TestEndpointPortsForHostNetworkPods(t *testing.T) {
+	pod := testPod("shop", "pod-192.168.0.10", "192.168.0.10", metav1.Now().Time)
+	pod.Spec.HostNetwork = true
+	pod.Spec.NodeName = "node-1"
+	r := newTestResolver(Config{}, pod)
+	r.indexEndpointSlice(testSlice("ingress", "ingress", discoveryv1.AddressTypeIPv4, 8443, "192.168.0.10"))
+	if err := r.refreshPods(); err != nil {
+		t.Fatal(err)
+	}
+	// containerPort를 선언하지 않은 hostNetwork pod도 EndpointSlice 포트로 찾는다.
+	if p := r.ResolveAddr(ip4("192.168.0.10"), 8443); p == nil || p.PodName != pod.Name {
+		t.Errorf("resolved to %+v, want %s", p, pod.Name)
+	}
+}
@@ -0,0 +1,42 @@
+package k8s
+
+import (
+	"context"
+	"log"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// startInformers registers Service, EndpointSlice and workload owner informers on every
+// namespace factory and the Node informer on the first one (Nodes are cluster-scoped, so the
+// factory namespace does not apply), then starts them. It waits up to apiTimeout for the initial sync;
+// on failure the informers keep retrying in the background and the indexes
+// fill in once the apiserver is reachable.
+func (r *Resolver) startInformers() {
+	synced := []cache.InformerSynced{r.registerNodeInformer(r.factories[0])}
+	for _, f := range r.factories {
+		synced = append(synced, r.registerServiceInformer(f), r.registerEndpointSliceInformer(f))
+		synced = append(synced, r.registerOwnerInformers(f)...)
+	}
+	if r.cfg.NetworkPolicies {
+		synced = append(synced, r.registerNamespaceInformer(r.factories[0]))
+		for _, f := range r.factories {
+			synced = append(synced, r.registerPolicyInformer(f))
+		}
+	}
+	for _, f := range r.metaFactories {
+		synced = append(synced, r.registerMetadataOwnerInformers(f)...)
+	}
+	for _, f := range r.factories {
+		f.Start(r.stop)
+	}
+	for _, f := range r.metaFactories {
+		f.Start(r.stop)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		log.Printf("[k8s] service/endpointslice/node/owner informers not synced within %v — VIPs, node IPs and workload owners resolve once they catch up", apiTimeout)
+	}
+}
@@ -0,0 +1,87 @@
+package k8s
+
+import (
+	"net/netip"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// meshSidecar는 service mesh 하나의 sidecar 식별 정보다.
+type meshSidecar struct {
+	mesh       string
+	annotation string   // injector가 pod에 남기는 annotation
+	container  string   // sidecar container 이름 (native sidecar면 initContainer)
+	comms      []string // sidecar 프로세스 comm (15자로 잘림)
+	ports      []uint16 // app 트래픽을 가로채는 sidecar listener 포트
+}
+
+var meshSidecars = []meshSidecar{
+	{
+		mesh:       model.MeshIstio,
+		annotation: "sidecar.istio.io/status",
+		container:  "istio-proxy",
+		comms:      []string{"envoy", "pilot-agent"},
+		ports:      []uint16{15001, 15006}, // outbound, inbound
+	},
+	{
+		mesh:       model.MeshLinkerd,
+		annotation: "linkerd.io/proxy-version",
+		container:  "linkerd-proxy",
+		comms:      []string{"linkerd2-proxy"},
+		ports:      []uint16{4140, 4143}, // outbound, inbound
+	},
+}
+
+// detectMesh returns the sidecar of the mesh pod is injected into, or nil.
+// It checks the injector annotation first and falls back to the sidecar
+// container name for manual or annotation-stripped injection.
+func detectMesh(pod *corev1.Pod) *meshSidecar {
+	for i := range meshSidecars {
+		m := &meshSidecars[i]
+		if _, ok := pod.Annotations[m.annotation]; ok {
+			return m
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name == m.container {
+				return m
+			}
+		}
+		for _, c := range pod.Spec.InitContainers {
+			if c.Name == m.container {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// MeshHop classifies an event observed in p by process comm and remote
+// address (host byte order). It returns "" when p is not in a mesh,
+// model.MeshHopProxy for the sidecar proxy process, model.MeshHopLocal for
+// the app's leg to its own sidecar (loopback, the pod's own IP or a sidecar
+// listener port), and model.MeshHopApp for the app's other traffic.
+func (p *PodInfo) MeshHop(comm string, remoteIP uint32, remotePort uint16) string {
+	if p == nil || p.mesh == nil {
+		return ""
+	}
+	for _, c := range p.mesh.comms {
+		if comm == c {
+			return model.MeshHopProxy
+		}
+	}
+	if remoteIP != 0 {
+		var b [4]byte
+		b[0], b[1], b[2], b[3] = byte(remoteIP>>24), byte(remoteIP>>16), byte(remoteIP>>8), byte(remoteIP)
+		if addr := netip.AddrFrom4(b); addr.IsLoopback() || addr == p.ip {
+			return model.MeshHopLocal
+		}
+	}
+	for _, port := range p.mesh.ports {
+		if remotePort == port {
+			return model.MeshHopLocal
+		}
+	}
+	return model.MeshHopApp
+}
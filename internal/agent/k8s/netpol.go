@@ -0,0 +1,286 @@
+package k8s
+
+import (
+	"net"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// policyIndex는 NetworkPolicy informer가 유지하는 컴파일된 정책과 namespace label이다.
+// 이벤트마다 평가하므로 selector는 색인 시점에 미리 변환해 둔다.
+type policyIndex struct {
+	mu       sync.RWMutex
+	byNs     map[string]map[string]*compiledPolicy // namespace → policy name → 정책
+	nsLabels map[string]labels.Set                 // namespace → labels (namespaceSelector용)
+}
+
+type compiledPolicy struct {
+	podSel  labels.Selector
+	ingress []compiledRule // nil이면 ingress 정책이 아님
+	egress  []compiledRule // nil이면 egress 정책이 아님
+}
+
+// compiledRule은 ingress/egress rule 하나다. peers/ports가 비어 있으면 모두 허용이다.
+type compiledRule struct {
+	peers []compiledPeer
+	ports []networkingv1.NetworkPolicyPort
+}
+
+type compiledPeer struct {
+	podSel labels.Selector // nil = namespace의 모든 pod
+	nsSel  labels.Selector // nil = 정책과 같은 namespace
+	cidr   *net.IPNet      // ipBlock (podSel/nsSel 대신)
+	except []*net.IPNet
+}
+
+// registerPolicyInformer는 factory의 namespace에서 NetworkPolicy를 watch하도록 등록한다.
+func (r *Resolver) registerPolicyInformer(factory informers.SharedInformerFactory) cache.InformerSynced {
+	informer := factory.Networking().V1().NetworkPolicies().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ //nolint:errcheck
+		AddFunc: func(obj any) {
+			if p, ok := obj.(*networkingv1.NetworkPolicy); ok {
+				r.policies.set(p.Namespace, p.Name, compilePolicy(p))
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if p, ok := obj.(*networkingv1.NetworkPolicy); ok {
+				r.policies.set(p.Namespace, p.Name, compilePolicy(p))
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if p, ok := obj.(*networkingv1.NetworkPolicy); ok {
+				r.policies.set(p.Namespace, p.Name, nil)
+			}
+		},
+	})
+	return informer.HasSynced
+}
+
+// registerNamespaceInformer는 namespaceSelector 평가를 위해 Namespace label을 watch한다.
+// Namespace는 cluster-scoped이므로 factory의 namespace 설정과 무관하다.
+func (r *Resolver) registerNamespaceInformer(factory informers.SharedInformerFactory) cache.InformerSynced {
+	informer := factory.Core().V1().Namespaces().Informer()
+	informer.SetTransform(func(obj any) (any, error) { //nolint:errcheck
+		if ns, ok := obj.(*corev1.Namespace); ok {
+			return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:            ns.Name,
+				ResourceVersion: ns.ResourceVersion,
+				Labels:          ns.Labels,
+			}}, nil
+		}
+		return obj, nil
+	})
+	setLabels := func(obj any) {
+		if ns, ok := obj.(*corev1.Namespace); ok {
+			r.policies.mu.Lock()
+			r.policies.nsLabels[ns.Name] = labels.Set(ns.Labels)
+			r.policies.mu.Unlock()
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ //nolint:errcheck
+		AddFunc:    setLabels,
+		UpdateFunc: func(_, obj any) { setLabels(obj) },
+		DeleteFunc: func(obj any) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				r.policies.mu.Lock()
+				delete(r.policies.nsLabels, ns.Name)
+				r.policies.mu.Unlock()
+			}
+		},
+	})
+	return informer.HasSynced
+}
+
+func (x *policyIndex) set(ns, name string, p *compiledPolicy) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if p == nil {
+		delete(x.byNs[ns], name)
+		if len(x.byNs[ns]) == 0 {
+			delete(x.byNs, ns)
+		}
+		return
+	}
+	if x.byNs[ns] == nil {
+		x.byNs[ns] = make(map[string]*compiledPolicy)
+	}
+	x.byNs[ns][name] = p
+}
+
+// compilePolicy converts p's selectors once so evaluation is map lookups
+// only. Invalid selectors match nothing.
+func compilePolicy(p *networkingv1.NetworkPolicy) *compiledPolicy {
+	cp := &compiledPolicy{podSel: selector(&p.Spec.PodSelector)}
+	ingress, egress := true, len(p.Spec.Egress) > 0
+	if len(p.Spec.PolicyTypes) > 0 {
+		ingress, egress = false, false
+		for _, t := range p.Spec.PolicyTypes {
+			ingress = ingress || t == networkingv1.PolicyTypeIngress
+			egress = egress || t == networkingv1.PolicyTypeEgress
+		}
+	}
+	if ingress {
+		cp.ingress = []compiledRule{}
+		for _, rule := range p.Spec.Ingress {
+			cp.ingress = append(cp.ingress, compiledRule{peers: compilePeers(rule.From), ports: rule.Ports})
+		}
+	}
+	if egress {
+		cp.egress = []compiledRule{}
+		for _, rule := range p.Spec.Egress {
+			cp.egress = append(cp.egress, compiledRule{peers: compilePeers(rule.To), ports: rule.Ports})
+		}
+	}
+	return cp
+}
+
+func compilePeers(peers []networkingv1.NetworkPolicyPeer) []compiledPeer {
+	out := make([]compiledPeer, 0, len(peers))
+	for _, p := range peers {
+		var cp compiledPeer
+		if p.IPBlock != nil {
+			_, cp.cidr, _ = net.ParseCIDR(p.IPBlock.CIDR)
+			if cp.cidr == nil {
+				continue
+			}
+			for _, e := range p.IPBlock.Except {
+				if _, n, err := net.ParseCIDR(e); err == nil {
+					cp.except = append(cp.except, n)
+				}
+			}
+		} else {
+			if p.PodSelector != nil {
+				cp.podSel = selector(p.PodSelector)
+			}
+			if p.NamespaceSelector != nil {
+				cp.nsSel = selector(p.NamespaceSelector)
+			}
+		}
+		out = append(out, cp)
+	}
+	return out
+}
+
+func selector(s *metav1.LabelSelector) labels.Selector {
+	sel, err := metav1.LabelSelectorAsSelector(s)
+	if err != nil {
+		return labels.Nothing()
+	}
+	return sel
+}
+
+// PolicyVerdict reports how the NetworkPolicies in local's namespace treat
+// an observed flow between local and a remote peer: model.PolicyNone when
+// no policy selects local for that direction (a policy gap),
+// model.PolicyAllowed when a rule admits the peer, and model.PolicyDenied
+// when local is isolated and no rule admits it (unexpected traffic).
+//
+// ingress is true when local is the server side. remote is nil for
+// non-pod peers, which only ipBlock rules can match. port is the remote
+// port and is checked for egress only, since the local (server) port of an
+// ingress flow is not captured. Named ports are treated as matching.
+// It returns "" when policy awareness is disabled or local is unknown.
+func (r *Resolver) PolicyVerdict(local, remote *PodInfo, remoteIP uint32, port uint16, ingress bool) string {
+	if !r.cfg.NetworkPolicies || local == nil {
+		return ""
+	}
+	ip := net.ParseIP(ipString(remoteIP))
+
+	r.policies.mu.RLock()
+	defer r.policies.mu.RUnlock()
+	selected := false
+	for _, p := range r.policies.byNs[local.Namespace] {
+		rules := p.egress
+		if ingress {
+			rules = p.ingress
+		}
+		if rules == nil || !p.podSel.Matches(labels.Set(local.selectorLabels)) {
+			continue
+		}
+		selected = true
+		for _, rule := range rules {
+			if r.policies.ruleMatches(rule, local.Namespace, remote, ip, port, ingress) {
+				return model.PolicyAllowed
+			}
+		}
+	}
+	if !selected {
+		return model.PolicyNone
+	}
+	return model.PolicyDenied
+}
+
+// ruleMatches reports whether rule admits the peer. x.mu must be held.
+func (x *policyIndex) ruleMatches(rule compiledRule, policyNs string, remote *PodInfo, ip net.IP, port uint16, ingress bool) bool {
+	if !ingress && len(rule.ports) > 0 && !portMatches(rule.ports, port) {
+		return false
+	}
+	if len(rule.peers) == 0 {
+		return true
+	}
+	for _, peer := range rule.peers {
+		if peer.cidr != nil {
+			if ip != nil && peer.cidr.Contains(ip) && !containedIn(ip, peer.except) {
+				return true
+			}
+			continue
+		}
+		if remote == nil {
+			continue
+		}
+		if peer.nsSel == nil {
+			if remote.Namespace != policyNs {
+				continue
+			}
+		} else if !peer.nsSel.Matches(x.nsLabels[remote.Namespace]) {
+			continue
+		}
+		if peer.podSel == nil || peer.podSel.Matches(labels.Set(remote.selectorLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+func portMatches(ports []networkingv1.NetworkPolicyPort, port uint16) bool {
+	for _, p := range ports {
+		if p.Protocol != nil && *p.Protocol != corev1.ProtocolTCP {
+			continue // 관측 대상은 TCP뿐이다
+		}
+		if p.Port == nil || p.Port.Type == intstr.String {
+			return true // 모든 포트 또는 named port
+		}
+		lo, hi := p.Port.IntVal, p.Port.IntVal
+		if p.EndPort != nil {
+			hi = *p.EndPort
+		}
+		if int32(port) >= lo && int32(port) <= hi {
+			return true
+		}
+	}
+	return false
+}
+
+func containedIn(ip net.IP, nets []*net.IPNet) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
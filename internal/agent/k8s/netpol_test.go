@@ -0,0 +1,99 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+func appSelector(app string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}
+}
+
+func TestPolicyVerdict(t *testing.T) {
+	r := newTestResolver(Config{NetworkPolicies: true})
+	r.policies.nsLabels["ops"] = labels.Set{"team": "ops"}
+	r.policies.nsLabels["prod"] = labels.Set{"team": "prod"}
+
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+	port443 := intstr.FromInt32(443)
+	port8000 := intstr.FromInt32(8000)
+	port53 := intstr.FromInt32(53)
+	endPort := int32(8100)
+	policies := []*networkingv1.NetworkPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "db-ingress"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: *appSelector("db"),
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: appSelector("api")},
+					{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}},
+					{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.9.0.0/16"}}},
+				}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web-egress"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: *appSelector("web"),
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				Egress: []networkingv1.NetworkPolicyEgressRule{{Ports: []networkingv1.NetworkPolicyPort{
+					{Protocol: &tcp, Port: &port443},
+					{Port: &port8000, EndPort: &endPort},
+					{Protocol: &udp, Port: &port53},
+				}}},
+			},
+		},
+	}
+	for _, p := range policies {
+		r.policies.set(p.Namespace, p.Name, compilePolicy(p))
+	}
+
+	pod := func(ns, app string) *PodInfo {
+		return &PodInfo{Namespace: ns, PodName: app, selectorLabels: map[string]string{"app": app}}
+	}
+	tests := []struct {
+		name     string
+		local    *PodInfo
+		remote   *PodInfo
+		remoteIP string
+		port     uint16
+		ingress  bool
+		want     string
+	}{
+		{"pod selector", pod("shop", "db"), pod("shop", "api"), "10.200.0.1", 0, true, model.PolicyAllowed},
+		{"pod selector in another namespace", pod("prod", "db"), pod("shop", "api"), "10.200.0.1", 0, true, model.PolicyNone},
+		{"same labels in another namespace", pod("shop", "db"), pod("prod", "api"), "172.16.0.1", 0, true, model.PolicyDenied},
+		{"namespace selector", pod("shop", "db"), pod("ops", "debug"), "172.16.0.2", 0, true, model.PolicyAllowed},
+		{"ipBlock", pod("shop", "db"), nil, "10.1.2.3", 0, true, model.PolicyAllowed},
+		{"ipBlock except", pod("shop", "db"), nil, "10.9.1.1", 0, true, model.PolicyDenied},
+		{"outside ipBlock", pod("shop", "db"), nil, "172.16.0.1", 0, true, model.PolicyDenied},
+		{"not selected", pod("shop", "cache"), pod("shop", "api"), "10.1.0.1", 0, true, model.PolicyNone},
+		{"ingress-only policy on egress", pod("shop", "db"), nil, "10.1.2.3", 443, false, model.PolicyNone},
+		{"egress port", pod("shop", "web"), nil, "203.0.113.1", 443, false, model.PolicyAllowed},
+		{"egress port range", pod("shop", "web"), nil, "203.0.113.1", 8050, false, model.PolicyAllowed},
+		{"egress port outside", pod("shop", "web"), nil, "203.0.113.1", 22, false, model.PolicyDenied},
+		{"egress UDP port", pod("shop", "web"), nil, "203.0.113.1", 53, false, model.PolicyDenied},
+		{"egress-only policy on ingress", pod("shop", "web"), nil, "10.1.2.3", 0, true, model.PolicyNone},
+	}
+	for _, tt := range tests {
+		if got := r.PolicyVerdict(tt.local, tt.remote, ip4(tt.remoteIP), tt.port, tt.ingress); got != tt.want {
+			t.Errorf("%s: PolicyVerdict = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	r.policies.set("shop", "db-ingress", nil)
+	if got := r.PolicyVerdict(pod("shop", "db"), nil, ip4("172.16.0.1"), 0, true); got != model.PolicyNone {
+		t.Errorf("after deleting the policy: %q, want %q", got, model.PolicyNone)
+	}
+	if got := newTestResolver(Config{}).PolicyVerdict(pod("shop", "db"), nil, 0, 0, true); got != "" {
+		t.Errorf("with policies disabled: %q, want \"\"", got)
+	}
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// registerNodeInformer는 Node를 watch해 모든 노드의 InternalIP/ExternalIP → 노드 이름
+// 색인(nodeIPs)과 이 agent 노드의 topology label(r.node)을 실시간으로 유지하도록 등록한다.
+//
+// kubelet, hostNetwork daemon, NodePort ingress처럼 node IP로 오가는 트래픽이
+// hostNetwork pod 유무와 관계없이 노드로 귀속된다.
+func (r *Resolver) registerNodeInformer(factory informers.SharedInformerFactory) cache.InformerSynced {
+	informer := factory.Core().V1().Nodes().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ //nolint:errcheck
+		AddFunc: func(obj any) {
+			if n, ok := obj.(*corev1.Node); ok {
+				r.indexNode(n)
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if n, ok := obj.(*corev1.Node); ok {
+				r.indexNode(n)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if n, ok := obj.(*corev1.Node); ok {
+				r.unindexNode(n.Name)
+			}
+		},
+	})
+	return informer.HasSynced
+}
+
+// indexNode replaces node's address entries and, for the agent's own node,
+// its topology labels.
+func (r *Resolver) indexNode(node *corev1.Node) {
+	var ips []string
+	for _, a := range node.Status.Addresses {
+		if a.Type == corev1.NodeInternalIP || a.Type == corev1.NodeExternalIP {
+			if ip := canonicalIP(a.Address); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unindexNodeLocked(node.Name)
+	for _, ip := range ips {
+		r.nodeIPs[ip] = node.Name
+	}
+	r.nodeAddrs[node.Name] = ips
+	if node.Name == r.nodeName {
+		r.node = NodeInfo{
+			Zone:         firstLabel(node.Labels, labelZone, labelZoneBeta),
+			Region:       firstLabel(node.Labels, labelRegion, labelRegionBeta),
+			InstanceType: firstLabel(node.Labels, labelInstanceType, labelInstanceTypeBeta),
+		}
+	}
+}
+
+func (r *Resolver) unindexNode(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unindexNodeLocked(name)
+}
+
+func (r *Resolver) unindexNodeLocked(name string) {
+	for _, ip := range r.nodeAddrs[name] {
+		if r.nodeIPs[ip] == name {
+			delete(r.nodeIPs, ip)
+		}
+	}
+	delete(r.nodeAddrs, name)
+}
+
+// nodeNameLocked returns the node owning ip (dotted string), from the Node
+// informer or, failing that, from hostNetwork pod IPs. r.mu must be held.
+func (r *Resolver) nodeNameLocked(ip string) string {
+	if name := r.nodeIPs[ip]; name != "" {
+		return name
+	}
+	return r.nodesByIP[ip]
+}
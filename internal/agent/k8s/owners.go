@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxOwnerDepth bounds the ownerReference walk (pod → ReplicaSet →
+// Deployment → Revision → Configuration → Service is five hops).
+const maxOwnerDepth = 8
+
+// ownerCache는 컨트롤러 → 소유 컨트롤러 색인이다. owner informer들이 유지하므로
+// pod마다 API를 호출하지 않고 메모리에서 owner chain을 따라간다.
+type ownerCache struct {
+	mu   sync.RWMutex
+	refs map[string]metav1.OwnerReference // "Kind/ns/name" → controller owner
+}
+
+func ownerKey(kind, ns, name string) string { return kind + "/" + ns + "/" + name }
+
+// ownerResource는 owner chain에 추가로 watch하는 리소스다 (Config.OwnerResources).
+type ownerResource struct {
+	gvr  schema.GroupVersionResource
+	kind string
+}
+
+// parseOwnerResources parses "group/version/resource" entries ("version/resource"
+// for the core group) and looks up each resource's Kind through discovery.
+func parseOwnerResources(dc discovery.DiscoveryInterface, specs []string) ([]ownerResource, error) {
+	out := make([]ownerResource, 0, len(specs))
+	for _, spec := range specs {
+		parts := strings.Split(spec, "/")
+		var gvr schema.GroupVersionResource
+		switch len(parts) {
+		case 2:
+			gvr = schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}
+		case 3:
+			gvr = schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}
+		default:
+			return nil, fmt.Errorf("owner resource %q: want group/version/resource", spec)
+		}
+		list, err := dc.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil {
+			return nil, fmt.Errorf("owner resource %q: %w", spec, err)
+		}
+		kind := ""
+		for _, res := range list.APIResources {
+			if res.Name == gvr.Resource {
+				kind = res.Kind
+				break
+			}
+		}
+		if kind == "" {
+			return nil, fmt.Errorf("owner resource %q: not served by the apiserver", spec)
+		}
+		out = append(out, ownerResource{gvr: gvr, kind: kind})
+	}
+	return out, nil
+}
+
+// registerOwnerInformers는 factory의 namespace에서 기본 owner(ReplicaSet, Job)를
+// watch하도록 등록한다. 두 리소스 모두 pod template 전체를 담고 있어 대형 클러스터에서
+// 메모리를 많이 쓰므로 캐시에는 메타데이터만 남긴다.
+func (r *Resolver) registerOwnerInformers(factory informers.SharedInformerFactory) []cache.InformerSynced {
+	rs := factory.Apps().V1().ReplicaSets().Informer()
+	rs.SetTransform(func(obj any) (any, error) { //nolint:errcheck
+		if o, ok := obj.(*appsv1.ReplicaSet); ok {
+			return &appsv1.ReplicaSet{ObjectMeta: ownerMeta(o)}, nil
+		}
+		return obj, nil
+	})
+	jobs := factory.Batch().V1().Jobs().Informer()
+	jobs.SetTransform(func(obj any) (any, error) { //nolint:errcheck
+		if o, ok := obj.(*batchv1.Job); ok {
+			return &batchv1.Job{ObjectMeta: ownerMeta(o)}, nil
+		}
+		return obj, nil
+	})
+	r.watchOwners("ReplicaSet", rs)
+	r.watchOwners("Job", jobs)
+	return []cache.InformerSynced{rs.HasSynced, jobs.HasSynced}
+}
+
+// registerMetadataOwnerInformers는 Config.OwnerResources(Argo Rollout, Knative
+// Revision 등 CRD 포함)를 메타데이터 전용 informer로 watch하도록 등록한다.
+func (r *Resolver) registerMetadataOwnerInformers(factory metadatainformer.SharedInformerFactory) []cache.InformerSynced {
+	synced := make([]cache.InformerSynced, 0, len(r.ownerResources))
+	for _, res := range r.ownerResources {
+		informer := factory.ForResource(res.gvr).Informer()
+		r.watchOwners(res.kind, informer)
+		synced = append(synced, informer.HasSynced)
+	}
+	return synced
+}
+
+func ownerMeta(o metav1.Object) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:       o.GetNamespace(),
+		Name:            o.GetName(),
+		ResourceVersion: o.GetResourceVersion(),
+		OwnerReferences: o.GetOwnerReferences(),
+	}
+}
+
+// watchOwners keeps r.owners in sync with the kind objects of informer.
+func (r *Resolver) watchOwners(kind string, informer cache.SharedIndexInformer) {
+	update := func(obj any) {
+		if o, err := meta.Accessor(obj); err == nil {
+			r.owners.set(ownerKey(kind, o.GetNamespace(), o.GetName()), o.GetOwnerReferences())
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ //nolint:errcheck
+		AddFunc:    update,
+		UpdateFunc: func(_, obj any) { update(obj) },
+		DeleteFunc: func(obj any) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if o, err := meta.Accessor(obj); err == nil {
+				r.owners.set(ownerKey(kind, o.GetNamespace(), o.GetName()), nil)
+			}
+		},
+	})
+}
+
+func (c *ownerCache) set(key string, refs []metav1.OwnerReference) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ref := range refs {
+		if ref.Controller != nil && *ref.Controller {
+			c.refs[key] = ref
+			return
+		}
+	}
+	delete(c.refs, key)
+}
+
+func (c *ownerCache) owner(kind, ns, name string) (metav1.OwnerReference, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ref, ok := c.refs[ownerKey(kind, ns, name)]
+	return ref, ok
+}
+
+// resolveWorkload returns the kind and name of the top-level workload that
+// owns pod, following controller ownerReferences in memory, e.g.
+// ReplicaSet → Deployment (or Argo Rollout), Job → CronJob, and with
+// OwnerResources configured Deployment → Revision → Configuration → Service.
+// The walk stops at the first owner whose kind is not watched. A pod
+// without a controller is its own workload.
+func (r *Resolver) resolveWorkload(pod *corev1.Pod) (kind, name string) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return "Pod", pod.Name
+	}
+	kind, name = ref.Kind, ref.Name
+	for i := 0; i < maxOwnerDepth; i++ {
+		next, ok := r.owners.owner(kind, pod.Namespace, name)
+		if !ok {
+			break
+		}
+		kind, name = next.Kind, next.Name
+	}
+	return kind, name
+}
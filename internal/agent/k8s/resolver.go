@@ -7,28 +7,170 @@
 //   API 호출 비용을 줄이기 위해 두 단계 캐시를 사용한다:
 //     1. podsByUID: 30초마다 갱신되는 노드 내 pod 목록 (UID → PodInfo)
 //     2. pidCache:  PID → PodInfo (pod가 재시작되면 무효화됨)
+//
+//   Service, EndpointSlice, Node와 workload owner(ReplicaSet 등)는 주기적 List 대신
+//   informer(watch)로 VIP/endpoint/node IP 색인과 이 노드의 topology label을 실시간 유지한다.
 package k8s
 
 import (
 	"bufio"
 	"context"
 	"fmt"
+	"log"
+	"net"
+	"net/netip"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // PodInfo holds the Kubernetes identity of a container process.
 type PodInfo struct {
 	Namespace string
 	PodName   string
+	Labels    map[string]string // allowlisted labels/annotations only (nil if none)
+
+	// WorkloadKind/Workload identify the owning controller, e.g.
+	// "Deployment"/"api". A pod without a controller is "Pod"/<pod name>.
+	WorkloadKind string
+	Workload     string
+
+	// UID and Created identify this pod among the pods that have held the
+	// same IP over time (see ResolveAddrAt).
+	UID     string
+	Created time.Time
+
+	// Mesh is the service mesh the pod's sidecar belongs to
+	// (model.MeshIstio/MeshLinkerd), or "" without a sidecar.
+	Mesh string
+
+	// selectorLabels holds all pod labels for NetworkPolicy selector
+	// matching; set only when Config.NetworkPolicies is enabled.
+	selectorLabels map[string]string
+
+	mesh *meshSidecar
+	ip   netip.Addr // pod IP, for MeshHop
+}
+
+// Config controls what the resolver copies from the K8s API.
+type Config struct {
+	// LabelKeys is the allowlist of pod label keys copied into PodInfo.Labels
+	// (e.g. "app.kubernetes.io/version", "team").
+	LabelKeys []string
+	// AnnotationKeys is the allowlist of pod annotation keys copied into
+	// PodInfo.Labels. A label with the same key takes precedence.
+	AnnotationKeys []string
+
+	// NodeName is the node this agent runs on. It scopes the local pod list
+	// used for PID resolution and selects the node whose topology labels are
+	// read. Empty falls back to the NODE_NAME environment variable; if that
+	// is unset too, all cluster pods are considered local candidates.
+	NodeName string
+
+	// Kubeconfig is the kubeconfig path used when not running in-cluster
+	// (e.g. local development). Empty means in-cluster config.
+	Kubeconfig string
+	// Context selects a kubeconfig context. Empty means the current context.
+	Context string
+	// QPS and Burst override client-go's client-side rate limit
+	// (defaults 5 QPS / 10 burst), which large clusters quickly exhaust.
+	// Zero keeps the client-go default.
+	QPS   float32
+	Burst int
+	// Resync is the pod cache refresh period and the informer resync
+	// period. Zero means 30s.
+	Resync time.Duration
+
+	// Namespaces limits the pod, EndpointSlice and Service lists/watches to
+	// these namespaces, which cuts apiserver load on large clusters and lets
+	// the agent run with per-namespace Roles instead of cluster-wide pod
+	// access. Peers in other namespaces then resolve as node or external
+	// traffic. Empty means cluster-wide. Nodes are always watched cluster-wide.
+	Namespaces []string
+	// PodFieldSelector further narrows the pod list used for remote IP
+	// resolution, e.g. "spec.nodeName=<node>" to index only this node's
+	// pods. Empty means all pods in the watched namespaces.
+	PodFieldSelector string
+
+	// OwnerResources adds "group/version/resource" entries (e.g.
+	// "serving.knative.dev/v1/revisions") to the workload owner chain,
+	// beyond the built-in ReplicaSet and Job. They are watched metadata-only
+	// in the same namespaces as pods. Owners of watched kinds need no entry:
+	// a ReplicaSet owned by an Argo Rollout already resolves to the Rollout.
+	OwnerResources []string
+
+	// MaxEntries caps each of the pod IP, hostPort and PID caches so that
+	// memory stays predictable on very large clusters; entries beyond it
+	// are not cached (nefi_agent_k8s_cache_dropped_total). Zero means
+	// unlimited.
+	MaxEntries int
+	// StaleTTL keeps pods that disappeared from the API resolvable for late
+	// events: local pods by UID, and previous owners of reused pod IPs.
+	// Zero means 5m.
+	StaleTTL time.Duration
+
+	// NetworkPolicies watches NetworkPolicies and Namespaces so that
+	// PolicyVerdict can tell whether an observed flow is covered by a
+	// policy. It keeps every pod's full label set in memory.
+	NetworkPolicies bool
 }
 
+const (
+	defaultResync = 30 * time.Second
+	// apiTimeout bounds each API request so that a hung apiserver
+	// connection cannot stall the refresh loop indefinitely.
+	apiTimeout = 30 * time.Second
+	// retryBackoff is the first retry delay after a failed refresh; it
+	// doubles on each consecutive failure, up to the resync period.
+	retryBackoff = 1 * time.Second
+
+	// ipHistoryLen bounds the previous owners kept per pod IP for
+	// attributing late events after the IP was reused.
+	ipHistoryLen    = 4
+	defaultStaleTTL = 5 * time.Minute
+	// creationSkew tolerates node/apiserver clock skew (and the 1s
+	// resolution of creationTimestamp) when comparing event times with
+	// pod creation times.
+	creationSkew = 2 * time.Second
+)
+
+// ipOwner is a pod that held an IP until it was replaced or removed.
+type ipOwner struct {
+	info  *PodInfo
+	until time.Time // refresh that first saw the IP gone or reassigned
+}
+
+// NodeInfo holds the topology labels of the node the agent runs on.
+// Empty fields mean the label is not set (e.g. bare-metal or kind clusters).
+type NodeInfo struct {
+	Zone         string
+	Region       string
+	InstanceType string
+}
+
+// Well-known node labels. 구버전 클러스터의 beta label도 fallback으로 읽는다.
+const (
+	labelZone             = "topology.kubernetes.io/zone"
+	labelRegion           = "topology.kubernetes.io/region"
+	labelInstanceType     = "node.kubernetes.io/instance-type"
+	labelZoneBeta         = "failure-domain.beta.kubernetes.io/zone"
+	labelRegionBeta       = "failure-domain.beta.kubernetes.io/region"
+	labelInstanceTypeBeta = "beta.kubernetes.io/instance-type"
+)
+
 // ServiceInfo holds the Kubernetes identity of a Service.
 type ServiceInfo struct {
 	Namespace string
@@ -37,43 +179,164 @@ type ServiceInfo struct {
 
 // Resolver maps host PIDs and pod IPs to Kubernetes pod metadata.
 type Resolver struct {
-	client       kubernetes.Interface
-	nodeName     string
-	podsByUID    map[string]*PodInfo    // pod UID → PodInfo  (this node only)
-	podsByIP     map[string]*PodInfo    // pod IP  → PodInfo  (cluster-wide)
-	servicesByIP map[string]*ServiceInfo // ClusterIP → ServiceInfo (cluster-wide)
-	pidCache     map[uint32]*PodInfo    // pid     → PodInfo  (nil = not a pod)
-	mu           sync.RWMutex
-}
-
-// NewResolver creates a resolver using the in-cluster kubeconfig.
-// It performs an initial pod list fetch and starts a background refresh loop.
-func NewResolver() (*Resolver, error) {
-	config, err := rest.InClusterConfig()
+	cfg            Config
+	client         kubernetes.Interface
+	nodeName       string
+	podsByUID      map[string]*PodInfo                      // pod UID → PodInfo  (this node only)
+	podsByIP       map[string]*PodInfo                      // pod IP (dual-stack이면 두 family 모두) → PodInfo (cluster-wide, hostNetwork pods excluded)
+	ipHistory      map[string][]ipOwner                     // pod IP  → 이전 소유 pod (최신순, IP 재사용 대비)
+	hostPorts      map[string]*PodInfo                      // "nodeIP:port" → hostNetwork PodInfo (cluster-wide)
+	nodesByIP      map[string]string                        // node IP → node name (IPs shared by hostNetwork pods)
+	nodeIPs        map[string]string                        // node InternalIP/ExternalIP → node name (informer)
+	nodeAddrs      map[string][]string                      // node name → 색인한 IP (informer 역색인)
+	servicesByIP   map[string]*ServiceInfo                  // Service VIP (ClusterIP, external/LB IP) → ServiceInfo (informer)
+	nodePorts      map[int32]*ServiceInfo                   // NodePort → ServiceInfo (informer)
+	endpoints      map[string][]endpointRef                 // "ip:port" → EndpointSlice 항목 (informer)
+	sliceAddrs     map[string][]string                      // EndpointSlice "ns/name" → 색인한 "ip:port" (informer 역색인)
+	svcIndex       serviceIndex                             // informer 역색인 (update/delete 시 이전 주소 제거)
+	policies       policyIndex                              // NetworkPolicy (Config.NetworkPolicies일 때만, informer)
+	owners         ownerCache                               // 컨트롤러 → owner (informer)
+	ownerResources []ownerResource                          // Config.OwnerResources (kind 해석 완료)
+	factories      []informers.SharedInformerFactory        // namespace별 하나 (cluster-wide면 하나)
+	metaFactories  []metadatainformer.SharedInformerFactory // OwnerResources용, namespace별 하나
+	stop           chan struct{}                            // informer 종료 (현재는 프로세스 수명 동안 유지)
+	goneUIDs       map[string]time.Time                     // 목록에서 사라진 로컬 pod UID → 처음 사라진 시각 (StaleTTL)
+	pidCache       map[uint32]*PodInfo                      // pid     → PodInfo  (nil = not a pod)
+	node           NodeInfo                                 // this node's topology labels
+	lastSync       time.Time                                // last successful refreshPods
+	lastErr        error                                    // last refreshPods error (nil after a success)
+	outageSince    time.Time                                // first failure of the current failure streak (zero when healthy)
+	stats          *cacheStats
+	mu             sync.RWMutex
+}
+
+// SyncState reports when the pod/service cache was last refreshed and the
+// error of the most recent failed refresh, if it has not succeeded since.
+func (r *Resolver) SyncState() (lastSync time.Time, lastErr error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastSync, r.lastErr
+}
+
+// OutageSince returns when the current streak of failed refreshes began,
+// or the zero time if the last refresh succeeded. While it is non-zero the
+// resolver keeps answering from the last good cache.
+func (r *Resolver) OutageSince() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outageSince
+}
+
+// NewResolver creates a resolver using the in-cluster kubeconfig, or
+// cfg.Kubeconfig when set. It performs an initial pod list fetch and starts
+// a background refresh loop.
+func NewResolver(cfg Config) (*Resolver, error) {
+	config, err := restConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("in-cluster config: %w", err)
+		return nil, err
+	}
+	if cfg.QPS > 0 {
+		config.QPS = cfg.QPS
+	}
+	if cfg.Burst > 0 {
+		config.Burst = cfg.Burst
+	}
+	if config.Timeout == 0 {
+		config.Timeout = apiTimeout
 	}
 	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("k8s client: %w", err)
 	}
 
-	r := &Resolver{
+	if cfg.NodeName == "" {
+		cfg.NodeName = os.Getenv("NODE_NAME")
+	}
+	resync := cfg.Resync
+	if resync <= 0 {
+		resync = defaultResync
+	}
+	if cfg.StaleTTL <= 0 {
+		cfg.StaleTTL = defaultStaleTTL
+	}
+	r := newResolver(cfg, client, resync)
+	if len(cfg.OwnerResources) > 0 {
+		if err := r.initOwnerResources(config, resync); err != nil {
+			// 잘못된 설정이나 미설치 CRD 때문에 pod 해석까지 멈추지 않는다.
+			log.Printf("[k8s] extra workload owners disabled: %v", err)
+		}
+	}
+	r.startInformers()
+	r.mu.RLock()
+	_, found := r.nodeAddrs[r.nodeName]
+	r.mu.RUnlock()
+	switch {
+	case r.nodeName == "":
+		log.Printf("[k8s] node topology labels unavailable: node name is unknown")
+	case !found:
+		// Node 조회 권한이 없어도 pod 해석은 계속 동작해야 한다.
+		log.Printf("[k8s] node topology labels unavailable: node %s not found (yet)", r.nodeName)
+	}
+
+	if err := r.refreshPods(); err != nil {
+		// apiserver 장애로 agent 전체가 멈추지 않도록 빈 캐시로 시작하고
+		// refresh loop에서 backoff로 재시도한다.
+		log.Printf("[k8s] initial pod list failed: %v — starting with an empty cache, retrying", err)
+		r.lastErr = err
+		r.outageSince = time.Now()
+	}
+
+	go r.runRefresh(resync)
+
+	return r, nil
+}
+
+// newResolver returns a resolver with empty caches and unstarted informers.
+func newResolver(cfg Config, client kubernetes.Interface, resync time.Duration) *Resolver {
+	return &Resolver{
+		cfg:          cfg,
 		client:       client,
-		nodeName:     os.Getenv("NODE_NAME"),
+		nodeName:     cfg.NodeName,
 		podsByUID:    make(map[string]*PodInfo),
 		podsByIP:     make(map[string]*PodInfo),
+		ipHistory:    make(map[string][]ipOwner),
+		hostPorts:    make(map[string]*PodInfo),
+		nodesByIP:    make(map[string]string),
+		nodeIPs:      make(map[string]string),
+		nodeAddrs:    make(map[string][]string),
 		servicesByIP: make(map[string]*ServiceInfo),
+		nodePorts:    make(map[int32]*ServiceInfo),
+		endpoints:    make(map[string][]endpointRef),
+		sliceAddrs:   make(map[string][]string),
+		svcIndex:     serviceIndex{ips: make(map[string][]string), ports: make(map[string][]int32)},
+		owners:       ownerCache{refs: make(map[string]metav1.OwnerReference)},
+		policies:     policyIndex{byNs: make(map[string]map[string]*compiledPolicy), nsLabels: make(map[string]labels.Set)},
+		goneUIDs:     make(map[string]time.Time),
 		pidCache:     make(map[uint32]*PodInfo),
+		stats:        newCacheStats(),
+		factories:    newFactories(client, resync, cfg.Namespaces),
+		stop:         make(chan struct{}),
 	}
+}
 
-	if err := r.refreshPods(); err != nil {
-		return nil, fmt.Errorf("initial pod list: %w", err)
+// restConfig builds the API client config from cfg.Kubeconfig/Context,
+// falling back to the in-cluster service account.
+func restConfig(cfg Config) (*rest.Config, error) {
+	if cfg.Kubeconfig == "" {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("in-cluster config: %w", err)
+		}
+		return config, nil
 	}
-
-	go r.runRefresh(30 * time.Second)
-
-	return r, nil
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: cfg.Kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: cfg.Context},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig %s: %w", cfg.Kubeconfig, err)
+	}
+	return config, nil
 }
 
 // Resolve returns the PodInfo for the given host PID, or nil if the
@@ -82,9 +345,11 @@ func (r *Resolver) Resolve(pid uint32) *PodInfo {
 	r.mu.RLock()
 	if info, ok := r.pidCache[pid]; ok {
 		r.mu.RUnlock()
+		r.stats.observe(lookupPID, true)
 		return info
 	}
 	r.mu.RUnlock()
+	r.stats.observe(lookupPID, false)
 
 	uid, _ := podUIDFromCgroup(pid)
 	var info *PodInfo
@@ -95,16 +360,73 @@ func (r *Resolver) Resolve(pid uint32) *PodInfo {
 	}
 
 	r.mu.Lock()
-	r.pidCache[pid] = info
+	if !r.stats.full(cachePIDs, len(r.pidCache), r.cfg.MaxEntries) {
+		r.pidCache[pid] = info
+	}
 	r.mu.Unlock()
 
 	return info
 }
 
-// refreshPods fetches pods and services, rebuilding all lookup maps:
+// newFactories returns one informer factory per namespace in namespaces,
+// or a single cluster-wide factory when namespaces is empty.
+func newFactories(client kubernetes.Interface, resync time.Duration, namespaces []string) []informers.SharedInformerFactory {
+	if len(namespaces) == 0 {
+		return []informers.SharedInformerFactory{informers.NewSharedInformerFactory(client, resync)}
+	}
+	out := make([]informers.SharedInformerFactory, 0, len(namespaces))
+	for _, ns := range namespaces {
+		out = append(out, informers.NewSharedInformerFactoryWithOptions(client, resync, informers.WithNamespace(ns)))
+	}
+	return out
+}
+
+// initOwnerResources resolves cfg.OwnerResources and creates the
+// metadata-only informer factories that watch them.
+func (r *Resolver) initOwnerResources(config *rest.Config, resync time.Duration) error {
+	mc, err := metadata.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("metadata client: %w", err)
+	}
+	res, err := parseOwnerResources(r.client.Discovery(), r.cfg.OwnerResources)
+	if err != nil {
+		return err
+	}
+	r.ownerResources = res
+	for _, ns := range r.namespaces() {
+		r.metaFactories = append(r.metaFactories, metadatainformer.NewFilteredSharedInformerFactory(mc, resync, ns, nil))
+	}
+	return nil
+}
+
+// namespaces returns the namespaces to list, metav1.NamespaceAll when unscoped.
+func (r *Resolver) namespaces() []string {
+	if len(r.cfg.Namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return r.cfg.Namespaces
+}
+
+// listPods lists pods matching opts in every watched namespace.
+func (r *Resolver) listPods(opts metav1.ListOptions) ([]corev1.Pod, error) {
+	var out []corev1.Pod
+	for _, ns := range r.namespaces() {
+		list, err := r.client.CoreV1().Pods(ns).List(context.Background(), opts)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, list.Items...)
+	}
+	return out, nil
+}
+
+// refreshPods fetches pods, rebuilding the pod lookup maps:
 //   - podsByUID:    this node's pods only (UID → PodInfo), used for PID resolution
 //   - podsByIP:     all cluster pods (IP → PodInfo), used for remote IP resolution
-//   - servicesByIP: all cluster services (ClusterIP → ServiceInfo), fallback for DNAT'd traffic
+//     (scoped by Config.Namespaces and Config.PodFieldSelector)
+//   - hostPorts/nodesByIP: hostNetwork pods by "nodeIP:port", and node IPs
+//
+// Services are indexed separately by the service informer.
 //
 // pidCache is cleared so stale entries are re-resolved on next access.
 func (r *Resolver) refreshPods() error {
@@ -113,68 +435,192 @@ func (r *Resolver) refreshPods() error {
 	if r.nodeName != "" {
 		nodeOpts.FieldSelector = "spec.nodeName=" + r.nodeName
 	}
-	nodePods, err := r.client.CoreV1().Pods("").List(context.Background(), nodeOpts)
+	nodePods, err := r.listPods(nodeOpts)
 	if err != nil {
 		return err
 	}
 
 	// Fetch all cluster pods for IP-based remote pod resolution.
-	allPods, err := r.client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+	allPods, err := r.listPods(metav1.ListOptions{FieldSelector: r.cfg.PodFieldSelector})
 	if err != nil {
 		return err
 	}
 
-	// Fetch all services for ClusterIP → service name resolution.
-	// connect() syscall에서 캡처한 remote IP가 DNAT 전 ClusterIP일 때 사용된다.
-	allSvcs, err := r.client.CoreV1().Services("").List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return err
+	newByUID := make(map[string]*PodInfo, len(nodePods))
+	for i := range nodePods {
+		pod := &nodePods[i]
+		newByUID[string(pod.UID)] = r.podInfo(pod)
 	}
 
-	newByUID := make(map[string]*PodInfo, len(nodePods.Items))
-	for i := range nodePods.Items {
-		pod := &nodePods.Items[i]
-		newByUID[string(pod.UID)] = &PodInfo{
-			Namespace: pod.Namespace,
-			PodName:   pod.Name,
+	// hostNetwork pod는 node IP를 공유하므로 IP만으로는 구분할 수 없다.
+	// 대신 "nodeIP:port"로 색인하고, 포트로도 구분되지 않으면 node로 귀속한다.
+	newByIP := make(map[string]*PodInfo, len(allPods))
+	newHostPorts := make(map[string]*PodInfo)
+	newNodesByIP := make(map[string]string)
+	hostNetPods := make(map[string]*PodInfo) // "ns/name" → PodInfo
+	for i := range allPods {
+		pod := &allPods[i]
+		ips := podIPs(pod)
+		if len(ips) == 0 {
+			continue
 		}
-	}
-
-	newByIP := make(map[string]*PodInfo, len(allPods.Items))
-	for i := range allPods.Items {
-		pod := &allPods.Items[i]
-		if pod.Status.PodIP == "" {
+		info := r.podInfo(pod)
+		if !pod.Spec.HostNetwork {
+			for _, ip := range ips {
+				if !r.stats.full(cachePodsByIP, len(newByIP), r.cfg.MaxEntries) {
+					newByIP[ip] = info
+				}
+			}
 			continue
 		}
-		newByIP[pod.Status.PodIP] = &PodInfo{
-			Namespace: pod.Namespace,
-			PodName:   pod.Name,
+		hostNetPods[pod.Namespace+"/"+pod.Name] = info
+		for _, ip := range ips {
+			newNodesByIP[ip] = pod.Spec.NodeName
+			for _, c := range pod.Spec.Containers {
+				for _, p := range c.Ports {
+					// hostNetwork에서는 containerPort가 곧 node의 listen 포트다.
+					if !r.stats.full(cacheHostPorts, len(newHostPorts), r.cfg.MaxEntries) {
+						newHostPorts[addrKey(ip, p.ContainerPort)] = info
+					}
+				}
+			}
 		}
 	}
 
-	newByServiceIP := make(map[string]*ServiceInfo, len(allSvcs.Items))
-	for i := range allSvcs.Items {
-		svc := &allSvcs.Items[i]
-		ip := svc.Spec.ClusterIP
-		if ip == "" || ip == "None" {
-			continue
-		}
-		newByServiceIP[ip] = &ServiceInfo{
-			Namespace: svc.Namespace,
-			Name:      svc.Name,
+	// EndpointSlice 포트로 보강: containerPort를 선언하지 않은 hostNetwork pod도
+	// Service에 속해 있으면 실제 포트를 알 수 있다 (EndpointSlice informer 색인).
+	if len(hostNetPods) > 0 {
+		r.mu.RLock()
+		for k, refs := range r.endpoints {
+			if strings.HasSuffix(k, ":0") {
+				continue // headless Service의 IP 단위 색인 (anyPort)
+			}
+			for _, e := range refs {
+				if info := hostNetPods[e.pod]; info != nil && !r.stats.full(cacheHostPorts, len(newHostPorts), r.cfg.MaxEntries) {
+					newHostPorts[k] = info
+				}
+			}
 		}
+		r.mu.RUnlock()
 	}
 
+	now := time.Now()
 	r.mu.Lock()
+	r.recordIPOwnersLocked(newByIP, now)
+	r.keepGonePodsLocked(newByUID, now)
 	r.podsByUID = newByUID
 	r.podsByIP = newByIP
-	r.servicesByIP = newByServiceIP
+	r.hostPorts = newHostPorts
+	r.nodesByIP = newNodesByIP
 	r.pidCache = make(map[uint32]*PodInfo)
+	r.lastSync = time.Now()
+	r.lastErr = nil
+	r.outageSince = time.Time{}
 	r.mu.Unlock()
 
 	return nil
 }
 
+// recordIPOwnersLocked moves pods that no longer own their IP in newByIP
+// into ipHistory and expires history older than the stale TTL.
+func (r *Resolver) recordIPOwnersLocked(newByIP map[string]*PodInfo, now time.Time) {
+	for ip, old := range r.podsByIP {
+		if cur := newByIP[ip]; cur != nil && cur.UID == old.UID {
+			continue
+		}
+		h := append([]ipOwner{{info: old, until: now}}, r.ipHistory[ip]...)
+		if len(h) > ipHistoryLen {
+			h = h[:ipHistoryLen]
+		}
+		r.ipHistory[ip] = h
+	}
+	for ip, h := range r.ipHistory {
+		n := len(h)
+		for n > 0 && now.Sub(h[n-1].until) > r.cfg.StaleTTL {
+			n--
+		}
+		if n == 0 {
+			delete(r.ipHistory, ip)
+		} else {
+			r.ipHistory[ip] = h[:n]
+		}
+	}
+}
+
+// keepGonePodsLocked carries local pods missing from newByUID over for up to
+// the stale TTL, so PIDs of a just-deleted pod still resolve while its last
+// events drain.
+func (r *Resolver) keepGonePodsLocked(newByUID map[string]*PodInfo, now time.Time) {
+	for uid, info := range r.podsByUID {
+		if _, ok := newByUID[uid]; ok {
+			delete(r.goneUIDs, uid)
+			continue
+		}
+		gone, ok := r.goneUIDs[uid]
+		if !ok {
+			gone = now
+			r.goneUIDs[uid] = now
+		}
+		if now.Sub(gone) < r.cfg.StaleTTL {
+			newByUID[uid] = info
+		} else {
+			delete(r.goneUIDs, uid)
+		}
+	}
+}
+
+// podInfo builds the PodInfo for pod, copying only allowlisted labels and
+// annotations so that the exported events stay small.
+func (r *Resolver) podInfo(pod *corev1.Pod) *PodInfo {
+	info := &PodInfo{
+		Namespace: pod.Namespace,
+		PodName:   pod.Name,
+		UID:       string(pod.UID),
+		Created:   pod.CreationTimestamp.Time,
+	}
+	info.WorkloadKind, info.Workload = r.resolveWorkload(pod)
+	if info.mesh = detectMesh(pod); info.mesh != nil {
+		info.Mesh = info.mesh.mesh
+		info.ip, _ = netip.ParseAddr(canonicalIP(pod.Status.PodIP))
+	}
+	if r.cfg.NetworkPolicies {
+		info.selectorLabels = pod.Labels
+	}
+	for _, k := range r.cfg.AnnotationKeys {
+		if v, ok := pod.Annotations[k]; ok {
+			if info.Labels == nil {
+				info.Labels = make(map[string]string)
+			}
+			info.Labels[k] = v
+		}
+	}
+	for _, k := range r.cfg.LabelKeys {
+		if v, ok := pod.Labels[k]; ok {
+			if info.Labels == nil {
+				info.Labels = make(map[string]string)
+			}
+			info.Labels[k] = v
+		}
+	}
+	return info
+}
+
+// Node returns the topology labels of the node this agent runs on.
+func (r *Resolver) Node() NodeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.node
+}
+
+func firstLabel(labels map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := labels[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // ResolveIP returns the PodInfo for the given remote IP (host byte order),
 // or nil if no pod with that IP is known.
 // ip is in host byte order as returned by bpf_ntohl in the BPF program.
@@ -189,25 +635,141 @@ func (r *Resolver) ResolveIP(ip uint32) *PodInfo {
 	return r.podsByIP[ipStr]
 }
 
-// ResolveServiceIP returns the ServiceInfo for the given ClusterIP (host byte order),
-// or nil if no service with that IP is known.
-// connect() syscall이 DNAT 전 ClusterIP를 캡처하는 경우의 fallback으로 사용된다.
-func (r *Resolver) ResolveServiceIP(ip uint32) *ServiceInfo {
+// ResolveAddr returns the PodInfo for the given remote IP and port
+// (host byte order). Unlike ResolveIP it also attributes traffic to
+// hostNetwork pods, which share the node IP, by matching the port against
+// their container and EndpointSlice ports.
+//
+// When ip belongs to a node and the port does not identify a single pod
+// (e.g. an ephemeral client port), it returns nil; callers should then
+// attribute the traffic to the node via ResolveNodeIP.
+func (r *Resolver) ResolveAddr(ip uint32, port uint16) *PodInfo {
+	return r.ResolveAddrAt(ip, port, time.Time{})
+}
+
+// ResolveAddrAt is ResolveAddr for an event observed at time at. When the
+// pod IP has been reused, it picks the pod that held the IP at that time:
+// the current pod if it was created by then, otherwise a recent previous
+// owner (see ipHistoryLen/ipHistoryTTL). A zero at means "now".
+func (r *Resolver) ResolveAddrAt(ip uint32, port uint16, at time.Time) *PodInfo {
 	if ip == 0 {
 		return nil
 	}
-	ipStr := fmt.Sprintf("%d.%d.%d.%d",
-		(ip>>24)&0xff, (ip>>16)&0xff, (ip>>8)&0xff, ip&0xff)
+	return r.resolveAddrAt(ipString(ip), port, at)
+}
+
+func (r *Resolver) resolveAddrAt(ipStr string, port uint16, at time.Time) *PodInfo {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.servicesByIP[ipStr]
+	info := r.podIPOwnerLocked(ipStr, at)
+	if info == nil && port != 0 {
+		info = r.hostPorts[addrKey(ipStr, int32(port))]
+	}
+	r.mu.RUnlock()
+	r.stats.observe(lookupPodIP, info != nil)
+	return info
+}
+
+// podIPOwnerLocked returns the pod owning ip at time at. r.mu must be held.
+func (r *Resolver) podIPOwnerLocked(ip string, at time.Time) *PodInfo {
+	cur := r.podsByIP[ip]
+	if at.IsZero() || (cur != nil && !at.Before(cur.Created.Add(-creationSkew))) {
+		return cur
+	}
+	for _, o := range r.ipHistory[ip] {
+		if at.Before(o.until) && !at.Before(o.info.Created.Add(-creationSkew)) {
+			return o.info
+		}
+	}
+	// 이력에 없으면 현재 pod로 귀속한다 (이력 TTL 경과 또는 clock skew).
+	return cur
+}
+
+// ResolveNodeIP returns the node name owning ip (host byte order), or "" if
+// ip is not a known node address (Node informer) or hostNetwork pod IP.
+func (r *Resolver) ResolveNodeIP(ip uint32) string {
+	if ip == 0 {
+		return ""
+	}
+	r.mu.RLock()
+	name := r.nodeNameLocked(ipString(ip))
+	r.mu.RUnlock()
+	r.stats.observe(lookupNode, name != "")
+	return name
+}
+
+func ipString(ip uint32) string {
+	return fmt.Sprintf("%d.%d.%d.%d", (ip>>24)&0xff, (ip>>16)&0xff, (ip>>8)&0xff, ip&0xff)
 }
 
+// canonicalIP returns s in the form the caches are keyed by ("10.0.0.1",
+// "fd00::1"), or "" if s is not an IP address. The API server does not
+// normalize every IPv6 field, so "fd00:0::01" and "fd00::1" must collapse
+// to one key; IPv4-mapped IPv6 addresses are keyed as IPv4.
+func canonicalIP(s string) string {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return ""
+	}
+	return addr.Unmap().String()
+}
+
+// podIPs returns all of pod's IPs (both families on dual-stack clusters)
+// in canonical form.
+func podIPs(pod *corev1.Pod) []string {
+	var out []string
+	add := func(s string) {
+		if ip := canonicalIP(s); ip != "" && !slices.Contains(out, ip) {
+			out = append(out, ip)
+		}
+	}
+	add(pod.Status.PodIP)
+	for _, p := range pod.Status.PodIPs {
+		add(p.IP)
+	}
+	return out
+}
+
+func addrKey(ip string, port int32) string {
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
+}
+
+// runRefresh refreshes the caches every interval. After a failure it retries
+// with exponential backoff (retryBackoff … interval) and keeps serving the
+// last good cache until the apiserver is reachable again.
 func (r *Resolver) runRefresh(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for range ticker.C {
-		r.refreshPods() //nolint:errcheck
+	delay := interval
+	r.mu.RLock()
+	if r.lastErr != nil {
+		delay = retryBackoff // 초기 동기화 실패 — 바로 재시도
+	}
+	r.mu.RUnlock()
+
+	backoff := retryBackoff
+	for {
+		time.Sleep(delay)
+		outage := r.OutageSince()
+		err := r.refreshPods()
+		if err == nil {
+			if !outage.IsZero() {
+				log.Printf("[k8s] cache resynced after %v of apiserver errors", time.Since(outage).Round(time.Second))
+			}
+			delay, backoff = interval, retryBackoff
+			continue
+		}
+
+		r.mu.Lock()
+		r.lastErr = err
+		if r.outageSince.IsZero() {
+			r.outageSince = time.Now()
+			log.Printf("[k8s] cache refresh failed: %v — serving stale cache, retrying with backoff", err)
+		}
+		r.mu.Unlock()
+
+		delay = backoff
+		backoff *= 2
+		if backoff > interval {
+			backoff = interval
+		}
 	}
 }
 
@@ -0,0 +1,180 @@
+package k8s
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestResolver는 objs를 가진 fake clientset 위의 resolver를 만든다. informer는 시작하지
+// 않으므로 Service/EndpointSlice/Node는 index 함수로 직접 넣는다.
+func newTestResolver(cfg Config, objs ...runtime.Object) *Resolver {
+	if cfg.StaleTTL <= 0 {
+		cfg.StaleTTL = defaultStaleTTL
+	}
+	return newResolver(cfg, fake.NewClientset(objs...), defaultResync)
+}
+
+func testPod(ns, name, ip string, created time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         ns,
+			Name:              name,
+			UID:               types.UID(name + "-uid"),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: corev1.PodStatus{PodIP: ip},
+	}
+}
+
+// ip4는 "10.0.0.1"을 BPF가 넘기는 host byte order 정수로 바꾼다.
+func ip4(s string) uint32 {
+	a := netip.MustParseAddr(s).As4()
+	return binary.BigEndian.Uint32(a[:])
+}
+
+func TestResolveAddr(t *testing.T) {
+	now := time.Now()
+	web := testPod("shop", "web", "10.1.0.5", now)
+	web.Status.PodIPs = []corev1.PodIP{{IP: "10.1.0.5"}, {IP: "fd00:0::05"}}
+	exporter := testPod("monitoring", "node-exporter", "192.168.0.10", now)
+	exporter.Spec.HostNetwork = true
+	exporter.Spec.NodeName = "node-1"
+	exporter.Spec.Containers = []corev1.Container{{Ports: []corev1.ContainerPort{{ContainerPort: 9100}}}}
+
+	r := newTestResolver(Config{}, web, exporter)
+	if err := r.refreshPods(); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := r.ResolveAddr(ip4("10.1.0.5"), 0); p == nil || p.PodName != "web" {
+		t.Errorf("pod IP resolved to %+v, want web", p)
+	}
+	if p := r.podsByIP["fd00::5"]; p == nil || p.PodName != "web" {
+		t.Errorf("IPv6 pod IP not indexed in canonical form: %+v", p)
+	}
+	if p := r.ResolveAddr(ip4("192.168.0.10"), 9100); p == nil || p.PodName != "node-exporter" {
+		t.Errorf("hostNetwork port resolved to %+v, want node-exporter", p)
+	}
+	if p := r.ResolveAddr(ip4("192.168.0.10"), 40000); p != nil {
+		t.Errorf("ephemeral port on a node resolved to %s, want nil", p.PodName)
+	}
+	if node := r.ResolveNodeIP(ip4("192.168.0.10")); node != "node-1" {
+		t.Errorf("ResolveNodeIP = %q, want node-1", node)
+	}
+	if p := r.ResolveIP(ip4("10.9.9.9")); p != nil {
+		t.Errorf("unknown IP resolved to %s", p.PodName)
+	}
+}
+
+func TestResolveAddrAtReusedIP(t *testing.T) {
+	t0 := time.Now().Add(-time.Hour)
+	r := newTestResolver(Config{}, testPod("shop", "old", "10.1.0.7", t0))
+	if err := r.refreshPods(); err != nil {
+		t.Fatal(err)
+	}
+	pods := r.client.CoreV1().Pods("shop")
+	if err := pods.Delete(context.Background(), "old", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pods.Create(context.Background(), testPod("shop", "new", "10.1.0.7", t0.Add(30*time.Minute)), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.refreshPods(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{t0.Add(time.Minute), "old"},
+		{t0.Add(31 * time.Minute), "new"},
+		{time.Time{}, "new"},
+	}
+	for _, tt := range tests {
+		if p := r.ResolveAddrAt(ip4("10.1.0.7"), 0, tt.at); p == nil || p.PodName != tt.want {
+			t.Errorf("at %v: resolved to %+v, want %s", tt.at, p, tt.want)
+		}
+	}
+}
+
+func TestResolveServiceAddr(t *testing.T) {
+	r := newTestResolver(Config{NodeName: "node-1"})
+	r.indexNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+			labelZoneBeta: "zone-a",
+			labelRegion:   "region-1",
+		}},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.168.0.11"},
+			{Type: corev1.NodeHostName, Address: "node-1"},
+		}},
+	})
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:  "10.96.0.10",
+			ClusterIPs: []string{"10.96.0.10", "fd00:96::10"},
+			Ports:      []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+		},
+	}
+	r.indexService(svc)
+
+	if s := r.ResolveServiceAddr(ip4("10.96.0.10"), 80); s == nil || s.Name != "web" {
+		t.Errorf("ClusterIP resolved to %+v, want shop/web", s)
+	}
+	if s := r.servicesByIP["fd00:96::10"]; s == nil || s.Name != "web" {
+		t.Errorf("IPv6 ClusterIP not indexed: %+v", s)
+	}
+	if s := r.ResolveServiceAddr(ip4("192.168.0.11"), 30080); s == nil || s.Name != "web" {
+		t.Errorf("NodePort on a node IP resolved to %+v, want shop/web", s)
+	}
+	if s := r.ResolveServiceAddr(ip4("10.1.0.5"), 30080); s != nil {
+		t.Errorf("NodePort on a non-node IP resolved to %s", s.Name)
+	}
+	if n := r.Node(); n.Zone != "zone-a" || n.Region != "region-1" {
+		t.Errorf("Node() = %+v, want zone-a/region-1", n)
+	}
+
+	svc.Spec.ClusterIP, svc.Spec.ClusterIPs = "10.96.0.20", nil
+	r.indexService(svc)
+	if s := r.ResolveServiceAddr(ip4("10.96.0.10"), 80); s != nil {
+		t.Errorf("old ClusterIP still resolves to %s after update", s.Name)
+	}
+	r.unindexService("shop/web")
+	if s := r.ResolveServiceAddr(ip4("10.96.0.20"), 80); s != nil {
+		t.Errorf("deleted Service still resolves")
+	}
+	r.unindexNode("node-1")
+	if node := r.ResolveNodeIP(ip4("192.168.0.11")); node != "" {
+		t.Errorf("deleted node still resolves to %q", node)
+	}
+}
+
+func TestExtractPodUID(t *testing.T) {
+	const uid = "0a1b2c3d-4e5f-6789-abcd-ef0123456789"
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/kubepods/burstable/pod" + uid + "/4f3c9e", uid},
+		{"/kubepods/pod" + uid, uid},
+		{"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod0a1b2c3d_4e5f_6789_abcd_ef0123456789.slice/cri-containerd-4f3c9e.scope", uid},
+		{"/system.slice/containerd.service", ""},
+		{"/kubepods/burstable/podnot-a-uid/4f3c9e", ""},
+	}
+	for _, tt := range tests {
+		if got := extractPodUID(tt.path); got != tt.want {
+			t.Errorf("extractPodUID(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// serviceIndex는 Service informer가 유지하는 VIP 색인이다.
+// servicesByIP와 nodePorts는 r.mu로 보호되며, 이 구조는 update/delete 시
+// 이전 주소를 지우기 위한 역색인(key → 주소 목록)을 함께 가진다.
+type serviceIndex struct {
+	ips   map[string][]string // "ns/name" → 색인한 IP
+	ports map[string][]int32  // "ns/name" → 색인한 nodePort
+}
+
+// registerServiceInformer는 factory의 namespace에서 Service를 watch해 ClusterIP,
+// externalIPs, LoadBalancer ingress IP, NodePort 색인을 실시간으로 유지하도록 등록한다.
+func (r *Resolver) registerServiceInformer(factory informers.SharedInformerFactory) cache.InformerSynced {
+	informer := factory.Core().V1().Services().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{ //nolint:errcheck
+		AddFunc: func(obj any) {
+			if svc, ok := obj.(*corev1.Service); ok {
+				r.indexService(svc)
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if svc, ok := obj.(*corev1.Service); ok {
+				r.indexService(svc)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if svc, ok := obj.(*corev1.Service); ok {
+				r.unindexService(svc.Namespace + "/" + svc.Name)
+			}
+		},
+	})
+	return informer.HasSynced
+}
+
+// serviceAddrs returns the IPs a client may use to reach svc without DNAT
+// having been applied yet: ClusterIPs (dual-stack 포함), externalIPs,
+// spec.loadBalancerIP와 status.loadBalancer.ingress IP.
+func serviceAddrs(svc *corev1.Service) []string {
+	var out []string
+	add := func(s string) {
+		if ip := canonicalIP(s); ip != "" {
+			out = append(out, ip)
+		}
+	}
+	add(svc.Spec.ClusterIP)
+	for _, ip := range svc.Spec.ClusterIPs {
+		if ip != svc.Spec.ClusterIP {
+			add(ip)
+		}
+	}
+	for _, ip := range svc.Spec.ExternalIPs {
+		add(ip)
+	}
+	add(svc.Spec.LoadBalancerIP) //nolint:staticcheck // deprecated but still set by some providers
+	for _, ing := range svc.Status.LoadBalancer.Ingress {
+		add(ing.IP)
+	}
+	return out
+}
+
+// indexService replaces svc's entries in servicesByIP and nodePorts.
+// ExternalName Service는 IP가 없으므로 색인하지 않는다.
+func (r *Resolver) indexService(svc *corev1.Service) {
+	key := svc.Namespace + "/" + svc.Name
+	info := &ServiceInfo{Namespace: svc.Namespace, Name: svc.Name}
+	ips := serviceAddrs(svc)
+	var ports []int32
+	for _, p := range svc.Spec.Ports {
+		if p.NodePort != 0 {
+			ports = append(ports, p.NodePort)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unindexServiceLocked(key)
+	for _, ip := range ips {
+		r.servicesByIP[ip] = info
+	}
+	for _, p := range ports {
+		r.nodePorts[p] = info
+	}
+	r.svcIndex.ips[key] = ips
+	r.svcIndex.ports[key] = ports
+}
+
+func (r *Resolver) unindexService(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unindexServiceLocked(key)
+}
+
+func (r *Resolver) unindexServiceLocked(key string) {
+	for _, ip := range r.svcIndex.ips[key] {
+		if s := r.servicesByIP[ip]; s != nil && s.Namespace+"/"+s.Name == key {
+			delete(r.servicesByIP, ip)
+		}
+	}
+	for _, p := range r.svcIndex.ports[key] {
+		if s := r.nodePorts[p]; s != nil && s.Namespace+"/"+s.Name == key {
+			delete(r.nodePorts, p)
+		}
+	}
+	delete(r.svcIndex.ips, key)
+	delete(r.svcIndex.ports, key)
+}
+
+// ResolveServiceAddr returns the Service reached through ip:port (host byte
+// order), or nil. It matches Service VIPs (ClusterIP, externalIPs,
+// LoadBalancer IPs) and, when ip is a known node IP, NodePorts.
+// connect()가 DNAT 전 주소를 캡처하는 경우 remote pod 대신 서비스로 귀속하는 데 쓴다.
+func (r *Resolver) ResolveServiceAddr(ip uint32, port uint16) *ServiceInfo {
+	if ip == 0 {
+		return nil
+	}
+	ipStr := ipString(ip)
+	r.mu.RLock()
+	s := r.servicesByIP[ipStr]
+	if s == nil && port != 0 && r.nodeNameLocked(ipStr) != "" {
+		s = r.nodePorts[int32(port)]
+	}
+	r.mu.RUnlock()
+	r.stats.observe(lookupService, s != nil)
+	return s
+}
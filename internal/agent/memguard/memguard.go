@@ -0,0 +1,311 @@
+// Package memguard keeps the agent's memory below a budget so that it is
+// never OOM-killed alongside the workloads on its node.
+//
+// 사용량(RSS, 읽을 수 없으면 Go heap)을 주기적으로 측정해 단계적으로 대응한다:
+//
+//	LevelNormal      — 제한 없음
+//	LevelShrinkQueue — soft limit 초과: exporter 큐를 줄여 메모리에 쌓이는 이벤트를 제한
+//	LevelSample      — soft/hard 중간 초과: 이벤트를 샘플링(1/4)해 처리량 자체를 줄임
+//	LevelPause       — hard limit 초과: 이벤트 읽기를 멈춤 (커널 ringbuf가 넘치며 drop)
+//
+// 한 단계씩 올라가고, 사용량이 해당 임계값의 90% 아래로 내려가면 한 단계씩 내려온다.
+// 모든 상태와 전환은 메트릭으로 노출된다.
+package memguard
+
+import (
+	"bufio"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/metrics"
+)
+
+// Level은 보호 단계다.
+type Level int32
+
+const (
+	LevelNormal Level = iota
+	LevelShrinkQueue
+	LevelSample
+	LevelPause
+)
+
+var levelNames = [...]string{"normal", "shrink_queue", "sample", "pause"}
+
+func (l Level) String() string {
+	if int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return "unknown"
+}
+
+const (
+	// SampleRate는 LevelSample 이상에서 유지하는 이벤트 비율이다.
+	SampleRate = 0.25
+	// hysteresis는 단계를 내릴 때 임계값에 곱하는 비율이다 (진동 방지).
+	hysteresis = 0.9
+	// cgroup limit에서 자동 설정할 때의 비율.
+	autoSoftRatio = 0.7
+	autoHardRatio = 0.9
+)
+
+// Config는 Guard 설정이다.
+type Config struct {
+	SoftLimit uint64        // bytes; 초과 시 LevelShrinkQueue
+	HardLimit uint64        // bytes; 초과 시 LevelPause
+	Interval  time.Duration // 측정 주기
+	// OnLevel은 단계가 바뀔 때 호출된다 (예: exporter 큐 크기 조정).
+	OnLevel func(Level)
+}
+
+// Guard는 메모리 사용량을 감시하고 보호 단계를 결정한다.
+type Guard struct {
+	cfg         Config
+	level       atomic.Int32
+	usage       atomic.Uint64
+	transitions [len(levelNames)]atomic.Uint64
+	sampledOut  atomic.Uint64
+	pausedNs    atomic.Int64
+	done        chan struct{}
+}
+
+// AutoLimits는 cgroup 메모리 limit에서 soft/hard limit을 계산한다.
+// limit이 없거나 읽을 수 없으면 ok=false다.
+func AutoLimits() (soft, hard uint64, ok bool) {
+	limit, ok := cgroupLimit()
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(float64(limit) * autoSoftRatio), uint64(float64(limit) * autoHardRatio), true
+}
+
+// New는 Guard를 만들고 측정을 시작한다.
+func New(cfg Config) *Guard {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	g := &Guard{cfg: cfg, done: make(chan struct{})}
+	g.usage.Store(usage())
+	go g.run()
+	return g
+}
+
+// Level은 현재 보호 단계를 반환한다.
+func (g *Guard) Level() Level {
+	return Level(g.level.Load())
+}
+
+// Paused는 이벤트 읽기를 멈춰야 하는지 반환한다.
+func (g *Guard) Paused() bool {
+	return g.Level() >= LevelPause
+}
+
+// Allow는 샘플링 단계에서 이벤트를 유지할지 결정한다.
+func (g *Guard) Allow() bool {
+	if g.Level() < LevelSample || rand.Float64() < SampleRate {
+		return true
+	}
+	g.sampledOut.Add(1)
+	return false
+}
+
+// Usage는 마지막으로 측정한 메모리 사용량(bytes)이다.
+func (g *Guard) Usage() uint64 {
+	return g.usage.Load()
+}
+
+// Close는 측정을 멈춘다.
+func (g *Guard) Close() {
+	close(g.done)
+}
+
+func (g *Guard) run() {
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-ticker.C:
+		}
+		u := usage()
+		g.usage.Store(u)
+		if g.Paused() {
+			g.pausedNs.Add(int64(g.cfg.Interval))
+		}
+		cur := g.Level()
+		next := g.evaluate(cur, u)
+		if next == cur {
+			continue
+		}
+		g.level.Store(int32(next))
+		g.transitions[next].Add(1)
+		log.Printf("[memguard] memory %d MiB (soft %d MiB, hard %d MiB): %s → %s",
+			u>>20, g.cfg.SoftLimit>>20, g.cfg.HardLimit>>20, cur, next)
+		if next == LevelPause {
+			// 읽기를 멈춘 동안 해제 가능한 메모리를 OS에 돌려준다.
+			debug.FreeOSMemory()
+		}
+		if g.cfg.OnLevel != nil {
+			g.cfg.OnLevel(next)
+		}
+	}
+}
+
+// threshold는 level에 진입하는 사용량이다.
+func (g *Guard) threshold(l Level) uint64 {
+	switch l {
+	case LevelShrinkQueue:
+		return g.cfg.SoftLimit
+	case LevelSample:
+		return g.cfg.SoftLimit + (g.cfg.HardLimit-g.cfg.SoftLimit)/2
+	case LevelPause:
+		return g.cfg.HardLimit
+	}
+	return 0
+}
+
+// evaluate는 한 번에 한 단계씩만 움직인다.
+func (g *Guard) evaluate(cur Level, u uint64) Level {
+	if cur < LevelPause && u >= g.threshold(cur+1) {
+		return cur + 1
+	}
+	if cur > LevelNormal && float64(u) < float64(g.threshold(cur))*hysteresis {
+		return cur - 1
+	}
+	return cur
+}
+
+// RegisterMetrics는 보호 상태 지표를 reg에 등록한다.
+func (g *Guard) RegisterMetrics(reg *metrics.Registry) {
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_memory_bytes",
+		Help: "Agent memory usage (RSS, or Go heap when RSS is unavailable) as seen by the memory guard.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(g.Usage())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_memory_limit_bytes",
+		Help: "Memory guard thresholds.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{
+				{Labels: metrics.Labels{"limit": "soft"}, Value: float64(g.cfg.SoftLimit)},
+				{Labels: metrics.Labels{"limit": "hard"}, Value: float64(g.cfg.HardLimit)},
+			}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_memguard_level",
+		Help: "Current memory protection level (0 normal, 1 shrink_queue, 2 sample, 3 pause).",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(g.Level())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_memguard_transitions_total",
+		Help: "Memory protection level changes, by level entered.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, len(levelNames))
+			for l := range g.transitions {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"level": Level(l).String()},
+					Value:  float64(g.transitions[l].Load()),
+				})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_memguard_sampled_out_total",
+		Help: "Events dropped by memory-pressure sampling.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(g.sampledOut.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_agent_memguard_paused_seconds_total",
+		Help: "Time event reading was paused because memory exceeded the hard limit.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: time.Duration(g.pausedNs.Load()).Seconds()}}
+		},
+	})
+}
+
+// usage는 프로세스 RSS를 반환한다. /proc을 읽을 수 없으면 Go runtime이 OS에서 받은 메모리를 쓴다.
+func usage() uint64 {
+	if rss, ok := rss(); ok {
+		return rss
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys - ms.HeapReleased
+}
+
+func rss() (uint64, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(line) // "VmRSS:  12345 kB"
+		if len(fields) < 2 {
+			return 0, false
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return kb << 10, true
+	}
+	return 0, false
+}
+
+// cgroupLimit은 cgroup v2(memory.max) 또는 v1(memory.limit_in_bytes)의 메모리 limit을 읽는다.
+func cgroupLimit() (uint64, bool) {
+	return cgroupLimitAt("/sys/fs/cgroup")
+}
+
+// cgroupLimitAt은 cgroupLimit을 cgroup mount root 아래에서 읽는다.
+func cgroupLimitAt(root string) (uint64, bool) {
+	for _, p := range []string{
+		filepath.Join(root, "memory.max"),
+		filepath.Join(root, "memory", "memory.limit_in_bytes"),
+	} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		s := strings.TrimSpace(string(data))
+		if s == "max" {
+			return 0, false
+		}
+		v, err := strconv.ParseUint(s, 10, 64)
+		// v1은 limit이 없으면 매우 큰 값(page 정렬된 int64 최대값)을 보고한다.
+		if err != nil || v == 0 || v >= 1<<62 {
+			return 0, false
+		}
+		return v, true
+	}
+	return 0, false
+}
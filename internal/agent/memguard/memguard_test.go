@@ -0,0 +1,95 @@
+package memguard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testGuard는 측정 고루틴 없이 evaluate만 쓰는 Guard다.
+// 임계값: shrink_queue 100, sample 150, pause 200.
+func testGuard() *Guard {
+	return &Guard{cfg: Config{SoftLimit: 100, HardLimit: 200}}
+}
+
+func TestEvaluate(t *testing.T) {
+	g := testGuard()
+	tests := []struct {
+		cur  Level
+		u    uint64
+		want Level
+	}{
+		{LevelNormal, 99, LevelNormal},
+		{LevelNormal, 100, LevelShrinkQueue},
+		{LevelNormal, 1000, LevelShrinkQueue}, // hard limit을 넘어도 한 단계씩
+		{LevelShrinkQueue, 150, LevelSample},
+		{LevelSample, 200, LevelPause},
+		{LevelPause, 1000, LevelPause},
+		{LevelPause, 0, LevelSample}, // 내려갈 때도 한 단계씩
+		{LevelSample, 0, LevelShrinkQueue},
+		{LevelShrinkQueue, 0, LevelNormal},
+
+		// 내려가려면 현재 단계 임계값의 90% 아래여야 한다.
+		{LevelPause, 180, LevelPause},
+		{LevelPause, 179, LevelSample},
+		{LevelSample, 135, LevelSample},
+		{LevelSample, 134, LevelShrinkQueue},
+		{LevelShrinkQueue, 90, LevelShrinkQueue},
+		{LevelShrinkQueue, 89, LevelNormal},
+		{LevelSample, 149, LevelSample}, // 진입 임계값 바로 아래에서 머문다
+	}
+	for _, tt := range tests {
+		if got := g.evaluate(tt.cur, tt.u); got != tt.want {
+			t.Errorf("evaluate(%s, %d) = %s, want %s", tt.cur, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateSteps(t *testing.T) {
+	g := testGuard()
+	usage := []uint64{500, 500, 500, 500, 185, 170, 170, 140, 120, 95, 80, 80}
+	want := []Level{
+		LevelShrinkQueue, LevelSample, LevelPause, LevelPause,
+		LevelPause, LevelSample, LevelSample, LevelSample,
+		LevelShrinkQueue, LevelShrinkQueue, LevelNormal, LevelNormal,
+	}
+	cur := LevelNormal
+	for i, u := range usage {
+		cur = g.evaluate(cur, u)
+		if cur != want[i] {
+			t.Fatalf("step %d (usage %d): %s, want %s", i, u, cur, want[i])
+		}
+	}
+}
+
+func TestCgroupLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  uint64 // 0 = limit 없음
+	}{
+		{"v2", map[string]string{"memory.max": "536870912\n"}, 512 << 20},
+		{"v2 unlimited", map[string]string{"memory.max": "max\n"}, 0},
+		{"v1", map[string]string{"memory/memory.limit_in_bytes": "268435456\n"}, 256 << 20},
+		{"v1 unlimited", map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712\n"}, 0},
+		{"v1 zero", map[string]string{"memory/memory.limit_in_bytes": "0\n"}, 0},
+		{"garbage", map[string]string{"memory.max": "lots\n"}, 0},
+		{"no cgroup files", nil, 0},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		for name, content := range tt.files {
+			path := filepath.Join(root, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, ok := cgroupLimitAt(root)
+		if got != tt.want || ok != (tt.want != 0) {
+			t.Errorf("%s: cgroupLimit = %d, %v; want %d", tt.name, got, ok, tt.want)
+		}
+	}
+}
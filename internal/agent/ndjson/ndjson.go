@@ -0,0 +1,93 @@
+// Package ndjson은 보강된 이벤트를 한 줄에 하나씩 JSON(NDJSON)으로 기록하는 로컬 exporter다.
+//
+// nefi-server 없이 단일 노드에서 eBPF 수집/보강 결과를 확인하거나,
+// 네트워크가 격리된 환경에서 파일로 수집해 나중에 분석할 때 사용한다.
+// 필드 이름은 proto 필드 이름(snake_case)을 따른다.
+package ndjson
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+const queueSize = 512
+
+var marshaler = protojson.MarshalOptions{UseProtoNames: true}
+
+// Exporter는 이벤트를 NDJSON으로 w에 쓴다. 쓰기는 백그라운드 고루틴에서 수행한다.
+type Exporter struct {
+	w        *bufio.Writer
+	closer   io.Closer // 파일 모드에서만 설정
+	ch       chan *nefiv1.TraceEvent
+	finished chan struct{}
+	dropped  uint64
+}
+
+// New는 w에 쓰는 Exporter를 시작한다.
+func New(w io.Writer) *Exporter {
+	e := &Exporter{
+		w:        bufio.NewWriter(w),
+		ch:       make(chan *nefiv1.TraceEvent, queueSize),
+		finished: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Open은 path 파일에 이어 쓰는 Exporter를 시작한다.
+func Open(path string) (*Exporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	e := New(f)
+	e.closer = f
+	return e, nil
+}
+
+// Send는 이벤트를 기록 큐에 넣는다. 큐가 가득 차면 drop한다 (캡처 루프 블로킹 방지).
+func (e *Exporter) Send(ev *nefiv1.TraceEvent) {
+	select {
+	case e.ch <- ev:
+	default:
+		e.dropped++
+	}
+}
+
+// Close는 큐에 남은 이벤트를 모두 기록하고 파일을 닫는다.
+func (e *Exporter) Close() {
+	close(e.ch)
+	<-e.finished
+	if e.dropped > 0 {
+		log.Printf("[ndjson] dropped %d events (writer too slow)", e.dropped)
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.finished)
+	for ev := range e.ch {
+		b, err := marshaler.Marshal(ev)
+		if err != nil {
+			log.Printf("[ndjson] marshal error: %v", err)
+			continue
+		}
+		e.w.Write(b)        //nolint:errcheck
+		e.w.WriteByte('\n') //nolint:errcheck
+		// 큐가 비면 flush — tail -f 등으로 바로 볼 수 있게 한다.
+		if len(e.ch) == 0 {
+			if err := e.w.Flush(); err != nil {
+				log.Printf("[ndjson] write error: %v", err)
+			}
+		}
+	}
+	e.w.Flush() //nolint:errcheck
+	if e.closer != nil {
+		e.closer.Close() //nolint:errcheck
+	}
+}
@@ -0,0 +1,331 @@
+// Package netclass는 K8s resolver가 모르는 remote IP를 분류해, 외부 의존성이 이름 없는
+// IP 주소 대신 논리적인 이름으로 보이게 한다.
+//
+// 분류 우선순위 (가장 긴 prefix가 우선):
+//  1. 사용자 매핑 파일의 CIDR    (예: 10.50.0.0/16 corp-oracle)
+//  2. 클라우드 공개 IP 대역      (AWS ip-ranges.json, GCP cloud.json, Azure ServiceTags)
+//  3. 사설 대역 (RFC 1918 등)    → "private-network"
+//  4. 그 외                      → "internet"
+//
+// 매핑 파일에는 hostname 규칙(예: *.rds.amazonaws.com orders-db)도 쓸 수 있다.
+// IP만으로는 알 수 없으므로 reverse DNS 등으로 얻은 hostname에 MapHost로 적용한다.
+//
+// 모든 주소는 BPF 이벤트와 동일하게 host byte order의 IPv4 uint32를 사용한다.
+package netclass
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	NameInternet       = "internet"
+	NamePrivateNetwork = "private-network"
+)
+
+// privateCIDRs는 클러스터 외부지만 인터넷도 아닌 대역이다.
+var privateCIDRs = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10", // CGNAT
+	"127.0.0.0/8",
+	"169.254.0.0/16", // link-local (cloud metadata 등)
+}
+
+// prefixTable은 prefix 길이별 network → name 맵이다.
+// 조회 시 /32부터 /0까지 내려가며 최대 33번의 map lookup으로 longest-prefix match를 수행한다.
+type prefixTable struct {
+	byLen [33]map[uint32]string
+}
+
+func (t *prefixTable) insert(p netip.Prefix, name string, overwrite bool) {
+	if !p.Addr().Is4() {
+		return // IPv6는 아직 이벤트에서 지원하지 않는다
+	}
+	bits := p.Bits()
+	if t.byLen[bits] == nil {
+		t.byLen[bits] = make(map[uint32]string)
+	}
+	key := toUint32(p.Masked().Addr())
+	if _, exists := t.byLen[bits][key]; exists && !overwrite {
+		return
+	}
+	t.byLen[bits][key] = name
+}
+
+func (t *prefixTable) lookup(ip uint32) (string, bool) {
+	for bits := 32; bits >= 0; bits-- {
+		m := t.byLen[bits]
+		if m == nil {
+			continue
+		}
+		if name, ok := m[ip&mask(bits)]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (t *prefixTable) len() int {
+	n := 0
+	for _, m := range t.byLen {
+		n += len(m)
+	}
+	return n
+}
+
+// hostRule은 매핑 파일의 hostname 규칙이다. pattern이 "*."로 시작하면
+// 그 하위 도메인 전체, 아니면 정확히 같은 hostname에 적용된다.
+type hostRule struct {
+	pattern string // 소문자, 끝의 '.' 제거
+	name    string
+}
+
+func (r hostRule) matches(host string) bool {
+	if suffix, ok := strings.CutPrefix(r.pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == r.pattern
+}
+
+// Classifier는 외부 IPv4 주소를 논리적인 이름으로 바꾼다.
+// 만든 뒤에는 바뀌지 않으므로 여러 goroutine에서 동시에 써도 된다.
+type Classifier struct {
+	user    prefixTable
+	hosts   []hostRule // 긴 pattern 순 (더 구체적인 규칙 우선)
+	cloud   prefixTable
+	private prefixTable
+}
+
+// Config는 Classifier가 읽을 파일이다. 빈 경로는 건너뛴다.
+type Config struct {
+	CIDRFile   string // 한 줄에 "<cidr|hostname> name", '#' 주석 허용
+	AWSRanges  string // AWS ip-ranges.json
+	GCPRanges  string // GCP cloud.json
+	AzureRange string // Azure ServiceTags_Public.json
+}
+
+// New는 cfg의 파일을 읽어 Classifier를 만든다.
+func New(cfg Config) (*Classifier, error) {
+	c := &Classifier{}
+	for _, s := range privateCIDRs {
+		c.private.insert(netip.MustParsePrefix(s), NamePrivateNetwork, true)
+	}
+
+	if cfg.CIDRFile != "" {
+		if err := c.loadCIDRFile(cfg.CIDRFile); err != nil {
+			return nil, fmt.Errorf("CIDR mapping %s: %w", cfg.CIDRFile, err)
+		}
+	}
+	if cfg.AWSRanges != "" {
+		if err := c.loadAWS(cfg.AWSRanges); err != nil {
+			return nil, fmt.Errorf("AWS ranges %s: %w", cfg.AWSRanges, err)
+		}
+	}
+	if cfg.GCPRanges != "" {
+		if err := c.loadGCP(cfg.GCPRanges); err != nil {
+			return nil, fmt.Errorf("GCP ranges %s: %w", cfg.GCPRanges, err)
+		}
+	}
+	if cfg.AzureRange != "" {
+		if err := c.loadAzure(cfg.AzureRange); err != nil {
+			return nil, fmt.Errorf("Azure ranges %s: %w", cfg.AzureRange, err)
+		}
+	}
+	return c, nil
+}
+
+// Classify는 ip(host byte order)의 논리적인 이름을 반환한다.
+// 항상 비어 있지 않은 이름을 반환하며, 알 수 없는 공인 주소는 "internet"이다.
+func (c *Classifier) Classify(ip uint32) string {
+	if name, ok := c.user.lookup(ip); ok {
+		return name
+	}
+	if name, ok := c.cloud.lookup(ip); ok {
+		return name
+	}
+	if name, ok := c.private.lookup(ip); ok {
+		return name
+	}
+	return NameInternet
+}
+
+// Mapped는 운영자가 매핑 파일에 적은 ip의 CIDR 이름을 반환한다.
+// 명시적인 매핑이 reverse DNS 같은 다른 이름보다 우선하도록 호출자가 먼저 확인한다.
+func (c *Classifier) Mapped(ip uint32) (string, bool) {
+	return c.user.lookup(ip)
+}
+
+// MapHost는 hostname(예: reverse DNS 결과)에 맞는 hostname 규칙이 있으면 그 이름을 반환한다.
+// 가장 긴 pattern이 우선하며, "*.example.com"은 하위 도메인에만 맞고 example.com에는
+// 맞지 않는다.
+func (c *Classifier) MapHost(host string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, r := range c.hosts {
+		if r.matches(host) {
+			return r.name, true
+		}
+	}
+	return "", false
+}
+
+// Sizes는 읽어 들인 사용자 매핑(CIDR과 hostname 규칙) 수와 클라우드 prefix 수다.
+func (c *Classifier) Sizes() (user, cloud int) {
+	return c.user.len() + len(c.hosts), c.cloud.len()
+}
+
+func (c *Classifier) loadCIDRFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d: want \"<cidr|hostname> <name>\", got %q", lineNo, line)
+		}
+		if p, err := netip.ParsePrefix(fields[0]); err == nil {
+			c.user.insert(p, fields[1], true)
+			continue
+		}
+		if addr, err := netip.ParseAddr(fields[0]); err == nil {
+			c.user.insert(netip.PrefixFrom(addr, addr.BitLen()), fields[1], true)
+			continue
+		}
+		pattern, err := hostPattern(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		c.hosts = append(c.hosts, hostRule{pattern: pattern, name: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	sort.SliceStable(c.hosts, func(i, j int) bool { return len(c.hosts[i].pattern) > len(c.hosts[j].pattern) })
+	return nil
+}
+
+// hostPattern은 hostname 규칙("db.example.com" 또는 "*.example.com")을 검사하고
+// 소문자로 바꾸고 끝의 '.'을 뗀 pattern을 반환한다.
+func hostPattern(s string) (string, error) {
+	p := strings.ToLower(strings.TrimSuffix(s, "."))
+	rest := strings.TrimPrefix(p, "*.")
+	if !strings.Contains(rest, ".") || strings.ContainsAny(rest, "*/:") {
+		return "", fmt.Errorf("%q is neither a CIDR nor a hostname pattern", s)
+	}
+	return p, nil
+}
+
+// loadAWS는 https://ip-ranges.amazonaws.com/ip-ranges.json을 읽는다.
+// 범용 "AMAZON" 서비스는 모든 개별 서비스 대역과 겹치므로, 같은 prefix의 더 구체적인
+// 이름을 덮어쓰지 않는다.
+func (c *Classifier) loadAWS(path string) error {
+	var doc struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+	}
+	if err := readJSON(path, &doc); err != nil {
+		return err
+	}
+	for _, p := range doc.Prefixes {
+		prefix, err := netip.ParsePrefix(p.IPPrefix)
+		if err != nil {
+			continue
+		}
+		if p.Service == "AMAZON" {
+			c.cloud.insert(prefix, "amazonaws.com-range", false)
+			continue
+		}
+		c.cloud.insert(prefix, strings.ToLower(p.Service)+".amazonaws.com-range", true)
+	}
+	return nil
+}
+
+// loadGCP는 https://www.gstatic.com/ipranges/cloud.json을 읽는다.
+func (c *Classifier) loadGCP(path string) error {
+	var doc struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+		} `json:"prefixes"`
+	}
+	if err := readJSON(path, &doc); err != nil {
+		return err
+	}
+	for _, p := range doc.Prefixes {
+		if p.IPv4Prefix == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p.IPv4Prefix)
+		if err != nil {
+			continue
+		}
+		c.cloud.insert(prefix, "googlecloud.com-range", false)
+	}
+	return nil
+}
+
+// loadAzure는 Azure "Service Tags – Public" JSON 파일을 읽는다.
+func (c *Classifier) loadAzure(path string) error {
+	var doc struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := readJSON(path, &doc); err != nil {
+		return err
+	}
+	for _, v := range doc.Values {
+		name := strings.ToLower(v.Name) + ".azure.com-range"
+		for _, s := range v.Properties.AddressPrefixes {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				continue
+			}
+			c.cloud.insert(prefix, name, false)
+		}
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func toUint32(a netip.Addr) uint32 {
+	b := a.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func mask(bits int) uint32 {
+	if bits == 0 {
+		return 0
+	}
+	return ^uint32(0) << (32 - bits)
+}
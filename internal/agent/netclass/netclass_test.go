@@ -0,0 +1,78 @@
+package netclass
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func ip(a, b, c, d uint32) uint32 { return a<<24 | b<<16 | c<<8 | d }
+
+func TestClassify(t *testing.T) {
+	dir := t.TempDir()
+	cidrs := filepath.Join(dir, "cidrs.txt")
+	os.WriteFile(cidrs, []byte("# corp\n10.50.0.0/16 corp-oracle\n10.50.1.0/24 corp-oracle-primary\n"), 0o644)
+	aws := filepath.Join(dir, "ip-ranges.json")
+	os.WriteFile(aws, []byte(`{"prefixes":[
+		{"ip_prefix":"52.216.0.0/15","service":"S3"},
+		{"ip_prefix":"52.216.0.0/15","service":"AMAZON"},
+		{"ip_prefix":"3.0.0.0/9","service":"AMAZON"}]}`), 0o644)
+
+	c, err := New(Config{CIDRFile: cidrs, AWSRanges: aws})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   uint32
+		want string
+	}{
+		{ip(10, 50, 2, 7), "corp-oracle"},
+		{ip(10, 50, 1, 7), "corp-oracle-primary"},
+		{ip(10, 1, 2, 3), NamePrivateNetwork},
+		{ip(52, 217, 0, 1), "s3.amazonaws.com-range"},
+		{ip(3, 5, 0, 1), "amazonaws.com-range"},
+		{ip(8, 8, 8, 8), NameInternet},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.ip); got != tt.want {
+			t.Errorf("Classify(%08x) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestMapHost(t *testing.T) {
+	dir := t.TempDir()
+	mapping := filepath.Join(dir, "external.txt")
+	os.WriteFile(mapping, []byte("10.50.0.0/16 corp-oracle\n10.60.0.9 ldap\n*.rds.amazonaws.com orders-db\n*.billing.rds.amazonaws.com billing-db\napi.stripe.com. stripe\n"), 0o644)
+
+	c, err := New(Config{CIDRFile: mapping})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Classify(ip(10, 60, 0, 9)); got != "ldap" {
+		t.Errorf("Classify(10.60.0.9) = %q, want ldap", got)
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"orders.cx1.ap-northeast-2.rds.amazonaws.com", "orders-db"},
+		{"main.billing.rds.amazonaws.com.", "billing-db"},
+		{"API.Stripe.com", "stripe"},
+		{"rds.amazonaws.com", ""},
+		{"files.stripe.com", ""},
+	}
+	for _, tt := range tests {
+		got, ok := c.MapHost(tt.host)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("MapHost(%q) = %q, %v, want %q", tt.host, got, ok, tt.want)
+		}
+	}
+
+	os.WriteFile(mapping, []byte("*.* bad\n"), 0o644)
+	if _, err := New(Config{CIDRFile: mapping}); err == nil {
+		t.Error("New accepted an invalid hostname pattern")
+	}
+}
@@ -0,0 +1,101 @@
+// Package nodename determines the Kubernetes node the agent runs on.
+//
+// 탐지 순서 (처음 성공한 값 사용):
+//  1. NODE_NAME 환경변수       — DaemonSet의 downward API env (기본 manifest)
+//  2. downward API 파일        — volume으로 마운트한 spec.nodeName (기본 /etc/nefi/nodename)
+//  3. kubelet API              — https://127.0.0.1:10250/pods 의 spec.nodeName
+//     (service account에 nodes/proxy 권한이 필요하며, 기본 manifest에는 부여하지 않는다)
+//  4. os.Hostname()            — hostNetwork pod나 로컬 실행에서 대부분 노드 이름과 같다
+package nodename
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Source는 노드 이름을 얻은 경로다.
+const (
+	SourceEnv      = "env"
+	SourceFile     = "downward-api-file"
+	SourceKubelet  = "kubelet"
+	SourceHostname = "hostname"
+)
+
+const (
+	// DefaultFile은 downward API volume으로 노드 이름을 마운트하는 기본 경로다.
+	DefaultFile    = "/etc/nefi/nodename"
+	kubeletURL     = "https://127.0.0.1:10250/pods"
+	tokenFile      = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	kubeletTimeout = 2 * time.Second
+)
+
+// Detect는 노드 이름과 그 출처를 반환한다. 모든 방법이 실패하면 name은 ""다.
+// file이 비어 있으면 downward API 파일 단계를 건너뛴다.
+func Detect(file string) (name, source string) {
+	if v := strings.TrimSpace(os.Getenv("NODE_NAME")); v != "" {
+		return v, SourceEnv
+	}
+	if file != "" {
+		if data, err := os.ReadFile(file); err == nil {
+			if v := strings.TrimSpace(string(data)); v != "" {
+				return v, SourceFile
+			}
+		}
+	}
+	if v, err := fromKubelet(); err == nil && v != "" {
+		return v, SourceKubelet
+	}
+	if v, err := os.Hostname(); err == nil && v != "" {
+		return v, SourceHostname
+	}
+	return "", ""
+}
+
+// fromKubelet은 로컬 kubelet의 pod 목록에서 spec.nodeName을 읽는다.
+// kubelet serving 인증서는 보통 self-signed이므로 검증하지 않는다 (loopback 전용).
+func fromKubelet() (string, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kubeletTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, kubeletURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // loopback kubelet
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("kubelet: %s", resp.Status)
+	}
+	var pods struct {
+		Items []struct {
+			Spec struct {
+				NodeName string `json:"nodeName"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+		return "", err
+	}
+	for _, p := range pods.Items {
+		if p.Spec.NodeName != "" {
+			return p.Spec.NodeName, nil
+		}
+	}
+	return "", fmt.Errorf("kubelet: no pods with spec.nodeName")
+}
@@ -0,0 +1,393 @@
+// Package procnet은 eBPF를 사용할 수 없는 환경을 위한 대체 수집기다.
+//
+// 커널이 BPF 로드를 거부하는 제한된 환경에서도 topology 데이터를 얻을 수 있도록,
+// 주기적으로 /proc을 읽어 TCP 연결을 관측하고 payload 없는 연결 이벤트를 합성한다.
+//
+// 동작:
+//   - /proc/<pid>/ns/net으로 network namespace를 구분하고, namespace마다
+//     대표 pid의 /proc/<pid>/net/tcp{,6}를 읽는다 (pod별 netns 포함).
+//   - /proc/<pid>/fd의 socket inode로 연결을 소유한 pid를 찾는다 (pod 해석용).
+//   - 같은 namespace의 LISTEN 포트와 로컬 포트가 같으면 inbound(서버 측),
+//     아니면 outbound(클라이언트 측) 연결로 본다.
+//   - /proc/net/nf_conntrack을 읽을 수 있으면 ClusterIP 등 DNAT된 목적지를
+//     실제 backend 주소로 변환한다.
+//
+// 새 연결은 처음 관측될 때, 유지 중인 연결은 reemitInterval마다 한 번 이벤트를 낸다.
+// 짧게 끝나는 연결은 poll 사이에 놓칠 수 있으므로 eBPF 대비 거친(coarse) 데이터다.
+package procnet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+const (
+	queueSize      = 4096
+	reemitInterval = time.Minute
+
+	tcpEstablished = "01"
+	tcpListen      = "0A"
+)
+
+// ErrClosed는 Close 이후 Read가 반환하는 에러다.
+var ErrClosed = errors.New("procnet: poller closed")
+
+// conn은 /proc/net/tcp의 연결 하나다. IP는 host byte order다.
+type conn struct {
+	localIP, remoteIP     uint32
+	localPort, remotePort uint16
+	inode                 uint64
+}
+
+// Poller는 /proc을 주기적으로 읽어 연결 이벤트를 생성한다.
+type Poller struct {
+	interval time.Duration
+	procRoot string
+	out      chan *model.DataEvent
+	done     chan struct{}
+	once     sync.Once
+
+	seen map[uint64]time.Time // socket inode → 마지막 emit 시각 (run 고루틴 전용)
+}
+
+// New는 interval마다 /proc을 poll하는 Poller를 시작한다.
+func New(interval time.Duration) *Poller {
+	p := &Poller{
+		interval: interval,
+		procRoot: "/proc",
+		out:      make(chan *model.DataEvent, queueSize),
+		done:     make(chan struct{}),
+		seen:     make(map[uint64]time.Time),
+	}
+	go p.run()
+	return p
+}
+
+// Read는 다음 연결 이벤트를 블로킹 대기한다. Close 이후에는 ErrClosed를 반환한다.
+// Direction 0은 inbound(로컬이 서버), 1은 outbound(로컬이 클라이언트)로,
+// BPF 이벤트의 응답 송신/수신 방향과 같은 의미를 갖는다.
+func (p *Poller) Read() (*model.DataEvent, error) {
+	select {
+	case ev := <-p.out:
+		return ev, nil
+	case <-p.done:
+		return nil, ErrClosed
+	}
+}
+
+// Close는 polling을 멈추고 대기 중인 Read를 깨운다.
+func (p *Poller) Close() {
+	p.once.Do(func() { close(p.done) })
+}
+
+func (p *Poller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.poll()
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sockOwner는 소켓을 연 프로세스와 fd 번호다. fd는 eBPF 이벤트와 같은
+// conn_id(node/pid/fd)를 만들기 위해 함께 기록한다.
+type sockOwner struct {
+	pid uint32
+	fd  uint32
+}
+
+// poll은 모든 network namespace의 연결을 한 번 읽고 이벤트를 낸다.
+func (p *Poller) poll() {
+	now := time.Now()
+	netnsPID := make(map[string]uint32) // netns → 대표 pid
+	owner := make(map[uint64]sockOwner) // socket inode → pid/fd
+
+	pids, _ := filepath.Glob(filepath.Join(p.procRoot, "[0-9]*"))
+	for _, dir := range pids {
+		pid64, err := strconv.ParseUint(filepath.Base(dir), 10, 32)
+		if err != nil {
+			continue
+		}
+		pid := uint32(pid64)
+		if ns, err := os.Readlink(filepath.Join(dir, "ns", "net")); err == nil {
+			if _, ok := netnsPID[ns]; !ok {
+				netnsPID[ns] = pid
+			}
+		}
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(link[8:len(link)-1], 10, 64)
+			if err != nil {
+				continue
+			}
+			if fdNum, err := strconv.ParseUint(fd.Name(), 10, 32); err == nil {
+				owner[inode] = sockOwner{pid: pid, fd: uint32(fdNum)}
+			}
+		}
+	}
+
+	nat := readConntrack(filepath.Join(p.procRoot, "net", "nf_conntrack"))
+	ts := monotonicNs()
+	alive := make(map[uint64]struct{})
+
+	for _, pid := range netnsPID {
+		base := filepath.Join(p.procRoot, strconv.Itoa(int(pid)), "net")
+		var conns []conn
+		listening := make(map[uint16]struct{})
+		for _, name := range []string{"tcp", "tcp6"} {
+			c, l := readTCP(filepath.Join(base, name))
+			conns = append(conns, c...)
+			for port := range l {
+				listening[port] = struct{}{}
+			}
+		}
+		for _, c := range conns {
+			if c.inode == 0 || isLoopback(c.remoteIP) {
+				continue
+			}
+			alive[c.inode] = struct{}{}
+			if last, ok := p.seen[c.inode]; ok && now.Sub(last) < reemitInterval {
+				continue
+			}
+			o, ok := owner[c.inode]
+			if !ok {
+				continue // 소유 프로세스를 찾지 못한 소켓 (이미 종료됨 등)
+			}
+			p.seen[c.inode] = now
+
+			ev := &model.DataEvent{
+				TimestampNs: ts,
+				PID:         o.pid,
+				FD:          o.fd,
+				Direction:   1,
+				RemoteIP:    c.remoteIP,
+				RemotePort:  c.remotePort,
+			}
+			if _, ok := listening[c.localPort]; ok {
+				ev.Direction = 0
+			} else if real, ok := nat[natKey(c)]; ok {
+				// DNAT 전 목적지(ClusterIP 등) → 실제 backend
+				ev.RemoteIP, ev.RemotePort = real.ip, real.port
+			}
+			copy(ev.Comm[:], readComm(p.procRoot, o.pid))
+
+			select {
+			case p.out <- ev:
+			default:
+				// 소비가 늦으면 drop — 다음 reemit 주기에 다시 관측된다.
+			}
+		}
+	}
+
+	for inode := range p.seen {
+		if _, ok := alive[inode]; !ok {
+			delete(p.seen, inode)
+		}
+	}
+}
+
+// readTCP는 /proc/<pid>/net/tcp{,6} 파일에서 ESTABLISHED 연결과 LISTEN 포트를 읽는다.
+// tcp6에서는 IPv4-mapped 주소(::ffff:a.b.c.d)만 사용한다.
+func readTCP(path string) ([]conn, map[uint16]struct{}) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	var conns []conn
+	listening := make(map[uint16]struct{})
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		localIP, localPort, ok1 := parseAddr(fields[1])
+		remoteIP, remotePort, ok2 := parseAddr(fields[2])
+		if !ok1 || !ok2 {
+			continue
+		}
+		switch fields[3] {
+		case tcpListen:
+			listening[localPort] = struct{}{}
+		case tcpEstablished:
+			inode, _ := strconv.ParseUint(fields[9], 10, 64)
+			conns = append(conns, conn{
+				localIP: localIP, localPort: localPort,
+				remoteIP: remoteIP, remotePort: remotePort,
+				inode: inode,
+			})
+		}
+	}
+	return conns, listening
+}
+
+// parseAddr는 "0100007F:1F90" 형식을 host byte order IPv4와 포트로 변환한다.
+// 커널은 network order 주소를 native endian u32로 출력하므로, native endian으로
+// 되돌린 바이트가 곧 network order 주소다.
+func parseAddr(s string) (ip uint32, port uint16, ok bool) {
+	host, portHex, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, false
+	}
+	p, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	switch len(host) {
+	case 8:
+		v, err := strconv.ParseUint(host, 16, 32)
+		if err != nil {
+			return 0, 0, false
+		}
+		var b [4]byte
+		binary.NativeEndian.PutUint32(b[:], uint32(v))
+		return binary.BigEndian.Uint32(b[:]), uint16(p), true
+	case 32:
+		// IPv6: 4개의 native endian u32. IPv4-mapped(::ffff:a.b.c.d)만 지원한다.
+		raw, err := hex.DecodeString(host)
+		if err != nil {
+			return 0, 0, false
+		}
+		var b [16]byte
+		for i := 0; i < 4; i++ {
+			binary.NativeEndian.PutUint32(b[i*4:], binary.BigEndian.Uint32(raw[i*4:]))
+		}
+		for i := 0; i < 10; i++ {
+			if b[i] != 0 {
+				return 0, 0, false
+			}
+		}
+		if b[10] != 0xff || b[11] != 0xff {
+			return 0, 0, false
+		}
+		return binary.BigEndian.Uint32(b[12:]), uint16(p), true
+	}
+	return 0, 0, false
+}
+
+type endpoint struct {
+	ip   uint32
+	port uint16
+}
+
+func natKey(c conn) string {
+	return fmt.Sprintf("%d:%d>%d:%d", c.localIP, c.localPort, c.remoteIP, c.remotePort)
+}
+
+// readConntrack은 /proc/net/nf_conntrack에서 TCP 원래 방향 tuple → 응답 source(실제 목적지)
+// 매핑을 읽는다. 파일이 없거나 읽을 수 없으면 빈 맵을 반환한다.
+//
+//	ipv4 2 tcp 6 431999 ESTABLISHED src=A dst=VIP sport=X dport=80 src=B dst=A sport=8080 dport=X ...
+func readConntrack(path string) map[string]endpoint {
+	nat := make(map[string]endpoint)
+	f, err := os.Open(path)
+	if err != nil {
+		return nat
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "ipv4" || fields[2] != "tcp" {
+			continue
+		}
+		var src, dst, sport, dport []string
+		for _, f := range fields {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+			switch k {
+			case "src":
+				src = append(src, v)
+			case "dst":
+				dst = append(dst, v)
+			case "sport":
+				sport = append(sport, v)
+			case "dport":
+				dport = append(dport, v)
+			}
+		}
+		if len(src) < 2 || len(dst) < 1 || len(sport) < 2 || len(dport) < 1 {
+			continue
+		}
+		origSrc, ok1 := parseIPv4(src[0])
+		origDst, ok2 := parseIPv4(dst[0])
+		replySrc, ok3 := parseIPv4(src[1])
+		if !ok1 || !ok2 || !ok3 || origDst == replySrc {
+			continue // DNAT되지 않은 연결
+		}
+		op, _ := strconv.ParseUint(sport[0], 10, 16)
+		dp, _ := strconv.ParseUint(dport[0], 10, 16)
+		rp, _ := strconv.ParseUint(sport[1], 10, 16)
+		key := natKey(conn{localIP: origSrc, localPort: uint16(op), remoteIP: origDst, remotePort: uint16(dp)})
+		nat[key] = endpoint{ip: replySrc, port: uint16(rp)}
+	}
+	return nat
+}
+
+func parseIPv4(s string) (uint32, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return 0, false
+	}
+	var ip uint32
+	for _, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return 0, false
+		}
+		ip = ip<<8 | uint32(n)
+	}
+	return ip, true
+}
+
+func readComm(procRoot string, pid uint32) string {
+	b, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(int(pid)), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func isLoopback(ip uint32) bool {
+	return ip>>24 == 127
+}
+
+// monotonicNs는 BPF의 bpf_ktime_get_ns()와 같은 기준(CLOCK_MONOTONIC)의 현재 시각이다.
+func monotonicNs() uint64 {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return uint64(time.Now().UnixNano())
+	}
+	return uint64(ts.Nano())
+}
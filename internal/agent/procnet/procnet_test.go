@@ -0,0 +1,118 @@
+package procnet
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 아래 fixture는 little-endian 호스트의 /proc 출력이다 (커널은 주소를 native endian u32로 쓴다).
+func skipBigEndian(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("fixtures are little-endian /proc output")
+	}
+}
+
+func ip4(s string) uint32 {
+	a := netip.MustParseAddr(s).As4()
+	return binary.BigEndian.Uint32(a[:])
+}
+
+func writeFixture(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixture")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseAddr(t *testing.T) {
+	skipBigEndian(t)
+	tests := []struct {
+		in   string
+		ip   string // "" = ok false
+		port uint16
+	}{
+		{"0100007F:1F90", "127.0.0.1", 8080},
+		{"0501010A:C350", "10.1.1.5", 50000},
+		{"00000000:0000", "0.0.0.0", 0},
+		{"0000000000000000FFFF00000501010A:0050", "10.1.1.5", 80}, // ::ffff:10.1.1.5
+		{"00000000000000000000000000000000:1F90", "", 0},          // ::
+		{"B80D0120000000000000000001000000:0050", "", 0},          // 2001:db8::1
+		{"0000000000000000FFFF0001_501010A:0050", "", 0},
+		{"0501010A", "", 0},
+		{"0501010A:10000", "", 0}, // 포트가 16비트를 넘음
+		{"0501010A:ZZ", "", 0},
+		{"ZZ01010A:0050", "", 0},
+		{"01010A:0050", "", 0},
+	}
+	for _, tt := range tests {
+		ip, port, ok := parseAddr(tt.in)
+		if ok != (tt.ip != "") || ok && (ip != ip4(tt.ip) || port != tt.port) {
+			t.Errorf("parseAddr(%q) = %08x, %d, %v; want %s:%d", tt.in, ip, port, ok, tt.ip, tt.port)
+		}
+	}
+}
+
+func TestReadTCP(t *testing.T) {
+	skipBigEndian(t)
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0501010A:1F90 0701010A:D431 01 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 20 4 30 10 -1
+   2: 0501010A:C350 0A00600A:0050 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 0501010A:C351 0A00600A:0050 06 00000000:00000000 03:00000BB8 00000000     0        0 0 3 0000000000000000
+   4: 0501010A:C352 0A00600A:0050 01
+   5: ZZ01010A:C353 0A00600A:0050 01 00000000:00000000 00:00000000 00000000     0        0 1005 1 0000000000000000 20 4 30 10 -1
+`
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:2382 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00000501010A:C354 0000000000000000FFFF00000A02010A:01BB 01 00000000:00000000 00:00000000 00000000     0        0 2002 1 0000000000000000 20 4 30 10 -1
+   2: B80D0120000000000000000001000000:C355 B80D0120000000000000000002000000:01BB 01 00000000:00000000 00:00000000 00000000     0        0 2003 1 0000000000000000 20 4 30 10 -1
+`
+	conns, listening := readTCP(writeFixture(t, tcp))
+	want := []conn{
+		{localIP: ip4("10.1.1.5"), localPort: 8080, remoteIP: ip4("10.1.1.7"), remotePort: 54321, inode: 1002},
+		{localIP: ip4("10.1.1.5"), localPort: 50000, remoteIP: ip4("10.96.0.10"), remotePort: 80, inode: 1003},
+	}
+	if len(conns) != len(want) || conns[0] != want[0] || conns[1] != want[1] {
+		t.Errorf("tcp conns = %+v, want %+v (TIME_WAIT, short and malformed lines skipped)", conns, want)
+	}
+	if _, ok := listening[8080]; !ok || len(listening) != 1 {
+		t.Errorf("tcp listening = %v, want [8080]", listening)
+	}
+
+	conns, listening = readTCP(writeFixture(t, tcp6))
+	want6 := conn{localIP: ip4("10.1.1.5"), localPort: 50004, remoteIP: ip4("10.1.2.10"), remotePort: 443, inode: 2002}
+	if len(conns) != 1 || conns[0] != want6 {
+		t.Errorf("tcp6 conns = %+v, want only the IPv4-mapped %+v", conns, want6)
+	}
+	if len(listening) != 0 {
+		t.Errorf("tcp6 listening = %v, want none (:: is not IPv4-mapped)", listening)
+	}
+
+	if conns, listening := readTCP(filepath.Join(t.TempDir(), "missing")); conns != nil || listening != nil {
+		t.Errorf("missing file: %v, %v", conns, listening)
+	}
+}
+
+func TestReadConntrack(t *testing.T) {
+	const fixture = `ipv4     2 tcp      6 431999 ESTABLISHED src=10.1.1.5 dst=10.96.0.10 sport=50000 dport=80 src=10.1.2.7 dst=10.1.1.5 sport=8080 dport=50000 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 tcp      6 431999 ESTABLISHED src=10.1.1.5 dst=10.1.2.8 sport=50001 dport=8080 src=10.1.2.8 dst=10.1.1.5 sport=8080 dport=50001 [ASSURED] mark=0 zone=0 use=2
+ipv4     2 udp      17 29 src=10.1.1.5 dst=10.96.0.53 sport=5353 dport=53 src=10.1.3.3 dst=10.1.1.5 sport=53 dport=5353 mark=0 zone=0 use=2
+ipv6     10 tcp      6 431999 ESTABLISHED src=fd00::5 dst=fd00:96::10 sport=50002 dport=80 src=fd00::7 dst=fd00::5 sport=8080 dport=50002 [ASSURED] use=2
+ipv4     2 tcp      6 119 SYN_SENT src=10.1.1.5 dst=10.96.0.11 sport=50003 dport=80 [UNREPLIED]
+ipv4     2 tcp      6 431999 ESTABLISHED src=10.1.1.500 dst=10.96.0.12 sport=50004 dport=80 src=10.1.2.9 dst=10.1.1.5 sport=8080 dport=50004 use=2
+ipv4 2 tcp
+`
+	nat := readConntrack(writeFixture(t, fixture))
+	key := natKey(conn{localIP: ip4("10.1.1.5"), localPort: 50000, remoteIP: ip4("10.96.0.10"), remotePort: 80})
+	if got, ok := nat[key]; !ok || got != (endpoint{ip: ip4("10.1.2.7"), port: 8080}) || len(nat) != 1 {
+		t.Errorf("conntrack = %v, want only %s → 10.1.2.7:8080", nat, key)
+	}
+	if nat := readConntrack(filepath.Join(t.TempDir(), "missing")); nat == nil || len(nat) != 0 {
+		t.Errorf("missing file: %v, want an empty map", nat)
+	}
+}
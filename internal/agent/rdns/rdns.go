@@ -0,0 +1,143 @@
+// Package rdns는 Kubernetes pod나 service로 해석되지 않은 remote IP의 reverse DNS를
+// 속도를 제한해 조회한다.
+//
+// 이벤트 루프를 블로킹하지 않기 위해 조회는 비동기로 수행한다:
+//   - Lookup(ip)은 캐시에 있으면 hostname을 즉시 반환하고,
+//     없으면 빈 문자열을 반환한 뒤 백그라운드 조회를 예약한다.
+//   - 백그라운드 워커는 token bucket(rate)으로 DNS 서버 부하를 제한한다.
+//   - 성공/실패 모두 TTL 동안 캐시한다 (실패는 negative cache).
+package rdns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	queueSize     = 256
+	lookupTimeout = 2 * time.Second
+	maxEntries    = 65536 // 캐시 상한, 초과 시 만료 항목부터 정리하고 모자라면 임의의 항목을 비운다
+)
+
+type entry struct {
+	name      string // "" = 조회 실패 (negative cache)
+	expiresAt time.Time
+}
+
+// Resolver는 IPv4 주소(host byte order)별로 PTR 조회 결과를 캐시한다.
+type Resolver struct {
+	ttl     time.Duration
+	limiter *rate.Limiter
+	queue   chan uint32
+	done    chan struct{}
+
+	mu      sync.Mutex
+	cache   map[uint32]entry
+	pending map[uint32]struct{}
+
+	lookupAddr func(ctx context.Context, addr string) ([]string, error)
+}
+
+// New는 초당 최대 perSec번 조회하고 결과를 ttl 동안 캐시하는 Resolver를 시작한다.
+func New(perSec float64, ttl time.Duration) *Resolver {
+	r := &Resolver{
+		ttl:        ttl,
+		limiter:    rate.NewLimiter(rate.Limit(perSec), 1),
+		queue:      make(chan uint32, queueSize),
+		done:       make(chan struct{}),
+		cache:      make(map[uint32]entry),
+		pending:    make(map[uint32]struct{}),
+		lookupAddr: net.DefaultResolver.LookupAddr,
+	}
+	go r.run()
+	return r
+}
+
+// Lookup은 ip의 캐시된 hostname을 반환하고, 아직 모르면 ""다.
+// 캐시 miss면 백그라운드 조회를 예약하며, 블로킹하지 않는다.
+func (r *Resolver) Lookup(ip uint32) string {
+	if ip == 0 {
+		return ""
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.cache[ip]; ok && now.Before(e.expiresAt) {
+		return e.name
+	}
+	if _, ok := r.pending[ip]; ok {
+		return ""
+	}
+	select {
+	case r.queue <- ip:
+		r.pending[ip] = struct{}{}
+	default:
+		// 큐가 가득 차면 이번 조회는 건너뛰고 다음 이벤트에서 다시 시도한다.
+	}
+	return ""
+}
+
+// Close는 백그라운드 워커를 멈춘다.
+func (r *Resolver) Close() {
+	close(r.done)
+}
+
+func (r *Resolver) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.done
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case ip := <-r.queue:
+			if err := r.limiter.Wait(ctx); err != nil {
+				return
+			}
+			name := r.resolve(ctx, ip)
+			r.store(ip, name)
+		}
+	}
+}
+
+func (r *Resolver) resolve(ctx context.Context, ip uint32) string {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	addr := net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+	names, err := r.lookupAddr(ctx, addr)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+func (r *Resolver) store(ip uint32, name string) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, ip)
+	if len(r.cache) >= maxEntries {
+		for k, e := range r.cache {
+			if now.After(e.expiresAt) {
+				delete(r.cache, k)
+			}
+		}
+		// 만료 항목이 없으면 1/16을 임의로 내보낸다 (webhook stage와 같은 방식).
+		for k := range r.cache {
+			if len(r.cache) < maxEntries-maxEntries/16 {
+				break
+			}
+			delete(r.cache, k)
+		}
+	}
+	r.cache[ip] = entry{name: name, expiresAt: now.Add(r.ttl)}
+}
@@ -0,0 +1,20 @@
+package rdns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreEvictsWhenFull(t *testing.T) {
+	r := &Resolver{ttl: time.Hour, cache: make(map[uint32]entry), pending: make(map[uint32]struct{})}
+	for ip := uint32(1); ip <= maxEntries; ip++ {
+		r.store(ip, "live")
+	}
+	r.store(maxEntries+1, "new")
+	if e, ok := r.cache[maxEntries+1]; !ok || e.name != "new" {
+		t.Fatal("result dropped when the cache was full of live entries")
+	}
+	if n := len(r.cache); n > maxEntries-maxEntries/16+1 {
+		t.Errorf("%d entries after eviction, want at most %d", n, maxEntries-maxEntries/16+1)
+	}
+}
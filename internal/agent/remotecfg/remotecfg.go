@@ -0,0 +1,149 @@
+// Package remotecfg polls nefi-server for the fleet-wide agent runtime
+// configuration (NefiCollector.GetAgentConfig) and applies it to the event loop.
+//
+// 운영자는 server의 PUT /api/v1/agents/config로 설정을 바꾸고,
+// 각 agent는 poll 주기마다 revision을 비교해 바뀐 경우에만 적용한다.
+// server에 연결할 수 없으면 마지막으로 적용한 설정(처음에는 기본값)을 유지한다.
+package remotecfg
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const pollTimeout = 5 * time.Second
+
+// Settings는 적용 중인 원격 설정이다. 불변이며 교체 방식으로 갱신된다.
+type Settings struct {
+	Revision          uint64
+	SampleRate        float64 // 0 < rate ≤ 1
+	ExcludeNamespaces map[string]struct{}
+}
+
+// defaults는 server 설정이 없을 때의 동작(전량 export)이다.
+var defaults = &Settings{SampleRate: 1}
+
+// Poller는 server에서 설정을 주기적으로 받아온다.
+type Poller struct {
+	client   nefiv1.NefiCollectorClient
+	conn     *grpc.ClientConn
+	nodeName string
+	interval time.Duration
+	current  atomic.Pointer[Settings]
+	done     chan struct{}
+}
+
+// New는 serverAddr로 poll하는 Poller를 만들고 백그라운드 poll을 시작한다.
+// creds는 exporter와 같은 연결 보안 설정이다 (nil = 평문).
+func New(serverAddr, nodeName string, interval time.Duration, creds credentials.TransportCredentials) (*Poller, error) {
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(serverAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	p := &Poller{
+		client:   nefiv1.NewNefiCollectorClient(conn),
+		conn:     conn,
+		nodeName: nodeName,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	p.current.Store(defaults)
+	go p.run()
+	return p, nil
+}
+
+// Current는 적용 중인 설정을 반환한다.
+func (p *Poller) Current() *Settings {
+	return p.current.Load()
+}
+
+// Allow는 namespace의 이벤트를 export할지 결정한다 (제외 namespace, 샘플링).
+func (p *Poller) Allow(namespace string) bool {
+	s := p.current.Load()
+	if namespace != "" {
+		if _, excluded := s.ExcludeNamespaces[namespace]; excluded {
+			return false
+		}
+	}
+	return s.SampleRate >= 1 || rand.Float64() < s.SampleRate
+}
+
+// Close는 poll을 멈추고 연결을 닫는다.
+func (p *Poller) Close() {
+	close(p.done)
+	p.conn.Close()
+}
+
+func (p *Poller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	warned := false
+	for {
+		if err := p.poll(); err != nil {
+			if status.Code(err) == codes.Unimplemented {
+				// 원격 설정을 지원하지 않는 구버전 server — 한 번만 알린다.
+				if !warned {
+					log.Printf("[remotecfg] server does not support remote configuration; using local defaults")
+					warned = true
+				}
+			} else {
+				log.Printf("[remotecfg] poll failed: %v — keeping revision %d", err, p.Current().Revision)
+			}
+		}
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) poll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	defer cancel()
+	cur := p.current.Load()
+	cfg, err := p.client.GetAgentConfig(ctx, &nefiv1.AgentConfigRequest{
+		NodeName: p.nodeName,
+		Revision: cur.Revision,
+	})
+	if err != nil {
+		return err
+	}
+	if cfg.GetRevision() == cur.Revision {
+		return nil
+	}
+	next := fromProto(cfg)
+	p.current.Store(next)
+	log.Printf("[remotecfg] applied revision %d: sample_rate=%g exclude_namespaces=[%s]",
+		next.Revision, next.SampleRate, strings.Join(cfg.GetExcludeNamespaces(), ","))
+	return nil
+}
+
+func fromProto(cfg *nefiv1.AgentConfig) *Settings {
+	s := &Settings{
+		Revision:          cfg.GetRevision(),
+		SampleRate:        cfg.GetSampleRate(),
+		ExcludeNamespaces: make(map[string]struct{}, len(cfg.GetExcludeNamespaces())),
+	}
+	if s.SampleRate <= 0 || s.SampleRate > 1 {
+		s.SampleRate = 1
+	}
+	for _, ns := range cfg.GetExcludeNamespaces() {
+		s.ExcludeNamespaces[ns] = struct{}{}
+	}
+	return s
+}
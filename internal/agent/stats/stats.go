@@ -0,0 +1,150 @@
+// Package stats는 agent가 캡처/보강한 이벤트 양을 집계한다.
+//
+// dry-run 모드에서 export 없이 이벤트량(events/s, bytes/s)과 K8s 보강 성공률을
+// 확인하는 데 사용하며, admin 서버의 GET /stats로도 노출된다.
+// bytes는 server로 전송될 TraceEvent의 직렬화 크기(proto.Size)다.
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// ProtocolStat은 프로토콜별 누적 이벤트 수/바이트다.
+type ProtocolStat struct {
+	Protocol string `json:"protocol"`
+	Events   uint64 `json:"events"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+// Snapshot은 agent 시작 이후 누적 통계다.
+type Snapshot struct {
+	UptimeSec      float64        `json:"uptime_sec"`
+	Captured       uint64         `json:"captured"` // BPF에서 읽은 전체 이벤트 (필터 전)
+	Events         uint64         `json:"events"`   // 필터 통과 후 export 대상 이벤트
+	Bytes          uint64         `json:"bytes"`
+	EventsPerSec   float64        `json:"events_per_sec"`
+	BytesPerSec    float64        `json:"bytes_per_sec"`
+	AvgEventBytes  float64        `json:"avg_event_bytes"`
+	PodResolved    uint64         `json:"pod_resolved"`    // 로컬 pod 해석 성공
+	RemoteResolved uint64         `json:"remote_resolved"` // remote pod/service 해석 성공
+	RemoteExternal uint64         `json:"remote_external"` // 클러스터 외부 remote
+	SampledOut     uint64         `json:"sampled_out"`     // 메모리 보호가 export 전에 버린 이벤트
+	PausedSec      float64        `json:"paused_sec"`      // 메모리 보호로 캡처를 멈춘 시간
+	Protocols      []ProtocolStat `json:"protocols"`
+}
+
+// Stats는 이벤트 카운터다. 동시 사용에 안전하다.
+type Stats struct {
+	start time.Time
+
+	mu             sync.Mutex
+	captured       uint64
+	events         uint64
+	bytes          uint64
+	podResolved    uint64
+	remoteResolved uint64
+	remoteExternal uint64
+	sampledOut     uint64
+	paused         time.Duration
+	byProto        map[string]*ProtocolStat
+}
+
+// New는 현재 시각부터 집계하는 Stats를 반환한다.
+func New() *Stats {
+	return &Stats{start: time.Now(), byProto: make(map[string]*ProtocolStat)}
+}
+
+// Captured는 BPF에서 읽은 이벤트 하나를 기록한다.
+func (s *Stats) Captured() {
+	s.mu.Lock()
+	s.captured++
+	s.mu.Unlock()
+}
+
+// SampledOut은 메모리 보호가 export 전에 버린 이벤트 하나를 기록한다.
+func (s *Stats) SampledOut() {
+	s.mu.Lock()
+	s.sampledOut++
+	s.mu.Unlock()
+}
+
+// Paused는 메모리 보호로 캡처를 멈춘 시간 d를 더한다.
+func (s *Stats) Paused(d time.Duration) {
+	s.mu.Lock()
+	s.paused += d
+	s.mu.Unlock()
+}
+
+// Observe는 보강이 끝난 export 대상 이벤트 하나를 기록한다.
+func (s *Stats) Observe(te *nefiv1.TraceEvent) {
+	size := uint64(proto.Size(te))
+	name := model.Protocol(te.Protocol).String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events++
+	s.bytes += size
+	if te.PodName != "" {
+		s.podResolved++
+	}
+	if te.RemotePod != "" {
+		s.remoteResolved++
+	}
+	if te.RemoteExternal {
+		s.remoteExternal++
+	}
+	ps := s.byProto[name]
+	if ps == nil {
+		ps = &ProtocolStat{Protocol: name}
+		s.byProto[name] = ps
+	}
+	ps.Events++
+	ps.Bytes += size
+}
+
+// Snapshot은 현재까지의 누적 통계와 평균 속도를 반환한다.
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	up := time.Since(s.start).Seconds()
+	snap := Snapshot{
+		UptimeSec:      up,
+		Captured:       s.captured,
+		Events:         s.events,
+		Bytes:          s.bytes,
+		PodResolved:    s.podResolved,
+		RemoteResolved: s.remoteResolved,
+		RemoteExternal: s.remoteExternal,
+		SampledOut:     s.sampledOut,
+		PausedSec:      s.paused.Seconds(),
+		Protocols:      make([]ProtocolStat, 0, len(s.byProto)),
+	}
+	if up > 0 {
+		snap.EventsPerSec = float64(s.events) / up
+		snap.BytesPerSec = float64(s.bytes) / up
+	}
+	if s.events > 0 {
+		snap.AvgEventBytes = float64(s.bytes) / float64(s.events)
+	}
+	for _, ps := range s.byProto {
+		snap.Protocols = append(snap.Protocols, *ps)
+	}
+	sort.Slice(snap.Protocols, func(i, j int) bool { return snap.Protocols[i].Protocol < snap.Protocols[j].Protocol })
+	return snap
+}
+
+// ServeHTTP는 GET /stats 핸들러다.
+func (s *Stats) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(s.Snapshot()) //nolint:errcheck
+}
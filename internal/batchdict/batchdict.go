@@ -0,0 +1,120 @@
+// Package batchdict는 EventBatch의 문자열 사전 인코딩이다.
+//
+// 한 batch의 이벤트는 대부분 같은 node, namespace, pod, 서비스 이름을 반복한다.
+// Encode는 이런 문자열 필드를 batch의 문자열 테이블(EventBatch.strings)로 옮기고
+// 각 이벤트에는 (필드 번호, 테이블 index) 쌍(TraceEvent.dict_refs)만 남긴다.
+// Decode는 그 반대로, server가 ingest 전에 원래 필드를 복원한다.
+//
+// 사전 인코딩은 agent와 server가 Negotiate로 version.FeatureDict에 합의한 경우에만 쓴다.
+package batchdict
+
+import (
+	"fmt"
+	"sync"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// numFields는 사전으로 인코딩하는 TraceEvent 문자열 필드 수다.
+const numFields = 20
+
+type field struct {
+	num uint32 // TraceEvent 필드 번호 (events.proto)
+	s   *string
+}
+
+// fields는 ev에서 사전으로 인코딩하는 필드다. 반복될 가능성이 낮은 값(http_method 같은
+// 짧은 값이나 payload)은 제외한다. 필드 번호는 wire 형식이므로 바꾸면 안 된다.
+func fields(ev *nefiv1.TraceEvent) [numFields]field {
+	return [numFields]field{
+		{8, &ev.Comm},
+		{9, &ev.Namespace},
+		{10, &ev.PodName},
+		{11, &ev.NodeName},
+		{14, &ev.RemoteNs},
+		{15, &ev.RemotePod},
+		{18, &ev.HttpPath},
+		{23, &ev.RemoteName},
+		{24, &ev.NodeZone},
+		{25, &ev.NodeRegion},
+		{26, &ev.NodeInstanceType},
+		{30, &ev.ConnId},
+		{31, &ev.RemoteKind},
+		{32, &ev.Workload},
+		{33, &ev.RemoteWorkload},
+		{34, &ev.RemoteService},
+		{35, &ev.Policy},
+		{36, &ev.Cluster},
+		{37, &ev.MeshHop},
+		{38, &ev.RemoteHostname},
+	}
+}
+
+// indexPool은 Encode의 문자열 → index 색인이다. batch마다 새로 만들지 않는다.
+var indexPool = sync.Pool{New: func() any { return make(map[string]uint32) }}
+
+// Encode moves the string fields of b's events into b.Strings, leaving
+// (field number, index) pairs in each event's DictRefs. Encoding an already
+// encoded batch is a no-op.
+func Encode(b *nefiv1.EventBatch) {
+	if len(b.Strings) > 0 {
+		return
+	}
+	index := indexPool.Get().(map[string]uint32)
+	defer func() {
+		clear(index)
+		indexPool.Put(index)
+	}()
+	for _, ev := range b.Events {
+		for _, f := range fields(ev) {
+			if *f.s == "" {
+				continue
+			}
+			i, ok := index[*f.s]
+			if !ok {
+				i = uint32(len(b.Strings))
+				index[*f.s] = i
+				b.Strings = append(b.Strings, *f.s)
+			}
+			ev.DictRefs = append(ev.DictRefs, f.num, i)
+			*f.s = ""
+		}
+	}
+}
+
+// Decode restores the string fields Encode moved into b.Strings and clears
+// the table. A batch that is not encoded is left as is. It fails on a
+// reference to an unknown field or past the end of the table; b may then be
+// partially decoded.
+func Decode(b *nefiv1.EventBatch) error {
+	for i, ev := range b.Events {
+		refs := ev.DictRefs
+		if len(refs)%2 != 0 {
+			return fmt.Errorf("event %d: odd number of dict_refs (%d)", i, len(refs))
+		}
+		fs := fields(ev)
+		for j := 0; j < len(refs); j += 2 {
+			num, idx := refs[j], refs[j+1]
+			if int(idx) >= len(b.Strings) {
+				return fmt.Errorf("event %d: dict_refs index %d out of range (%d strings)", i, idx, len(b.Strings))
+			}
+			s := lookup(&fs, num)
+			if s == nil {
+				return fmt.Errorf("event %d: dict_refs field %d is not dictionary encoded", i, num)
+			}
+			*s = b.Strings[idx]
+		}
+		ev.DictRefs = nil
+	}
+	b.Strings = nil
+	return nil
+}
+
+func lookup(fs *[numFields]field, num uint32) *string {
+	for _, f := range fs {
+		if f.num == num {
+			return f.s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package batchdict
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// The field numbers in fields are wire format; they must name the string
+// fields they point at.
+func TestFieldNumbers(t *testing.T) {
+	ev := &nefiv1.TraceEvent{}
+	desc := ev.ProtoReflect().Descriptor().Fields()
+	for _, f := range fields(ev) {
+		fd := desc.ByNumber(protoreflect.FieldNumber(f.num))
+		if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+			t.Fatalf("field %d is not a string field of TraceEvent", f.num)
+		}
+		*f.s = string(fd.Name())
+		if got := ev.ProtoReflect().Get(fd).String(); got != string(fd.Name()) {
+			t.Errorf("field %d (%s) points at the wrong struct field", f.num, fd.Name())
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	events := []*nefiv1.TraceEvent{
+		{Pid: 1, Namespace: "shop", PodName: "cart-0", NodeName: "node-a", RemoteNs: "shop", RemoteService: "db"},
+		{Pid: 2, Namespace: "shop", PodName: "cart-1", NodeName: "node-a", Payload: []byte("GET /")},
+		{Pid: 3},
+	}
+	b := &nefiv1.EventBatch{Seq: 7}
+	for _, ev := range events {
+		b.Events = append(b.Events, proto.Clone(ev).(*nefiv1.TraceEvent))
+	}
+
+	Encode(b)
+	if want := []string{"shop", "cart-0", "node-a", "db", "cart-1"}; !slices.Equal(b.Strings, want) {
+		t.Fatalf("Strings = %q, want %q", b.Strings, want)
+	}
+	if b.Events[0].Namespace != "" || len(b.Events[2].DictRefs) != 0 {
+		t.Fatalf("unexpected encoding: %v", b.Events)
+	}
+	Encode(b) // no-op
+	if len(b.Strings) != 5 {
+		t.Fatalf("second Encode changed the table: %q", b.Strings)
+	}
+
+	if err := Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Strings != nil {
+		t.Errorf("Strings = %q after Decode, want nil", b.Strings)
+	}
+	for i, ev := range events {
+		if !proto.Equal(b.Events[i], ev) {
+			t.Errorf("event %d = %v, want %v", i, b.Events[i], ev)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		refs []uint32
+	}{
+		{"odd", []uint32{9}},
+		{"index", []uint32{9, 1}},
+		{"field", []uint32{16, 0}},
+	}
+	for _, tt := range tests {
+		b := &nefiv1.EventBatch{
+			Strings: []string{"shop"},
+			Events:  []*nefiv1.TraceEvent{{DictRefs: tt.refs}},
+		}
+		if err := Decode(b); err == nil {
+			t.Errorf("%s: Decode succeeded, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package configz는 프로세스가 실제로 사용 중인 설정값(effective config)을 JSON으로 노출한다.
+//
+// Helm values → container args → flag 순으로 전달되는 값이 의도대로 적용됐는지
+// 운영자가 pod 단위로 확인할 수 있도록, 모든 flag의 현재 값/기본값/출처를 보고한다.
+// 비밀 값(password, token, secret, *-key)은 값 대신 "<redacted>"로 표시한다.
+package configz
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gihongjo/nefi/internal/version"
+)
+
+const redacted = "<redacted>"
+
+// 설정값 출처.
+const (
+	SourceDefault = "default" // flag 미지정, 기본값 사용
+	SourceFlag    = "flag"    // 명령행에서 지정
+	SourceEnv     = "env"     // 환경변수
+)
+
+// Entry는 설정 항목 하나다.
+type Entry struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Default  string `json:"default,omitempty"`
+	Source   string `json:"source"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+// Report는 /configz 응답이다.
+type Report struct {
+	Component string       `json:"component"`
+	Version   version.Info `json:"version"`
+	Entries   []Entry      `json:"entries"`
+}
+
+// FromFlags는 fs의 모든 flag를 이름 순으로 Entry로 변환한다. fs.Parse 이후 호출해야 한다.
+func FromFlags(fs *flag.FlagSet) []Entry {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var entries []Entry
+	fs.VisitAll(func(f *flag.Flag) {
+		e := Entry{
+			Name:    f.Name,
+			Value:   f.Value.String(),
+			Default: f.DefValue,
+			Source:  SourceDefault,
+		}
+		if set[f.Name] {
+			e.Source = SourceFlag
+		}
+		entries = append(entries, redact(e))
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Env는 환경변수 name의 현재 값을 Entry로 반환한다.
+func Env(name string) Entry {
+	return redact(Entry{Name: name, Value: os.Getenv(name), Source: SourceEnv})
+}
+
+// Handler는 component의 설정 report를 JSON으로 반환하는 핸들러다.
+func Handler(component string, entries []Entry) http.Handler {
+	report := Report{Component: component, Version: version.Get(), Entries: entries}
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(report) //nolint:errcheck
+	})
+}
+
+// redact는 비밀로 보이는 항목의 값을 가린다. 빈 값은 설정 여부 확인을 위해 그대로 둔다.
+func redact(e Entry) Entry {
+	if !isSecret(e.Name) {
+		return e
+	}
+	if e.Value != "" {
+		e.Value = redacted
+		e.Redacted = true
+	}
+	if e.Default != "" {
+		e.Default = redacted
+	}
+	return e
+}
+
+func isSecret(name string) bool {
+	n := strings.ToLower(name)
+	for _, s := range []string{"password", "secret", "token", "credential"} {
+		if strings.Contains(n, s) {
+			return true
+		}
+	}
+	return strings.HasSuffix(n, "-key") || strings.HasSuffix(n, "_key")
+}
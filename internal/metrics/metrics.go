@@ -0,0 +1,224 @@
+// Package metrics는 Prometheus text exposition 형식으로 메트릭을 노출하는 최소 레지스트리다.
+//
+// 각 컴포넌트는 자신의 카운터를 직접 보유하고, Family.Collect 콜백으로
+// 스크레이프 시점의 값을 반환한다. 레지스트리는 값을 저장하지 않는다.
+// histogram은 컴포넌트가 HistogramValue를 보유하고 Collect에서 Samples를 반환한다.
+//
+//	reg := metrics.NewRegistry()
+//	reg.Register(metrics.Family{
+//	    Name: "nefi_store_written_events_total", Kind: metrics.Counter,
+//	    Collect: func() []metrics.Sample { ... },
+//	})
+//	http.Handle("/metrics", reg)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Kind는 메트릭 타입(# TYPE 줄)이다.
+type Kind string
+
+const (
+	Counter   Kind = "counter"
+	Gauge     Kind = "gauge"
+	Histogram Kind = "histogram" // 샘플은 HistogramValue.Samples로 만든다
+)
+
+// Labels는 샘플 하나의 label 집합이다. 출력 시 key 순으로 정렬된다.
+type Labels map[string]string
+
+// Sample은 label 집합 하나에 대한 값이다.
+type Sample struct {
+	Labels Labels
+	Value  float64
+	Suffix string // Family 이름 뒤에 붙는 접미사 (histogram의 "_bucket", "_sum", "_count")
+}
+
+// Family는 같은 이름/타입을 공유하는 샘플 묶음이다.
+type Family struct {
+	Name    string
+	Help    string
+	Kind    Kind
+	Collect func() []Sample
+}
+
+// Registry는 등록된 Family를 text 형식으로 렌더링한다.
+type Registry struct {
+	mu       sync.Mutex
+	families []Family
+}
+
+// NewRegistry는 빈 Registry를 반환한다.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register는 Family를 추가한다. 같은 이름을 두 번 등록하면 panic한다.
+func (r *Registry) Register(f Family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.families {
+		if existing.Name == f.Name {
+			panic("metrics: duplicate family " + f.Name)
+		}
+	}
+	r.families = append(r.families, f)
+}
+
+// WriteText는 모든 Family를 Prometheus text 형식(0.0.4)으로 w에 쓴다.
+func (r *Registry) WriteText(w io.Writer) error {
+	return r.write(w, false)
+}
+
+// WriteOpenMetrics는 모든 Family를 OpenMetrics text 형식(1.0.0)으로 w에 쓴다.
+// counter의 HELP/TYPE 줄은 _total 접미사를 뗀 이름을 쓰고, 출력은 "# EOF"로 끝난다.
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	return r.write(w, true)
+}
+
+func (r *Registry) write(w io.Writer, openMetrics bool) error {
+	r.mu.Lock()
+	families := make([]Family, len(r.families))
+	copy(families, r.families)
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		meta, sample := f.Name, f.Name
+		if openMetrics && f.Kind == Counter {
+			meta = strings.TrimSuffix(f.Name, "_total")
+			sample = meta + "_total"
+		}
+		if f.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", meta, escapeHelp(f.Help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", meta, f.Kind)
+		for _, s := range f.Collect() {
+			bw.WriteString(sample)
+			bw.WriteString(s.Suffix)
+			writeLabels(bw, s.Labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.Value))
+			bw.WriteByte('\n')
+		}
+	}
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP는 GET /metrics 핸들러다.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w) //nolint:errcheck
+}
+
+// OpenMetricsHandler는 레지스트리를 OpenMetrics 형식으로 노출하는 핸들러다.
+func (r *Registry) OpenMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		r.WriteOpenMetrics(w) //nolint:errcheck
+	})
+}
+
+// HistogramValue는 Histogram Family 하나(label 집합 하나)의 누적 bucket 값이다.
+// 0 값은 쓸 수 없고 NewHistogram으로 만든다. Observe는 여러 고루틴에서 동시에 호출할 수 있다.
+type HistogramValue struct {
+	bounds  []float64       // bucket 상한 (오름차순, +Inf 제외)
+	counts  []atomic.Uint64 // bucket별 관측 수 (누적 아님), 마지막은 +Inf
+	sumBits atomic.Uint64   // 관측값 합 (float64 bits)
+}
+
+// NewHistogram은 bounds를 bucket 상한으로 쓰는 HistogramValue를 반환한다. bounds는 오름차순이어야 한다.
+func NewHistogram(bounds ...float64) *HistogramValue {
+	return &HistogramValue{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// Observe는 값 v를 기록한다.
+func (h *HistogramValue) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)].Add(1)
+	for {
+		old := h.sumBits.Load()
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Samples는 h를 l label의 _bucket(누적, le label), _sum, _count 샘플로 변환한다.
+func (h *HistogramValue) Samples(l Labels) []Sample {
+	samples := make([]Sample, 0, len(h.counts)+2)
+	var cum uint64
+	for i := range h.counts {
+		cum += h.counts[i].Load()
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = formatValue(h.bounds[i])
+		}
+		bl := make(Labels, len(l)+1)
+		for k, v := range l {
+			bl[k] = v
+		}
+		bl["le"] = le
+		samples = append(samples, Sample{Labels: bl, Value: float64(cum), Suffix: "_bucket"})
+	}
+	return append(samples,
+		Sample{Labels: l, Value: math.Float64frombits(h.sumBits.Load()), Suffix: "_sum"},
+		Sample{Labels: l, Value: float64(cum), Suffix: "_count"},
+	)
+}
+
+func writeLabels(bw *bufio.Writer, l Labels) {
+	if len(l) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	bw.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(k)
+		bw.WriteString(`="`)
+		bw.WriteString(escapeLabel(l[k]))
+		bw.WriteByte('"')
+	}
+	bw.WriteByte('}')
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
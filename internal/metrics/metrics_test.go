@@ -0,0 +1,97 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gihongjo/nefi/internal/metrics"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.Register(metrics.Family{
+		Name: "nefi_test_total",
+		Help: "Test counter.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{
+				{Labels: metrics.Labels{"b": "2", "a": `x"y`}, Value: 3},
+				{Value: 0.5},
+			}
+		},
+	})
+
+	var sb strings.Builder
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP nefi_test_total Test counter.\n" +
+		"# TYPE nefi_test_total counter\n" +
+		"nefi_test_total{a=\"x\\\"y\",b=\"2\"} 3\n" +
+		"nefi_test_total 0.5\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.Register(metrics.Family{
+		Name: "nefi_test_total",
+		Help: "Test counter.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Labels: metrics.Labels{"a": "1"}, Value: 3}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_test_gauge",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: 0.5}}
+		},
+	})
+
+	var sb strings.Builder
+	if err := reg.WriteOpenMetrics(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "# TYPE nefi_test_gauge gauge\n" +
+		"nefi_test_gauge 0.5\n" +
+		"# HELP nefi_test Test counter.\n" +
+		"# TYPE nefi_test counter\n" +
+		"nefi_test_total{a=\"1\"} 3\n" +
+		"# EOF\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := metrics.NewHistogram(0.1, 1)
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v)
+	}
+	reg := metrics.NewRegistry()
+	reg.Register(metrics.Family{
+		Name: "nefi_test_seconds",
+		Kind: metrics.Histogram,
+		Collect: func() []metrics.Sample {
+			return h.Samples(metrics.Labels{"op": "x"})
+		},
+	})
+
+	var sb strings.Builder
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "# TYPE nefi_test_seconds histogram\n" +
+		"nefi_test_seconds_bucket{le=\"0.1\",op=\"x\"} 2\n" +
+		"nefi_test_seconds_bucket{le=\"1\",op=\"x\"} 3\n" +
+		"nefi_test_seconds_bucket{le=\"+Inf\",op=\"x\"} 4\n" +
+		"nefi_test_seconds_sum{op=\"x\"} 3.65\n" +
+		"nefi_test_seconds_count{op=\"x\"} 4\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -12,12 +12,16 @@
 //
 // 흐름:
 //   커널 BPF → ringbuf에 data_event_t(4143 bytes) 저장
-//   → loader.go가 binary.LittleEndian으로 읽음
+//   → loader.go가 DecodeDataEvent로 읽음 (커널이 쓴 host byte order = binary.NativeEndian)
 //   → DataEvent 구조체로 변환
 //   → main.go에서 출력
 package model
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
 
 const MaxMsgSize = 4096
 
@@ -55,6 +59,30 @@ func (p Protocol) String() string {
 	return "UNKNOWN"
 }
 
+// protoAliases는 설정에서 쓰는 흔한 별칭이다 (ParseProtocol).
+var protoAliases = map[string]Protocol{
+	"postgres":   ProtoPgSQL,
+	"postgresql": ProtoPgSQL,
+	"mongodb":    ProtoMongo,
+	"rabbitmq":   ProtoAMQP,
+	"ssl":        ProtoTLS,
+	"https":      ProtoTLS,
+}
+
+// ParseProtocol은 대소문자 구분 없이 프로토콜 이름(String()의 결과 또는 별칭)을 해석한다.
+func ParseProtocol(name string) (Protocol, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if p, ok := protoAliases[name]; ok {
+		return p, true
+	}
+	for i, n := range protoNames {
+		if i > 0 && strings.ToLower(n) == name {
+			return Protocol(i), true
+		}
+	}
+	return ProtoUnknown, false
+}
+
 // MsgType matches the BPF enum msg_type_t.
 type MsgType uint8
 
@@ -97,6 +125,56 @@ type DataEvent struct {
 	Msg         [MaxMsgSize]byte
 }
 
+// data_event_t field offsets (packed layout, see DataEvent).
+const (
+	offTimestamp  = 0
+	offPID        = 8
+	offFD         = 12
+	offMsgSize    = 16
+	offDirection  = 20
+	offProtocol   = 21
+	offMsgType    = 22
+	offComm       = 23
+	offRemoteIP   = 39
+	offRemotePort = 43
+	offMsg        = 47
+
+	// DataEventHeaderSize is the size of data_event_t without msg.
+	DataEventHeaderSize = offMsg
+	// DataEventSize is the full size of data_event_t.
+	DataEventSize = offMsg + MaxMsgSize
+)
+
+// DecodeDataEvent decodes a raw data_event_t record.
+//
+// The BPF program writes every integer field in the byte order of the host
+// it runs on, so callers pass binary.NativeEndian for live ringbuf records;
+// other orders are only useful for replaying captures from another machine.
+// remote_ip/remote_port are already converted from network order in the
+// kernel (bpf_ntohl/bpf_ntohs), so they decode like any other field.
+//
+// A record shorter than DataEventSize is accepted as long as it holds the
+// header; the missing tail of msg is left zeroed.
+func DecodeDataEvent(raw []byte, order binary.ByteOrder) (*DataEvent, error) {
+	if len(raw) < DataEventHeaderSize {
+		return nil, fmt.Errorf("data event too short: %d bytes, want at least %d", len(raw), DataEventHeaderSize)
+	}
+	e := &DataEvent{
+		TimestampNs: order.Uint64(raw[offTimestamp:]),
+		PID:         order.Uint32(raw[offPID:]),
+		FD:          order.Uint32(raw[offFD:]),
+		MsgSize:     order.Uint32(raw[offMsgSize:]),
+		Direction:   raw[offDirection],
+		Protocol:    Protocol(raw[offProtocol]),
+		MsgType:     MsgType(raw[offMsgType]),
+		RemoteIP:    order.Uint32(raw[offRemoteIP:]),
+		RemotePort:  order.Uint16(raw[offRemotePort:]),
+	}
+	copy(e.Comm[:], raw[offComm:offRemoteIP])
+	copy(e.Msg[:], raw[offMsg:])
+	return e, nil
+}
+
 // CommString returns the process name with null bytes trimmed.
 func (e *DataEvent) CommString() string {
 	for i, b := range e.Comm {
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// Golden data_event_t headers for the same event as written by a
+// little-endian (x86_64/arm64) and a big-endian (s390x) kernel:
+//
+//	ts=0x0102030405060708 pid=4242 fd=7 msg_size=5 SEND HTTP REQ
+//	comm="curl" remote=10.96.0.10:8080
+const (
+	goldenLE = "0807060504030201" + "92100000" + "07000000" + "05000000" + "00" + "01" + "01" +
+		"6375726c000000000000000000000000" + "0a00600a" + "901f" + "0000"
+	goldenBE = "0102030405060708" + "00001092" + "00000007" + "00000005" + "00" + "01" + "01" +
+		"6375726c000000000000000000000000" + "0a60000a" + "1f90" + "0000"
+)
+
+func golden(t *testing.T, header string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != DataEventHeaderSize {
+		t.Fatalf("golden header is %d bytes, want %d", len(b), DataEventHeaderSize)
+	}
+	return append(b, []byte("GET /")...)
+}
+
+func TestDecodeDataEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		order  binary.ByteOrder
+	}{
+		{"little-endian", goldenLE, binary.LittleEndian},
+		{"big-endian", goldenBE, binary.BigEndian},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := DecodeDataEvent(golden(t, tt.header), tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.TimestampNs != 0x0102030405060708 || e.PID != 4242 || e.FD != 7 || e.MsgSize != 5 {
+				t.Errorf("scalars = ts %x pid %d fd %d size %d", e.TimestampNs, e.PID, e.FD, e.MsgSize)
+			}
+			if e.Direction != 0 || e.Protocol != ProtoHTTP || e.MsgType != MsgRequest {
+				t.Errorf("dir/proto/type = %d/%v/%v", e.Direction, e.Protocol, e.MsgType)
+			}
+			if got := e.CommString(); got != "curl" {
+				t.Errorf("comm = %q", got)
+			}
+			if got := e.RemoteIPString(); got != "10.96.0.10" {
+				t.Errorf("remote ip = %q", got)
+			}
+			if e.RemotePort != 8080 {
+				t.Errorf("remote port = %d", e.RemotePort)
+			}
+			if !bytes.Equal(e.Payload(), []byte("GET /")) {
+				t.Errorf("payload = %q", e.Payload())
+			}
+		})
+	}
+}
+
+func TestDecodeDataEventMatchesBinaryRead(t *testing.T) {
+	raw := make([]byte, DataEventSize)
+	copy(raw, golden(t, goldenLE))
+	raw[DataEventSize-1] = 0xff
+
+	var want DataEvent
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeDataEvent(raw, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Error("DecodeDataEvent differs from binary.Read of the DataEvent layout")
+	}
+}
+
+func TestDecodeDataEventShort(t *testing.T) {
+	if _, err := DecodeDataEvent(make([]byte, DataEventHeaderSize-1), binary.LittleEndian); err == nil {
+		t.Error("expected error for truncated header")
+	}
+}
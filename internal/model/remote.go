@@ -0,0 +1,30 @@
+package model
+
+// TraceEvent.remote_kind 값 — remote endpoint를 무엇으로 해석했는지 나타낸다.
+const (
+	RemoteKindPod      = "Pod"      // 클러스터 pod (hostNetwork pod의 hostPort 포함)
+	RemoteKindService  = "Service"  // DNAT 전 Service VIP 또는 NodePort
+	RemoteKindNode     = "Node"     // node IP (kubelet, hostNetwork daemon 등)
+	RemoteKindExternal = "External" // 클러스터 외부 (CIDR/클라우드 대역/internet)
+)
+
+// TraceEvent.policy 값 — 관측된 흐름을 NetworkPolicy가 어떻게 다루는지 나타낸다.
+const (
+	PolicyNone    = "none"    // 로컬 pod를 선택하는 정책이 없음 (policy gap)
+	PolicyAllowed = "allowed" // 정책이 로컬 pod를 격리하고 rule이 이 peer를 허용함
+	PolicyDenied  = "denied"  // 정책이 로컬 pod를 격리했지만 허용하는 rule이 없음 (unexpected traffic)
+)
+
+// Service mesh 이름 — sidecar가 주입된 pod의 mesh.
+const (
+	MeshIstio   = "istio"
+	MeshLinkerd = "linkerd"
+)
+
+// TraceEvent.mesh_hop 값 — mesh pod에서 관측된 이벤트가 요청 경로의 어느 구간인지 나타낸다.
+// 요청 하나가 app↔sidecar, sidecar↔sidecar 구간에서 각각 관측되므로 중복 제거에 쓴다.
+const (
+	MeshHopApp   = "app"   // app 프로세스 ↔ 실제 peer (DNAT 전 Service VIP 포함)
+	MeshHopLocal = "local" // app 프로세스 ↔ 같은 pod의 sidecar (peer 정보 없음)
+	MeshHopProxy = "proxy" // sidecar 프로세스 (app 구간과 같은 요청의 사본)
+)
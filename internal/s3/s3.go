@@ -0,0 +1,312 @@
+// Package s3는 SDK 없이 SigV4로 서명하는 최소 S3 client다 — GetObject, ListObjectsV2,
+// PutObject, DeleteObject만 한다.
+//
+// 자격 증명과 위치는 AWS 표준 환경변수를 따른다: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// (, AWS_SESSION_TOKEN), AWS_REGION(없으면 AWS_DEFAULT_REGION, us-east-1). 자격 증명이 없으면
+// 서명하지 않는다 (공개 bucket). AWS_ENDPOINT_URL이 있으면 그 주소에 path-style로 요청한다
+// (MinIO, GCS XML API의 HMAC 키 등 S3 호환 저장소).
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Client는 S3 client다. 모든 메서드는 동시에 호출할 수 있다.
+type Client struct {
+	region    string
+	endpoint  string // 비어 있으면 AWS virtual-hosted 주소, 있으면 path-style
+	accessKey string
+	secretKey string
+	token     string
+}
+
+// NewClient는 AWS 표준 환경변수로 client를 만든다.
+func NewClient() *Client {
+	c := &Client{
+		region:    os.Getenv("AWS_REGION"),
+		endpoint:  strings.TrimRight(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.region == "" {
+		c.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if c.region == "" {
+		c.region = "us-east-1"
+	}
+	return c
+}
+
+// Parse는 "s3://bucket/key"를 나눈다.
+func Parse(s string) (bucket, key string, err error) {
+	rest := strings.TrimPrefix(s, "s3://")
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 location %q: want s3://bucket/key", s)
+	}
+	return bucket, key, nil
+}
+
+// Get은 객체를 읽는다.
+func (c *Client) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	req, err := c.request(ctx, http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Do(req)
+}
+
+// Delete는 객체를 지운다. 없는 객체도 성공이다.
+func (c *Client) Delete(ctx context.Context, bucket, key string) error {
+	req, err := c.request(ctx, http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	rc, err := Do(req)
+	if err != nil {
+		return err
+	}
+	return rc.Close()
+}
+
+// Put은 body를 객체로 쓴다.
+func (c *Client) Put(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	req, err := c.request(ctx, http.MethodPut, bucket, key, nil, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rc, err := Do(req)
+	if err != nil {
+		return err
+	}
+	return rc.Close()
+}
+
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List는 prefix 아래 객체 키를 이름순으로 반환한다 ("/"로 끝나는 디렉터리 표시 객체 제외).
+func (c *Client) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	err := c.list(ctx, bucket, map[string]string{"prefix": prefix}, func(res *listResult) {
+		for _, o := range res.Contents {
+			if !strings.HasSuffix(o.Key, "/") {
+				keys = append(keys, o.Key)
+			}
+		}
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Prefixes는 prefix 바로 아래의 하위 "디렉터리"("/"로 끝나는 공통 prefix)를 이름순으로 반환한다.
+func (c *Client) Prefixes(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var prefixes []string
+	err := c.list(ctx, bucket, map[string]string{"prefix": prefix, "delimiter": "/"}, func(res *listResult) {
+		for _, p := range res.CommonPrefixes {
+			prefixes = append(prefixes, p.Prefix)
+		}
+	})
+	sort.Strings(prefixes)
+	return prefixes, err
+}
+
+// list는 ListObjectsV2를 마지막 페이지까지 호출해 페이지마다 fn에 넘긴다.
+func (c *Client) list(ctx context.Context, bucket string, query map[string]string, fn func(*listResult)) error {
+	token := ""
+	for {
+		q := map[string]string{"list-type": "2"}
+		for k, v := range query {
+			q[k] = v
+		}
+		if token != "" {
+			q["continuation-token"] = token
+		}
+		req, err := c.request(ctx, http.MethodGet, bucket, "", q, nil)
+		if err != nil {
+			return err
+		}
+		body, err := Do(req)
+		if err != nil {
+			return err
+		}
+		var res listResult
+		err = xml.NewDecoder(body).Decode(&res)
+		body.Close()
+		if err != nil {
+			return fmt.Errorf("list s3://%s/%s: %w", bucket, query["prefix"], err)
+		}
+		fn(&res)
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// Do는 req를 보낸다. 2xx가 아니면 응답 앞부분을 담은 에러다.
+func Do(req *http.Request) (io.ReadCloser, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		// query(presigned URL의 서명)는 로그에 남기지 않는다.
+		return nil, fmt.Errorf("%s %s://%s%s: %s: %s", req.Method, req.URL.Scheme, req.URL.Host, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+// request는 bucket의 key(빈 문자열 = bucket 자체)에 대한 서명된 요청을 만든다.
+func (c *Client) request(ctx context.Context, method, bucket, key string, query map[string]string, body []byte) (*http.Request, error) {
+	base := "https://" + bucket + ".s3." + c.region + ".amazonaws.com"
+	path := "/" + key
+	if c.endpoint != "" {
+		base = c.endpoint
+		path = "/" + bucket + "/" + key
+		if key == "" {
+			path = "/" + bucket
+		}
+	}
+	rawPath := uriEncode(path, false)
+	rawQuery := canonicalQuery(query)
+	u := base + rawPath
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if c.accessKey != "" && c.secretKey != "" {
+		sum := sha256.Sum256(body)
+		c.sign(req, rawPath, rawQuery, hex.EncodeToString(sum[:]), time.Now().UTC())
+	}
+	return req, nil
+}
+
+// sign은 req에 AWS Signature Version 4 Authorization 헤더를 붙인다. host, range와 모든
+// x-amz-* 헤더를 서명한다. payloadHash는 body의 SHA-256(hex)이다.
+func (c *Client) sign(req *http.Request, rawPath, rawQuery, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if c.token != "" {
+		req.Header.Set("x-amz-security-token", c.token)
+	}
+
+	canonical, signed := canonicalRequest(req, rawPath, rawQuery, payloadHash)
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	sig := hex.EncodeToString(hmacSHA256(signingKey(c.secretKey, date, c.region, "s3"), stringToSign(canonical, amzDate, scope)))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.accessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+sig)
+}
+
+// canonicalRequest는 req의 SigV4 canonical request와 서명한 헤더 목록(";"로 구분)이다.
+func canonicalRequest(req *http.Request, rawPath, rawQuery, payloadHash string) (canonical, signed string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "range" {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed = strings.Join(names, ";")
+	canonical = strings.Join([]string{
+		req.Method, rawPath, rawQuery, canonHeaders.String(), signed, payloadHash,
+	}, "\n")
+	return canonical, signed
+}
+
+// stringToSign은 canonical request의 SigV4 string to sign이다. scope는 "<날짜>/<region>/<서비스>/aws4_request"다.
+func stringToSign(canonical, amzDate, scope string) string {
+	sum := sha256.Sum256([]byte(canonical))
+	return "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+}
+
+// signingKey는 secret에서 날짜, region, 서비스 순으로 유도한 SigV4 서명 키다.
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery는 SigV4 canonical query string이다 (키 순서, 값 인코딩). 요청 URL에도 그대로 쓴다.
+func canonicalQuery(q map[string]string) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = uriEncode(k, true) + "=" + uriEncode(q[k], true)
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode는 SigV4 규칙의 URI 인코딩이다: unreserved 문자(A-Z a-z 0-9 - _ . ~) 외에는
+// %XX로 바꾸고, encodeSlash가 false면 "/"는 그대로 둔다 (객체 경로).
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RemoteConfig는 server가 모든 agent에 내려주는 런타임 설정이다 (NefiCollector.GetAgentConfig).
+// agent는 주기적으로 poll해 revision이 바뀌었을 때만 적용한다.
+type RemoteConfig struct {
+	Revision          uint64    `json:"revision"`                     // 0 = 미설정 (agent 기본값 사용)
+	SampleRate        float64   `json:"sample_rate"`                  // 0 < rate ≤ 1, 0 = 기본값(1)
+	ExcludeNamespaces []string  `json:"exclude_namespaces,omitempty"` // 이 namespace의 pod 이벤트는 export하지 않음
+	UpdatedAt         time.Time `json:"updated_at,omitempty"`
+}
+
+// Validate는 운영자가 입력한 설정을 검사한다.
+func (c RemoteConfig) Validate() error {
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be within [0, 1], got %g", c.SampleRate)
+	}
+	for _, ns := range c.ExcludeNamespaces {
+		if ns == "" {
+			return fmt.Errorf("exclude_namespaces must not contain empty names")
+		}
+	}
+	return nil
+}
+
+// RemoteConfig는 현재 fleet 설정을 반환한다.
+func (r *Registry) RemoteConfig() RemoteConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.configCopy()
+}
+
+// SetRemoteConfig는 fleet 설정을 교체하고 revision을 올린다.
+// 입력의 Revision/UpdatedAt은 무시된다.
+func (r *Registry) SetRemoteConfig(c RemoteConfig) (RemoteConfig, error) {
+	if err := c.Validate(); err != nil {
+		return RemoteConfig{}, err
+	}
+	c.ExcludeNamespaces = append([]string(nil), c.ExcludeNamespaces...)
+	sort.Strings(c.ExcludeNamespaces)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.Revision = r.config.Revision + 1
+	c.UpdatedAt = time.Now()
+	r.config = c
+	return r.configCopy(), nil
+}
+
+// configCopy는 설정 사본을 반환한다. r.mu를 잡은 상태에서 호출한다.
+func (r *Registry) configCopy() RemoteConfig {
+	c := r.config
+	c.ExcludeNamespaces = append([]string(nil), c.ExcludeNamespaces...)
+	return c
+}
+
+// ConfigPolled는 agent가 poll 시 보고한 적용 revision을 기록한다.
+// node는 agent의 NODE_NAME이며, 등록되지 않은 노드는 무시한다.
+func (r *Registry) ConfigPolled(node string, revision uint64) {
+	r.mu.Lock()
+	if a, ok := r.agents[node]; ok {
+		a.ConfigRevision = revision
+		a.LastSeen = time.Now()
+	}
+	r.mu.Unlock()
+}
@@ -0,0 +1,457 @@
+// Package agents는 nefi-server에 연결된 agent 목록(registry)을 관리한다.
+//
+// collector가 스트림 시작/종료/이벤트 수신 시 registry를 갱신하고,
+// REST API가 List/Versions로 fleet 현황(연결 상태, 버전 skew)을 조회한다.
+// 연결 중이지만 staleAfter 동안 batch가 오지 않은 agent는 Stale로 표시한다
+// (스트림은 열려 있지만 캡처나 전송이 멈춘 노드).
+// 운영자가 설정한 agent 런타임 설정(RemoteConfig)도 여기서 보관해
+// collector의 GetAgentConfig RPC로 배포한다.
+package agents
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Info는 agent가 스트림 시작 시 보고하는 식별/버전 정보다.
+type Info struct {
+	NodeName      string `json:"node_name"`
+	Addr          string `json:"addr"`
+	Version       string `json:"version"`
+	GitCommit     string `json:"git_commit"`
+	BuildDate     string `json:"build_date"`
+	SchemaVersion int    `json:"schema_version"`
+	Identity      string `json:"identity,omitempty"` // mTLS client 인증서의 신원 (server가 검증한 값)
+	Tenant        string `json:"tenant,omitempty"`   // 인증서의 O 또는 agent --tenant
+
+	// handshake — 구버전 agent는 보내지 않으므로 비어 있을 수 있다.
+	KernelVersion string            `json:"kernel_version,omitempty"`
+	Probes        []string          `json:"probes,omitempty"`      // 활성 수집 방식 (tracepoints, ssl_uprobes, proc_fallback)
+	NodeLabels    map[string]string `json:"node_labels,omitempty"` // zone, region, instance_type
+	Cluster       string            `json:"cluster,omitempty"`     // agent --cluster-name
+}
+
+// Agent는 registry에 기록된 agent 하나의 상태다.
+type Agent struct {
+	Info
+	Compat         string    `json:"compat"`            // compatible / deprecated / incompatible
+	Warning        string    `json:"warning,omitempty"` // 호환성 안내 (deprecated/incompatible일 때)
+	Connected      bool      `json:"connected"`
+	ConnectedAt    time.Time `json:"connected_at"`
+	LastSeen       time.Time `json:"last_seen"`
+	LastBatch      time.Time `json:"last_batch"` // 마지막 batch(또는 SendEvents 이벤트) 수신 시각
+	Events         uint64    `json:"events"`
+	EventsPerSec   float64   `json:"events_per_sec"` // 최근 rateWindow 동안의 수신 속도
+	Errors         uint64    `json:"errors"`         // 스트림 에러와 거부한 batch 수
+	LastError      string    `json:"last_error,omitempty"`
+	Stale          bool      `json:"stale"`           // 연결 중이지만 staleAfter 동안 batch가 없다
+	ConfigRevision uint64    `json:"config_revision"` // agent가 마지막 poll에서 보고한 적용 RemoteConfig revision
+	Batch          bool      `json:"batch,omitempty"` // 스트림 대신 SendBatch로 전송하는 producer
+	Load           *Load     `json:"load,omitempty"`  // 마지막 부하 보고 (보고하지 않는 agent는 nil)
+
+	rateStart  time.Time // 현재 속도 측정 구간의 시작
+	rateEvents uint64    // rateStart 이후 받은 이벤트 수
+	gen        uint64    // 이 항목을 만든 Connect (Conn.gen)
+}
+
+// rateWindow는 Agent.EventsPerSec을 다시 계산하는 간격이다.
+const rateWindow = 10 * time.Second
+
+// DefaultStaleAfter는 NewRegistry에 0을 주면 쓰는 stale 판정 기준이다.
+const DefaultStaleAfter = 2 * time.Minute
+
+// forgetAfter 동안 연결도 batch도 없던 항목은 registry에서 지운다. pruneInterval마다 확인한다.
+const (
+	forgetAfter   = 24 * time.Hour
+	pruneInterval = time.Minute
+)
+
+// observe는 n개 이벤트를 받은 batch 하나를 a에 기록한다. r.mu를 잡고 호출한다.
+func (a *Agent) observe(n uint64, now time.Time) {
+	a.Events += n
+	a.LastSeen = now
+	a.LastBatch = now
+	if a.rateStart.IsZero() {
+		a.rateStart = now
+	}
+	a.rateEvents += n
+	if elapsed := now.Sub(a.rateStart); elapsed >= rateWindow {
+		a.EventsPerSec = float64(a.rateEvents) / elapsed.Seconds()
+		a.rateStart, a.rateEvents = now, 0
+	}
+}
+
+// Load는 agent가 batch에 실어 보낸 부하 보고다. 카운터는 agent 시작 이후 누적이다.
+type Load struct {
+	ReportedAt       time.Time `json:"reported_at"`
+	EventsPerSec     float64   `json:"events_per_sec"`
+	Captured         uint64    `json:"captured"`      // eBPF에서 읽은 이벤트 (필터 전)
+	Enqueued         uint64    `json:"enqueued"`      // 전송 큐에 들어온 이벤트
+	QueueDropped     uint64    `json:"queue_dropped"` // 전송 큐가 가득 차 버린 이벤트
+	QueueDepth       int       `json:"queue_depth"`
+	QueueCapacity    int       `json:"queue_capacity"`
+	UnackedEvents    uint64    `json:"unacked_events"`
+	SampledOut       uint64    `json:"sampled_out"` // 메모리 보호가 버린 이벤트
+	CapturePausedSec float64   `json:"capture_paused_sec"`
+}
+
+// Lost는 agent가 server로 보내지 못하고 버린 이벤트 수다.
+func (l *Load) Lost() uint64 {
+	return l.QueueDropped + l.SampledOut
+}
+
+// LossRatio는 export 대상 이벤트 중 버린 비율이다 (0 = 유실 없음).
+func (l *Load) LossRatio() float64 {
+	total := l.Enqueued + l.SampledOut
+	if total == 0 {
+		return 0
+	}
+	return float64(l.Lost()) / float64(total)
+}
+
+// Registry는 agent 상태를 노드 이름(없으면 peer 주소) 단위로 보관한다.
+type Registry struct {
+	mu         sync.Mutex
+	agents     map[string]*Agent
+	config     RemoteConfig  // agent에 배포하는 fleet 런타임 설정
+	staleAfter time.Duration // 연결 중인 agent를 Stale로 표시하는 batch 공백
+	gen        uint64        // 마지막 Connect 번호
+	pruned     time.Time     // 마지막 prune 시각
+
+	now func() time.Time // 테스트에서 바꾼다
+}
+
+// NewRegistry는 빈 Registry를 반환한다. staleAfter가 0 이하이면 DefaultStaleAfter를 쓴다.
+func NewRegistry(staleAfter time.Duration) *Registry {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	return &Registry{agents: make(map[string]*Agent), staleAfter: staleAfter, now: time.Now}
+}
+
+// pruneLocked는 연결이 끊긴 뒤 forgetAfter가 지난 항목을 지운다. r.mu를 잡고 호출한다.
+func (r *Registry) pruneLocked(now time.Time) {
+	if now.Sub(r.pruned) < pruneInterval {
+		return
+	}
+	r.pruned = now
+	for k, a := range r.agents {
+		if !a.Connected && now.Sub(a.LastSeen) > forgetAfter {
+			delete(r.agents, k)
+		}
+	}
+}
+
+// StaleAfter는 연결 중인 agent를 Stale로 표시하는 batch 공백이다.
+func (r *Registry) StaleAfter() time.Duration {
+	return r.staleAfter
+}
+
+// key는 registry 키를 반환한다. NODE_NAME이 없는 로컬 실행은 peer 호스트로 구분한다 —
+// 재연결마다 바뀌는 포트까지 넣으면 연결할 때마다 항목이 새로 생긴다.
+// tenant가 있으면 "<tenant>/<노드>"다 — 다른 tenant의 같은 이름 노드를 덮어쓰지 않는다.
+func key(info Info) string {
+	k := info.NodeName
+	if k == "" {
+		k = info.Addr
+		if host, _, err := net.SplitHostPort(k); err == nil {
+			k = host
+		}
+	}
+	if info.Tenant != "" {
+		k = info.Tenant + "/" + k
+	}
+	return k
+}
+
+// Conn은 Connect가 기록한 스트림 하나다.
+type Conn struct {
+	Key string // Observe/Error/ReportLoad에 쓰는 registry 키
+	gen uint64
+}
+
+// Connect는 스트림 시작을 기록하고 이후 Observe/Disconnect에 쓸 Conn을 반환한다.
+// compat/warning은 version.CheckAgent 판정 결과다.
+func (r *Registry) Connect(info Info, compat, warning string) Conn {
+	k := key(info)
+	r.mu.Lock()
+	now := r.now()
+	r.pruneLocked(now)
+	r.gen++
+	a := &Agent{
+		Info:        info,
+		Compat:      compat,
+		Warning:     warning,
+		Connected:   true,
+		ConnectedAt: now,
+		LastSeen:    now,
+		LastBatch:   now,
+		gen:         r.gen,
+	}
+	if prev, ok := r.agents[k]; ok {
+		// 재연결해도 에러 이력은 이어서 센다.
+		a.Errors, a.LastError = prev.Errors, prev.LastError
+	}
+	r.agents[k] = a
+	r.mu.Unlock()
+	return Conn{Key: k, gen: a.gen}
+}
+
+// Reject는 호환성 검사에서 거부된 연결 시도를 기록한다.
+// 거부된 agent도 fleet 조회에 나타나야 운영자가 업그레이드 대상을 찾을 수 있다.
+func (r *Registry) Reject(info Info, warning string) {
+	k := key(info)
+	r.mu.Lock()
+	now := r.now()
+	r.pruneLocked(now)
+	r.agents[k] = &Agent{
+		Info:     info,
+		Compat:   "incompatible",
+		Warning:  warning,
+		LastSeen: now,
+	}
+	r.mu.Unlock()
+}
+
+// Observe는 n개의 이벤트 수신을 기록한다.
+func (r *Registry) Observe(k string, n uint64) {
+	r.mu.Lock()
+	if a, ok := r.agents[k]; ok {
+		a.observe(n, r.now())
+	}
+	r.mu.Unlock()
+}
+
+// Error는 agent k의 스트림 에러나 거부한 batch를 기록한다.
+func (r *Registry) Error(k string, err error) {
+	r.mu.Lock()
+	if a, ok := r.agents[k]; ok {
+		a.Errors++
+		a.LastError = err.Error()
+	}
+	r.mu.Unlock()
+}
+
+// ObserveBatch는 SendBatch 호출 하나를 기록하고 그 항목의 키를 반환한다. batch
+// producer는 스트림이 없으므로 Connected는 false이며, LastSeen으로 활동 여부를 판단한다.
+// 같은 노드에서 스트림이 연결 중이면 그 항목에 이벤트 수만 더한다.
+func (r *Registry) ObserveBatch(info Info, compat, warning string, n uint64) string {
+	k := key(info)
+	r.mu.Lock()
+	now := r.now()
+	r.pruneLocked(now)
+	a, ok := r.agents[k]
+	if ok && a.Connected {
+		a.observe(n, now)
+		r.mu.Unlock()
+		return k
+	}
+	if !ok || !a.Batch {
+		a = &Agent{ConnectedAt: now, Batch: true}
+		r.agents[k] = a
+	}
+	a.Info = info
+	a.Compat = compat
+	a.Warning = warning
+	a.observe(n, now)
+	r.mu.Unlock()
+	return k
+}
+
+// ReportLoad는 agent k의 부하 보고를 기록한다. 마지막 보고만 보관한다.
+func (r *Registry) ReportLoad(k string, l Load) {
+	r.mu.Lock()
+	if a, ok := r.agents[k]; ok {
+		a.Load = &l
+	}
+	r.mu.Unlock()
+}
+
+// Disconnect는 스트림 c의 종료를 기록한다. 그 사이 같은 agent가 다시 연결했으면(예: 끊긴 줄
+// 모르던 이전 스트림이 idle timeout으로 늦게 닫힘) 새 연결의 상태를 바꾸지 않는다.
+// 항목은 fleet 조회를 위해 forgetAfter 동안 남겨둔다.
+func (r *Registry) Disconnect(c Conn) {
+	r.mu.Lock()
+	if a, ok := r.agents[c.Key]; ok && a.gen == c.gen {
+		a.Connected = false
+		a.LastSeen = r.now()
+	}
+	r.mu.Unlock()
+}
+
+// List는 모든 agent를 노드 이름 순으로 반환한다. Stale과 EventsPerSec은 조회 시점 기준이다.
+func (r *Registry) List() []Agent {
+	r.mu.Lock()
+	now := r.now()
+	r.pruneLocked(now)
+	result := make([]Agent, 0, len(r.agents))
+	for _, a := range r.agents {
+		c := *a
+		c.Stale = c.Connected && now.Sub(c.LastBatch) > r.staleAfter
+		if elapsed := now.Sub(c.rateStart); !c.rateStart.IsZero() && elapsed >= rateWindow {
+			// batch가 끊겨 측정 구간이 닫히지 않았다 — 예전 속도 대신 지금까지의 속도로 낮춘다.
+			c.EventsPerSec = float64(c.rateEvents) / elapsed.Seconds()
+		}
+		result = append(result, c)
+	}
+	r.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool { return key(result[i].Info) < key(result[j].Info) })
+	return result
+}
+
+// VersionGroup은 같은 빌드를 실행 중인 agent 묶음이다.
+type VersionGroup struct {
+	Version       string   `json:"version"`
+	GitCommit     string   `json:"git_commit"`
+	SchemaVersion int      `json:"schema_version"`
+	Count         int      `json:"count"`
+	Nodes         []string `json:"nodes"`
+}
+
+// Versions는 연결 중인 agent를 빌드(version+commit+schema) 단위로 묶어 반환한다.
+// 그룹이 2개 이상이면 rolling upgrade가 진행 중이거나 버전 skew가 있다는 뜻이다.
+func (r *Registry) Versions() []VersionGroup {
+	type groupKey struct {
+		version, commit string
+		schema          int
+	}
+	groups := make(map[groupKey]*VersionGroup)
+	for _, a := range r.List() {
+		if !a.Connected {
+			continue
+		}
+		gk := groupKey{a.Version, a.GitCommit, a.SchemaVersion}
+		g, ok := groups[gk]
+		if !ok {
+			g = &VersionGroup{Version: a.Version, GitCommit: a.GitCommit, SchemaVersion: a.SchemaVersion}
+			groups[gk] = g
+		}
+		g.Count++
+		g.Nodes = append(g.Nodes, key(a.Info))
+	}
+
+	result := make([]VersionGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
+
+// Coverage는 연결 중인 agent의 수집 능력 분포다.
+type Coverage struct {
+	Agents  int            `json:"agents"`
+	Probes  map[string]int `json:"probes"`  // 수집 방식 → agent 수
+	Kernels map[string]int `json:"kernels"` // 커널 버전 → agent 수 ("unknown" = handshake 없음)
+}
+
+// Coverage는 연결 중인 agent의 probe/커널 분포를 집계한다.
+// 예: tracepoints < agents 이면 일부 노드가 eBPF 없이 fallback으로 수집 중이다.
+func (r *Registry) Coverage() Coverage {
+	c := Coverage{Probes: make(map[string]int), Kernels: make(map[string]int)}
+	for _, a := range r.List() {
+		if !a.Connected {
+			continue
+		}
+		c.Agents++
+		for _, p := range a.Probes {
+			c.Probes[p]++
+		}
+		kernel := a.KernelVersion
+		if kernel == "" {
+			kernel = "unknown"
+		}
+		c.Kernels[kernel]++
+	}
+	return c
+}
+
+// Warning은 호환성 경고가 있는 agent 하나다.
+type Warning struct {
+	Node    string `json:"node"`
+	Compat  string `json:"compat"`
+	Message string `json:"message"`
+}
+
+// Warnings는 deprecated/incompatible 판정을 받은 agent 목록을 반환한다.
+func (r *Registry) Warnings() []Warning {
+	var result []Warning
+	for _, a := range r.List() {
+		if a.Warning == "" {
+			continue
+		}
+		result = append(result, Warning{Node: key(a.Info), Compat: a.Compat, Message: a.Warning})
+	}
+	return result
+}
+
+// Rates는 연결 중인 agent별 연결 이후 평균 events/sec를 반환한다.
+func (r *Registry) Rates() map[string]float64 {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rates := make(map[string]float64)
+	for k, a := range r.agents {
+		if !a.Connected {
+			continue
+		}
+		if elapsed := now.Sub(a.ConnectedAt).Seconds(); elapsed > 0 {
+			rates[k] = float64(a.Events) / elapsed
+		}
+	}
+	return rates
+}
+
+// FleetLoad는 부하 보고를 보낸 연결 중 agent의 합계와 유실이 있는 노드 목록이다.
+type FleetLoad struct {
+	Agents       int          `json:"agents"` // 부하 보고를 보낸 연결 중 agent 수
+	EventsPerSec float64      `json:"events_per_sec"`
+	Lost         uint64       `json:"lost"`  // 모든 agent가 버린 이벤트 합
+	Lossy        []LossyAgent `json:"lossy"` // 유실 비율이 높은 순
+}
+
+// LossyAgent는 이벤트를 버렸거나 캡처를 멈춘 적이 있는 agent 하나다.
+type LossyAgent struct {
+	Node             string  `json:"node"`
+	LossRatio        float64 `json:"loss_ratio"`
+	QueueDropped     uint64  `json:"queue_dropped"`
+	SampledOut       uint64  `json:"sampled_out"`
+	CapturePausedSec float64 `json:"capture_paused_sec"`
+}
+
+// FleetLoad는 연결 중인 agent의 마지막 부하 보고를 합산한다.
+// Lossy로 fleet에서 데이터 유실이 몰리는 노드를 찾는다.
+func (r *Registry) FleetLoad() FleetLoad {
+	return LoadOf(r.List())
+}
+
+// LoadOf는 list(예: 한 tenant의 agent)의 FleetLoad다.
+func LoadOf(list []Agent) FleetLoad {
+	f := FleetLoad{Lossy: []LossyAgent{}}
+	for _, a := range list {
+		if !a.Connected || a.Load == nil {
+			continue
+		}
+		f.Agents++
+		f.EventsPerSec += a.Load.EventsPerSec
+		f.Lost += a.Load.Lost()
+		if a.Load.Lost() > 0 || a.Load.CapturePausedSec > 0 {
+			f.Lossy = append(f.Lossy, LossyAgent{
+				Node:             key(a.Info),
+				LossRatio:        a.Load.LossRatio(),
+				QueueDropped:     a.Load.QueueDropped,
+				SampledOut:       a.Load.SampledOut,
+				CapturePausedSec: a.Load.CapturePausedSec,
+			})
+		}
+	}
+	sort.SliceStable(f.Lossy, func(i, j int) bool { return f.Lossy[i].LossRatio > f.Lossy[j].LossRatio })
+	return f
+}
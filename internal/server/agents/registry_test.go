@@ -0,0 +1,49 @@
+package agents
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleDisconnectKeepsReconnectedAgent(t *testing.T) {
+	r := NewRegistry(0)
+	info := Info{NodeName: "node-1", Addr: "10.0.0.1:40000"}
+	old := r.Connect(info, "compatible", "")
+	info.Addr = "10.0.0.1:40001"
+	cur := r.Connect(info, "compatible", "")
+
+	r.Disconnect(old) // 이전 스트림이 재연결 뒤에 닫힘
+	if list := r.List(); len(list) != 1 || !list[0].Connected {
+		t.Fatalf("after the old stream closed: %+v, want node-1 still connected", list)
+	}
+	r.Disconnect(cur)
+	if list := r.List(); len(list) != 1 || list[0].Connected {
+		t.Fatalf("after the current stream closed: %+v, want node-1 disconnected", list)
+	}
+}
+
+func TestKeyWithoutNodeNameIgnoresPort(t *testing.T) {
+	r := NewRegistry(0)
+	for _, addr := range []string{"10.0.0.1:40000", "10.0.0.1:40001", "10.0.0.1:40002"} {
+		r.Disconnect(r.Connect(Info{Addr: addr}, "compatible", ""))
+	}
+	if n := len(r.List()); n != 1 {
+		t.Errorf("%d entries after three reconnects from one host, want 1", n)
+	}
+}
+
+func TestForgetDisconnected(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := NewRegistry(0)
+	r.now = func() time.Time { return now }
+
+	gone := r.Connect(Info{NodeName: "gone"}, "compatible", "")
+	r.Connect(Info{NodeName: "live"}, "compatible", "")
+	r.Disconnect(gone)
+
+	now = now.Add(forgetAfter + pruneInterval)
+	list := r.List()
+	if len(list) != 1 || list[0].NodeName != "live" {
+		t.Errorf("List = %+v, want only the connected agent after forgetAfter", list)
+	}
+}
@@ -37,6 +37,23 @@ func WorkloadName(podName string) string {
 	return podName
 }
 
+// EventWorkload는 이벤트 로컬 pod의 workload 이름이다. agent가 ownerReferences로
+// 해석한 값을 우선하고, 없으면(구버전 agent, K8s 해석 실패) pod 이름에서 추출한다.
+func EventWorkload(ev *nefiv1.TraceEvent) string {
+	if ev.Workload != "" {
+		return ev.Workload
+	}
+	return WorkloadName(ev.PodName)
+}
+
+// RemoteWorkload는 EventWorkload의 remote pod 버전이다.
+func RemoteWorkload(ev *nefiv1.TraceEvent) string {
+	if ev.RemoteWorkload != "" {
+		return ev.RemoteWorkload
+	}
+	return WorkloadName(ev.RemotePod)
+}
+
 const (
 	maxWindowSec     = 300 // 최대 윈도우: 5분
 	DefaultWindowSec = 60  // Subscribe() 기본 윈도우: 60초
@@ -45,7 +62,10 @@ const (
 
 // EndpointKey는 집계 단위 키다.
 type EndpointKey struct {
+	Tenant    string
+	Cluster   string
 	Namespace string
+	Workload  string
 	PodName   string
 	Method    string
 	Path      string
@@ -62,11 +82,13 @@ type Counts struct {
 
 // EndpointStat는 윈도우 집계 결과 하나다.
 type EndpointStat struct {
+	Tenant       string  `json:"tenant,omitempty"`  // 이벤트를 보낸 agent의 tenant (collector가 기록)
+	Cluster      string  `json:"cluster,omitempty"` // agent --cluster-name, 단일 클러스터면 빈 값
 	Namespace    string  `json:"namespace"`
-	WorkloadName string  `json:"workload_name"` // Deployment/StatefulSet 이름 (pod 이름에서 파싱)
+	WorkloadName string  `json:"workload_name"` // Deployment/StatefulSet 이름 (agent 해석, 없으면 pod 이름에서 파싱)
 	PodName      string  `json:"pod_name"`
 	Method       string  `json:"method"`
-	Path         string  `json:"path"`
+	Path         string  `json:"path"` // http_route (ID를 템플릿으로 바꾼 path), 없으면 http_path
 	Total        int32   `json:"total"`
 	Success      int32   `json:"success"`
 	Error        int32   `json:"error"`
@@ -77,6 +99,7 @@ type EndpointStat struct {
 type bucket struct {
 	sec   int64
 	stats map[EndpointKey]Counts
+	conns map[ServiceKey]int32 // 서비스별 연결 관측 이벤트 수
 }
 
 // Aggregator는 슬라이딩 윈도우 bucket 집계기다.
@@ -104,31 +127,7 @@ func New(s store.Store) *Aggregator {
 
 // Snapshot은 주어진 windowSec(1~300) 범위의 집계 결과를 반환한다.
 func (a *Aggregator) Snapshot(windowSec int) []EndpointStat {
-	if windowSec < 1 {
-		windowSec = 1
-	}
-	if windowSec > maxWindowSec {
-		windowSec = maxWindowSec
-	}
-	cutoff := time.Now().Unix() - int64(windowSec)
-
-	a.mu.Lock()
-	merged := make(map[EndpointKey]Counts)
-	for _, b := range a.buckets {
-		if b.sec <= cutoff {
-			continue
-		}
-		for k, c := range b.stats {
-			m := merged[k]
-			m.Total += c.Total
-			m.Success += c.Success
-			m.Error += c.Error
-			m.LatencySum += c.LatencySum
-			m.LatencyCount += c.LatencyCount
-			merged[k] = m
-		}
-	}
-	a.mu.Unlock()
+	merged := a.merge(windowSec)
 
 	result := make([]EndpointStat, 0, len(merged))
 	for k, c := range merged {
@@ -141,8 +140,10 @@ func (a *Aggregator) Snapshot(windowSec int) []EndpointStat {
 			avgLatencyMs = float64(c.LatencySum) / float64(c.LatencyCount) / 1e6
 		}
 		result = append(result, EndpointStat{
+			Tenant:       k.Tenant,
+			Cluster:      k.Cluster,
 			Namespace:    k.Namespace,
-			WorkloadName: WorkloadName(k.PodName),
+			WorkloadName: k.Workload,
 			PodName:      k.PodName,
 			Method:       k.Method,
 			Path:         k.Path,
@@ -156,6 +157,41 @@ func (a *Aggregator) Snapshot(windowSec int) []EndpointStat {
 	return result
 }
 
+// clampWindow는 windowSec을 1~maxWindowSec 범위로 맞춘다.
+func clampWindow(windowSec int) int {
+	if windowSec < 1 {
+		return 1
+	}
+	if windowSec > maxWindowSec {
+		return maxWindowSec
+	}
+	return windowSec
+}
+
+// merge는 windowSec 범위의 bucket을 엔드포인트별로 합산한다.
+func (a *Aggregator) merge(windowSec int) map[EndpointKey]Counts {
+	cutoff := time.Now().Unix() - int64(clampWindow(windowSec))
+
+	a.mu.Lock()
+	merged := make(map[EndpointKey]Counts)
+	for _, b := range a.buckets {
+		if b.sec <= cutoff {
+			continue
+		}
+		for k, c := range b.stats {
+			m := merged[k]
+			m.Total += c.Total
+			m.Success += c.Success
+			m.Error += c.Error
+			m.LatencySum += c.LatencySum
+			m.LatencyCount += c.LatencyCount
+			merged[k] = m
+		}
+	}
+	a.mu.Unlock()
+	return merged
+}
+
 // Subscribe는 매 1초마다 defaultWindowSec 범위의 집계 결과를 받는 채널을 반환한다.
 func (a *Aggregator) Subscribe() <-chan []EndpointStat {
 	ch := make(chan []EndpointStat, subChanSize)
@@ -203,29 +239,32 @@ func (a *Aggregator) consume() {
 // status가 없는 이벤트(요청)는 집계에서 제외한다.
 // collector의 connTracker가 응답 이벤트에 요청의 method/path를 채워주므로
 // 응답만 집계해도 엔드포인트별 성공률을 올바르게 산출할 수 있다.
+//
+// 연결 관측 이벤트는 서비스별 연결 수로 센다. collector가 저장 전에 샘플링으로 뺀 연결
+// 이벤트도 store.Publish로 받으므로, 저장된 이벤트 수와 달리 이 값은 샘플링 전 전체 수다.
 func (a *Aggregator) record(ev *nefiv1.TraceEvent) {
+	if ev.Connection {
+		a.recordConnection(ev)
+		return
+	}
 	if ev.HttpStatus == 0 {
 		return
 	}
 	key := EndpointKey{
+		Tenant:    ev.Tenant,
+		Cluster:   ev.Cluster,
 		Namespace: ev.Namespace,
+		Workload:  EventWorkload(ev),
 		PodName:   ev.PodName,
 		Method:    ev.HttpMethod,
-		Path:      ev.HttpPath,
+		Path:      endpointPath(ev),
 	}
 	sec := time.Now().Unix()
 
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// 현재 초 bucket이 없으면 추가
-	if len(a.buckets) == 0 || a.buckets[len(a.buckets)-1].sec != sec {
-		a.buckets = append(a.buckets, bucket{
-			sec:   sec,
-			stats: make(map[EndpointKey]Counts),
-		})
-	}
-	b := &a.buckets[len(a.buckets)-1]
+	b := a.current(sec)
 	c := b.stats[key]
 	c.Total++
 	if ev.HttpStatus >= 200 && ev.HttpStatus < 400 {
@@ -240,6 +279,42 @@ func (a *Aggregator) record(ev *nefiv1.TraceEvent) {
 	b.stats[key] = c
 }
 
+// endpointPath는 집계에 쓰는 path다. route enricher가 만든 http_route가 있으면 그것을 쓴다 —
+// 원본 path로 묶으면 /users/123, /users/456이 각각 엔드포인트가 된다.
+func endpointPath(ev *nefiv1.TraceEvent) string {
+	if ev.HttpRoute != "" {
+		return ev.HttpRoute
+	}
+	return ev.HttpPath
+}
+
+// recordConnection은 연결 관측 이벤트를 현재 초 bucket의 서비스별 연결 수에 더한다.
+func (a *Aggregator) recordConnection(ev *nefiv1.TraceEvent) {
+	key := ServiceKey{
+		Tenant:    ev.Tenant,
+		Cluster:   ev.Cluster,
+		Namespace: ev.Namespace,
+		Workload:  EventWorkload(ev),
+	}
+	sec := time.Now().Unix()
+
+	a.mu.Lock()
+	a.current(sec).conns[key]++
+	a.mu.Unlock()
+}
+
+// current는 sec의 bucket을 반환하고, 없으면 추가한다. a.mu를 잡은 상태에서 호출해야 한다.
+func (a *Aggregator) current(sec int64) *bucket {
+	if len(a.buckets) == 0 || a.buckets[len(a.buckets)-1].sec != sec {
+		a.buckets = append(a.buckets, bucket{
+			sec:   sec,
+			stats: make(map[EndpointKey]Counts),
+			conns: make(map[ServiceKey]int32),
+		})
+	}
+	return &a.buckets[len(a.buckets)-1]
+}
+
 // tick은 매 1초마다 오래된 bucket을 제거하고 구독자에게 stats를 전파한다.
 func (a *Aggregator) tick() {
 	ticker := time.NewTicker(time.Second)
@@ -279,6 +354,7 @@ func (a *Aggregator) prune() {
 	if i > 0 {
 		for j := 0; j < i; j++ {
 			a.buckets[j].stats = nil // GC 가능하도록 map 참조 해제
+			a.buckets[j].conns = nil
 		}
 		a.buckets = a.buckets[i:]
 	}
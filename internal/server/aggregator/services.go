@@ -0,0 +1,124 @@
+package aggregator
+
+import (
+	"time"
+
+	"github.com/gihongjo/nefi/internal/metrics"
+)
+
+// ServiceKey는 서비스(workload) 단위 집계 키다.
+type ServiceKey struct {
+	Tenant    string
+	Cluster   string
+	Namespace string
+	Workload  string
+}
+
+// ServiceStat는 서비스 하나의 RED(Rate/Errors/Duration) 값이다.
+type ServiceStat struct {
+	ServiceKey
+	RequestsPerSec float64
+	ErrorsPerSec   float64 // 4xx, 5xx
+	AvgLatencySec  float64 // latency가 측정된 요청의 평균, 없으면 0
+	// ConnectionsPerSec는 초당 연결 관측 이벤트 수다. 저장 전 샘플링과 무관한 전체 수다.
+	ConnectionsPerSec float64
+}
+
+// Services는 windowSec(1~300) 범위를 엔드포인트 대신 workload 단위로 합산한
+// RED 값과 연결 수를 반환한다. rate는 윈도우 길이로 나눈 초당 값이다.
+func (a *Aggregator) Services(windowSec int) []ServiceStat {
+	window := float64(clampWindow(windowSec))
+
+	byService := make(map[ServiceKey]Counts)
+	for k, c := range a.merge(windowSec) {
+		sk := ServiceKey{Tenant: k.Tenant, Cluster: k.Cluster, Namespace: k.Namespace, Workload: k.Workload}
+		m := byService[sk]
+		m.Total += c.Total
+		m.Error += c.Error
+		m.LatencySum += c.LatencySum
+		m.LatencyCount += c.LatencyCount
+		byService[sk] = m
+	}
+	conns := a.mergeConnections(windowSec)
+	for k := range conns {
+		if _, ok := byService[k]; !ok {
+			byService[k] = Counts{}
+		}
+	}
+
+	result := make([]ServiceStat, 0, len(byService))
+	for k, c := range byService {
+		st := ServiceStat{
+			ServiceKey:        k,
+			RequestsPerSec:    float64(c.Total) / window,
+			ErrorsPerSec:      float64(c.Error) / window,
+			ConnectionsPerSec: float64(conns[k]) / window,
+		}
+		if c.LatencyCount > 0 {
+			st.AvgLatencySec = float64(c.LatencySum) / float64(c.LatencyCount) / 1e9
+		}
+		result = append(result, st)
+	}
+	return result
+}
+
+// mergeConnections는 windowSec 범위의 bucket을 서비스별 연결 수로 합산한다.
+func (a *Aggregator) mergeConnections(windowSec int) map[ServiceKey]int64 {
+	cutoff := time.Now().Unix() - int64(clampWindow(windowSec))
+
+	a.mu.Lock()
+	merged := make(map[ServiceKey]int64)
+	for _, b := range a.buckets {
+		if b.sec <= cutoff {
+			continue
+		}
+		for k, n := range b.conns {
+			merged[k] += int64(n)
+		}
+	}
+	a.mu.Unlock()
+	return merged
+}
+
+// RegisterMetrics는 DefaultWindowSec 윈도우의 서비스별 RED 값과 연결 수를 gauge로 reg에 등록한다.
+// 값은 스크레이프 시점에 Services로 계산한다. 모든 tenant의 서비스를 tenant label로 구분해
+// 내보내므로, 격리 모드에서는 operator의 스크레이프 대상으로만 노출해야 한다.
+func RegisterMetrics(reg *metrics.Registry, a *Aggregator) {
+	collect := func(value func(ServiceStat) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			stats := a.Services(DefaultWindowSec)
+			samples := make([]metrics.Sample, 0, len(stats))
+			for _, st := range stats {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"tenant": st.Tenant, "cluster": st.Cluster, "namespace": st.Namespace, "workload": st.Workload},
+					Value:  value(st),
+				})
+			}
+			return samples
+		}
+	}
+	reg.Register(metrics.Family{
+		Name:    "nefi_service_requests_per_second",
+		Help:    "HTTP requests per second over the last 60s, by service.",
+		Kind:    metrics.Gauge,
+		Collect: collect(func(st ServiceStat) float64 { return st.RequestsPerSec }),
+	})
+	reg.Register(metrics.Family{
+		Name:    "nefi_service_errors_per_second",
+		Help:    "HTTP 4xx/5xx responses per second over the last 60s, by service.",
+		Kind:    metrics.Gauge,
+		Collect: collect(func(st ServiceStat) float64 { return st.ErrorsPerSec }),
+	})
+	reg.Register(metrics.Family{
+		Name:    "nefi_service_latency_avg_seconds",
+		Help:    "Mean HTTP request latency over the last 60s, by service.",
+		Kind:    metrics.Gauge,
+		Collect: collect(func(st ServiceStat) float64 { return st.AvgLatencySec }),
+	})
+	reg.Register(metrics.Family{
+		Name:    "nefi_service_connections_per_second",
+		Help:    "Connection events per second over the last 60s, by service, counted before storage sampling.",
+		Kind:    metrics.Gauge,
+		Collect: collect(func(st ServiceStat) float64 { return st.ConnectionsPerSec }),
+	})
+}
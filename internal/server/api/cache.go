@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/server/tenant"
+)
+
+// 토폴로지 캐시 설정.
+const (
+	topologyCacheTTL     = 2 * time.Second
+	topologyCacheEntries = 256
+)
+
+// queryCache는 같은 조회 파라미터의 계산 결과를 잠시 재사용한다. 대시보드 탭마다 토폴로지를
+// 몇 초 간격으로 다시 요청하는데, 매번 최근 이벤트 수천 개를 훑어 다시 만들 필요는 없다.
+//
+// 항목은 계산할 때의 store 위치(마지막으로 저장한 이벤트 번호)를 기억한다. 그 뒤로 저장한
+// 이벤트가 없으면 ttl이 지나도 그대로 쓰고, 새 이벤트가 있으면 ttl 동안만 쓴다 — 결과가
+// 최신 이벤트보다 최대 ttl만큼 늦을 수 있다. 값은 여러 요청이 공유하므로 읽기만 해야 한다.
+type queryCache[V any] struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry[V]
+
+	now func() time.Time // 테스트에서 교체
+}
+
+type cacheEntry[V any] struct {
+	value V
+	pos   uint64
+	at    time.Time
+}
+
+func newQueryCache[V any](ttl time.Duration, max int) *queryCache[V] {
+	return &queryCache[V]{ttl: ttl, max: max, entries: make(map[string]cacheEntry[V]), now: time.Now}
+}
+
+// get은 key의 결과를 반환한다. 없거나 낡았으면 compute로 계산해 저장한다. pos는 현재 store 위치다.
+func (c *queryCache[V]) get(key string, pos uint64, compute func() V) V {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && (e.pos == pos || now.Sub(e.at) < c.ttl) {
+		return e.value
+	}
+
+	v := compute()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.max {
+		for k, old := range c.entries {
+			if now.Sub(old.at) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.max {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = cacheEntry[V]{value: v, pos: pos, at: now}
+	return v
+}
+
+// storePosition은 가장 최근에 저장한 이벤트의 위치다 (저장한 이벤트가 없으면 0).
+func (h *Handler) storePosition() uint64 {
+	_, pos := h.store.Before(0, 1)
+	return pos
+}
+
+// topology는 buildTopology 결과다.
+type topology struct {
+	nodes []topoNode
+	edges []topoEdge
+}
+
+// cachedTopology는 scope의 최근 limit개 이벤트 중 cluster, sel에 맞는 이벤트로 만든 토폴로지다.
+func (h *Handler) cachedTopology(scope tenant.Scope, limit int, cluster string, sel labelSelector) topology {
+	key := fmt.Sprintf("%t|%s|%d|%s|%s", scope.All, scope.Tenant, limit, cluster, sel.key())
+	return h.topoCache.get(key, h.storePosition(), func() topology {
+		nodes, edges := buildTopology(sel.filter(inCluster(h.recent(scope, limit), cluster)))
+		return topology{nodes, edges}
+	})
+}
+
+// key는 selector를 순서와 무관한 문자열로 나타낸다.
+func (sel labelSelector) key() string {
+	parts := make([]string, 0, len(sel))
+	for k, v := range sel {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryCacheReuse(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newQueryCache[int](2*time.Second, 10)
+	c.now = func() time.Time { return now }
+	calls := 0
+	compute := func() int { calls++; return calls }
+
+	tests := []struct {
+		advance time.Duration
+		pos     uint64
+		want    int
+	}{
+		{0, 5, 1},
+		{time.Second, 5, 1},
+		{time.Minute, 5, 1},     // 새 이벤트가 없으면 ttl이 지나도 재사용한다
+		{0, 6, 2},               // 새 이벤트 + ttl 지남 → 다시 계산
+		{time.Second, 7, 2},     // ttl 안이면 새 이벤트가 있어도 재사용한다
+		{time.Second, 7, 3},     // 계산한 지 2초: ttl 지남
+		{3 * time.Second, 7, 3}, // 계산한 위치 그대로
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		if got := c.get("k", tt.pos, compute); got != tt.want {
+			t.Errorf("step %d (pos %d): got result #%d, want #%d", i, tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestQueryCacheEviction(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newQueryCache[string](2*time.Second, 3)
+	c.now = func() time.Time { return now }
+	val := func(v string) func() string { return func() string { return v } }
+
+	c.get("a", 1, val("a"))
+	now = now.Add(3 * time.Second)
+	c.get("b", 1, val("b"))
+	c.get("c", 1, val("c"))
+	c.get("d", 1, val("d")) // 가득 참: ttl이 지난 a만 버린다
+	if _, ok := c.entries["a"]; ok || len(c.entries) != 3 {
+		t.Errorf("entries after evicting expired ones: %v, want b, c, d", c.entries)
+	}
+
+	c.get("e", 1, val("e")) // 가득 찼는데 지난 항목이 없으면 모두 비운다
+	if _, ok := c.entries["e"]; !ok || len(c.entries) != 1 {
+		t.Errorf("entries after a full reset: %v, want only e", c.entries)
+	}
+	if got := c.get("b", 1, val("b2")); got != "b2" {
+		t.Errorf("evicted key served %q, want a fresh result", got)
+	}
+}
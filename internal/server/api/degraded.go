@@ -0,0 +1,28 @@
+package api
+
+import "github.com/gihongjo/nefi/internal/server/store"
+
+// 응답을 구성하는 데이터 소스 이름.
+const (
+	sourceStore      = "store"      // 이벤트 저장소 (events, topology)
+	sourceAggregator = "aggregator" // 실시간 집계 (stats)
+)
+
+// degradedSource는 응답에서 빠진(또는 불완전한) 데이터 소스다.
+// UI는 이 목록을 보고 가능한 부분만 렌더링한다.
+type degradedSource struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// storeHealth는 store가 HealthReporter를 구현하고 비정상이면 degraded 항목을 반환한다.
+func (h *Handler) storeHealth() []degradedSource {
+	hr, ok := h.store.(store.HealthReporter)
+	if !ok {
+		return nil
+	}
+	if err := hr.Health(); err != nil {
+		return []degradedSource{{Source: sourceStore, Reason: err.Error()}}
+	}
+	return nil
+}
@@ -3,82 +3,176 @@
 // 엔드포인트:
 //
 //	GET /healthz               — 헬스체크
+//	GET /version               — server 빌드/스키마 버전
 //	GET /api/stats?window=60   — aggregator 슬라이딩 윈도우 집계 결과
-//	GET /api/events?limit=100  — store 최근 이벤트 목록
+//	GET /api/events?limit=100  — store 최근 이벤트 목록 (label=team=payments 로 pod label 필터)
+//
+// stats/events/topology/namespaces는 cluster=<이름>으로 한 클러스터만 조회할 수 있다
+// (agent --cluster-name). 지정하지 않으면 모든 클러스터를 클러스터별로 구분해 반환한다.
+//
+//	GET /api/v1/connections?conn_id=<node>/<pid>/<fd> — 한 소켓의 연결 이벤트와 그 위의 HTTP 이벤트
+//	GET /api/v1/connections/<id> — 응답의 id로 연결 이벤트나 HTTP 이벤트 하나 (deep link)
+//	GET /api/v1/admin/storage  — 이벤트 타입별 저장소 기록/거부 카운터
+//	GET /api/v1/admin/configz  — effective 설정값 (app에서 등록, internal/configz)
+//	GET /api/v1/admin/sizing   — 실측 이벤트량 기반 저장소/용량 추정
+//	GET /api/v1/agents         — agent 목록(클러스터, 최근 events/sec, 에러 수, stale 여부)과 부하 보고, 유실이 있는 노드
+//	GET /api/v1/agents/versions — 연결된 agent의 빌드별 분포 (fleet 버전 skew, probe/커널 coverage)
+//	GET /api/v1/agents/config  — agent에 배포 중인 런타임 설정과 노드별 적용 revision
+//	PUT /api/v1/agents/config  — 런타임 설정 교체 (agent는 다음 poll에서 적용)
+//	POST /api/v1/ingest        — EventBatch JSON/NDJSON 수집 (--http-ingest일 때 app에서 CORS 밖에 등록, collector.Service.HTTPHandler)
+//
+// 여러 팀이 server 하나를 나눠 쓰면 /api/v1 요청은 X-Nefi-Tenant 헤더의 tenant 데이터만 본다
+// (tenant 패키지). 격리 모드(--tenant-isolation)에서는 헤더가 없는 요청과, operator가 아닌
+// tenant의 admin/*, agents/versions, agents/config 요청을 403으로 거부한다.
+//
+// 데이터 소스(store, aggregator) 일부를 쓸 수 없으면 엔드포인트 전체를 실패시키지 않고
+// 가능한 데이터로 응답하며, 빠진 소스를 "degraded" 필드에 나열한다.
 package api
 
 import (
 	"fmt"
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+	"github.com/gihongjo/nefi/internal/server/agents"
 	"github.com/gihongjo/nefi/internal/server/aggregator"
+	"github.com/gihongjo/nefi/internal/server/sizing"
 	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/server/tenant"
+	"github.com/gihongjo/nefi/internal/version"
 )
 
 // ---- Request / Response 타입 ----
 
 type statsQuery struct {
-	Window int `form:"window" binding:"omitempty,min=1,max=300"`
+	Window  int    `form:"window" binding:"omitempty,min=1,max=300"`
+	Cluster string `form:"cluster"`
 }
 
 type eventsQuery struct {
-	Limit int `form:"limit" binding:"omitempty,min=1,max=10000"`
+	Limit   int      `form:"limit" binding:"omitempty,min=1,max=10000"`
+	Labels  []string `form:"label"` // "key=value", 반복 지정 시 AND
+	Cluster string   `form:"cluster"`
+	Cursor  string   `form:"cursor"` // 이전 응답의 next_cursor
+}
+
+type connectionQuery struct {
+	ConnID string `form:"conn_id" binding:"required"`
+}
+
+type connectionResponse struct {
+	ConnID      string           `json:"conn_id"`
+	Connections []eventResponse  `json:"connections"` // 연결 관측 (agent /proc/net fallback)
+	Requests    []eventResponse  `json:"requests"`    // 같은 소켓의 HTTP 요청/응답
+	Degraded    []degradedSource `json:"degraded,omitempty"`
 }
 
 type statsResponse struct {
 	WindowSec int                       `json:"window_sec"`
 	Endpoints []aggregator.EndpointStat `json:"endpoints"`
+	Degraded  []degradedSource          `json:"degraded,omitempty"`
 }
 
 type eventsResponse struct {
-	Count  int             `json:"count"`
-	Events []eventResponse `json:"events"`
+	Count      int              `json:"count"`
+	Events     []eventResponse  `json:"events"`
+	NextCursor string           `json:"next_cursor,omitempty"` // 더 오래된 이벤트 페이지 (없으면 생략)
+	Degraded   []degradedSource `json:"degraded,omitempty"`
 }
 
 type eventResponse struct {
-	TimestampNs     uint64  `json:"ts"`
-	PID             uint32  `json:"pid"`
-	FD              uint32  `json:"fd"`
-	MsgSize         uint32  `json:"msg_size"`
-	Direction       uint32  `json:"direction"`
-	Protocol        uint32  `json:"protocol"`
-	Comm            string  `json:"comm"`
-	Namespace       string  `json:"namespace,omitempty"`
-	PodName         string  `json:"pod_name,omitempty"`
-	NodeName        string  `json:"node_name,omitempty"`
-	HttpMethod      string  `json:"http_method,omitempty"`
-	HttpPath        string  `json:"http_path,omitempty"`
-	HttpStatus      int32   `json:"http_status,omitempty"`
-	HttpContentType string  `json:"http_content_type,omitempty"`
-	LatencyMs       float64 `json:"latency_ms,omitempty"` // 레이턴시 (ms), 0이면 미측정
+	ID              string            `json:"id,omitempty"` // GET /api/v1/connections/:id로 다시 조회 (ring buffer에 남아 있는 동안)
+	TimestampNs     uint64            `json:"ts"`
+	PID             uint32            `json:"pid"`
+	FD              uint32            `json:"fd"`
+	MsgSize         uint32            `json:"msg_size"`
+	Direction       uint32            `json:"direction"`
+	Protocol        uint32            `json:"protocol"`
+	Comm            string            `json:"comm"`
+	Tenant          string            `json:"tenant,omitempty"`
+	Cluster         string            `json:"cluster,omitempty"`
+	Namespace       string            `json:"namespace,omitempty"`
+	PodName         string            `json:"pod_name,omitempty"`
+	Workload        string            `json:"workload,omitempty"`
+	NodeName        string            `json:"node_name,omitempty"`
+	NodeZone        string            `json:"node_zone,omitempty"`
+	NodeRegion      string            `json:"node_region,omitempty"`
+	RemoteNs        string            `json:"remote_ns,omitempty"`
+	RemotePod       string            `json:"remote_pod,omitempty"`
+	RemoteWorkload  string            `json:"remote_workload,omitempty"`
+	RemoteService   string            `json:"remote_service,omitempty"`
+	RemoteHostname  string            `json:"remote_hostname,omitempty"`
+	RemoteExternal  bool              `json:"remote_external,omitempty"`
+	RemoteName      string            `json:"remote_name,omitempty"`
+	RemoteKind      string            `json:"remote_kind,omitempty"`
+	Policy          string            `json:"policy,omitempty"`
+	MeshHop         string            `json:"mesh_hop,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	RemoteLabels    map[string]string `json:"remote_labels,omitempty"`
+	Connection      bool              `json:"connection,omitempty"`
+	ConnID          string            `json:"conn_id,omitempty"`
+	AgentIdentity   string            `json:"agent_identity,omitempty"`
+	HttpMethod      string            `json:"http_method,omitempty"`
+	HttpPath        string            `json:"http_path,omitempty"`
+	HttpRoute       string            `json:"http_route,omitempty"`
+	HttpStatus      int32             `json:"http_status,omitempty"`
+	HttpContentType string            `json:"http_content_type,omitempty"`
+	LatencyMs       float64           `json:"latency_ms,omitempty"` // 레이턴시 (ms), 0이면 미측정
 }
 
 // ---- Handler ----
 
 // Handler는 REST API 핸들러 의존성을 보유한다.
 type Handler struct {
-	store store.Store
-	agg   *aggregator.Aggregator
+	store   store.Store
+	agg     *aggregator.Aggregator
+	agents  *agents.Registry
+	tenants tenant.Policy
+
+	topoCache *queryCache[topology]
 }
 
-// New는 Handler를 생성한다.
-func New(s store.Store, agg *aggregator.Aggregator) *Handler {
-	return &Handler{store: s, agg: agg}
+// New는 Handler를 생성한다. agg가 nil이면(query 모드) /api/v1/stats는
+// 빈 결과에 degraded: aggregator를 표시해 반환한다.
+func New(s store.Store, agg *aggregator.Aggregator, reg *agents.Registry, tenants tenant.Policy) *Handler {
+	return &Handler{
+		store:     s,
+		agg:       agg,
+		agents:    reg,
+		tenants:   tenants,
+		topoCache: newQueryCache[topology](topologyCacheTTL, topologyCacheEntries),
+	}
 }
 
 // Register는 라우터에 엔드포인트를 등록한다.
 // gin.Engine 대신 gin.IRouter를 받아 RouterGroup에도 마운트 가능하다.
 func (h *Handler) Register(r gin.IRouter) {
 	r.GET("/healthz", h.healthz)
+	r.GET("/version", h.getVersion)
 
-	v1 := r.Group("/api/v1")
+	v1 := r.Group("/api/v1", h.resolveScope)
 	{
 		v1.GET("/stats", h.getStats)
 		v1.GET("/events", h.getEvents)
 		v1.GET("/topology", h.getTopology)
+		v1.GET("/namespaces", h.getNamespaces)
+		v1.GET("/connections", h.getConnection)
+		v1.GET("/connections/:id", h.getConnectionByID)
+		v1.GET("/agents", h.getAgents)
+	}
+	fleet := v1.Group("", h.fleetOnly)
+	{
+		fleet.GET("/admin/storage", h.getStorageStats)
+		fleet.GET("/admin/sizing", h.getSizing)
+		fleet.GET("/agents/versions", h.getAgentVersions)
+		fleet.GET("/agents/config", h.getAgentConfig)
+		fleet.PUT("/agents/config", h.putAgentConfig)
 	}
 }
 
@@ -87,6 +181,102 @@ func (h *Handler) healthz(c *gin.Context) {
 	c.String(http.StatusOK, "ok")
 }
 
+// GET /version
+func (h *Handler) getVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
+
+type agentsResponse struct {
+	Agents []agents.Agent   `json:"agents"`
+	Load   agents.FleetLoad `json:"load"` // 연결 중인 agent의 부하 합계와 유실 노드
+}
+
+// GET /api/v1/agents
+// agent마다 연결 상태와 마지막 부하 보고(큐 점유, drop, 메모리 보호 유실)를 보여준다.
+func (h *Handler) getAgents(c *gin.Context) {
+	list := agentsIn(h.agents.List(), scopeOf(c))
+	c.JSON(http.StatusOK, agentsResponse{
+		Agents: list,
+		Load:   agents.LoadOf(list),
+	})
+}
+
+type agentVersionsResponse struct {
+	Server   version.Info          `json:"server"`
+	Skewed   bool                  `json:"skewed"` // 연결된 agent의 빌드가 2종 이상이거나 server와 스키마가 다름
+	Versions []agents.VersionGroup `json:"versions"`
+	Coverage agents.Coverage       `json:"coverage"`           // 수집 방식/커널 분포
+	Warnings []agents.Warning      `json:"warnings,omitempty"` // deprecated/거부된 agent와 안내 문구
+}
+
+// GET /api/v1/agents/versions
+// 연결 중인 agent를 빌드 단위로 묶어 rolling upgrade 진행 상황을 보여준다.
+func (h *Handler) getAgentVersions(c *gin.Context) {
+	server := version.Get()
+	groups := h.agents.Versions()
+	skewed := len(groups) > 1
+	for _, g := range groups {
+		if g.SchemaVersion != server.SchemaVersion {
+			skewed = true
+		}
+	}
+	c.JSON(http.StatusOK, agentVersionsResponse{
+		Server:   server,
+		Skewed:   skewed,
+		Versions: groups,
+		Coverage: h.agents.Coverage(),
+		Warnings: h.agents.Warnings(),
+	})
+}
+
+type agentConfigResponse struct {
+	Config  agents.RemoteConfig `json:"config"`
+	Applied map[string]uint64   `json:"applied"` // 연결된 노드 → 적용된 revision
+	Pending []string            `json:"pending"` // 아직 최신 revision을 적용하지 않은 노드
+}
+
+// GET /api/v1/agents/config
+func (h *Handler) getAgentConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.agentConfigResponse())
+}
+
+// PUT /api/v1/agents/config
+// body: {"sample_rate": 0.5, "exclude_namespaces": ["kube-system"]}
+func (h *Handler) putAgentConfig(c *gin.Context) {
+	var body agents.RemoteConfig
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.agents.SetRemoteConfig(body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.agentConfigResponse())
+}
+
+func (h *Handler) agentConfigResponse() agentConfigResponse {
+	resp := agentConfigResponse{
+		Config:  h.agents.RemoteConfig(),
+		Applied: make(map[string]uint64),
+		Pending: []string{},
+	}
+	for _, a := range h.agents.List() {
+		if !a.Connected {
+			continue
+		}
+		node := a.NodeName
+		if node == "" {
+			node = a.Addr
+		}
+		resp.Applied[node] = a.ConfigRevision
+		if a.ConfigRevision != resp.Config.Revision {
+			resp.Pending = append(resp.Pending, node)
+		}
+	}
+	return resp
+}
+
 // GET /api/v1/stats?window=60
 // window: 1~300 (초), 기본값 60
 func (h *Handler) getStats(c *gin.Context) {
@@ -98,15 +288,40 @@ func (h *Handler) getStats(c *gin.Context) {
 	if q.Window == 0 {
 		q.Window = aggregator.DefaultWindowSec
 	}
+	if h.agg == nil {
+		// query 모드: 실시간 집계는 수집 server에서만 수행한다.
+		// 요청 전체를 실패시키지 않고 빈 결과와 degraded 표시를 반환한다.
+		c.JSON(http.StatusOK, statsResponse{
+			WindowSec: q.Window,
+			Endpoints: []aggregator.EndpointStat{},
+			Degraded:  []degradedSource{{Source: sourceAggregator, Reason: "live stats are not available on query-only servers"}},
+		})
+		return
+	}
 
+	endpoints := h.agg.Snapshot(q.Window)
+	if scope := scopeOf(c); q.Cluster != "" || !scope.All {
+		filtered := endpoints[:0]
+		for _, e := range endpoints {
+			if scope.Allows(e.Tenant) && (q.Cluster == "" || e.Cluster == q.Cluster) {
+				filtered = append(filtered, e)
+			}
+		}
+		endpoints = filtered
+	}
 	c.JSON(http.StatusOK, statsResponse{
 		WindowSec: q.Window,
-		Endpoints: h.agg.Snapshot(q.Window),
+		Endpoints: endpoints,
 	})
 }
 
-// GET /api/v1/events?limit=100
+// GET /api/v1/events?limit=100&label=team=payments&cluster=prod&cursor=...
 // limit: 1~10000, 기본값 100
+// label: 로컬 pod label 필터 ("key=value"), 지정 시 필터 후 최근 limit개를 반환한다.
+// cluster: 지정 시 해당 클러스터 이벤트만 반환한다.
+// cursor: 응답의 next_cursor를 넘기면 그 페이지보다 오래된 limit개를 반환한다. 페이지는 저장
+// 순서로 나뉘므로 새 이벤트가 들어와도 겹치거나 빠지지 않는다. next_cursor가 없으면 마지막
+// 페이지다. ring buffer에서 이미 밀려난 위치의 cursor는 남은 이벤트까지만 반환한다.
 func (h *Handler) getEvents(c *gin.Context) {
 	var q eventsQuery
 	if err := c.ShouldBindQuery(&q); err != nil {
@@ -116,52 +331,216 @@ func (h *Handler) getEvents(c *gin.Context) {
 	if q.Limit == 0 {
 		q.Limit = 100
 	}
+	sel, err := parseLabelSelector(q.Labels)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, err := parseCursor(q.Cursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	events := h.store.Recent(q.Limit)
+	scope := scopeOf(c)
+	events, pos, next := h.pageEvents(before, q.Limit, func(ev *nefiv1.TraceEvent) bool {
+		return scope.Allows(ev.Tenant) && (q.Cluster == "" || ev.Cluster == q.Cluster) && sel.matches(ev.Labels)
+	})
 	c.JSON(http.StatusOK, eventsResponse{
-		Count:  len(events),
-		Events: toEventList(events),
+		Count:      len(events),
+		Events:     toEventList(events, pos),
+		NextCursor: formatCursor(next),
+		Degraded:   h.storeHealth(),
 	})
 }
 
-func toEventList(events []*nefiv1.TraceEvent) []eventResponse {
-	result := make([]eventResponse, 0, len(events))
-	for _, ev := range events {
-		latencyMs := 0.0
-		if ev.LatencyNs > 0 {
-			latencyMs = float64(ev.LatencyNs) / 1e6
+// GET /api/v1/connections/:id
+// events/connections 응답의 id로 이벤트 하나를 반환한다 (연결 관측과 HTTP 요청 모두). 이벤트가
+// ring buffer에서 밀려났거나, 만료됐거나, 요청 scope의 tenant가 아니면 404다.
+func (h *Handler) getConnectionByID(c *gin.Context) {
+	id, err := parseEventID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ev, ok := h.eventAt(id)
+	if !ok || !scopeOf(c).Allows(ev.Tenant) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found (it may have been evicted from the buffer)"})
+		return
+	}
+	c.JSON(http.StatusOK, toEvent(ev, id.pos))
+}
+
+// GET /api/v1/connections?conn_id=node-1/1234/17
+// store에 남아 있는 이벤트 중 conn_id가 같은 것을 연결 관측과 HTTP 이벤트로 나눠
+// 시간순으로 반환한다. fd는 close 후 재사용되므로 한 conn_id에 서로 다른 연결이
+// 섞일 수 있으며, 이 경우 타임스탬프로 구분한다.
+func (h *Handler) getConnection(c *gin.Context) {
+	var q connectionQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := scopeOf(c)
+	var conns, reqs []*nefiv1.TraceEvent // 최신 것부터
+	var connPos, reqPos []uint64
+	h.scanEvents(func(ev *nefiv1.TraceEvent, pos uint64) bool {
+		if ev.ConnId != q.ConnID || !scope.Allows(ev.Tenant) {
+			return true
 		}
-		result = append(result, eventResponse{
-			TimestampNs:     ev.TimestampNs,
-			PID:             ev.Pid,
-			FD:              ev.Fd,
-			MsgSize:         ev.MsgSize,
-			Direction:       ev.Direction,
-			Protocol:        ev.Protocol,
-			Comm:            ev.Comm,
-			Namespace:       ev.Namespace,
-			PodName:         ev.PodName,
-			NodeName:        ev.NodeName,
-			HttpMethod:      ev.HttpMethod,
-			HttpPath:        ev.HttpPath,
-			HttpStatus:      ev.HttpStatus,
-			HttpContentType: ev.HttpContentType,
-			LatencyMs:       latencyMs,
-		})
+		if ev.Connection {
+			conns = append(conns, ev)
+			connPos = append(connPos, pos)
+		} else {
+			reqs = append(reqs, ev)
+			reqPos = append(reqPos, pos)
+		}
+		return true
+	})
+	slices.Reverse(conns)
+	slices.Reverse(connPos)
+	slices.Reverse(reqs)
+	slices.Reverse(reqPos)
+	c.JSON(http.StatusOK, connectionResponse{
+		ConnID:      q.ConnID,
+		Connections: toEventList(conns, connPos),
+		Requests:    toEventList(reqs, reqPos),
+		Degraded:    h.storeHealth(),
+	})
+}
+
+type storageStatsResponse struct {
+	EventTypes []store.WriteStat `json:"event_types"`
+}
+
+// GET /api/v1/admin/storage
+// 이벤트 타입별 누적 기록 건수/바이트/거부 건수를 반환한다.
+func (h *Handler) getStorageStats(c *gin.Context) {
+	c.JSON(http.StatusOK, storageStatsResponse{EventTypes: h.store.WriteStats()})
+}
+
+type sizingQuery struct {
+	Nodes         int     `form:"nodes" binding:"omitempty,min=1"`
+	RetentionDays float64 `form:"retention_days" binding:"omitempty,gt=0"`
+	Replicas      *int    `form:"replicas" binding:"omitempty,min=0"`
+	Overhead      float64 `form:"overhead" binding:"omitempty,gte=1"`
+}
+
+// GET /api/v1/admin/sizing?nodes=200&retention_days=7&replicas=1&overhead=1.3
+// 연결된 agent의 실측 events/sec와 저장소 평균 이벤트 크기로 용량을 추정한다.
+// nodes를 생략하면 현재 연결된 노드 수 기준으로 계산한다.
+func (h *Handler) getSizing(c *gin.Context) {
+	var q sizingQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	in := sizing.Input{
+		TargetNodes:   q.Nodes,
+		RetentionDays: q.RetentionDays,
+		Replicas:      1,
+		Overhead:      q.Overhead,
+		RingCapacity:  h.store.Capacity(),
+	}
+	if in.RetentionDays == 0 {
+		in.RetentionDays = 7
+	}
+	if q.Replicas != nil {
+		in.Replicas = *q.Replicas
+	}
+	if in.Overhead == 0 {
+		in.Overhead = 1.3
+	}
+
+	var written, bytes uint64
+	for _, ws := range h.store.WriteStats() {
+		written += ws.Written
+		bytes += ws.Bytes
+	}
+	if written > 0 {
+		in.AvgEventBytes = float64(bytes) / float64(written)
+	}
+	for node, eps := range h.agents.Rates() {
+		in.Nodes = append(in.Nodes, sizing.NodeRate{Node: node, EventsPerSec: eps})
+	}
+	sort.Slice(in.Nodes, func(i, j int) bool { return in.Nodes[i].Node < in.Nodes[j].Node })
+
+	c.JSON(http.StatusOK, sizing.Project(in))
+}
+
+// toEventList는 events를 응답 형식으로 바꾼다. pos는 각 이벤트의 store 위치다.
+func toEventList(events []*nefiv1.TraceEvent, pos []uint64) []eventResponse {
+	result := make([]eventResponse, 0, len(events))
+	for i, ev := range events {
+		result = append(result, toEvent(ev, pos[i]))
 	}
 	return result
 }
 
+func toEvent(ev *nefiv1.TraceEvent, pos uint64) eventResponse {
+	latencyMs := 0.0
+	if ev.LatencyNs > 0 {
+		latencyMs = float64(ev.LatencyNs) / 1e6
+	}
+	return eventResponse{
+		ID:              eventID{pos: pos, ts: ev.TimestampNs}.String(),
+		TimestampNs:     ev.TimestampNs,
+		PID:             ev.Pid,
+		FD:              ev.Fd,
+		MsgSize:         ev.MsgSize,
+		Direction:       ev.Direction,
+		Protocol:        ev.Protocol,
+		Comm:            ev.Comm,
+		Tenant:          ev.Tenant,
+		Cluster:         ev.Cluster,
+		Namespace:       ev.Namespace,
+		PodName:         ev.PodName,
+		Workload:        ev.Workload,
+		NodeName:        ev.NodeName,
+		NodeZone:        ev.NodeZone,
+		NodeRegion:      ev.NodeRegion,
+		RemoteNs:        ev.RemoteNs,
+		RemotePod:       ev.RemotePod,
+		RemoteWorkload:  ev.RemoteWorkload,
+		RemoteService:   ev.RemoteService,
+		RemoteHostname:  ev.RemoteHostname,
+		RemoteExternal:  ev.RemoteExternal,
+		RemoteName:      ev.RemoteName,
+		RemoteKind:      ev.RemoteKind,
+		Policy:          ev.Policy,
+		MeshHop:         ev.MeshHop,
+		Labels:          ev.Labels,
+		RemoteLabels:    ev.RemoteLabels,
+		Connection:      ev.Connection,
+		ConnID:          ev.ConnId,
+		AgentIdentity:   ev.AgentIdentity,
+		HttpMethod:      ev.HttpMethod,
+		HttpPath:        ev.HttpPath,
+		HttpRoute:       ev.HttpRoute,
+		HttpStatus:      ev.HttpStatus,
+		HttpContentType: ev.HttpContentType,
+		LatencyMs:       latencyMs,
+	}
+}
+
 // ---- Topology ----
 
 type topoQuery struct {
-	Limit int `form:"limit" binding:"omitempty,min=1,max=50000"`
+	Limit   int      `form:"limit" binding:"omitempty,min=1,max=50000"`
+	Labels  []string `form:"label"` // "key=value", 로컬 pod label 기준 필터
+	Cluster string   `form:"cluster"`
 }
 
 type topoNode struct {
-	ID        string `json:"id"`
-	Namespace string `json:"namespace"`
-	Workload  string `json:"workload"`
+	ID        string   `json:"id"`
+	Tenant    string   `json:"tenant,omitempty"`  // external 노드는 tenant 간 공유하므로 빈 값
+	Cluster   string   `json:"cluster,omitempty"` // external 노드는 클러스터 간 공유하므로 빈 값
+	Namespace string   `json:"namespace"`
+	Workload  string   `json:"workload"`
+	Kind      string   `json:"kind,omitempty"`     // remote 해석 종류 (Pod/Service/Node/External)
+	External  bool     `json:"external,omitempty"` // 클러스터 외부 endpoint (CIDR/클라우드 대역/internet)
+	Zones     []string `json:"zones,omitempty"`    // workload pod가 실행 중인 zone 목록
 }
 
 type topoEdge struct {
@@ -173,11 +552,14 @@ type topoEdge struct {
 	Error        int64   `json:"error"`
 	SuccessRate  float64 `json:"success_rate"`
 	AvgLatencyMs float64 `json:"avg_latency_ms"` // 평균 레이턴시 (ms), 0이면 미측정
+	CrossZone    int64   `json:"cross_zone"`     // 양 끝 zone이 다른 요청 수 (zone을 아는 경우만)
+	Connections  int64   `json:"connections"`    // payload 없는 연결 관측 수 (agent /proc/net fallback)
 }
 
 type topoResponse struct {
-	Nodes []topoNode `json:"nodes"`
-	Edges []topoEdge `json:"edges"`
+	Nodes    []topoNode       `json:"nodes"`
+	Edges    []topoEdge       `json:"edges"`
+	Degraded []degradedSource `json:"degraded,omitempty"`
 }
 
 type edgeKey struct {
@@ -191,10 +573,16 @@ type edgeCounts struct {
 	error        int64
 	latencySum   int64 // ns 누적
 	latencyCount int64
+	crossZone    int64
+	connections  int64
 }
 
-// GET /api/v1/topology?limit=5000
-// store의 최근 이벤트에서 workload 간 트래픽 토폴로지를 반환한다.
+// GET /api/v1/topology?limit=5000&label=team=payments&cluster=prod
+// store의 최근 이벤트에서 workload 간 트래픽 토폴로지를 반환한다. 같은 조건의 결과는
+// 새 이벤트가 들어와도 topologyCacheTTL 동안 재사용한다 (queryCache).
+// label을 지정하면 해당 label을 가진 로컬 pod가 관측한 트래픽만 포함한다.
+// 클러스터 이름이 있는 이벤트의 노드 ID는 "<cluster>:"로 시작해 클러스터 간에 섞이지 않는다
+// (tenant가 있으면 "<tenant>/<cluster>:").
 //
 // 노드 식별 우선순위: K8s PodName > Comm (프로세스명)
 // 엣지 방향: 요청 방향 (A→B = A가 B를 호출함)
@@ -209,14 +597,34 @@ func (h *Handler) getTopology(c *gin.Context) {
 	if q.Limit == 0 {
 		q.Limit = 5000
 	}
+	sel, err := parseLabelSelector(q.Labels)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	events := h.store.Recent(q.Limit)
+	topo := h.cachedTopology(scopeOf(c), q.Limit, q.Cluster, sel)
+	c.JSON(http.StatusOK, topoResponse{Nodes: topo.nodes, Edges: topo.edges, Degraded: h.storeHealth()})
+}
+
+// buildTopology는 events에서 workload 노드와 요청 방향 엣지를 만든다.
+func buildTopology(events []*nefiv1.TraceEvent) ([]topoNode, []topoEdge) {
+	// pod → zone: agent가 보고한 로컬 pod의 노드 zone.
+	// remote pod도 다른 agent의 로컬 pod로 관측되면 zone을 알 수 있다.
+	podZone := make(map[string]string)
+	for _, ev := range events {
+		if ev.PodName != "" && ev.NodeZone != "" {
+			podZone[clusterID(ev.Tenant, ev.Cluster, ev.Namespace+"/"+ev.PodName)] = ev.NodeZone
+		}
+	}
 
 	nodeSet := make(map[string]topoNode)
+	zoneSet := make(map[string]map[string]struct{}) // node ID → zones
 	edgeMap := make(map[edgeKey]*edgeCounts)
 
 	for _, ev := range events {
-		if ev.HttpStatus == 0 {
+		// HTTP 응답 이벤트와, eBPF 없이 수집된 연결 이벤트만 엣지를 만든다.
+		if ev.HttpStatus == 0 && !ev.Connection {
 			continue
 		}
 
@@ -224,14 +632,24 @@ func (h *Handler) getTopology(c *gin.Context) {
 		if ev.PodName == "" {
 			continue
 		}
-		localWorkload := aggregator.WorkloadName(ev.PodName)
-		localID := localWorkload
-		if ev.Namespace != "" {
-			localID = ev.Namespace + "/" + localWorkload
+		// mesh의 app↔sidecar 구간은 peer가 자기 sidecar이므로 엣지를 만들지 않는다.
+		// (호출 수는 aggregator가 로컬 엔드포인트 기준으로 센다)
+		if ev.MeshHop == model.MeshHopLocal {
+			continue
+		}
+		localWorkload := aggregator.EventWorkload(ev)
+		localID := clusterID(ev.Tenant, ev.Cluster, nodeID(ev.Namespace, localWorkload))
+
+		// 리모트 workload 식별: pod 이름 > external 분류 이름 > pod IP 순서
+		remoteWorkload := aggregator.RemoteWorkload(ev)
+		remoteID := nodeID(ev.RemoteNs, remoteWorkload)
+		if remoteID == "" && ev.RemoteName != "" {
+			remoteID = ev.RemoteName
+			remoteWorkload = ev.RemoteName
+			if ev.RemoteKind == model.RemoteKindNode {
+				remoteWorkload = strings.TrimPrefix(ev.RemoteName, "node/")
+			}
 		}
-
-		// 리모트 workload 식별: pod 이름 > pod IP 순서
-		remoteID := nodeID(ev.RemoteNs, ev.RemotePod)
 		if remoteID == "" {
 			if ev.RemoteIp != 0 {
 				remoteID = fmt.Sprintf("%d.%d.%d.%d",
@@ -241,20 +659,39 @@ func (h *Handler) getTopology(c *gin.Context) {
 				continue
 			}
 		}
+		remoteTenant, remoteCluster := ev.Tenant, ev.Cluster
+		if ev.RemoteExternal {
+			remoteTenant, remoteCluster = "", ""
+		}
+		remoteID = clusterID(remoteTenant, remoteCluster, remoteID)
 
 		if _, ok := nodeSet[localID]; !ok {
 			nodeSet[localID] = topoNode{
 				ID:        localID,
+				Tenant:    ev.Tenant,
+				Cluster:   ev.Cluster,
 				Namespace: ev.Namespace,
 				Workload:  localWorkload,
+				Kind:      model.RemoteKindPod,
 			}
 		}
 		if _, ok := nodeSet[remoteID]; !ok {
 			nodeSet[remoteID] = topoNode{
 				ID:        remoteID,
+				Tenant:    remoteTenant,
+				Cluster:   remoteCluster,
 				Namespace: ev.RemoteNs,
-				Workload:  aggregator.WorkloadName(ev.RemotePod),
+				Workload:  remoteWorkload,
+				Kind:      ev.RemoteKind,
+				External:  ev.RemoteExternal,
+			}
+		}
+
+		if ev.NodeZone != "" {
+			if zoneSet[localID] == nil {
+				zoneSet[localID] = make(map[string]struct{})
 			}
+			zoneSet[localID][ev.NodeZone] = struct{}{}
 		}
 
 		// 요청 방향 엣지: A→B = A가 B를 호출
@@ -273,6 +710,10 @@ func (h *Handler) getTopology(c *gin.Context) {
 			ec = &edgeCounts{}
 			edgeMap[ek] = ec
 		}
+		if ev.Connection {
+			ec.connections++
+			continue
+		}
 		ec.total++
 		if ev.HttpStatus >= 200 && ev.HttpStatus < 400 {
 			ec.success++
@@ -283,10 +724,19 @@ func (h *Handler) getTopology(c *gin.Context) {
 			ec.latencySum += int64(ev.LatencyNs)
 			ec.latencyCount++
 		}
+		if ev.NodeZone != "" && ev.RemotePod != "" {
+			if rz := podZone[clusterID(ev.Tenant, ev.Cluster, ev.RemoteNs+"/"+ev.RemotePod)]; rz != "" && rz != ev.NodeZone {
+				ec.crossZone++
+			}
+		}
 	}
 
 	nodes := make([]topoNode, 0, len(nodeSet))
-	for _, n := range nodeSet {
+	for id, n := range nodeSet {
+		for z := range zoneSet[id] {
+			n.Zones = append(n.Zones, z)
+		}
+		sort.Strings(n.Zones)
 		nodes = append(nodes, n)
 	}
 
@@ -309,16 +759,86 @@ func (h *Handler) getTopology(c *gin.Context) {
 			Error:        ec.error,
 			SuccessRate:  rate,
 			AvgLatencyMs: avgLatencyMs,
+			CrossZone:    ec.crossZone,
+			Connections:  ec.connections,
 		})
 	}
-
-	c.JSON(http.StatusOK, topoResponse{Nodes: nodes, Edges: edges})
+	return nodes, edges
 }
 
-func nodeID(ns, podName string) string {
-	workload := aggregator.WorkloadName(podName)
+func nodeID(ns, workload string) string {
 	if ns == "" {
 		return workload
 	}
 	return ns + "/" + workload
 }
+
+// clusterID는 토폴로지 노드 ID에 클러스터 이름을 붙인다 (단일 클러스터면 그대로).
+// tenant가 있으면 그 앞에 "<tenant>/"를 붙여, 같은 이름의 클러스터를 쓰는 tenant를 구분한다.
+func clusterID(tenant, cluster, id string) string {
+	if id == "" {
+		return id
+	}
+	if cluster != "" {
+		id = cluster + ":" + id
+	}
+	if tenant != "" {
+		id = tenant + "/" + id
+	}
+	return id
+}
+
+// inCluster는 cluster가 지정되면 그 클러스터의 이벤트만 반환한다. 순서는 유지된다.
+func inCluster(events []*nefiv1.TraceEvent, cluster string) []*nefiv1.TraceEvent {
+	if cluster == "" {
+		return events
+	}
+	out := make([]*nefiv1.TraceEvent, 0, len(events))
+	for _, ev := range events {
+		if ev.Cluster == cluster {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// labelSelector는 "key=value" 조건의 AND 집합이다.
+type labelSelector map[string]string
+
+func parseLabelSelector(exprs []string) (labelSelector, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	sel := make(labelSelector, len(exprs))
+	for _, e := range exprs {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label filter %q: want key=value", e)
+		}
+		sel[k] = v
+	}
+	return sel, nil
+}
+
+// filter는 로컬 pod label이 모든 조건을 만족하는 이벤트만 반환한다. 순서는 유지된다.
+func (sel labelSelector) filter(events []*nefiv1.TraceEvent) []*nefiv1.TraceEvent {
+	if len(sel) == 0 {
+		return events
+	}
+	out := make([]*nefiv1.TraceEvent, 0, len(events))
+	for _, ev := range events {
+		if sel.matches(ev.Labels) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func (sel labelSelector) matches(labels map[string]string) bool {
+	for k, v := range sel {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
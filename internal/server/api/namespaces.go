@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/gihongjo/nefi/internal/model"
+	"github.com/gihongjo/nefi/internal/server/aggregator"
+)
+
+type namespacesQuery struct {
+	Window  int    `form:"window" binding:"omitempty,min=1,max=300"`
+	Limit   int    `form:"limit" binding:"omitempty,min=1,max=50000"`
+	Cluster string `form:"cluster"` // 지정 시 해당 클러스터만
+}
+
+// namespaceSummary는 namespace 하나의 집계 요약이다.
+// 여러 클러스터가 보고하면 같은 이름의 namespace도 클러스터별로 따로 요약한다.
+type namespaceSummary struct {
+	Tenant           string   `json:"tenant,omitempty"`
+	Cluster          string   `json:"cluster,omitempty"`
+	Namespace        string   `json:"namespace"`
+	Services         int      `json:"services"`          // 관측된 workload 수
+	ExternalInbound  int      `json:"external_inbound"`  // external → namespace 엣지 수
+	ExternalOutbound int      `json:"external_outbound"` // namespace → external 엣지 수
+	RequestsPerSec   float64  `json:"rps"`
+	ErrorRate        float64  `json:"error_rate"`           // 0.0~100.0 (4xx, 5xx)
+	DependsOn        []string `json:"depends_on,omitempty"` // 이 namespace가 호출하는 다른 namespace
+	Dependents       []string `json:"dependents,omitempty"` // 이 namespace를 호출하는 다른 namespace
+}
+
+// nsKey는 (tenant의) 클러스터 안의 namespace다.
+type nsKey struct {
+	Tenant    string
+	Cluster   string
+	Namespace string
+}
+
+type namespacesResponse struct {
+	WindowSec  int                `json:"window_sec"`
+	Namespaces []namespaceSummary `json:"namespaces"`
+	Degraded   []degradedSource   `json:"degraded,omitempty"`
+}
+
+// GET /api/v1/namespaces?window=60&limit=5000&cluster=prod
+// (클러스터,) namespace별 서비스 수, external 엣지 수, RPS/에러율, namespace 간 의존성을 반환한다.
+// 서비스와 의존성은 store의 최근 limit개 이벤트로 만든 토폴로지에서,
+// RPS/에러율은 aggregator의 window초 집계에서 계산한다.
+func (h *Handler) getNamespaces(c *gin.Context) {
+	var q namespacesQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if q.Window == 0 {
+		q.Window = aggregator.DefaultWindowSec
+	}
+	if q.Limit == 0 {
+		q.Limit = 5000
+	}
+
+	byNs := make(map[nsKey]*namespaceSummary)
+	get := func(k nsKey) *namespaceSummary {
+		s := byNs[k]
+		if s == nil {
+			s = &namespaceSummary{Tenant: k.Tenant, Cluster: k.Cluster, Namespace: k.Namespace}
+			byNs[k] = s
+		}
+		return s
+	}
+
+	// 토폴로지: workload 수, external 엣지, namespace 간 의존성
+	scope := scopeOf(c)
+	topo := h.cachedTopology(scope, q.Limit, q.Cluster, nil)
+	nodeByID := make(map[string]topoNode, len(topo.nodes))
+	workloads := make(map[nsKey]map[string]struct{}) // namespace → workload
+	for _, n := range topo.nodes {
+		nodeByID[n.ID] = n
+		if n.Namespace == "" || n.External || n.Kind == model.RemoteKindNode {
+			continue
+		}
+		k := nsKey{n.Tenant, n.Cluster, n.Namespace}
+		get(k)
+		if workloads[k] == nil {
+			workloads[k] = make(map[string]struct{})
+		}
+		workloads[k][n.Workload] = struct{}{}
+	}
+	deps := make(map[[2]nsKey]struct{}) // {src, dst}
+	for _, e := range topo.edges {
+		src, dst := nodeByID[e.Source], nodeByID[e.Target]
+		srcKey, dstKey := nsKey{src.Tenant, src.Cluster, src.Namespace}, nsKey{dst.Tenant, dst.Cluster, dst.Namespace}
+		switch {
+		case src.External && dst.Namespace != "":
+			get(dstKey).ExternalInbound++
+		case dst.External && src.Namespace != "":
+			get(srcKey).ExternalOutbound++
+		case src.Namespace != "" && dst.Namespace != "" && srcKey != dstKey:
+			deps[[2]nsKey{srcKey, dstKey}] = struct{}{}
+		}
+	}
+	for d := range deps {
+		get(d[0]).DependsOn = append(get(d[0]).DependsOn, d[1].Namespace)
+		get(d[1]).Dependents = append(get(d[1]).Dependents, d[0].Namespace)
+	}
+
+	// 집계: RPS, 에러율
+	var degraded []degradedSource
+	if h.agg == nil {
+		degraded = append(degraded, degradedSource{Source: sourceAggregator, Reason: "live stats are not available on query-only servers"})
+	} else {
+		type counts struct{ rps, errs float64 }
+		red := make(map[nsKey]counts)
+		for _, st := range h.agg.Services(q.Window) {
+			if st.Namespace == "" || (q.Cluster != "" && st.Cluster != q.Cluster) || !scope.Allows(st.Tenant) {
+				continue
+			}
+			k := nsKey{st.Tenant, st.Cluster, st.Namespace}
+			get(k)
+			if workloads[k] == nil {
+				workloads[k] = make(map[string]struct{})
+			}
+			workloads[k][st.Workload] = struct{}{}
+			r := red[k]
+			r.rps += st.RequestsPerSec
+			r.errs += st.ErrorsPerSec
+			red[k] = r
+		}
+		for k, r := range red {
+			s := get(k)
+			s.RequestsPerSec = r.rps
+			if r.rps > 0 {
+				s.ErrorRate = r.errs / r.rps * 100
+			}
+		}
+	}
+	degraded = append(degraded, h.storeHealth()...)
+
+	result := make([]namespaceSummary, 0, len(byNs))
+	for k, s := range byNs {
+		s.Services = len(workloads[k])
+		sort.Strings(s.DependsOn)
+		sort.Strings(s.Dependents)
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tenant != result[j].Tenant {
+			return result[i].Tenant < result[j].Tenant
+		}
+		if result[i].Cluster != result[j].Cluster {
+			return result[i].Cluster < result[j].Cluster
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+
+	c.JSON(http.StatusOK, namespacesResponse{WindowSec: q.Window, Namespaces: result, Degraded: degraded})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// pageScan은 첫 읽기로 페이지를 채우지 못했을 때(필터) store에서 한 번에 더 읽는 이벤트 수의
+// 최솟값이다.
+const pageScan = 1000
+
+// pageEvents는 위치가 before보다 앞선(0 = 가장 최근부터) 이벤트 중 keep을 통과하는 최근
+// limit개와 그 위치를 오래된 것부터 반환한다. next는 다음(더 오래된) 페이지의 cursor 위치이며,
+// 페이지가 limit개를 채우지 못했으면 0이다 — 더 읽을 이벤트가 없다.
+func (h *Handler) pageEvents(before uint64, limit int, keep func(*nefiv1.TraceEvent) bool) (events []*nefiv1.TraceEvent, pos []uint64, next uint64) {
+	newest := make([]*nefiv1.TraceEvent, 0, limit) // 최신 것부터
+	newestPos := make([]uint64, 0, limit)
+	scan := limit
+	for len(newest) < limit {
+		chunk, first := h.store.Before(before, scan)
+		if len(chunk) == 0 {
+			break
+		}
+		for i := len(chunk) - 1; i >= 0 && len(newest) < limit; i-- {
+			if keep(chunk[i]) {
+				newest = append(newest, chunk[i])
+				newestPos = append(newestPos, first+uint64(i))
+			}
+		}
+		before = first
+		scan = max(limit, pageScan)
+	}
+	slices.Reverse(newest)
+	slices.Reverse(newestPos)
+	if len(newest) == limit && newestPos[0] > 1 {
+		next = newestPos[0]
+	}
+	return newest, newestPos, next
+}
+
+// scanEvents는 store의 이벤트를 최신 것부터 pageScan개씩 읽어 위치와 함께 fn에 넘긴다.
+// ring buffer 전체를 한 번에 복사하지 않는다. fn이 false를 반환하면 멈춘다.
+func (h *Handler) scanEvents(fn func(ev *nefiv1.TraceEvent, pos uint64) bool) {
+	var before uint64
+	for {
+		chunk, first := h.store.Before(before, pageScan)
+		if len(chunk) == 0 {
+			return
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if !fn(chunk[i], first+uint64(i)) {
+				return
+			}
+		}
+		before = first
+	}
+}
+
+// eventAt은 id가 가리키는 이벤트다. 이벤트가 ring buffer에서 밀려났거나 만료됐으면 false다.
+// server가 다시 시작하면(embedded store의 복원 포함) 위치가 다시 매겨지므로, 위치와 함께
+// id에 담은 timestamp로 같은 이벤트인지 확인한다.
+func (h *Handler) eventAt(id eventID) (*nefiv1.TraceEvent, bool) {
+	events, first := h.store.Before(id.pos+1, 1)
+	if len(events) != 1 || first != id.pos || events[0].GetTimestampNs() != id.ts {
+		return nil, false
+	}
+	return events[0], true
+}
+
+// formatCursor와 parseCursor는 페이지 위치를 응답의 next_cursor 문자열로 주고받는다.
+// client에게는 불투명한 값이다.
+func formatCursor(pos uint64) string {
+	if pos == 0 {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(pos, 10)))
+}
+
+func parseCursor(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		var pos uint64
+		if pos, err = strconv.ParseUint(string(b), 10, 64); err == nil && pos > 0 {
+			return pos, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", s)
+}
+
+// eventID는 이벤트 하나를 가리키는 응답의 id다 — store 위치와 이벤트 timestamp.
+// client에게는 불투명한 값이다.
+type eventID struct {
+	pos uint64
+	ts  uint64
+}
+
+func (id eventID) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", id.pos, id.ts)))
+}
+
+func parseEventID(s string) (eventID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		p, t, ok := strings.Cut(string(b), ".")
+		var id eventID
+		if id.pos, err = strconv.ParseUint(p, 10, 64); ok && err == nil && id.pos > 0 {
+			if id.ts, err = strconv.ParseUint(t, 10, 64); err == nil {
+				return id, nil
+			}
+		}
+	}
+	return eventID{}, fmt.Errorf("invalid event id %q", s)
+}
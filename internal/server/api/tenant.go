@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/server/tenant"
+)
+
+// scopeKey는 resolveScope가 gin.Context에 둔 tenant.Scope의 키다.
+const scopeKey = "nefi.tenant.scope"
+
+// resolveScope는 요청의 tenant 범위를 정해 context에 둔다. 격리 모드에서 tenant가 없으면 403이다.
+func (h *Handler) resolveScope(c *gin.Context) {
+	s, err := h.tenants.Resolve(c.Request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.Set(scopeKey, s)
+}
+
+// fleetOnly는 fleet 전체에 걸친 엔드포인트(저장소 통계, agent 버전/설정)를 격리 모드에서
+// operator tenant로 제한한다. resolveScope 뒤에 등록한다.
+func (h *Handler) fleetOnly(c *gin.Context) {
+	if err := h.tenants.Fleet(scopeOf(c)); err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	}
+}
+
+// scopeOf는 resolveScope가 정한 범위다.
+func scopeOf(c *gin.Context) tenant.Scope {
+	v, _ := c.Get(scopeKey)
+	s, _ := v.(tenant.Scope)
+	return s
+}
+
+// recent는 store의 최근 이벤트 중 s 범위의 마지막 n개다. 다른 tenant의 트래픽이 많아도
+// 요청한 tenant의 이벤트를 n개까지 반환하도록 최신 쪽부터 거슬러 찾는다.
+func (h *Handler) recent(s tenant.Scope, n int) []*nefiv1.TraceEvent {
+	if s.All {
+		return h.store.Recent(n)
+	}
+	return store.RecentMatching(h.store, n, func(ev *nefiv1.TraceEvent) bool { return s.Allows(ev.Tenant) })
+}
+
+// inTenant는 s 범위의 이벤트만 반환한다. 순서는 유지된다.
+func inTenant(events []*nefiv1.TraceEvent, s tenant.Scope) []*nefiv1.TraceEvent {
+	if s.All {
+		return events
+	}
+	out := make([]*nefiv1.TraceEvent, 0, len(events))
+	for _, ev := range events {
+		if s.Allows(ev.Tenant) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// agentsIn은 s 범위의 agent만 반환한다.
+func agentsIn(list []agents.Agent, s tenant.Scope) []agents.Agent {
+	if s.All {
+		return list
+	}
+	out := make([]agents.Agent, 0, len(list))
+	for _, a := range list {
+		if s.Allows(a.Tenant) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -4,6 +4,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"net"
@@ -13,29 +14,130 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/configz"
+	"github.com/gihongjo/nefi/internal/metrics"
 	"github.com/gihongjo/nefi/internal/server/aggregator"
+	"github.com/gihongjo/nefi/internal/server/agents"
 	"github.com/gihongjo/nefi/internal/server/api"
+	"github.com/gihongjo/nefi/internal/server/archive"
+	"github.com/gihongjo/nefi/internal/server/audit"
 	"github.com/gihongjo/nefi/internal/server/collector"
+	"github.com/gihongjo/nefi/internal/server/demo"
 	"github.com/gihongjo/nefi/internal/server/hub"
 	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/server/tenant"
 	"github.com/gihongjo/nefi/web"
 )
 
+// 서버 실행 모드.
+const (
+	// ModeAll은 수집(gRPC)과 조회(HTTP API/WebSocket)를 모두 수행한다.
+	ModeAll = "all"
+	// ModeQuery는 수집과 백그라운드 집계/broadcast를 끄고 저장소 조회 API만 제공한다.
+	// 대시보드 트래픽을 쓰기 경로와 독립적으로 확장하기 위한 read replica용으로, 수집
+	// server가 쓰는 저장소를 함께 읽을 수 있는 backend(store.Shared)에서만 시작한다.
+	// 현재 backend는 모두 한 프로세스 전용이므로 New는 이 모드를 거부한다.
+	ModeQuery = "query"
+)
+
 // Config는 서버 설정값을 담는다.
 type Config struct {
-	GRPCAddr string
-	HTTPAddr string
-	Capacity int
+	Mode      string // ModeAll(기본) 또는 ModeQuery
+	GRPCAddr  string
+	HTTPAddr  string
+	Capacity  int
+	Collector collector.Config
+	Keepalive Keepalive
+	TLS       TLS // agent gRPC 연결의 TLS/mTLS (0 값 = 평문)
+
+	// Storage는 이벤트 저장소 backend다 (store.BackendMemory(기본) 또는 store.BackendEmbedded).
+	// embedded는 DataDir에 이벤트를 덧붙여 써서 재시작 후에도 최근 이벤트를 복원한다.
+	Storage string
+	DataDir string
+	// Retention이 있으면 저장한 지 Retention이 지난 이벤트를 조회에서 빼고 버린다 (0 = Capacity로만 제한).
+	Retention time.Duration
+	// Archive.Dest가 있으면 저장한 이벤트를 Parquet 파일로 object store에 장기 보관한다 (ModeAll 전용).
+	Archive archive.Config
+
+	// GRPCMaxRecvMsgSize는 server가 받는 gRPC 메시지(batch) 하나의 최대 크기(바이트)다
+	// (0 = gRPC 기본값 4MiB). payload를 캡처하는 agent의 큰 batch는 기본값을 넘을 수 있다.
+	GRPCMaxRecvMsgSize int
+	// GRPCReflection이 true면 gRPC server reflection을 등록해 grpcurl 등으로
+	// NefiCollector를 조회하고 호출할 수 있게 한다.
+	GRPCReflection bool
+
+	// Tenants는 REST API/WebSocket 조회의 tenant 정책이다 (0 값 = 격리하지 않음).
+	// /metrics/services는 모든 tenant의 서비스를 담으므로 격리 모드에서는 operator tenant만
+	// 조회할 수 있다. /metrics는 서버 자체 메트릭이라 tenant를 구분하지 않는다.
+	Tenants tenant.Policy
+
+	// AuditOutput이 nil이 아니면 수집 감사 기록(audit 패키지)을 한 줄에 JSON 하나씩 쓴다.
+	AuditOutput io.Writer
+	// AuditCapacity는 /api/v1/admin/audit로 조회하도록 메모리에 보관하는 감사 기록 수다
+	// (0 = audit.DefaultCapacity).
+	AuditCapacity int
+
+	// AgentStaleAfter 동안 batch가 오지 않은 연결 중 agent를 stale로 표시한다 (0 = agents.DefaultStaleAfter).
+	AgentStaleAfter time.Duration
+
+	// HTTPIngest가 true면 HTTP 포트에서 POST /api/v1/ingest(collector.Service.HTTPHandler)를
+	// 받는다 (ModeAll 전용). HTTP 포트는 보낸 쪽을 인증하지 않고 tenant도 요청 헤더를 그대로
+	// 쓰므로, agent client 인증서를 검증하는 설정(TLS.ClientCAFile)과 함께 켤 수 없다.
+	HTTPIngest bool
+
+	// Demo가 true면 내장 합성 트래픽 생성기가 자기 gRPC collector로 이벤트를 보낸다 (ModeAll 전용).
+	Demo     bool
+	DemoRate float64 // 진입점 초당 요청 수 (0 = 기본값)
+
+	// Configz는 /api/v1/admin/configz로 노출할 effective 설정값이다 (main에서 flag로부터 생성).
+	Configz []configz.Entry
+}
+
+// Keepalive는 agent gRPC 연결의 상태 확인 설정이다.
+// NLB/ELB 뒤에서 상대가 사라진 half-open 연결은 TCP만으로는 오래 감지되지 않으므로
+// 유휴 연결에 ping을 보내 응답이 없으면 닫는다.
+type Keepalive struct {
+	Time    time.Duration // 유휴 연결에 server가 ping을 보내는 간격 (0 = gRPC 기본값 2시간)
+	Timeout time.Duration // ping 응답 대기 시간. 넘으면 연결을 닫는다 (0 = gRPC 기본값 20초)
+	// MinClientTime은 허용하는 agent ping의 최소 간격이다. 더 자주 ping하는
+	// client는 GOAWAY(too_many_pings)로 끊는다. agent의 keepalive 간격 이하여야 한다.
+	MinClientTime time.Duration
+	// MaxConnAge가 0보다 크면 연결을 이 시간 후 GOAWAY로 닫아 agent가 재연결하게 한다.
+	// L4 load balancer 뒤에서 server replica 간 연결을 재분산하는 데 쓴다.
+	// 열린 스트림은 MaxConnAgeGrace 동안 마무리할 시간을 받는다.
+	MaxConnAge      time.Duration
+	MaxConnAgeGrace time.Duration
+}
+
+// serverOptions는 k를 gRPC server option으로 변환한다.
+func (k Keepalive) serverOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  k.Time,
+			Timeout:               k.Timeout,
+			MaxConnectionAge:      k.MaxConnAge,
+			MaxConnectionAgeGrace: k.MaxConnAgeGrace,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime: k.MinClientTime,
+			// agent는 스트림이 없는 재연결 대기 중에도 ping한다.
+			PermitWithoutStream: true,
+		}),
+	}
 }
 
 // Server는 nefi-server의 모든 컴포넌트를 소유한다.
+// query 모드에서는 agg, hub, coll, grpcSrv, grpcLis가 nil이다.
 type Server struct {
 	cfg     Config
 	store   store.Store
 	agg     *aggregator.Aggregator
 	hub     *hub.Hub
+	coll    *collector.Service
 	grpcSrv *grpc.Server
 	grpcLis net.Listener
 	httpSrv *http.Server
@@ -44,26 +146,116 @@ type Server struct {
 // New는 컴포넌트를 초기화하고 포트를 바인딩한다.
 // 실제 요청 처리는 Run() 호출 이후 시작된다.
 func New(cfg Config) (*Server, error) {
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = ModeAll
+	case ModeAll, ModeQuery:
+	default:
+		return nil, fmt.Errorf("unknown mode %q (want %q or %q)", cfg.Mode, ModeAll, ModeQuery)
+	}
+	queryOnly := cfg.Mode == ModeQuery
+	if cfg.Storage == "" {
+		cfg.Storage = store.BackendMemory
+	}
+	if queryOnly && !store.Shared(cfg.Storage) {
+		return nil, fmt.Errorf("mode %q needs a storage backend that several servers can read at once, and storage %q is private to one process (a query server would only serve its own empty store)", ModeQuery, cfg.Storage)
+	}
+	if queryOnly && cfg.Demo {
+		return nil, fmt.Errorf("demo traffic requires mode %q (query mode has no ingestion)", ModeAll)
+	}
+	if queryOnly && cfg.HTTPIngest {
+		return nil, fmt.Errorf("HTTP ingest requires mode %q (query mode has no ingestion)", ModeAll)
+	}
+	if cfg.HTTPIngest && cfg.TLS.ClientCAFile != "" {
+		return nil, fmt.Errorf("HTTP ingest cannot be enabled with a gRPC client CA: the HTTP port does not verify client certificates, so any caller could write events as any agent or tenant")
+	}
+	if cfg.Demo && cfg.TLS.Enabled() {
+		return nil, fmt.Errorf("demo traffic requires plaintext gRPC (the demo generator has no client certificate)")
+	}
+	grpcOpts := cfg.Keepalive.serverOptions()
+	if cfg.GRPCMaxRecvMsgSize > 0 {
+		grpcOpts = append(grpcOpts, grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMsgSize))
+	}
+	var auditLog *audit.Log
+	if !queryOnly {
+		auditLog = audit.New(cfg.AuditCapacity, cfg.AuditOutput)
+		cfg.Collector.Audit = auditLog
+		creds, err := cfg.TLS.serverOption(auditLog)
+		if err != nil {
+			return nil, fmt.Errorf("gRPC TLS: %w", err)
+		}
+		if creds != nil {
+			grpcOpts = append(grpcOpts, creds)
+		}
+	}
 
-	s := store.New(cfg.Capacity)
-	agg := aggregator.New(s)
-	h := hub.New(s, agg)
-
-	grpcLis, err := net.Listen("tcp", cfg.GRPCAddr)
+	reg := metrics.NewRegistry()
+	s, err := store.Open(store.Config{
+		Backend:  cfg.Storage,
+		Capacity: cfg.Capacity,
+		TTL:      cfg.Retention,
+		DataDir:  cfg.DataDir,
+	})
 	if err != nil {
-		s.Close()
-		agg.Close()
-		h.Close()
-		return nil, fmt.Errorf("gRPC listen %s: %w", cfg.GRPCAddr, err)
+		return nil, err
+	}
+	store.RegisterMetrics(reg, s)
+	if cfg.Archive.Dest != "" && !queryOnly {
+		arch, err := archive.New(cfg.Archive)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("archive: %w", err)
+		}
+		arch.RegisterMetrics(reg)
+		s = archive.Wrap(s, arch)
+	}
+	agentReg := agents.NewRegistry(cfg.AgentStaleAfter)
+
+	var (
+		agg     *aggregator.Aggregator
+		h       *hub.Hub
+		coll    *collector.Service
+		grpcSrv *grpc.Server
+		grpcLis net.Listener
+	)
+	if !queryOnly {
+		agg = aggregator.New(s)
+		h = hub.New(s, agg, agentReg, cfg.Tenants)
+
+		grpcLis, err = net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			s.Close()
+			agg.Close()
+			h.Close()
+			return nil, fmt.Errorf("gRPC listen %s: %w", cfg.GRPCAddr, err)
+		}
+		grpcSrv = grpc.NewServer(grpcOpts...)
+		coll = collector.New(s, agentReg, cfg.Collector)
+		coll.RegisterMetrics(reg)
+		nefiv1.RegisterNefiCollectorServer(grpcSrv, coll)
+		if cfg.GRPCReflection {
+			reflection.Register(grpcSrv)
+		}
 	}
-	grpcSrv := grpc.NewServer()
-	nefiv1.RegisterNefiCollectorServer(grpcSrv, collector.New(s))
 
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(cors.Default(), gin.Logger(), gin.Recovery())
-	api.New(s, agg).Register(r)
-	r.GET("/ws", gin.WrapH(h))
+	api.New(s, agg, agentReg, cfg.Tenants).Register(r)
+	if h != nil {
+		r.GET("/ws", gin.WrapH(h))
+	}
+	if coll != nil {
+		r.GET("/api/v1/admin/audit", gin.WrapH(cfg.Tenants.OperatorOnly(auditLog.Handler())))
+	}
+	r.GET("/metrics", gin.WrapH(reg))
+	if agg != nil {
+		// 서비스별 RED 값은 서버 자체 메트릭과 분리해 별도 스크레이프 대상으로 노출한다.
+		svcReg := metrics.NewRegistry()
+		aggregator.RegisterMetrics(svcReg, agg)
+		r.GET("/metrics/services", gin.WrapH(cfg.Tenants.OperatorOnly(svcReg.OpenMetricsHandler())))
+	}
+	r.GET("/api/v1/admin/configz", gin.WrapH(cfg.Tenants.OperatorOnly(configz.Handler("nefi-server", cfg.Configz))))
 
 	// Svelte 빌드 결과물 (web/dist/) 서빙
 	// SPA 라우팅: /assets/* 는 파일 그대로, 나머지는 index.html 반환
@@ -78,14 +270,25 @@ func New(cfg Config) (*Server, error) {
 		c.Data(http.StatusOK, "text/html; charset=utf-8", data)
 	})
 
+	// HTTP 수집은 CORS를 적용하는 gin router 밖에서 받는다. 브라우저 페이지가 cross-origin으로
+	// 이벤트를 쓰지 못하게 preflight를 승인하지 않는다 (HTTPHandler는 POST만 받는다).
+	var handler http.Handler = r
+	if coll != nil && cfg.HTTPIngest {
+		mux := http.NewServeMux()
+		mux.Handle("/api/v1/ingest", coll.HTTPHandler())
+		mux.Handle("/", r)
+		handler = mux
+	}
+
 	return &Server{
 		cfg:     cfg,
 		store:   s,
 		agg:     agg,
 		hub:     h,
+		coll:    coll,
 		grpcSrv: grpcSrv,
 		grpcLis: grpcLis,
-		httpSrv: &http.Server{Addr: cfg.HTTPAddr, Handler: r},
+		httpSrv: &http.Server{Addr: cfg.HTTPAddr, Handler: handler},
 	}, nil
 }
 
@@ -94,12 +297,23 @@ func New(cfg Config) (*Server, error) {
 func (s *Server) Run(ctx context.Context) error {
 	errCh := make(chan error, 2)
 
-	go func() {
-		log.Printf("[+] gRPC listening on %s", s.cfg.GRPCAddr)
-		if err := s.grpcSrv.Serve(s.grpcLis); err != nil {
-			errCh <- fmt.Errorf("gRPC: %w", err)
+	// demo 스트림은 GracefulStop 전에 끝나야 하므로 별도 cancel로 먼저 닫는다.
+	demoCtx, stopDemo := context.WithCancel(ctx)
+	defer stopDemo()
+
+	if s.grpcSrv != nil {
+		go func() {
+			log.Printf("[+] gRPC listening on %s%s", s.cfg.GRPCAddr, s.cfg.TLS.logSuffix())
+			if err := s.grpcSrv.Serve(s.grpcLis); err != nil {
+				errCh <- fmt.Errorf("gRPC: %w", err)
+			}
+		}()
+		if s.cfg.Demo {
+			go demo.Run(demoCtx, s.grpcLis.Addr().String(), demo.Config{Rate: s.cfg.DemoRate})
 		}
-	}()
+	} else {
+		log.Printf("[*] query-only mode: ingestion disabled")
+	}
 	go func() {
 		log.Printf("[+] HTTP/WebSocket listening on %s", s.cfg.HTTPAddr)
 		if err := s.httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -113,6 +327,7 @@ func (s *Server) Run(ctx context.Context) error {
 	case runErr = <-errCh:
 	}
 
+	stopDemo()
 	return s.shutdown(runErr)
 }
 
@@ -129,7 +344,9 @@ func mustSub(fsys fs.FS, dir string) fs.FS {
 func (s *Server) shutdown(cause error) error {
 	log.Println("[*] Shutting down...")
 
-	s.grpcSrv.GracefulStop()
+	if s.grpcSrv != nil {
+		s.grpcSrv.GracefulStop()
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -137,9 +354,17 @@ func (s *Server) shutdown(cause error) error {
 		log.Printf("[HTTP] shutdown error: %v", err)
 	}
 
-	// 외부 네트워크 연결 종료 후 내부 컴포넌트 정리
-	s.hub.Close()
-	s.agg.Close()
+	// 외부 네트워크 연결 종료 후 내부 컴포넌트 정리.
+	// collector 큐에 남은 batch는 store를 닫기 전에 저장한다.
+	if s.coll != nil {
+		s.coll.Close()
+	}
+	if s.hub != nil {
+		s.hub.Close()
+	}
+	if s.agg != nil {
+		s.agg.Close()
+	}
 	s.store.Close()
 
 	return cause
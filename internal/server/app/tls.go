@@ -0,0 +1,70 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/gihongjo/nefi/internal/server/audit"
+)
+
+// TLS는 agent gRPC 연결의 TLS 설정이다. ClientCAFile을 지정하면 그 CA가 서명한
+// client 인증서가 없는 연결은 handshake에서 거부하고(mTLS), collector는 인증서의
+// SAN/CN을 agent 신원으로 모든 이벤트에 기록한다.
+type TLS struct {
+	CertFile     string // server 인증서 (PEM). 비우면 평문
+	KeyFile      string // CertFile의 개인 키 (PEM)
+	ClientCAFile string // agent client 인증서를 검증할 CA bundle (PEM). 비우면 client 인증서를 요구하지 않는다
+}
+
+// Enabled는 gRPC를 TLS로 제공하는지 여부다.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || t.ClientCAFile != ""
+}
+
+// logSuffix는 listen 로그에 덧붙일 보안 모드 표기다 (평문이면 빈 문자열).
+func (t TLS) logSuffix() string {
+	switch {
+	case t.ClientCAFile != "":
+		return " (mTLS: client certificates required)"
+	case t.Enabled():
+		return " (TLS)"
+	}
+	return ""
+}
+
+// serverOption은 t를 gRPC server credentials option으로 변환한다. handshake 실패(client
+// 인증서 없음, 검증 실패)는 auditLog에 기록한다. TLS를 쓰지 않으면 nil이다.
+func (t TLS) serverOption(auditLog *audit.Log) (grpc.ServerOption, error) {
+	if !t.Enabled() {
+		return nil, nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, fmt.Errorf("TLS needs both a server certificate and key")
+	}
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if t.ClientCAFile != "" {
+		pem, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA bundle %s has no PEM certificates", t.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return grpc.Creds(auditLog.Credentials(credentials.NewTLS(cfg))), nil
+}
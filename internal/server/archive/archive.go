@@ -0,0 +1,299 @@
+// Package archive는 저장한 이벤트를 주기적으로 Parquet 파일로 내보내 object store(S3, GCS)에
+// 장기 보관한다 (nefi-server --archive).
+//
+// 조회용 저장소(ring buffer)는 최근 이벤트만 들고 있으므로, 수개월치 원본 이벤트는 Athena,
+// Trino, Spark, DuckDB 같은 도구로 object store의 파일을 직접 조회한다.
+//
+// 동작 방식:
+//   - store.Add를 감싸 저장한 이벤트를 (날짜, cluster) 파티션별 버퍼에 모음
+//   - Interval마다, 또는 파티션 버퍼가 MaxRows에 닿으면 파티션마다 Parquet 파일 하나를 씀
+//   - 경로: <dest>/date=YYYY-MM-DD/cluster=<cluster>/events-<시각>-<번호>.parquet
+//     (Hive 스타일 파티션 — 쿼리 엔진이 경로에서 date, cluster 열을 읽는다)
+//   - 날짜는 server가 이벤트를 받은 UTC 날짜다 (agent의 timestamp_ns는 노드 부팅 기준일 수 있다)
+//   - 업로드가 실패하면 몇 번 재시도하고, 그래도 실패하면 다음 주기에 다시 보낸다. 버퍼가
+//     MaxBuffered를 넘으면 새 이벤트는 보관하지 않고 센다 (조회용 저장은 영향받지 않음)
+//   - Retention이 있으면 시작 시와 매시간 그보다 오래된 date= 파티션을 지운다. S3에서는
+//     bucket lifecycle 규칙(prefix + 만료 일수)이 더 싸므로, 이 삭제는 lifecycle을 쓸 수 없는
+//     S3 호환 저장소와 로컬 디렉터리를 위한 것이다
+//
+// dest는 "s3://bucket/prefix"(S3와 S3 호환 저장소 — GCS는 AWS_ENDPOINT_URL과 HMAC 키로) 또는
+// 로컬 디렉터리다. payload와 label은 보관하지 않는다.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/metrics"
+)
+
+// 기본값.
+const (
+	DefaultInterval = 10 * time.Minute
+	DefaultMaxRows  = 500000
+
+	uploadAttempts = 3
+	uploadBackoff  = 2 * time.Second
+	expireInterval = time.Hour
+)
+
+// Config는 archive 설정이다.
+type Config struct {
+	Dest        string        // "s3://bucket/prefix" 또는 로컬 디렉터리 (빈 값 = archive 끔)
+	Interval    time.Duration // 파일로 내보내는 주기 (0 = DefaultInterval)
+	MaxRows     int           // 파일 하나의 최대 이벤트 수 (0 = DefaultMaxRows)
+	MaxBuffered int           // 내보내기 전 버퍼에 둘 최대 이벤트 수 (0 = MaxRows의 4배)
+	Retention   time.Duration // 이보다 오래된 날짜 파티션을 지운다 (0 = 지우지 않음)
+}
+
+// record는 보관할 이벤트 하나다.
+type record struct {
+	at time.Time // server가 받은 시각
+	ev *nefiv1.TraceEvent
+}
+
+// partition은 파일 하나에 모을 이벤트 범위다.
+type partition struct {
+	date    string
+	cluster string
+}
+
+// Archiver는 이벤트를 모아 Parquet 파일로 내보낸다.
+type Archiver struct {
+	cfg  Config
+	dest destination
+
+	mu       sync.Mutex
+	parts    map[partition][]*record
+	buffered int
+	full     chan struct{} // 가득 찬 파티션이 있음 (버퍼 1)
+
+	seq      atomic.Uint64 // 파일 이름 번호
+	archived atomic.Uint64 // 파일로 내보낸 이벤트 수
+	files    atomic.Uint64
+	bytes    atomic.Uint64
+	failures atomic.Uint64 // 재시도 후에도 실패한 업로드 수
+	dropped  atomic.Uint64 // 버퍼가 가득 차 보관하지 못한 이벤트 수
+	expired  atomic.Uint64 // 보관 기간이 지나 지운 파일 수
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New는 cfg.Dest로 내보내는 Archiver를 만들고 내보내기 고루틴을 시작한다. Close로 멈춘다.
+func New(cfg Config) (*Archiver, error) {
+	if cfg.Dest == "" {
+		return nil, fmt.Errorf("archive destination is empty")
+	}
+	dest, err := newDestination(cfg.Dest)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = DefaultMaxRows
+	}
+	if cfg.MaxBuffered <= 0 {
+		cfg.MaxBuffered = 4 * cfg.MaxRows
+	}
+	a := &Archiver{
+		cfg:   cfg,
+		dest:  dest,
+		parts: make(map[partition][]*record),
+		full:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	go a.run()
+	return a, nil
+}
+
+// Add는 이벤트를 보관 버퍼에 넣는다. 블로킹하지 않는다.
+func (a *Archiver) Add(ev *nefiv1.TraceEvent) {
+	now := time.Now().UTC()
+	p := partition{date: now.Format(time.DateOnly), cluster: ev.GetCluster()}
+	if p.cluster == "" {
+		p.cluster = "unknown"
+	}
+	a.mu.Lock()
+	if a.buffered >= a.cfg.MaxBuffered {
+		a.mu.Unlock()
+		a.dropped.Add(1)
+		return
+	}
+	rows := append(a.parts[p], &record{at: now, ev: ev})
+	a.parts[p] = rows
+	a.buffered++
+	a.mu.Unlock()
+	if len(rows) >= a.cfg.MaxRows {
+		select {
+		case a.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *Archiver) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	var expire <-chan time.Time
+	if a.cfg.Retention > 0 {
+		a.expire(a.ctx)
+		t := time.NewTicker(expireInterval)
+		defer t.Stop()
+		expire = t.C
+	}
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			a.flush(a.ctx, false)
+		case <-a.full:
+			a.flush(a.ctx, true)
+		case <-expire:
+			a.expire(a.ctx)
+		}
+	}
+}
+
+// expire는 Retention보다 오래된 날짜 파티션을 지운다. 보관 기간은 날짜 단위로 센다 —
+// 받은 날짜(UTC)가 지금부터 Retention 전의 날짜보다 이른 파티션을 지운다.
+func (a *Archiver) expire(ctx context.Context) {
+	cutoff := time.Now().UTC().Add(-a.cfg.Retention).Format(time.DateOnly)
+	n, err := a.dest.expire(ctx, cutoff)
+	a.expired.Add(uint64(n))
+	if n > 0 {
+		log.Printf("[archive] deleted %d files from partitions before %s", n, cutoff)
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[archive] delete partitions before %s failed, retrying in %s: %v", cutoff, expireInterval, err)
+	}
+}
+
+// flush는 버퍼의 파티션을 파일로 내보낸다. onlyFull이면 MaxRows에 닿은 파티션만 내보낸다.
+// 실패한 파티션은 버퍼로 되돌려 다음 주기에 다시 보낸다.
+func (a *Archiver) flush(ctx context.Context, onlyFull bool) {
+	a.mu.Lock()
+	taken := make(map[partition][]*record)
+	for p, rows := range a.parts {
+		if onlyFull && len(rows) < a.cfg.MaxRows {
+			continue
+		}
+		taken[p] = rows
+		delete(a.parts, p)
+	}
+	a.mu.Unlock()
+
+	for p, rows := range taken {
+		for len(rows) > 0 {
+			n := min(len(rows), a.cfg.MaxRows)
+			if err := a.export(ctx, p, rows[:n]); err != nil {
+				a.failures.Add(1)
+				log.Printf("[archive] export %d events (%s, cluster %s) failed, retrying next interval: %v", len(rows), p.date, p.cluster, err)
+				a.requeue(p, rows)
+				break
+			}
+			a.mu.Lock()
+			a.buffered -= n
+			a.mu.Unlock()
+			rows = rows[n:]
+		}
+	}
+}
+
+// requeue는 내보내지 못한 rows를 그 사이 쌓인 이벤트 앞에 되돌린다.
+func (a *Archiver) requeue(p partition, rows []*record) {
+	a.mu.Lock()
+	a.parts[p] = append(rows, a.parts[p]...)
+	a.mu.Unlock()
+}
+
+// export는 rows를 Parquet 파일 하나로 써서 올린다. 일시적 실패는 몇 번 재시도한다.
+func (a *Archiver) export(ctx context.Context, p partition, rows []*record) error {
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, rows); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("date=%s/cluster=%s/events-%s-%06d.parquet",
+		p.date, pathSafe(p.cluster), time.Now().UTC().Format("20060102T150405Z"), a.seq.Add(1))
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		if err = a.dest.put(ctx, key, buf.Bytes()); err == nil {
+			break
+		}
+		if attempt == uploadAttempts || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(uploadBackoff * time.Duration(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	a.archived.Add(uint64(len(rows)))
+	a.files.Add(1)
+	a.bytes.Add(uint64(buf.Len()))
+	return nil
+}
+
+// pathSafe는 cluster 이름을 경로 한 단계로 쓸 수 있게 바꾼다.
+func pathSafe(s string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(s)
+}
+
+// Close는 버퍼에 남은 이벤트를 모두 내보내고 멈춘다. timeout 안에 끝나지 않은 업로드는 취소한다.
+func (a *Archiver) Close(timeout time.Duration) {
+	a.cancel()
+	<-a.done
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	a.flush(ctx, false)
+	a.mu.Lock()
+	left := a.buffered
+	a.mu.Unlock()
+	if left > 0 {
+		log.Printf("[archive] %d events were not archived before shutdown", left)
+	}
+}
+
+// RegisterMetrics는 archive 메트릭을 reg에 등록한다.
+func (a *Archiver) RegisterMetrics(reg *metrics.Registry) {
+	counter := func(name, help string, v *atomic.Uint64) {
+		reg.Register(metrics.Family{
+			Name: name,
+			Help: help,
+			Kind: metrics.Counter,
+			Collect: func() []metrics.Sample {
+				return []metrics.Sample{{Value: float64(v.Load())}}
+			},
+		})
+	}
+	counter("nefi_archive_events_total", "Events written to archive Parquet files.", &a.archived)
+	counter("nefi_archive_files_total", "Parquet files written to the archive destination.", &a.files)
+	counter("nefi_archive_bytes_total", "Bytes of Parquet files written to the archive destination.", &a.bytes)
+	counter("nefi_archive_failures_total", "Archive exports that failed after retries and were requeued.", &a.failures)
+	counter("nefi_archive_dropped_events_total", "Events not archived because the archive buffer was full.", &a.dropped)
+	counter("nefi_archive_expired_files_total", "Archive files deleted because their day partition is older than the archive retention.", &a.expired)
+	reg.Register(metrics.Family{
+		Name: "nefi_archive_buffered_events",
+		Help: "Events waiting to be written to the archive.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			return []metrics.Sample{{Value: float64(a.buffered)}}
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// columns는 보관 파일의 스키마다. 열을 추가할 때는 끝에 붙인다 — 이미 쓴 파일과 새 파일을
+// 함께 조회하는 쿼리 엔진은 열 이름으로 맞추지만, 기존 열의 이름과 타입은 바꾸지 않는다.
+var columns = []column{
+	{name: "received_at", typ: typeInt64, conv: convertedTimestampMillis, i64: func(r *record) int64 { return r.at.UnixMilli() }},
+	{name: "timestamp_ns", typ: typeInt64, conv: convertedNone, i64: func(r *record) int64 { return int64(r.ev.GetTimestampNs()) }},
+	{name: "cluster", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetCluster() }},
+	{name: "tenant", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetTenant() }},
+	{name: "node_name", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetNodeName() }},
+	{name: "connection", typ: typeBoolean, conv: convertedNone, bool: func(r *record) bool { return r.ev.GetConnection() }},
+	{name: "protocol", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return model.Protocol(r.ev.GetProtocol()).String() }},
+	{name: "direction", typ: typeInt32, conv: convertedNone, i32: func(r *record) int32 { return int32(r.ev.GetDirection()) }},
+	{name: "msg_type", typ: typeInt32, conv: convertedNone, i32: func(r *record) int32 { return int32(r.ev.GetMsgType()) }},
+	{name: "msg_size", typ: typeInt64, conv: convertedNone, i64: func(r *record) int64 { return int64(r.ev.GetMsgSize()) }},
+
+	{name: "namespace", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetNamespace() }},
+	{name: "pod_name", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetPodName() }},
+	{name: "workload", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetWorkload() }},
+	{name: "comm", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetComm() }},
+	{name: "pid", typ: typeInt64, conv: convertedNone, i64: func(r *record) int64 { return int64(r.ev.GetPid()) }},
+
+	{name: "remote_ip", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return ipString(r.ev.GetRemoteIp()) }},
+	{name: "remote_port", typ: typeInt32, conv: convertedNone, i32: func(r *record) int32 { return int32(r.ev.GetRemotePort()) }},
+	{name: "remote_namespace", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemoteNs() }},
+	{name: "remote_pod", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemotePod() }},
+	{name: "remote_workload", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemoteWorkload() }},
+	{name: "remote_service", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemoteService() }},
+	{name: "remote_kind", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemoteKind() }},
+	{name: "remote_name", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemoteName() }},
+	{name: "remote_hostname", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetRemoteHostname() }},
+	{name: "remote_external", typ: typeBoolean, conv: convertedNone, bool: func(r *record) bool { return r.ev.GetRemoteExternal() }},
+
+	{name: "conn_id", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetConnId() }},
+	{name: "http_method", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetHttpMethod() }},
+	{name: "http_path", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetHttpPath() }},
+	{name: "http_status", typ: typeInt32, conv: convertedNone, i32: func(r *record) int32 { return r.ev.GetHttpStatus() }},
+	{name: "latency_ns", typ: typeInt64, conv: convertedNone, i64: func(r *record) int64 { return int64(r.ev.GetLatencyNs()) }},
+	{name: "policy", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetPolicy() }},
+	{name: "mesh_hop", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetMeshHop() }},
+	{name: "agent_identity", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetAgentIdentity() }},
+	{name: "http_route", typ: typeByteArray, conv: convertedUTF8, str: func(r *record) string { return r.ev.GetHttpRoute() }},
+}
+
+// ipString은 host byte order IPv4 주소를 점 표기로 바꾼다 (0 = 빈 문자열).
+func ipString(ip uint32) string {
+	if ip == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", (ip>>24)&0xff, (ip>>16)&0xff, (ip>>8)&0xff, ip&0xff)
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gihongjo/nefi/internal/s3"
+)
+
+// destination은 보관 파일을 쓰는 곳이다. key는 "date=.../cluster=.../<파일>" 형태의 상대 경로다.
+type destination interface {
+	put(ctx context.Context, key string, data []byte) error
+	// expire는 날짜가 cutoff(YYYY-MM-DD)보다 이른 date= 파티션의 파일을 지우고 지운 파일 수를 반환한다.
+	expire(ctx context.Context, cutoff string) (int, error)
+}
+
+const datePrefix = "date="
+
+// expired는 "date=YYYY-MM-DD" 경로 단계가 cutoff보다 이른 날짜인지 판단한다.
+func expired(segment, cutoff string) bool {
+	date, ok := strings.CutPrefix(strings.TrimSuffix(segment, "/"), datePrefix)
+	return ok && len(date) == len(cutoff) && date < cutoff
+}
+
+// newDestination은 "s3://bucket/prefix" 또는 로컬 디렉터리를 연다.
+func newDestination(dest string) (destination, error) {
+	if !strings.HasPrefix(dest, "s3://") {
+		if err := os.MkdirAll(dest, 0o750); err != nil {
+			return nil, err
+		}
+		return localDest{dir: dest}, nil
+	}
+	bucket, prefix, err := s3.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return s3Dest{client: s3.NewClient(), bucket: bucket, prefix: prefix}, nil
+}
+
+type s3Dest struct {
+	client *s3.Client
+	bucket string
+	prefix string // 비어 있거나 "/"로 끝난다
+}
+
+func (d s3Dest) put(ctx context.Context, key string, data []byte) error {
+	return d.client.Put(ctx, d.bucket, d.prefix+key, data, "application/vnd.apache.parquet")
+}
+
+// expire는 bucket의 lifecycle 규칙을 쓸 수 없는 저장소를 위한 것이다 — date= 파티션을
+// 나열하고 오래된 파티션의 객체를 하나씩 지운다.
+func (d s3Dest) expire(ctx context.Context, cutoff string) (int, error) {
+	parts, err := d.client.Prefixes(ctx, d.bucket, d.prefix+datePrefix)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, p := range parts {
+		if !expired(path.Base(p), cutoff) {
+			continue
+		}
+		keys, err := d.client.List(ctx, d.bucket, p)
+		if err != nil {
+			return deleted, err
+		}
+		for _, k := range keys {
+			if err := d.client.Delete(ctx, d.bucket, k); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type localDest struct{ dir string }
+
+// put은 data를 임시 파일에 쓴 뒤 이름을 바꿔, 읽는 쪽이 쓰다 만 파일을 보지 않게 한다.
+func (d localDest) put(_ context.Context, key string, data []byte) error {
+	p := filepath.Join(d.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (d localDest) expire(_ context.Context, cutoff string) (int, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, e := range entries {
+		if !e.IsDir() || !expired(e.Name(), cutoff) {
+			continue
+		}
+		dir := filepath.Join(d.dir, e.Name())
+		filepath.WalkDir(dir, func(_ string, de fs.DirEntry, err error) error { //nolint:errcheck
+			if err == nil && !de.IsDir() {
+				deleted++
+			}
+			return nil
+		})
+		if err := os.RemoveAll(dir); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
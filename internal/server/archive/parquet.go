@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// 외부 의존성 없는 최소 Parquet writer다. 파일 하나가 row group 하나이고, 모든 열은
+// REQUIRED(null 없음)이며 열마다 PLAIN 인코딩 data page(v1) 하나를 gzip으로 압축해 쓴다.
+// 사전(dictionary) 인코딩, 통계, 중첩 타입은 쓰지 않는다 — Spark, Athena/Trino, DuckDB,
+// pyarrow가 읽을 수 있는 가장 단순한 형태다.
+//
+// 형식: "PAR1" | 열마다 (PageHeader | 압축된 값) | FileMetaData | footer 길이(4바이트 LE) | "PAR1".
+// 메타데이터는 Thrift compact protocol로 인코딩한다 (parquet.thrift의 필드 번호).
+
+const parquetMagic = "PAR1"
+
+// parquet.thrift의 enum 값.
+const (
+	typeBoolean   int32 = 0
+	typeInt32     int32 = 1
+	typeInt64     int32 = 2
+	typeByteArray int32 = 6
+
+	repetitionRequired int32 = 0
+
+	convertedNone            int32 = -1
+	convertedUTF8            int32 = 0
+	convertedTimestampMillis int32 = 9
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	codecGzip int32 = 2
+
+	pageData int32 = 0
+)
+
+// column은 Parquet 열 하나의 정의다. typ에 맞는 값 함수 하나만 채운다.
+type column struct {
+	name string
+	typ  int32
+	conv int32
+
+	i32  func(*record) int32
+	i64  func(*record) int64
+	str  func(*record) string
+	bool func(*record) bool
+}
+
+// writeParquet은 rows를 columns 스키마의 Parquet 파일로 w에 쓴다.
+func writeParquet(w io.Writer, columns []column, rows []*record) error {
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, parquetMagic); err != nil {
+		return err
+	}
+	chunks := make([]chunkMeta, len(columns))
+	var total int64
+	for i, col := range columns {
+		meta, err := writeColumn(cw, col, rows)
+		if err != nil {
+			return err
+		}
+		chunks[i] = meta
+		total += meta.uncompressed
+	}
+
+	footer := fileMetaData(columns, chunks, int64(len(rows)), total)
+	if _, err := cw.Write(footer); err != nil {
+		return err
+	}
+	var tail [4]byte
+	binary.LittleEndian.PutUint32(tail[:], uint32(len(footer)))
+	if _, err := cw.Write(tail[:]); err != nil {
+		return err
+	}
+	_, err := io.WriteString(cw, parquetMagic)
+	return err
+}
+
+// chunkMeta는 파일에 쓴 column chunk의 위치와 크기다 (PageHeader 포함).
+type chunkMeta struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+// writeColumn은 열 하나를 data page 하나로 쓴다.
+func writeColumn(cw *countingWriter, col column, rows []*record) (chunkMeta, error) {
+	values := plainValues(col, rows)
+
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	if _, err := zw.Write(values); err != nil {
+		return chunkMeta{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return chunkMeta{}, err
+	}
+
+	var t thriftWriter
+	t.i32(1, pageData)
+	t.i32(2, int32(len(values)))
+	t.i32(3, int32(zbuf.Len()))
+	t.beginStruct(5) // DataPageHeader
+	t.i32(1, int32(len(rows)))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.endStruct()
+	header := t.finish()
+
+	meta := chunkMeta{
+		offset:       cw.n,
+		uncompressed: int64(len(header) + len(values)),
+		compressed:   int64(len(header) + zbuf.Len()),
+	}
+	if _, err := cw.Write(header); err != nil {
+		return chunkMeta{}, err
+	}
+	_, err := cw.Write(zbuf.Bytes())
+	return meta, err
+}
+
+// plainValues는 열의 값을 PLAIN 인코딩한다.
+func plainValues(col column, rows []*record) []byte {
+	var b []byte
+	switch col.typ {
+	case typeInt32:
+		for _, r := range rows {
+			b = binary.LittleEndian.AppendUint32(b, uint32(col.i32(r)))
+		}
+	case typeInt64:
+		for _, r := range rows {
+			b = binary.LittleEndian.AppendUint64(b, uint64(col.i64(r)))
+		}
+	case typeByteArray:
+		for _, r := range rows {
+			s := col.str(r)
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+			b = append(b, s...)
+		}
+	case typeBoolean:
+		// 한 바이트에 8개씩, 낮은 비트부터.
+		b = make([]byte, (len(rows)+7)/8)
+		for i, r := range rows {
+			if col.bool(r) {
+				b[i/8] |= 1 << (i % 8)
+			}
+		}
+	}
+	return b
+}
+
+// fileMetaData는 footer(FileMetaData)를 인코딩한다.
+func fileMetaData(columns []column, chunks []chunkMeta, numRows, totalBytes int64) []byte {
+	var t thriftWriter
+	t.i32(1, 1) // version
+
+	t.listBegin(2, thriftStruct, len(columns)+1) // schema: root + 열
+	t.elemBegin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.elemEnd()
+	for _, col := range columns {
+		t.elemBegin()
+		t.i32(1, col.typ)
+		t.i32(3, repetitionRequired)
+		t.binary(4, col.name)
+		if col.conv != convertedNone {
+			t.i32(6, col.conv)
+		}
+		t.elemEnd()
+	}
+
+	t.i64(3, numRows)
+
+	t.listBegin(4, thriftStruct, 1) // row_groups
+	t.elemBegin()
+	t.listBegin(1, thriftStruct, len(columns)) // columns
+	for i, col := range columns {
+		c := chunks[i]
+		t.elemBegin()
+		t.i64(2, c.offset) // file_offset
+		t.beginStruct(3)   // ColumnMetaData
+		t.i32(1, col.typ)
+		t.listBegin(2, thriftI32, 1)
+		t.elemI32(encodingPlain)
+		t.listBegin(3, thriftBinary, 1)
+		t.elemBinary(col.name)
+		t.i32(4, codecGzip)
+		t.i64(5, numRows)
+		t.i64(6, c.uncompressed)
+		t.i64(7, c.compressed)
+		t.i64(9, c.offset) // data_page_offset
+		t.endStruct()
+		t.elemEnd()
+	}
+	t.i64(2, totalBytes)
+	t.i64(3, numRows)
+	t.elemEnd()
+
+	t.binary(6, "nefi-server")
+	return t.finish()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol 타입 번호.
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter는 Parquet 메타데이터에 필요한 만큼만 구현한 Thrift compact protocol 인코더다.
+// 최상위 struct 안에서 시작하며, 필드는 번호 순으로 써야 한다.
+type thriftWriter struct {
+	buf   []byte
+	last  int16   // 현재 struct에서 마지막으로 쓴 필드 번호
+	outer []int16 // 바깥 struct들의 last
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.buf = append(t.buf, byte(d)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.elemBinary(s)
+}
+
+// beginStruct는 struct 필드를 연다. endStruct로 닫는다.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.elemEnd()
+}
+
+// listBegin은 n개짜리 list 필드를 연다. 이어서 원소 n개를 elem*로 쓴다.
+func (t *thriftWriter) listBegin(id int16, elemType byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elemType)
+	} else {
+		t.buf = append(t.buf, 0xf0|elemType)
+		t.varint(uint64(n))
+	}
+}
+
+// elemBegin은 struct 값(list 원소)을 연다. elemEnd로 닫는다.
+func (t *thriftWriter) elemBegin() {
+	t.outer = append(t.outer, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.buf = append(t.buf, 0) // stop
+	t.last = t.outer[len(t.outer)-1]
+	t.outer = t.outer[:len(t.outer)-1]
+}
+
+func (t *thriftWriter) elemI32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) elemBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// finish는 최상위 struct를 닫고 인코딩 결과를 반환한다.
+func (t *thriftWriter) finish() []byte {
+	return append(t.buf, 0)
+}
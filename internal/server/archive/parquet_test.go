@@ -0,0 +1,232 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// thriftReader는 테스트용 Thrift compact protocol 디코더다. struct는 map[필드 번호]값, list는
+// []any, 정수는 int64, binary는 string으로 읽는다.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	c := r.b[r.pos]
+	r.pos++
+	return c
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("bad varint at %d", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2: // bool true/false (필드 헤더에 값이 있다)
+		return typ == 1
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.uvarint())
+		s := string(r.b[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		h := r.byte()
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d at %d", typ, r.pos))
+}
+
+func (r *thriftReader) structure() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return fields
+		}
+		typ := h & 0x0f
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(typ)
+		last = id
+	}
+}
+
+func TestThriftWriterFieldEncoding(t *testing.T) {
+	var w thriftWriter
+	w.i32(1, -3)
+	w.i64(20, 1<<40) // 번호 차이가 15를 넘으면 번호를 따로 쓴다
+	w.beginStruct(21)
+	w.binary(2, "x")
+	w.endStruct()
+	w.listBegin(22, thriftI32, 16) // 원소가 15개 이상이면 개수를 따로 쓴다
+	for i := 0; i < 16; i++ {
+		w.elemI32(int32(i))
+	}
+	r := &thriftReader{b: w.finish()}
+	got := r.structure()
+	if r.pos != len(r.b) {
+		t.Fatalf("decoded %d of %d bytes", r.pos, len(r.b))
+	}
+	if got[1] != int64(-3) || got[20] != int64(1<<40) || got[21].(map[int16]any)[2] != "x" {
+		t.Errorf("decoded %v", got)
+	}
+	if list := got[22].([]any); len(list) != 16 || list[15] != int64(15) {
+		t.Errorf("list = %v, want 0..15", list)
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	at := time.UnixMilli(1700000000123)
+	rows := []*record{
+		{at: at, ev: &nefiv1.TraceEvent{TimestampNs: 11, Cluster: "prod", Namespace: "shop", Connection: true, RemoteIp: 0x0a000001, RemotePort: 443}},
+		{at: at.Add(time.Second), ev: &nefiv1.TraceEvent{TimestampNs: 22, Cluster: "prod", HttpMethod: "GET", HttpPath: "/orders/1", HttpStatus: 200}},
+		{at: at.Add(2 * time.Second), ev: &nefiv1.TraceEvent{TimestampNs: 33, PodName: "pod-ü"}},
+	}
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, rows); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("file does not start and end with %q", parquetMagic)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	r := &thriftReader{b: data[:len(data)-8], pos: footerStart}
+	meta := r.structure()
+	if r.pos != len(data)-8 {
+		t.Fatalf("FileMetaData ends at %d, footer length says %d", r.pos, len(data)-8)
+	}
+
+	if meta[1] != int64(1) || meta[3] != int64(len(rows)) || meta[6] != "nefi-server" {
+		t.Errorf("FileMetaData version/num_rows/created_by = %v/%v/%v", meta[1], meta[3], meta[6])
+	}
+	schema := meta[2].([]any)
+	if len(schema) != len(columns)+1 || schema[0].(map[int16]any)[5] != int64(len(columns)) {
+		t.Fatalf("schema has %d elements, want root + %d columns", len(schema), len(columns))
+	}
+	groups := meta[4].([]any)
+	if len(groups) != 1 {
+		t.Fatalf("%d row groups, want 1", len(groups))
+	}
+	group := groups[0].(map[int16]any)
+	chunks := group[1].([]any)
+	if len(chunks) != len(columns) || group[3] != int64(len(rows)) {
+		t.Fatalf("row group has %d chunks and %v rows", len(chunks), group[3])
+	}
+
+	var totalBytes int64
+	next := int64(len(parquetMagic)) // column chunk는 magic 뒤에 이어 붙는다
+	for i, col := range columns {
+		el := schema[i+1].(map[int16]any)
+		if el[1] != int64(col.typ) || el[3] != int64(repetitionRequired) || el[4] != col.name {
+			t.Errorf("schema element %d = %v, want %s", i, el, col.name)
+		}
+		if conv, ok := el[6]; (col.conv == convertedNone) == ok || ok && conv != int64(col.conv) {
+			t.Errorf("%s: converted_type = %v, want %d", col.name, conv, col.conv)
+		}
+
+		chunk := chunks[i].(map[int16]any)
+		cm := chunk[3].(map[int16]any)
+		offset := cm[9].(int64)
+		if chunk[2] != offset || offset != next {
+			t.Errorf("%s: chunk at %v (file_offset %v), want %d", col.name, offset, chunk[2], next)
+		}
+		if cm[1] != int64(col.typ) || cm[3].([]any)[0] != col.name || cm[4] != int64(codecGzip) || cm[5] != int64(len(rows)) {
+			t.Errorf("%s: ColumnMetaData = %v", col.name, cm)
+		}
+
+		pr := &thriftReader{b: data, pos: int(offset)}
+		page := pr.structure()
+		dp := page[5].(map[int16]any)
+		if page[1] != int64(pageData) || dp[1] != int64(len(rows)) || dp[2] != int64(encodingPlain) {
+			t.Errorf("%s: page header = %v", col.name, page)
+		}
+		compressed := data[pr.pos : pr.pos+int(page[3].(int64))]
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("%s: %v", col.name, err)
+		}
+		values, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("%s: %v", col.name, err)
+		}
+		headerLen := int64(pr.pos) - offset
+		if int64(len(values)) != page[2] ||
+			cm[6] != headerLen+int64(len(values)) || cm[7] != headerLen+int64(len(compressed)) {
+			t.Errorf("%s: sizes page %v/%v, chunk %v/%v; decoded %d values bytes, header %d",
+				col.name, page[2], page[3], cm[6], cm[7], len(values), headerLen)
+		}
+		if want := plainValues(col, rows); !bytes.Equal(values, want) {
+			t.Errorf("%s: values %x, want %x", col.name, values, want)
+		}
+		totalBytes += cm[6].(int64)
+		next = offset + cm[7].(int64)
+	}
+	if group[2] != totalBytes {
+		t.Errorf("row group total_byte_size = %v, want %d", group[2], totalBytes)
+	}
+	if int64(footerStart) != next {
+		t.Errorf("footer starts at %d, last chunk ends at %d", footerStart, next)
+	}
+}
+
+func TestPlainValues(t *testing.T) {
+	rows := []*record{
+		{ev: &nefiv1.TraceEvent{Namespace: "ab", Connection: true, Direction: 1}},
+		{ev: &nefiv1.TraceEvent{Namespace: "", Direction: 2}},
+		{ev: &nefiv1.TraceEvent{Namespace: "c", Connection: true}},
+	}
+	byName := make(map[string]column)
+	for _, c := range columns {
+		byName[c.name] = c
+	}
+	tests := []struct {
+		column string
+		want   []byte
+	}{
+		{"namespace", []byte{2, 0, 0, 0, 'a', 'b', 0, 0, 0, 0, 1, 0, 0, 0, 'c'}},
+		{"connection", []byte{0b101}},
+		{"direction", []byte{1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := plainValues(byName[tt.column], rows); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: %x, want %x", tt.column, got, tt.want)
+		}
+	}
+}
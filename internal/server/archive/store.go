@@ -0,0 +1,40 @@
+package archive
+
+import (
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/store"
+)
+
+// closeTimeout은 종료 시 남은 이벤트를 내보내는 데 쓰는 최대 시간이다.
+const closeTimeout = 30 * time.Second
+
+// Wrap은 s에 저장하는 이벤트를 a에도 보관하는 Store를 반환한다. 반환한 Store를 Close하면
+// s를 닫은 뒤 a의 남은 이벤트를 내보낸다.
+func Wrap(s store.Store, a *Archiver) store.Store {
+	return &archivedStore{Store: s, a: a}
+}
+
+type archivedStore struct {
+	store.Store
+	a *Archiver
+}
+
+func (s *archivedStore) Add(event *nefiv1.TraceEvent) {
+	s.Store.Add(event)
+	s.a.Add(event)
+}
+
+// Health는 감싼 store의 상태를 그대로 보고한다.
+func (s *archivedStore) Health() error {
+	if hr, ok := s.Store.(store.HealthReporter); ok {
+		return hr.Health()
+	}
+	return nil
+}
+
+func (s *archivedStore) Close() {
+	s.Store.Close()
+	s.a.Close(closeTimeout)
+}
@@ -0,0 +1,208 @@
+// Package audit은 수집 세션의 감사 기록을 남긴다 — 어느 agent(신원, 노드, tenant)가 언제
+// 연결해 얼마나 썼고, 어떤 쓰기가 거부됐는지.
+//
+// collector가 스트림 시작/종료, unary batch, 거부(수락 속도 제한, 스키마 비호환, 수집 속도
+// 제한, 큐 포화, 잘못된 batch)를, gRPC TLS credentials가 인증 실패(client 인증서 검증 실패 등)를
+// 기록한다. 기록은 메모리 ring buffer에 보관해 GET /api/v1/admin/audit로 조회하고, 출력
+// 스트림(--audit-log)이 있으면 한 줄에 JSON 하나씩 그대로 써서 로그 수집기로 장기 보관한다.
+// ring buffer는 server 재시작 시 사라지므로 보안 검토에 쓰려면 출력 스트림을 켜야 한다.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCapacity는 메모리에 보관하는 기록 수 기본값이다.
+const DefaultCapacity = 10000
+
+// 기록 종류.
+const (
+	KindSessionStart = "session_start" // 스트림 수락
+	KindSessionEnd   = "session_end"   // 스트림 종료 (받은 batch/이벤트 수, 종료 원인)
+	KindBatch        = "batch"         // unary batch 수락 (SendBatch, POST /api/v1/ingest)
+	KindRejected     = "rejected"      // 스트림 또는 batch 거부
+	KindAuthFailed   = "auth_failed"   // TLS handshake 실패 (client 인증서 없음, 검증 실패)
+)
+
+// Record는 감사 기록 하나다.
+type Record struct {
+	ID       uint64    `json:"id"`
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	Session  uint64    `json:"session,omitempty"` // 같은 스트림의 session_start/rejected/session_end가 공유
+	RPC      string    `json:"rpc,omitempty"`
+	Addr     string    `json:"addr,omitempty"`     // peer 주소
+	Identity string    `json:"identity,omitempty"` // mTLS 신원
+	Tenant   string    `json:"tenant,omitempty"`
+	Node     string    `json:"node,omitempty"`
+	Cluster  string    `json:"cluster,omitempty"`
+	Version  string    `json:"version,omitempty"`  // agent 버전
+	Producer string    `json:"producer,omitempty"` // batch의 producer_id
+
+	Batches    uint64  `json:"batches,omitempty"`
+	Events     uint64  `json:"events,omitempty"`
+	Duplicates uint64  `json:"duplicates,omitempty"` // 이미 저장돼 건너뛴 batch 수
+	Duration   float64 `json:"duration_sec,omitempty"`
+
+	Code  string `json:"code,omitempty"` // 거부/종료의 gRPC status code
+	Error string `json:"error,omitempty"`
+}
+
+// Log는 감사 기록 저장소다. 모든 메서드는 동시에 호출할 수 있다.
+type Log struct {
+	session atomic.Uint64
+
+	mu       sync.Mutex
+	ring     []Record
+	next     int // 다음 쓰기 위치
+	count    int
+	lastID   uint64
+	enc      *json.Encoder // nil이면 출력 스트림 없음
+	writeErr bool          // 출력 실패를 이미 로그로 남겼는지
+}
+
+// New는 capacity개를 보관하고 w(nil 가능)에 NDJSON으로 쓰는 Log를 반환한다.
+func New(capacity int, w io.Writer) *Log {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	l := &Log{ring: make([]Record, capacity)}
+	if w != nil {
+		l.enc = json.NewEncoder(w)
+	}
+	return l
+}
+
+// Session은 새 스트림 세션 번호를 반환한다.
+func (l *Log) Session() uint64 {
+	return l.session.Add(1)
+}
+
+// Add는 r에 번호와 시각(비어 있으면)을 붙여 기록한다.
+func (l *Log) Add(r Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	r.ID = l.lastID
+	l.ring[l.next] = r
+	l.next = (l.next + 1) % len(l.ring)
+	if l.count < len(l.ring) {
+		l.count++
+	}
+	if l.enc == nil {
+		return
+	}
+	if err := l.enc.Encode(r); err != nil {
+		if !l.writeErr {
+			log.Printf("[audit] write failed, records are kept in memory only: %v", err)
+			l.writeErr = true
+		}
+	} else {
+		l.writeErr = false
+	}
+}
+
+// Query는 감사 기록 조회 조건이다. 빈 필드는 조건 없음이다.
+type Query struct {
+	Kind     string
+	Identity string
+	Tenant   string
+	Node     string
+	Session  uint64
+	Since    time.Time
+	AfterID  uint64 // 이 번호 이후 기록만 (폴링)
+	Limit    int    // 0 = 100
+}
+
+// maxLimit는 한 번에 반환하는 최대 기록 수다.
+const maxLimit = 1000
+
+// Records는 q에 맞는 기록 중 최신 q.Limit개를 오래된 것부터 반환한다.
+func (l *Log) Records(q Query) []Record {
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+	q.Limit = min(q.Limit, maxLimit)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var result []Record
+	// 최신 기록부터 거꾸로 훑고 마지막에 뒤집는다.
+	for i := 0; i < l.count && len(result) < q.Limit; i++ {
+		r := l.ring[(l.next-1-i+len(l.ring))%len(l.ring)]
+		if r.ID <= q.AfterID || (!q.Since.IsZero() && r.Time.Before(q.Since)) {
+			break
+		}
+		if q.match(r) {
+			result = append(result, r)
+		}
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+func (q Query) match(r Record) bool {
+	return (q.Kind == "" || r.Kind == q.Kind) &&
+		(q.Identity == "" || r.Identity == q.Identity) &&
+		(q.Tenant == "" || r.Tenant == q.Tenant) &&
+		(q.Node == "" || r.Node == q.Node) &&
+		(q.Session == 0 || r.Session == q.Session)
+}
+
+// Handler는 GET /api/v1/admin/audit?kind=&identity=&tenant=&node=&session=&since=&after_id=&limit=
+// 핸들러다. since는 RFC 3339 시각 또는 현재부터의 기간(15m)이다. 모든 tenant의 기록을
+// 반환하므로 격리 모드에서는 operator만 호출할 수 있게 감싸야 한다 (tenant.Policy.OperatorOnly).
+func (l *Log) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.URL.Query()
+		q := Query{
+			Kind:     v.Get("kind"),
+			Identity: v.Get("identity"),
+			Tenant:   v.Get("tenant"),
+			Node:     v.Get("node"),
+		}
+		var err error
+		if s := v.Get("session"); s != "" {
+			q.Session, err = strconv.ParseUint(s, 10, 64)
+		}
+		if s := v.Get("after_id"); s != "" && err == nil {
+			q.AfterID, err = strconv.ParseUint(s, 10, 64)
+		}
+		if s := v.Get("limit"); s != "" && err == nil {
+			q.Limit, err = strconv.Atoi(s)
+		}
+		if s := v.Get("since"); s != "" && err == nil {
+			q.Since, err = parseSince(s)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}) //nolint:errcheck
+			return
+		}
+		records := l.Records(q)
+		if records == nil {
+			records = []Record{}
+		}
+		json.NewEncoder(w).Encode(map[string][]Record{"records": records}) //nolint:errcheck
+	})
+}
+
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"errors"
+	"io"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// Credentials는 c의 TLS handshake 실패를 KindAuthFailed로 기록하는 server credentials다.
+// handshake 전에 끊은 연결(TCP health check, port scan)은 기록하지 않는다.
+func (l *Log) Credentials(c credentials.TransportCredentials) credentials.TransportCredentials {
+	return &auditedCreds{TransportCredentials: c, log: l}
+}
+
+type auditedCreds struct {
+	credentials.TransportCredentials
+	log *Log
+}
+
+func (c *auditedCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	out, info, err := c.TransportCredentials.ServerHandshake(conn)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.log.Add(Record{Kind: KindAuthFailed, Addr: conn.RemoteAddr().String(), Error: err.Error()})
+	}
+	return out, info, err
+}
+
+func (c *auditedCreds) Clone() credentials.TransportCredentials {
+	return &auditedCreds{TransportCredentials: c.TransportCredentials.Clone(), log: c.log}
+}
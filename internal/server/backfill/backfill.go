@@ -0,0 +1,297 @@
+// Package backfill은 파일이나 S3에 저장된 이벤트 덤프를 nefi-server로 다시 보낸다
+// (nefi-server backfill).
+//
+// 장애로 server가 받지 못한 구간을 agent의 NDJSON 파일(--exporter=file)로 채우거나, 기록해 둔
+// 운영 트래픽으로 저장소 부하 테스트를 할 때 쓴다. 이벤트는 server의 SendBatch RPC로 보내므로
+// agent batch와 같은 경로(검증, 수집 속도 제한, 중복 제거, 저장 큐, 보강)를 거친다.
+//
+// 입력 (인자 하나가 source 하나, 디렉터리와 S3 prefix는 그 안의 파일 모두):
+//
+//	경로, 디렉터리, "-"         — 로컬 파일 (stdin)
+//	http(s)://...              — URL (presigned S3 URL 등)
+//	s3://bucket/key            — S3 객체. prefix가 "/"로 끝나면 그 아래 객체 모두
+//
+// S3는 AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY(, AWS_SESSION_TOKEN)와 AWS_REGION으로 서명한다
+// (자격 증명이 없으면 서명하지 않는다 — 공개 bucket). AWS_ENDPOINT_URL이 있으면 그 주소에
+// path-style로 요청한다 (MinIO 등).
+//
+// 형식 (".gz"로 끝나면 gzip을 푼다):
+//
+//	NDJSON (기본)   — 줄마다 TraceEvent(agent --exporter=file 출력) 또는 EventBatch(POST /api/v1/ingest 형식)
+//	.pb, .binpb     — 길이(varint)가 앞에 붙은 EventBatch의 연속 (protodelim)
+//
+// 재개: batch마다 producer_id "backfill/<run-id>/<source>"와 1부터 증가하는 seq를 붙인다. 중간에
+// 실패한 backfill을 같은 run-id로 다시 실행하면 server가 이미 저장한 batch는 저장하지 않는다
+// (server가 그 사이 재시작하지 않은 경우). 같은 덤프를 부하 테스트로 여러 번 보내려면 Repeat을 쓴다.
+//
+// 주의: 실시간 집계(aggregator)는 이벤트를 받은 시각 기준으로 세므로, backfill한 이벤트는
+// /api/v1/stats에서 과거가 아니라 지금의 트래픽으로 보인다.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/version"
+)
+
+// 기본값.
+const (
+	DefaultBatchSize = 1000
+	maxBatchSize     = 10000 // collector의 batch당 이벤트 상한
+	DefaultRunID     = "default"
+	DefaultNode      = "backfill"
+	minRetry         = 100 * time.Millisecond // server가 안내한 재시도 대기의 하한
+)
+
+// Config는 backfill 설정이다.
+type Config struct {
+	Addr  string                           // nefi-server gRPC 주소
+	Creds credentials.TransportCredentials // nil이면 평문
+
+	BatchSize int     // batch 하나의 최대 이벤트 수 (0 = DefaultBatchSize)
+	Rate      float64 // 초당 보낼 이벤트 수 (0 = server가 받는 만큼)
+	Parallel  int     // 동시에 보내는 source 수 (0 = 1)
+	Repeat    int     // source를 몇 번 보낼지 (0 = 1, 부하 테스트용)
+	Format    string  // FormatAuto(기본), FormatNDJSON, FormatProto
+
+	RunID   string // producer_id에 들어가는 실행 이름 (0 값 = DefaultRunID)
+	Node    string // x-nefi-node-name (0 값 = DefaultNode). server의 노드별 수집 속도 제한 키다
+	Cluster string // x-nefi-cluster
+	Tenant  string // x-nefi-tenant (mTLS 인증서의 O가 있으면 server는 그것을 쓴다)
+}
+
+// Stats는 backfill 결과다.
+type Stats struct {
+	Sources int    // 끝까지 보낸 source 수
+	Failed  int    // 중간에 실패한 source 수
+	Batches uint64 // server가 받은 batch 수 (이미 저장돼 건너뛴 batch 포함)
+	Events  uint64
+}
+
+// Run은 sources의 이벤트를 cfg.Addr로 보낸다. 실패한 source는 로그를 남기고 다음 source로
+// 넘어가며, 하나라도 실패하면 에러를 반환한다. ctx가 취소되면 보내던 batch까지만 보낸다.
+func Run(ctx context.Context, cfg Config, sources []string) (Stats, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchSize > maxBatchSize {
+		return Stats{}, fmt.Errorf("batch size %d exceeds the server limit of %d events", cfg.BatchSize, maxBatchSize)
+	}
+	if cfg.Parallel <= 0 {
+		cfg.Parallel = 1
+	}
+	if cfg.Repeat <= 0 {
+		cfg.Repeat = 1
+	}
+	if cfg.RunID == "" {
+		cfg.RunID = DefaultRunID
+	}
+	if cfg.Node == "" {
+		cfg.Node = DefaultNode
+	}
+	if cfg.Creds == nil {
+		return Stats{}, errors.New("backfill: no transport credentials")
+	}
+
+	conn, err := grpc.NewClient(cfg.Addr, grpc.WithTransportCredentials(cfg.Creds))
+	if err != nil {
+		return Stats{}, err
+	}
+	defer conn.Close()
+
+	info := version.Get()
+	md := metadata.Pairs(
+		version.MDNodeName, cfg.Node,
+		version.MDVersion, info.Version,
+		version.MDGitCommit, info.GitCommit,
+		version.MDBuildDate, info.BuildDate,
+		version.MDSchemaVersion, strconv.Itoa(info.SchemaVersion),
+	)
+	if cfg.Cluster != "" {
+		md.Append(version.MDCluster, cfg.Cluster)
+	}
+	if cfg.Tenant != "" {
+		md.Append(version.MDTenant, cfg.Tenant)
+	}
+
+	r := &runner{
+		cfg:    cfg,
+		client: nefiv1.NewNefiCollectorClient(conn),
+		md:     md,
+	}
+	if cfg.Rate > 0 {
+		r.limiter = rate.NewLimiter(rate.Limit(cfg.Rate), cfg.BatchSize)
+	}
+
+	// source 인자를 파일 단위로 펼친다 (디렉터리, S3 prefix).
+	var files []string
+	for _, s := range sources {
+		expanded, err := expand(ctx, s)
+		if err != nil {
+			return Stats{}, fmt.Errorf("%s: %w", s, err)
+		}
+		files = append(files, expanded...)
+	}
+
+	type job struct {
+		file string
+		pass int
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for range cfg.Parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := r.send(ctx, j.file, j.pass); err != nil {
+					log.Printf("[backfill] %s: %v", j.file, err)
+					r.failed.Add(1)
+					continue
+				}
+				r.sources.Add(1)
+			}
+		}()
+	}
+feed:
+	for pass := 1; pass <= cfg.Repeat; pass++ {
+		for _, f := range files {
+			select {
+			case jobs <- job{file: f, pass: pass}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	st := Stats{
+		Sources: int(r.sources.Load()),
+		Failed:  int(r.failed.Load()),
+		Batches: r.batches.Load(),
+		Events:  r.events.Load(),
+	}
+	if st.Failed > 0 {
+		return st, fmt.Errorf("%d of %d sources failed", st.Failed, st.Failed+st.Sources)
+	}
+	return st, ctx.Err()
+}
+
+type runner struct {
+	cfg     Config
+	client  nefiv1.NefiCollectorClient
+	md      metadata.MD
+	limiter *rate.Limiter
+
+	sources, failed atomic.Int64
+	batches, events atomic.Uint64
+}
+
+// send는 source 하나를 pass번째로 보낸다.
+func (r *runner) send(ctx context.Context, file string, pass int) error {
+	rc, err := open(ctx, file)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	producer := "backfill/" + r.cfg.RunID + "/" + file
+	if r.cfg.Repeat > 1 {
+		producer += "#" + strconv.Itoa(pass)
+	}
+	var (
+		seq     uint64
+		pending []*nefiv1.TraceEvent
+		sent    uint64
+	)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		seq++
+		batch := &nefiv1.EventBatch{
+			Events:        pending,
+			Seq:           seq,
+			ProducerId:    producer,
+			SchemaVersion: version.SchemaVersion,
+		}
+		if err := r.sendBatch(ctx, batch); err != nil {
+			return fmt.Errorf("batch %d: %w", seq, err)
+		}
+		sent += uint64(len(pending))
+		pending = nil
+		return nil
+	}
+	err = decode(rc, format(r.cfg.Format, file), func(ev *nefiv1.TraceEvent) error {
+		pending = append(pending, ev)
+		if len(pending) >= r.cfg.BatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[backfill] %s: sent %d events in %d batches", file, sent, seq)
+	return nil
+}
+
+// sendBatch는 batch를 보낸다. server가 속도 제한이나 큐 포화로 거부하면 안내받은 시간 뒤에
+// 같은 batch를 다시 보낸다.
+func (r *runner) sendBatch(ctx context.Context, batch *nefiv1.EventBatch) error {
+	n := len(batch.GetEvents())
+	if r.limiter != nil {
+		if err := r.limiter.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, r.md)
+	for {
+		_, err := r.client.SendBatch(ctx, batch)
+		if err == nil {
+			r.batches.Add(1)
+			r.events.Add(uint64(n))
+			return nil
+		}
+		// 재시도는 server가 RetryInfo로 안내한 거부(수집 속도 제한, 큐 포화, 수락 속도 제한)만
+		// 한다. 연결 실패(주소, TLS)는 같은 run-id로 다시 실행하면 이어서 보낸다.
+		st := status.Convert(err)
+		if c := st.Code(); c != codes.ResourceExhausted && c != codes.Unavailable {
+			return err
+		}
+		delay := time.Duration(0)
+		for _, d := range st.Details() {
+			if ri, ok := d.(*errdetails.RetryInfo); ok {
+				delay = max(ri.GetRetryDelay().AsDuration(), minRetry)
+			}
+		}
+		if delay == 0 {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package backfill
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/batchdict"
+)
+
+// 덤프 형식 (Config.Format, --format).
+const (
+	FormatAuto   = "auto"   // 파일 확장자로 정한다 (.pb/.binpb = proto, 그 외 NDJSON)
+	FormatNDJSON = "ndjson" // 줄마다 TraceEvent 또는 EventBatch (protojson)
+	FormatProto  = "proto"  // 길이가 앞에 붙은 EventBatch의 연속 (protodelim)
+)
+
+// maxLine은 NDJSON 한 줄(또는 proto batch 하나)의 최대 크기다.
+const maxLine = 32 << 20
+
+// format은 name에 쓸 덤프 형식이다.
+func format(want, name string) string {
+	if want != "" && want != FormatAuto {
+		return want
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	if strings.HasSuffix(name, ".pb") || strings.HasSuffix(name, ".binpb") {
+		return FormatProto
+	}
+	return FormatNDJSON
+}
+
+// ValidFormat은 --format 값이 올바른지 검사한다.
+func ValidFormat(f string) error {
+	switch f {
+	case "", FormatAuto, FormatNDJSON, FormatProto:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (want %s, %s or %s)", f, FormatAuto, FormatNDJSON, FormatProto)
+}
+
+// decode는 r의 이벤트를 순서대로 fn에 넘긴다. fn이 에러를 반환하면 멈춘다.
+func decode(r io.Reader, format string, fn func(*nefiv1.TraceEvent) error) error {
+	if format == FormatProto {
+		return decodeProto(r, fn)
+	}
+	return decodeNDJSON(r, fn)
+}
+
+func decodeNDJSON(r io.Reader, fn func(*nefiv1.TraceEvent) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxLine)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		// agent --exporter=file은 이벤트를, POST /api/v1/ingest 형식은 batch를 한 줄에 쓴다.
+		ev := &nefiv1.TraceEvent{}
+		evErr := protojson.Unmarshal(b, ev)
+		if evErr == nil {
+			if err := fn(ev); err != nil {
+				return err
+			}
+			continue
+		}
+		batch := &nefiv1.EventBatch{}
+		if err := protojson.Unmarshal(b, batch); err != nil {
+			return fmt.Errorf("line %d: not a TraceEvent (%v) or an EventBatch (%v)", line, evErr, err)
+		}
+		if err := batchEvents(batch, fn); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return sc.Err()
+}
+
+func decodeProto(r io.Reader, fn func(*nefiv1.TraceEvent) error) error {
+	br := bufio.NewReader(r)
+	opts := protodelim.UnmarshalOptions{MaxSize: maxLine}
+	for n := 1; ; n++ {
+		batch := &nefiv1.EventBatch{}
+		if err := opts.UnmarshalFrom(br, batch); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("batch %d: %w", n, err)
+		}
+		if err := batchEvents(batch, fn); err != nil {
+			return fmt.Errorf("batch %d: %w", n, err)
+		}
+	}
+}
+
+// batchEvents는 덤프에 있던 batch의 이벤트를 넘긴다. 사전 인코딩된 batch는 먼저 푼다.
+// seq와 producer_id는 버리고 backfill의 것을 새로 붙인다.
+func batchEvents(batch *nefiv1.EventBatch, fn func(*nefiv1.TraceEvent) error) error {
+	if err := batchdict.Decode(batch); err != nil {
+		return err
+	}
+	for _, ev := range batch.GetEvents() {
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
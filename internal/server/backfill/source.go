@@ -0,0 +1,124 @@
+package backfill
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gihongjo/nefi/internal/s3"
+)
+
+// expand는 source 인자 하나를 보낼 파일 목록으로 펼친다. 디렉터리는 그 아래 파일 모두(숨김
+// 파일 제외), "/"로 끝나는 S3 prefix는 그 아래 객체 모두를 이름순으로 반환한다.
+func expand(ctx context.Context, src string) ([]string, error) {
+	switch {
+	case src == "-", isURL(src):
+		return []string{src}, nil
+	case strings.HasPrefix(src, "s3://"):
+		bucket, key, err := s3.Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" && !strings.HasSuffix(key, "/") {
+			return []string{src}, nil
+		}
+		keys, err := s3.NewClient().List(ctx, bucket, key)
+		if err != nil {
+			return nil, err
+		}
+		files := make([]string, len(keys))
+		for i, k := range keys {
+			files[i] = "s3://" + bucket + "/" + k
+		}
+		return files, nil
+	}
+
+	fi, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{src}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != src {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// open은 파일, URL 또는 S3 객체를 연다. 이름이 ".gz"로 끝나면 gzip을 풀어 읽는다.
+func open(ctx context.Context, name string) (io.ReadCloser, error) {
+	var (
+		rc  io.ReadCloser
+		err error
+	)
+	switch {
+	case name == "-":
+		rc = io.NopCloser(os.Stdin)
+	case isURL(name):
+		rc, err = get(ctx, name)
+	case strings.HasPrefix(name, "s3://"):
+		bucket, key, perr := s3.Parse(name)
+		if perr != nil {
+			return nil, perr
+		}
+		rc, err = s3.NewClient().Get(ctx, bucket, key)
+	default:
+		rc, err = os.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".gz") {
+		return rc, nil
+	}
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return gzipReadCloser{zr, rc}, nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	src io.Closer
+}
+
+func (g gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.src.Close()
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// get은 URL을 GET한다. 2xx가 아니면 에러다.
+func get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s3.Do(req)
+}
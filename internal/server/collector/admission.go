@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// stormWindow는 reconnect storm 감지에 사용하는 최근 연결 시도 집계 구간이다.
+	stormWindow = 10 * time.Second
+	// minRetryDelay는 throttle된 agent에게 안내하는 최소 재시도 대기 시간이다.
+	minRetryDelay = time.Second
+)
+
+// admission은 새 스트림의 수락 속도를 제한한다.
+//
+// server 재시작 직후 모든 agent가 동시에 재연결하면(thundering herd) 초기 처리 부하가 몰린다.
+// token bucket으로 초당 수락 수를 제한하고, 초과분은 RetryInfo와 함께 Unavailable로 거부해
+// agent가 서로 다른 시점에 다시 시도하도록 분산시킨다.
+type admission struct {
+	limiter *rate.Limiter // nil이면 제한 없음
+
+	accepted  atomic.Uint64
+	throttled atomic.Uint64
+
+	mu       sync.Mutex
+	attempts []time.Time // 최근 stormWindow 내 연결 시도 시각
+}
+
+func newAdmission(perSec float64, burst int) *admission {
+	a := &admission{}
+	if perSec > 0 {
+		if burst < 1 {
+			burst = 1
+		}
+		a.limiter = rate.NewLimiter(rate.Limit(perSec), burst)
+	}
+	return a
+}
+
+// admit은 새 스트림을 수락할지 판단한다. 거부 시 agent에 반환할 에러를 돌려준다.
+func (a *admission) admit() error {
+	a.record()
+	if a.limiter == nil {
+		a.accepted.Add(1)
+		return nil
+	}
+	r := a.limiter.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		a.accepted.Add(1)
+		return nil
+	}
+	// 토큰을 소비하지 않고 반환 — 대기 시간만 agent에게 힌트로 준다.
+	r.Cancel()
+	a.throttled.Add(1)
+	if delay < minRetryDelay {
+		delay = minRetryDelay
+	}
+	st := status.New(codes.Unavailable, "server is ramping up agent connections")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// record는 연결 시도 시각을 기록하고 stormWindow보다 오래된 항목을 버린다.
+func (a *admission) record() {
+	now := time.Now()
+	a.mu.Lock()
+	a.attempts = append(a.attempts, now)
+	a.pruneLocked(now)
+	a.mu.Unlock()
+}
+
+func (a *admission) pruneLocked(now time.Time) {
+	cutoff := now.Add(-stormWindow)
+	i := 0
+	for i < len(a.attempts) && a.attempts[i].Before(cutoff) {
+		i++
+	}
+	a.attempts = a.attempts[i:]
+}
+
+// attemptRate는 최근 stormWindow 동안의 초당 연결 시도 수다.
+func (a *admission) attemptRate() float64 {
+	a.mu.Lock()
+	a.pruneLocked(time.Now())
+	n := len(a.attempts)
+	a.mu.Unlock()
+	return float64(n) / stormWindow.Seconds()
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"io"
+	"time"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"github.com/gihongjo/nefi/internal/server/audit"
+)
+
+// auditRecord는 info가 rpc로 보낸 요청의 감사 기록 틀이다.
+func auditRecord(kind string, rpc rpcKind, info agents.Info) audit.Record {
+	return audit.Record{
+		Kind:     kind,
+		RPC:      rpcKindNames[rpc],
+		Addr:     info.Addr,
+		Identity: info.Identity,
+		Tenant:   info.Tenant,
+		Node:     info.NodeName,
+		Cluster:  info.Cluster,
+		Version:  info.Version,
+	}
+}
+
+// withError는 r에 거부/종료 원인을 채운다. 정상 종료(io.EOF, nil)는 채우지 않는다.
+func withError(r audit.Record, err error) audit.Record {
+	if err == nil || err == io.EOF {
+		return r
+	}
+	st := status.Convert(err)
+	r.Code = st.Code().String()
+	r.Error = st.Message()
+	return r
+}
+
+// auditReject는 수락 단계(수락 속도 제한, 스키마 비호환, 잘못된 batch)에서 거부한 요청을 기록한다.
+func (s *Service) auditReject(rpc rpcKind, info agents.Info, err error) {
+	s.audit.Add(withError(auditRecord(audit.KindRejected, rpc, info), err))
+}
+
+// auditSession은 스트림 하나의 감사 기록이다. 스트림 고루틴에서만 쓴다.
+type auditSession struct {
+	log     *audit.Log
+	base    audit.Record
+	started time.Time
+
+	batches, events, duplicates uint64
+}
+
+// startSession은 수락한 스트림의 session_start를 기록한다.
+func (s *Service) startSession(rpc rpcKind, info agents.Info) *auditSession {
+	a := &auditSession{log: s.audit, base: auditRecord("", rpc, info), started: time.Now()}
+	a.base.Session = s.audit.Session()
+	r := a.base
+	r.Kind = audit.KindSessionStart
+	a.log.Add(r)
+	return a
+}
+
+// received는 스트림이 저장 큐에 넣은 batch 하나를 센다.
+func (a *auditSession) received(events int) {
+	a.batches++
+	a.events += uint64(events)
+}
+
+// end는 session_end를 기록한다. err는 스트림을 끝낸 에러다 (nil, io.EOF = agent가 정상 종료).
+func (a *auditSession) end(err error) {
+	r := withError(a.base, err)
+	r.Kind = audit.KindSessionEnd
+	r.Batches, r.Events, r.Duplicates = a.batches, a.events, a.duplicates
+	r.Duration = time.Since(a.started).Seconds()
+	a.log.Add(r)
+}
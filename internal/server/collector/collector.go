@@ -8,53 +8,481 @@
 //   요청 이벤트(method/path 있음, status 없음) → connTracker에 {pod, pid, fd} → {method, path} 저장
 //   응답 이벤트(status 있음, method 없음)      → connTracker에서 꺼내 method/path 채움
 //   이를 통해 응답 이벤트에도 엔드포인트 정보가 기록된다.
+//
+// 확인 응답(ack) 전송:
+//   NefiCollector.StreamBatches: agent가 seq를 붙인 EventBatch를 양방향 스트림으로 보내면
+//   batch를 저장 큐에 넣은 뒤 같은 seq의 BatchAck를 돌려준다. agent는 ack되지 않은 batch를
+//   보관했다가 재연결 시 다시 보내므로, 연결이 끊겨도 이벤트를 잃지 않는다.
+//   SendEvents는 구버전 agent와 demo generator를 위해 남겨 둔다.
+//
+// 버전 협상:
+//   NefiCollector.Negotiate: agent가 스트림을 열기 전에 자신의 스키마 버전과 기능을 보내면
+//   양쪽이 모두 지원하는 스키마(낮은 쪽)와 기능(batch ack, gzip 압축, 문자열 사전)을 돌려준다.
+//   agent는 합의된 스키마를 스트림 메타데이터와 EventBatch.schema_version에 싣는다.
+//   사전 인코딩된 batch(EventBatch.strings)는 ingest 전에 batchdict.Decode로 복원한다.
+//
+// Unary 전송:
+//   NefiCollector.SendBatch: 스트리밍 없이 이벤트 묶음을 한 번에 push하는 외부 producer용.
+//   long-lived stream을 끊는 proxy 뒤의 agent도 스트림이 계속 실패하면 이 경로로 보낸다.
+//   메타데이터 해석, 스키마 호환성 검사, 수락 속도 제한(admission), HTTP 보강과 저장은
+//   SendEvents와 같은 경로(accept, ingest)를 쓴다. 호출마다 admission 토큰을 하나 쓰므로
+//   producer는 호출 빈도 대신 batch 크기를 늘려야 한다.
+//   POST /api/v1/ingest는 같은 EventBatch를 JSON/NDJSON으로 받아 SendBatch로 넘긴다 (http.go, --http-ingest일 때만).
+//
+// 저장 파이프라인:
+//   Recv 고루틴은 batch를 bounded 큐에 넣기만 하고, worker pool이 보강, 검증, 저장한다
+//   (pipeline.go). 저장이 느려도 수신과 ack가 멈추지 않는다. 큐는 worker마다 있고 한 노드의
+//   batch는 항상 같은 worker가 순서대로 처리한다. 큐가 가득 차면 수집 속도 제한과 같이
+//   ResourceExhausted로 거부한다. 종료 시 Close가 큐에 남은 batch를 모두 저장하므로,
+//   ack된 batch는 프로세스 메모리 store에 저장된 batch와 같은 보장을 받는다.
+//
+// 중복 제거:
+//   batch는 (producer_id, seq)로 식별한다. agent는 프로세스마다 새 producer_id를 만들고 seq를
+//   1부터 올린다. producer별로 최근 seq의 저장 여부를 기억해, 이미 저장한 batch는 ack가 유실돼
+//   다시 보낸 것으로 보고 저장하지 않은 채 ack한다 (dedup.go). 순서가 섞여 도착한 batch는
+//   그대로 저장한다. 상태는 메모리에만 있어 server 재시작이나
+//   replica 이동 직후의 재전송은 다시 저장될 수 있다. producer_id가 없는 batch는 그대로 저장한다.
+//
+// 구버전 agent:
+//   rolling upgrade 중에는 server보다 한두 스키마 오래된 agent가 함께 보낸다
+//   (version.MinAgentSchemaVersion). 저장 전에 batch를 인코딩 스키마(batch의 schema_version,
+//   없으면 stream 메타데이터)에서 현재 스키마로 올린다: 구버전 agent가 보내지 않는 필드 중
+//   server가 알 수 있는 것(노드 이름, conn_id, workload, remote_kind)을 채운다 (upgrade.go).
+//
+// 검증:
+//   저장 전에 명백히 잘못된 이벤트(타임스탬프가 0이거나 같은 batch의 중앙값에서 1시간 넘게 떨어짐,
+//   범위 밖 HTTP status, path 없는 method, 비정상적인 메시지 크기)는 버리고, 너무 긴 path와
+//   payload는 자르고, 1시간 넘는 latency는 지운다 (validate.go). batch는 그대로 ack한다.
+//
+// 보강 stage:
+//   검증을 통과한 이벤트는 저장 직전 Config.Enrichers(enrich 패키지)를 순서대로 거친다.
+//   내장 stage는 클러스터 이름, external remote 이름(CIDR/클라우드 대역), 국가 label을 붙이고,
+//   조직별 메타데이터는 enrich.Enricher를 구현해 추가한다. stage가 버린 이벤트는 세기만 한다.
+//
+// 수집 속도 제한:
+//   노드별, 전체 초당 이벤트 수를 token bucket으로 제한한다 (quota). 초과한 batch는 저장하지
+//   않고 ResourceExhausted와 RetryInfo로 거부하며, 스트림이면 ack 없이 닫는다. agent는 안내받은
+//   시간 뒤에 ack받지 못한 batch를 다시 보내므로, 폭주하는 노드 하나가 store를 독점하지 못한다.
+//
+// 저장 샘플링:
+//   연결 관측 이벤트가 서비스(또는 노드)별 초당 상한(Config.ConnSampleRate)을 넘으면 넘는
+//   만큼 확률 샘플링해 저장하지 않는다 (sample.go). 트래픽 폭주 때 store가 연결 관측으로
+//   가득 차 HTTP 이벤트를 밀어내지 않게 한다. 빠진 이벤트도 store.Publish로 aggregator와
+//   WebSocket 구독자에게 넘기므로 서비스별 연결 수 집계는 전체 수 그대로다.
+//
+// 멈춘 스트림:
+//   열린 스트림마다 마지막 수신 시각을 추적해 registry의 stale 기준보다 오래 조용한 스트림을
+//   stalled로 센다 (nefi_collector_streams). Config.StreamIdleTimeout 동안 아무것도 받지 못한
+//   스트림은 Unavailable로 닫아, 멈춘 agent의 스트림이 고루틴과 registry 항목을 붙잡고
+//   쌓이지 않게 한다 (streams.go).
+//
+// 부하 보고:
+//   agent는 몇 초마다 batch 하나에 LoadReport(큐 점유, drop, 메모리 보호 유실)를 싣는다.
+//   노드별 마지막 보고를 agents.Registry에 기록해 /api/v1/agents로 보여준다.
+//
+// agent 신원 (mTLS):
+//   server가 client 인증서를 검증하면(--grpc-tls-client-ca) 인증서의 URI SAN, DNS SAN 또는
+//   CN을 agent 신원으로 삼아 agents.Info.Identity와 모든 이벤트의 agent_identity에 기록한다.
+//   agent가 보낸 agent_identity는 덮어쓰므로, 인증서 없는 writer는 연결할 수 없고 인증서가
+//   있는 writer도 다른 agent를 사칭한 이벤트를 topology에 섞을 수 없다.
+//
+// tenant:
+//   이벤트의 tenant는 검증된 client 인증서의 O(organization), 없으면 x-nefi-tenant
+//   메타데이터(agent --tenant, HTTP 수집은 같은 이름의 헤더)다. agent가 보낸 tenant 필드는
+//   덮어쓴다. 조회 API와 WebSocket은 요청의 tenant 범위 밖 데이터를 반환하지 않는다 (tenant 패키지).
+//   registry와 노드별 수집 속도 제한은 tenant별로 노드를 구분한다.
+//
+// 감사 로그:
+//   스트림 시작/종료(받은 batch와 이벤트 수, 종료 원인), unary batch, 거부된 스트림과 batch를
+//   보낸 agent의 신원, 노드, tenant와 함께 Config.Audit(audit 패키지)에 기록한다 (audit.go).
+//   TLS handshake 실패는 app이 gRPC credentials를 audit.Log.Credentials로 감싸 기록한다.
+//
+// 원격 설정:
+//   NefiCollector.GetAgentConfig: agent가 주기적으로 poll하면 agents.Registry의
+//   RemoteConfig(샘플링 비율, 제외 namespace)를 반환한다.
 package collector
 
 import (
+	"context"
 	"io"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/batchdict"
+	"github.com/gihongjo/nefi/internal/metrics"
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"github.com/gihongjo/nefi/internal/server/audit"
+	"github.com/gihongjo/nefi/internal/server/enrich"
 	"github.com/gihongjo/nefi/internal/server/httpparse"
 	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/version"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // agent가 협상한 gzip 압축 해제
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// maxBatchEvents는 SendBatch 호출 또는 StreamBatches batch 하나에 받는 최대 이벤트 수다.
+const maxBatchEvents = 10000
+
+// Config는 collector 동작 설정이다.
+type Config struct {
+	// AdmitRate는 초당 수락하는 새 스트림 수다. 0이면 제한하지 않는다.
+	AdmitRate float64
+	// AdmitBurst는 순간적으로 수락 가능한 새 스트림 수다.
+	AdmitBurst int
+
+	// NodeEventRate는 노드 하나에서 초당 수락하는 이벤트 수다. 0이면 제한하지 않는다.
+	NodeEventRate float64
+	// NodeEventBurst는 노드 하나에서 순간적으로 수락 가능한 이벤트 수다.
+	NodeEventBurst int
+	// GlobalEventRate는 모든 agent를 합쳐 초당 수락하는 이벤트 수다. 0이면 제한하지 않는다.
+	GlobalEventRate float64
+	// GlobalEventBurst는 모든 agent를 합쳐 순간적으로 수락 가능한 이벤트 수다.
+	GlobalEventBurst int
+
+	// ConnSampleRate는 ConnSampleBy 키 하나에서 초당 저장하는 연결 관측 이벤트 수다. 넘는
+	// 이벤트는 확률 샘플링해 저장하지 않고 집계에만 넘긴다. 0이면 샘플링하지 않는다.
+	ConnSampleRate float64
+	// ConnSampleBy는 연결 이벤트 샘플링 키다: SampleByService(기본) 또는 SampleByNode.
+	ConnSampleBy string
+
+	// StreamIdleTimeout 동안 batch(SendEvents는 이벤트)를 하나도 보내지 않은 스트림은 닫는다.
+	// 0이면 닫지 않는다.
+	StreamIdleTimeout time.Duration
+
+	// IngestWorkers는 batch를 저장하는 worker 수다. 0이면 GOMAXPROCS.
+	IngestWorkers int
+	// IngestQueueBatches는 worker 하나의 큐에 쌓을 수 있는 batch 수다. 0이면 기본값(256).
+	IngestQueueBatches int
+
+	// Enrichers는 검증을 통과한 이벤트에 저장 직전 순서대로 실행하는 보강 stage다.
+	Enrichers []enrich.Enricher
+
+	// Audit은 수집 세션과 거부를 기록할 감사 로그다. nil이면 메모리에만 기본 크기로 남긴다.
+	Audit *audit.Log
+}
+
 // Service는 NefiCollectorServer 인터페이스를 구현한다.
 type Service struct {
 	nefiv1.UnimplementedNefiCollectorServer
-	store   store.Store
-	tracker *connTracker
+	store     store.Store
+	agents    *agents.Registry
+	tracker   *connTracker
+	streams   *streams
+	admission *admission
+	quota     *quota
+	validator validator
+	sampler   *connSampler
+	dedup     *dedup
+	pipeline  *pipeline
+	stats     *ingestStats
+	enrich    *enrich.Pipeline
+	audit     *audit.Log
 }
 
 // New는 주어진 Store를 사용하는 CollectorService를 반환한다.
-func New(s store.Store) *Service {
-	return &Service{
-		store:   s,
-		tracker: newConnTracker(),
+// 연결된 agent는 reg에 기록된다.
+// Close로 큐에 남은 batch를 저장하기 전까지 worker가 돈다.
+func New(s store.Store, reg *agents.Registry, cfg Config) *Service {
+	svc := &Service{
+		store:     s,
+		agents:    reg,
+		tracker:   newConnTracker(),
+		streams:   newStreams(cfg.StreamIdleTimeout, reg.StaleAfter()),
+		admission: newAdmission(cfg.AdmitRate, cfg.AdmitBurst),
+		quota:     newQuota(cfg.NodeEventRate, cfg.NodeEventBurst, cfg.GlobalEventRate, cfg.GlobalEventBurst),
+		sampler:   newConnSampler(cfg.ConnSampleRate, cfg.ConnSampleBy),
+		dedup:     newDedup(),
+		stats:     newIngestStats(),
+		enrich:    enrich.New(cfg.Enrichers...),
+		audit:     cfg.Audit,
+	}
+	if svc.audit == nil {
+		svc.audit = audit.New(0, nil)
 	}
+	svc.pipeline = newPipeline(cfg.IngestWorkers, cfg.IngestQueueBatches, svc.process)
+	return svc
 }
 
-// SendEvents는 agent의 이벤트 스트림을 수신한다.
-func (s *Service) SendEvents(stream nefiv1.NefiCollector_SendEventsServer) error {
+// Close는 큐에 남은 batch를 모두 저장하고 worker를 멈춘다. gRPC server를 멈춘 뒤,
+// store를 닫기 전에 호출한다.
+func (s *Service) Close() {
+	s.pipeline.close()
+}
+
+// RegisterMetrics는 수집 경로(수신, 큐, 처리 시간, 검증, 속도 제한, 중복 제거, 저장 샘플링)와 스트림
+// 수락/throttle, 열린 스트림 상태 및 reconnect storm 지표를 reg에 등록한다. 저장 결과는 store.RegisterMetrics가 센다.
+func (s *Service) RegisterMetrics(reg *metrics.Registry) {
+	s.stats.register(reg)
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_streams_accepted_total",
+		Help: "Agent streams accepted by the collector.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.admission.accepted.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_streams_throttled_total",
+		Help: "Agent streams rejected by connection ramp-up pacing.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.admission.throttled.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_streams",
+		Help: "Open agent streams: active, or stalled with no batch for longer than the agent stale threshold.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			active, stalled := s.streams.counts()
+			return []metrics.Sample{
+				{Labels: metrics.Labels{"state": "active"}, Value: float64(active)},
+				{Labels: metrics.Labels{"state": "stalled"}, Value: float64(stalled)},
+			}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_streams_idle_closed_total",
+		Help: "Agent streams closed because no batch arrived within the stream idle timeout.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.streams.idleClosed.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_events_throttled_total",
+		Help: "Events rejected by the per-node or global ingestion rate limit; agents resend them later.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{
+				{Labels: metrics.Labels{"limit": "node"}, Value: float64(s.quota.throttledNode.Load())},
+				{Labels: metrics.Labels{"limit": "global"}, Value: float64(s.quota.throttledGlobal.Load())},
+			}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_connection_events_sampled_total",
+		Help: "Connection events not stored because their service or node exceeded the storage sampling rate; they are still counted by the aggregator.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Labels: metrics.Labels{"by": s.sampler.by}, Value: float64(s.sampler.dropped.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_events_rejected_total",
+		Help: "Events dropped by ingestion validation (timestamp far from the rest of the batch, invalid HTTP endpoint or status, absurd size).",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, numRejectReasons-1)
+			for r := rejectNone + 1; r < numRejectReasons; r++ {
+				samples = append(samples, metrics.Sample{Labels: metrics.Labels{"reason": rejectReasonNames[r]}, Value: float64(s.validator.rejected[r].Load())})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_events_clamped_total",
+		Help: "Events stored with a field truncated or cleared by ingestion validation.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, numClampFields)
+			for f := range numClampFields {
+				samples = append(samples, metrics.Sample{Labels: metrics.Labels{"field": clampFieldNames[f]}, Value: float64(s.validator.clamped[f].Load())})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_ingest_queue_batches",
+		Help: "Batches received and waiting for an ingestion worker to store them.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.pipeline.depth())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_ingest_queue_events",
+		Help: "Events in batches waiting for an ingestion worker.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.pipeline.queued.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_ingest_queue_capacity_batches",
+		Help: "Batches the ingestion queues can hold across all workers.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.pipeline.capacity())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_ingest_queue_rejected_events_total",
+		Help: "Events rejected because the ingestion queue was full; agents resend them later.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.pipeline.rejected.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_batches_deduplicated_total",
+		Help: "Resent batches (same producer_id and seq) acknowledged without storing them again.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.dedup.batches.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_events_deduplicated_total",
+		Help: "Events in resent batches that were not stored again.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.dedup.events.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_connect_attempts_per_second",
+		Help: "Agent stream attempts per second over the last 10s; spikes indicate a reconnect storm.",
+		Kind: metrics.Gauge,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: s.admission.attemptRate()}}
+		},
+	})
+}
+
+// accept는 스트림/batch 호출을 수락할지 판단한다: 메타데이터에서 agent 정보를 읽고,
+// 수락 속도 제한과 스키마 호환성 검사를 통과해야 한다.
+func (s *Service) accept(ctx context.Context) (agents.Info, version.Compat, string, error) {
+	info := peerInfo(ctx)
+	compat, warning, err := s.admit(info)
+	return info, compat, warning, err
+}
+
+// peerInfo는 요청의 peer 주소, 메타데이터와 mTLS 신원/tenant로 agent 정보를 만든다.
+func peerInfo(ctx context.Context) agents.Info {
 	addr := ""
-	if p, ok := peer.FromContext(stream.Context()); ok {
+	if p, ok := peer.FromContext(ctx); ok {
 		addr = p.Addr.String()
 	}
-	log.Printf("[collector] agent connected: %s", addr)
+	info := agentInfo(ctx, addr)
+	info.Identity = peerIdentity(ctx)
+	if t := peerTenant(ctx); t != "" {
+		info.Tenant = t
+	}
+	return info
+}
+
+// admit은 info의 요청을 수락 속도 제한과 스키마 호환성으로 거르고, 호환성 판정을 반환한다.
+func (s *Service) admit(info agents.Info) (version.Compat, string, error) {
+	addr := info.Addr
+	if err := s.admission.admit(); err != nil {
+		return 0, "", err
+	}
+	compat, warning := version.CheckAgent(info.SchemaVersion)
+	if compat == version.Incompatible {
+		log.Printf("[collector] rejected agent %s node=%s: %s", addr, info.NodeName, warning)
+		s.agents.Reject(info, warning)
+		return compat, warning, incompatibleError(info, warning)
+	}
+	if compat == version.Deprecated {
+		log.Printf("[collector] WARN agent %s node=%s: %s", addr, info.NodeName, warning)
+	}
+	return compat, warning, nil
+}
+
+// ingest는 이벤트 하나를 보강하고 검증해 저장한다. src.Identity(보낸 agent의 mTLS 신원)는
+// agent가 이벤트에 넣어 보낸 값을 덮어쓴다. ref는 같은 batch의 타임스탬프 중앙값이다
+// (0 = 비교하지 않음). 검증이나 보강 stage에서 버린 이벤트는 저장하지 않는다.
+func (s *Service) ingest(event *nefiv1.TraceEvent, src enrich.Source, ref uint64) {
+	event.AgentIdentity = src.Identity
+	event.Tenant = src.Tenant
+	s.enrichHTTP(event)
+	if !s.validator.check(event, ref) {
+		return
+	}
+	if !s.enrich.Enrich(src, event) {
+		s.stats.enrichDropped.Add(1)
+		return
+	}
+	if !s.sampler.keep(event, src) {
+		s.store.Publish(event)
+		return
+	}
+	s.store.Add(event)
+}
+
+// process는 worker가 큐에서 꺼낸 batch를 저장한다. 같은 batch가 두 스트림에서 동시에
+// 큐에 들어왔으면 먼저 꺼낸 쪽만 저장한다.
+func (s *Service) process(j ingestJob) {
+	started := time.Now()
+	if !s.dedup.claim(j.dedupKey, j.batch.GetSeq(), len(j.batch.GetEvents())) {
+		return
+	}
+	s.upgrade(j.batch, j.schema, j.src.Node)
+	s.ingestBatch(j.batch, j.src)
+	s.stats.processed(j.enqueued, started)
+}
+
+// ingestBatch는 batch의 이벤트를 ingest한다. 잘못된 이벤트만 버리고 batch는 받은 것으로
+// 처리한다 — 같은 batch를 다시 보내도 결과가 같기 때문이다.
+func (s *Service) ingestBatch(batch *nefiv1.EventBatch, src enrich.Source) {
+	ref := medianTimestamp(batch.GetEvents())
+	for _, event := range batch.GetEvents() {
+		s.ingest(event, src, ref)
+	}
+}
+
+// source는 info가 보낸 이벤트의 보강 stage 입력이다.
+func source(info agents.Info) enrich.Source {
+	return enrich.Source{Node: info.NodeName, Cluster: info.Cluster, Identity: info.Identity, Tenant: info.Tenant}
+}
+
+// SendEvents는 agent의 이벤트 스트림을 수신한다.
+func (s *Service) SendEvents(stream nefiv1.NefiCollector_SendEventsServer) (err error) {
+	info, compat, warning, err := s.accept(stream.Context())
+	if err != nil {
+		s.auditReject(rpcSendEvents, info, err)
+		return err
+	}
+	session := s.startSession(rpcSendEvents, info)
+	defer func() { session.end(err) }()
+	addr := info.Addr
+	conn := s.agents.Connect(info, compat.String(), warning)
+	defer s.agents.Disconnect(conn)
+	agentKey := conn.Key
+	log.Printf("[collector] agent connected: %s node=%s version=%s (%s) schema=%d kernel=%s probes=%s%s",
+		addr, info.NodeName, info.Version, info.GitCommit, info.SchemaVersion, info.KernelVersion, strings.Join(info.Probes, ","), identityLog(info))
 
+	in := newStreamReader(stream.Context(), s.streams, stream.Recv)
+	defer in.close()
+	node := quotaKey(info)
 	var received uint64
 	for {
-		event, err := stream.Recv()
+		event, err := in.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			log.Printf("[collector] stream error from %s: %v", addr, err)
-			return err
+			return s.streamError(agentKey, err)
+		}
+		s.stats.received(rpcSendEvents, 1)
+		if err := s.quota.take(node, 1); err != nil {
+			return s.streamError(agentKey, err)
 		}
-		s.enrichHTTP(event)
-		s.store.Add(event)
+		job := ingestJob{batch: &nefiv1.EventBatch{Events: []*nefiv1.TraceEvent{event}}, src: source(info), schema: batchSchema(nil, info)}
+		if err := s.pipeline.submit(node, job); err != nil {
+			return s.streamError(agentKey, err)
+		}
+		s.agents.Observe(agentKey, 1)
+		session.received(1)
 		received++
 	}
 
@@ -62,6 +490,244 @@ func (s *Service) SendEvents(stream nefiv1.NefiCollector_SendEventsServer) error
 	return stream.SendAndClose(&nefiv1.CollectSummary{Received: received})
 }
 
+// StreamBatches는 agent의 batch 스트림을 수신하고, batch마다 저장 큐에 넣은 뒤 ack를 보낸다.
+// 한 노드의 batch는 받은 순서대로 저장되므로 ack는 누적(seq 이하 모두 큐에 들어감)이다.
+func (s *Service) StreamBatches(stream nefiv1.NefiCollector_StreamBatchesServer) (err error) {
+	info, compat, warning, err := s.accept(stream.Context())
+	if err != nil {
+		s.auditReject(rpcStreamBatches, info, err)
+		return err
+	}
+	session := s.startSession(rpcStreamBatches, info)
+	defer func() { session.end(err) }()
+	addr := info.Addr
+	conn := s.agents.Connect(info, compat.String(), warning)
+	defer s.agents.Disconnect(conn)
+	agentKey := conn.Key
+	log.Printf("[collector] agent connected (batched): %s node=%s version=%s (%s) schema=%d kernel=%s probes=%s%s",
+		addr, info.NodeName, info.Version, info.GitCommit, info.SchemaVersion, info.KernelVersion, strings.Join(info.Probes, ","), identityLog(info))
+
+	in := newStreamReader(stream.Context(), s.streams, stream.Recv)
+	defer in.close()
+	node := quotaKey(info)
+	var received uint64
+	for {
+		batch, err := in.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("[collector] stream error from %s: %v", addr, err)
+			return s.streamError(agentKey, err)
+		}
+		s.stats.received(rpcStreamBatches, len(batch.GetEvents()))
+		if n := len(batch.GetEvents()); n > maxBatchEvents {
+			return s.streamError(agentKey, status.Errorf(codes.InvalidArgument, "batch %d has %d events, limit is %d", batch.GetSeq(), n, maxBatchEvents))
+		}
+		if v := batch.GetSchemaVersion(); v > version.SchemaVersion {
+			return s.streamError(agentKey, status.Errorf(codes.InvalidArgument, "batch %d is encoded with schema %d, server supports up to %d (call Negotiate first)", batch.GetSeq(), v, version.SchemaVersion))
+		}
+		// 이미 저장한 batch(ack가 유실돼 다시 보낸 것)는 저장하지 않고 ack만 다시 보낸다.
+		dk := dedupKey(batch.GetProducerId(), batch.GetSeq(), info.Identity)
+		n := len(batch.GetEvents())
+		if !s.dedup.seen(dk, batch.GetSeq(), n) {
+			// 제한을 넘으면 ack 없이 스트림을 닫는다. ack는 누적이므로 batch 하나만 건너뛸 수 없고,
+			// agent는 RetryInfo의 시간 뒤에 재연결해 ack받지 못한 batch를 모두 다시 보낸다.
+			if err := s.quota.take(node, n); err != nil {
+				return s.streamError(agentKey, err)
+			}
+			if err := batchdict.Decode(batch); err != nil {
+				return s.streamError(agentKey, status.Errorf(codes.InvalidArgument, "batch %d: %v", batch.GetSeq(), err))
+			}
+			// 큐가 가득 찬 경우도 같다.
+			if err := s.pipeline.submit(node, ingestJob{batch: batch, src: source(info), schema: batchSchema(batch, info), dedupKey: dk}); err != nil {
+				return s.streamError(agentKey, err)
+			}
+			s.agents.Observe(agentKey, uint64(n))
+			if l := batch.GetLoad(); l != nil {
+				s.agents.ReportLoad(agentKey, loadReport(l))
+			}
+			session.received(n)
+			received += uint64(n)
+		} else {
+			session.duplicates++
+		}
+		if err := stream.Send(&nefiv1.BatchAck{Seq: batch.GetSeq()}); err != nil {
+			log.Printf("[collector] ack to %s failed: %v", addr, err)
+			return s.streamError(agentKey, err)
+		}
+	}
+
+	log.Printf("[collector] agent %s disconnected — received %d events", addr, received)
+	return nil
+}
+
+// SendBatch는 외부 producer가 unary로 보낸 이벤트 묶음을 저장한다.
+// producer는 registry에 연결 상태 없이(batch producer로) 기록된다.
+// 수락한 batch와 거부한 batch는 모두 감사 로그에 남는다.
+func (s *Service) SendBatch(ctx context.Context, batch *nefiv1.EventBatch) (*nefiv1.CollectSummary, error) {
+	info := peerInfo(ctx)
+	duplicate, err := s.sendBatch(info, batch)
+	if err != nil {
+		s.auditReject(rpcSendBatch, info, err)
+		return nil, err
+	}
+	received := uint64(len(batch.GetEvents()))
+	r := auditRecord(audit.KindBatch, rpcSendBatch, info)
+	r.Producer = batch.GetProducerId()
+	r.Batches, r.Events = 1, received
+	if duplicate {
+		r.Duplicates = 1
+	}
+	s.audit.Add(r)
+	return &nefiv1.CollectSummary{Received: received}, nil
+}
+
+// sendBatch는 SendBatch의 batch를 검사해 저장 큐에 넣는다. 이미 저장한 batch면 duplicate다.
+func (s *Service) sendBatch(info agents.Info, batch *nefiv1.EventBatch) (duplicate bool, err error) {
+	s.stats.received(rpcSendBatch, len(batch.GetEvents()))
+	if n := len(batch.GetEvents()); n > maxBatchEvents {
+		return false, status.Errorf(codes.InvalidArgument, "batch has %d events, limit is %d", n, maxBatchEvents)
+	}
+	if v := batch.GetSchemaVersion(); v > version.SchemaVersion {
+		return false, status.Errorf(codes.InvalidArgument, "batch is encoded with schema %d, server supports up to %d (call Negotiate first)", v, version.SchemaVersion)
+	}
+	compat, warning, err := s.admit(info)
+	if err != nil {
+		return false, err
+	}
+	received := uint64(len(batch.GetEvents()))
+	dk := dedupKey(batch.GetProducerId(), batch.GetSeq(), info.Identity)
+	if s.dedup.seen(dk, batch.GetSeq(), len(batch.GetEvents())) {
+		// 이전 호출에서 저장했지만 응답이 producer에 닿지 않은 batch다. 저장된 것으로 응답한다.
+		return true, nil
+	}
+	node := quotaKey(info)
+	if err := s.quota.take(node, len(batch.GetEvents())); err != nil {
+		return false, err
+	}
+	// 사전 인코딩 복원은 batch 크기에 비례하므로 admission과 quota를 통과한 batch에만 한다.
+	if err := batchdict.Decode(batch); err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "batch: %v", err)
+	}
+	if err := s.pipeline.submit(node, ingestJob{batch: batch, src: source(info), schema: batchSchema(batch, info), dedupKey: dk}); err != nil {
+		return false, err
+	}
+	agentKey := s.agents.ObserveBatch(info, compat.String(), warning, received)
+	if l := batch.GetLoad(); l != nil {
+		s.agents.ReportLoad(agentKey, loadReport(l))
+	}
+	return false, nil
+}
+
+// streamError는 agent k의 스트림을 끝내는 err를 registry에 기록하고 그대로 반환한다.
+// agent가 스트림을 취소한 경우(종료, 재연결)는 에러로 세지 않는다.
+func (s *Service) streamError(k string, err error) error {
+	if status.Code(err) != codes.Canceled {
+		s.agents.Error(k, err)
+	}
+	return err
+}
+
+// Negotiate는 agent와 이 server가 모두 지원하는 스키마 버전과 전송 기능을 정한다.
+// agent가 너무 오래돼 받을 수 없으면 SendEvents와 같은 FailedPrecondition을 반환한다.
+func (s *Service) Negotiate(ctx context.Context, req *nefiv1.NegotiateRequest) (*nefiv1.NegotiateResponse, error) {
+	schema, features, ok := version.Negotiate(int(req.GetSchemaVersion()), req.GetFeatures())
+	if !ok {
+		info := agentInfo(ctx, "")
+		info.NodeName = req.GetNodeName()
+		_, guidance := version.CheckAgent(schema)
+		return nil, incompatibleError(info, guidance)
+	}
+	return &nefiv1.NegotiateResponse{
+		SchemaVersion:       uint32(schema),
+		ServerSchemaVersion: version.SchemaVersion,
+		MinSchemaVersion:    version.MinAgentSchemaVersion,
+		Features:            features,
+	}, nil
+}
+
+// loadReport는 batch에 실린 agent 부하 보고를 registry 형식으로 바꾼다.
+func loadReport(l *nefiv1.LoadReport) agents.Load {
+	return agents.Load{
+		ReportedAt:       time.Now(),
+		EventsPerSec:     l.GetEventsPerSec(),
+		Captured:         l.GetCaptured(),
+		Enqueued:         l.GetEnqueued(),
+		QueueDropped:     l.GetQueueDropped(),
+		QueueDepth:       int(l.GetQueueDepth()),
+		QueueCapacity:    int(l.GetQueueCapacity()),
+		UnackedEvents:    l.GetUnackedEvents(),
+		SampledOut:       l.GetSampledOut(),
+		CapturePausedSec: l.GetCapturePausedSec(),
+	}
+}
+
+// GetAgentConfig는 registry에 설정된 fleet 런타임 설정을 반환하고,
+// agent가 보고한 적용 revision을 기록한다.
+func (s *Service) GetAgentConfig(_ context.Context, req *nefiv1.AgentConfigRequest) (*nefiv1.AgentConfig, error) {
+	s.agents.ConfigPolled(req.GetNodeName(), req.GetRevision())
+	c := s.agents.RemoteConfig()
+	return &nefiv1.AgentConfig{
+		Revision:          c.Revision,
+		SampleRate:        c.SampleRate,
+		ExcludeNamespaces: c.ExcludeNamespaces,
+	}, nil
+}
+
+// agentInfo는 스트림 메타데이터에서 agent 식별/버전 정보를 읽는다.
+// 메타데이터를 보내지 않는 구버전 agent는 빈 값(schema 0)으로 기록된다.
+func agentInfo(ctx context.Context, addr string) agents.Info {
+	info := agents.Info{Addr: addr}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return info
+	}
+	get := func(k string) string {
+		if v := md.Get(k); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	info.NodeName = get(version.MDNodeName)
+	info.Version = get(version.MDVersion)
+	info.GitCommit = get(version.MDGitCommit)
+	info.BuildDate = get(version.MDBuildDate)
+	info.SchemaVersion, _ = strconv.Atoi(get(version.MDSchemaVersion))
+	info.KernelVersion = get(version.MDKernelVersion)
+	info.Cluster = get(version.MDCluster)
+	info.Tenant = get(version.MDTenant)
+	if v := get(version.MDProbes); v != "" {
+		info.Probes = strings.Split(v, ",")
+	}
+	if v := get(version.MDNodeLabels); v != "" {
+		info.NodeLabels = make(map[string]string)
+		for _, kv := range strings.Split(v, ",") {
+			if k, val, ok := strings.Cut(kv, "="); ok {
+				info.NodeLabels[k] = val
+			}
+		}
+	}
+	return info
+}
+
+// incompatibleError는 호환되지 않는 agent에게 반환하는 FailedPrecondition 에러다.
+// agent는 PreconditionFailure detail에서 안내 문구를 꺼내 로그로 남긴다.
+func incompatibleError(info agents.Info, guidance string) error {
+	st := status.New(codes.FailedPrecondition, "incompatible agent version")
+	detailed, err := st.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        version.ViolationAgentSchema,
+			Subject:     info.NodeName,
+			Description: guidance,
+		}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
 // enrichHTTP는 HTTP 이벤트의 payload를 파싱해 메타데이터 필드를 채운다.
 //
 // 요청 이벤트: method/path를 connTracker에 저장.
@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// producerIdleTTL이 지나도록 batch가 없는 producer는 잊는다. agent는 재시작할 때마다 새
+// producer_id를 쓰므로, 오래된 항목을 지우지 않으면 map이 계속 커진다.
+const producerIdleTTL = time.Hour
+
+// dedup은 재전송된 batch를 알아보고 한 번만 저장하게 한다.
+//
+// agent는 ack받지 못한 batch를 재연결 후 다시 보내므로, batch를 저장한 뒤 ack가 agent에
+// 닿기 전에 연결이 끊기면 같은 batch가 두 번 온다. batch는 (producer_id, seq)로 식별한다.
+// 한 producer의 seq는 1부터 증가하지만 unary SendBatch를 병렬로 부르는 producer의 batch는
+// 순서가 섞여 도착하므로, producer별로 가장 큰 seq와 그 아래 dedupWindow개 seq의 저장 여부를
+// bitmap으로 기억한다. window보다 오래된 seq는 판단할 수 없어 중복으로 보고 건너뛴다.
+//
+// 상태는 server 메모리에만 있으므로 server가 재시작하거나 agent가 다른 replica로 옮겨 가면
+// 그 직후의 재전송은 다시 저장될 수 있다.
+type dedup struct {
+	batches atomic.Uint64 // 중복으로 건너뛴 batch 수
+	events  atomic.Uint64 // 중복으로 건너뛴 batch의 이벤트 수
+
+	mu        sync.Mutex
+	producers map[string]*producerSeq
+	lastPrune time.Time
+}
+
+// dedupWindow는 producer별로 기억하는 seq 수다. 동시에 전송 중인 batch 수보다 충분히 커야 한다.
+const dedupWindow = 1024
+
+type producerSeq struct {
+	max      uint64                   // 저장한 가장 큰 seq
+	window   [dedupWindow / 64]uint64 // seq % dedupWindow 번째 bit = (max-dedupWindow, max] 범위의 seq를 저장함
+	lastUsed time.Time
+}
+
+// has는 seq가 이미 저장됐는지 여부다. window 아래의 seq는 저장된 것으로 본다.
+func (p *producerSeq) has(seq uint64) bool {
+	switch {
+	case seq > p.max:
+		return false
+	case p.max-seq >= dedupWindow:
+		return true
+	}
+	i := seq % dedupWindow
+	return p.window[i/64]&(1<<(i%64)) != 0
+}
+
+// add는 seq를 저장한 것으로 기록하고, max가 올라가면 window에서 밀려난 bit를 지운다.
+func (p *producerSeq) add(seq uint64) {
+	if seq > p.max {
+		if seq-p.max >= dedupWindow {
+			p.window = [dedupWindow / 64]uint64{}
+		} else {
+			for s := p.max + 1; s < seq; s++ {
+				i := s % dedupWindow
+				p.window[i/64] &^= 1 << (i % 64)
+			}
+		}
+		p.max = seq
+	}
+	i := seq % dedupWindow
+	p.window[i/64] |= 1 << (i % 64)
+}
+
+func newDedup() *dedup {
+	return &dedup{producers: make(map[string]*producerSeq), lastPrune: time.Now()}
+}
+
+// dedupKey는 batch의 producer 키다. mTLS 신원으로 범위를 나눠, 다른 agent의 producer_id를
+// 흉내 내 그 agent의 batch를 버리게 할 수 없다. producer_id나 seq가 없는 batch(구버전
+// agent, 외부 producer)는 식별할 수 없으므로 빈 문자열이다.
+func dedupKey(producer string, seq uint64, identity string) string {
+	if producer == "" || seq == 0 {
+		return ""
+	}
+	return identity + "/" + producer
+}
+
+// seen은 key의 seq batch가 이미 저장됐는지 여부다. 중복이면 n개의 이벤트를 건너뛴 것으로 센다.
+func (d *dedup) seen(key string, seq uint64, n int) bool {
+	if key == "" {
+		return false
+	}
+	d.mu.Lock()
+	p, ok := d.producers[key]
+	dup := ok && p.has(seq)
+	d.mu.Unlock()
+	if dup {
+		d.batches.Add(1)
+		d.events.Add(uint64(n))
+	}
+	return dup
+}
+
+// claim은 key의 seq batch를 저장하기로 기록한다. 같은 producer의 이전 스트림이 아직 server에서
+// 정리되지 않아 두 스트림이 같은 batch를 동시에 받은 경우 한쪽만 true를 받는다.
+func (d *dedup) claim(key string, seq uint64, n int) bool {
+	if key == "" {
+		return true
+	}
+	now := time.Now()
+	d.mu.Lock()
+	if now.Sub(d.lastPrune) > producerIdleTTL {
+		for k, p := range d.producers {
+			if now.Sub(p.lastUsed) > producerIdleTTL {
+				delete(d.producers, k)
+			}
+		}
+		d.lastPrune = now
+	}
+	p, ok := d.producers[key]
+	if !ok {
+		p = &producerSeq{}
+		d.producers[key] = p
+	}
+	p.lastUsed = now
+	claimed := !p.has(seq)
+	if claimed {
+		p.add(seq)
+	}
+	d.mu.Unlock()
+	if !claimed {
+		d.batches.Add(1)
+		d.events.Add(uint64(n))
+	}
+	return claimed
+}
@@ -0,0 +1,52 @@
+package collector
+
+import "testing"
+
+func TestDedupOutOfOrder(t *testing.T) {
+	d := newDedup()
+	key := dedupKey("producer-1", 1, "agent-a")
+	for _, seq := range []uint64{3, 1, 5, 2} { // 병렬 SendBatch: 순서가 섞여 도착한다
+		if d.seen(key, seq, 1) || !d.claim(key, seq, 1) {
+			t.Fatalf("seq %d dropped as a duplicate", seq)
+		}
+	}
+	for _, seq := range []uint64{1, 2, 3, 5} {
+		if !d.seen(key, seq, 1) || d.claim(key, seq, 1) {
+			t.Errorf("resent seq %d stored again", seq)
+		}
+	}
+	if d.seen(key, 4, 1) || !d.claim(key, 4, 1) {
+		t.Error("seq 4 dropped as a duplicate")
+	}
+	if got := d.batches.Load(); got != 8 {
+		t.Errorf("deduplicated batches = %d, want 8", got)
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	d := newDedup()
+	key := dedupKey("producer-1", 1, "agent-a")
+	d.claim(key, 1, 1)
+	d.claim(key, 10+dedupWindow, 1) // seq 1은 window 밖으로 밀려난다
+
+	tests := []struct {
+		seq  uint64
+		want bool
+	}{
+		{1, true}, // window보다 오래됨: 중복으로 본다
+		{10, true},
+		{11, false},
+		{1 + dedupWindow, false}, // seq 1과 같은 bit를 쓰지만 seq 1의 기록이 남지 않는다
+		{9 + dedupWindow, false},
+		{10 + dedupWindow, true},
+		{11 + dedupWindow, false},
+	}
+	for _, tt := range tests {
+		if got := d.seen(key, tt.seq, 1); got != tt.want {
+			t.Errorf("seen(%d) = %v, want %v", tt.seq, got, tt.want)
+		}
+	}
+	if d.seen(dedupKey("producer-1", 1, "agent-b"), 1, 1) {
+		t.Error("another identity's producer shares the window")
+	}
+}
@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxIngestBody는 HTTP 수집 요청 body의 최대 크기다.
+const maxIngestBody = 32 << 20
+
+type ingestResponse struct {
+	Batches  int    `json:"batches"`         // 수락한 batch 수
+	Received uint64 `json:"received"`        // 수락한 batch의 이벤트 수
+	Error    string `json:"error,omitempty"` // 중간에 실패한 경우, 실패한 batch의 에러
+}
+
+// HTTPHandler는 POST /api/v1/ingest 핸들러를 반환한다. gRPC 스트림을 구현하기 어려운
+// producer(sidecar, service mesh access log, 테스트 harness)가 EventBatch를 JSON으로 보낸다.
+//
+// body는 protojson 형식의 EventBatch 하나(application/json)이거나, 줄마다 EventBatch 하나인
+// NDJSON(application/x-ndjson)이다. batch는 SendBatch와 같은 경로(수락 속도 제한, 검증,
+// 수집 속도 제한, 중복 제거, 저장 큐)로 처리한다. agent 메타데이터는 gRPC와 같은 이름의
+// 헤더(x-nefi-node-name, x-nefi-schema-version 등)로 보낸다.
+//
+// NDJSON은 앞에서부터 처리하다 실패한 batch에서 멈추고, 그 전까지 수락한 batch 수와 에러를
+// 반환한다. producer_id와 seq를 붙인 batch는 모두 다시 보내도 한 번만 저장된다.
+//
+// 이 핸들러는 보낸 쪽을 인증하지 않는다 (tenant는 x-nefi-tenant 헤더를 그대로 쓴다). 그래서
+// POST 외의 method(CORS preflight 포함)와 JSON/NDJSON이 아닌 Content-Type은 거부한다 —
+// text/plain 같은 "simple" 요청은 브라우저가 preflight 없이 cross-origin으로 보낼 수 있다.
+func (s *Service) HTTPHandler() http.Handler {
+	return http.HandlerFunc(s.serveIngest)
+}
+
+func (s *Service) serveIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeIngestJSON(w, http.StatusMethodNotAllowed, ingestResponse{Error: "use POST"})
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	ndjson := mediaType == "application/x-ndjson" || mediaType == "application/jsonl"
+	if !ndjson && mediaType != "application/json" {
+		writeIngestJSON(w, http.StatusUnsupportedMediaType, ingestResponse{
+			Error: "Content-Type must be application/json, application/x-ndjson or application/jsonl",
+		})
+		return
+	}
+
+	ctx := metadata.NewIncomingContext(r.Context(), ingestMetadata(r.Header))
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+	body := http.MaxBytesReader(w, r.Body, maxIngestBody)
+
+	var resp ingestResponse
+	send := func(line []byte) error {
+		batch := &nefiv1.EventBatch{}
+		if err := protojson.Unmarshal(line, batch); err != nil {
+			return status.Errorf(codes.InvalidArgument, "batch %d: %v", resp.Batches+1, err)
+		}
+		summary, err := s.SendBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		resp.Batches++
+		resp.Received += summary.GetReceived()
+		return nil
+	}
+
+	if ndjson {
+		sc := bufio.NewScanner(body)
+		sc.Buffer(make([]byte, 0, 64<<10), maxIngestBody+1) // 한도는 body reader가 정한다 (413)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if err := send(line); err != nil {
+				writeIngestError(w, err, resp)
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			writeBodyError(w, err, resp)
+			return
+		}
+	} else {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			writeBodyError(w, err, resp)
+			return
+		}
+		if err := send(data); err != nil {
+			writeIngestError(w, err, resp)
+			return
+		}
+	}
+	writeIngestJSON(w, http.StatusOK, resp)
+}
+
+// ingestMetadata는 x-nefi-* 헤더를 gRPC 메타데이터로 옮긴다 (agentInfo가 읽는다).
+func ingestMetadata(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, v := range h {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-nefi-") {
+			md.Append(k, v...)
+		}
+	}
+	return md
+}
+
+// writeBodyError는 body를 읽지 못한 에러를 응답한다.
+func writeBodyError(w http.ResponseWriter, err error, resp ingestResponse) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		resp.Error = fmt.Sprintf("request body exceeds %d bytes; split it into several requests", maxIngestBody)
+		writeIngestJSON(w, http.StatusRequestEntityTooLarge, resp)
+		return
+	}
+	resp.Error = "read body: " + err.Error()
+	writeIngestJSON(w, http.StatusBadRequest, resp)
+}
+
+// writeIngestError는 SendBatch의 gRPC 에러를 HTTP 상태로 바꿔 응답한다. 재시도할 수 있는
+// 거부(수집 속도 제한, 큐 포화, 수락 속도 제한)는 RetryInfo를 Retry-After 헤더로 옮긴다.
+func writeIngestError(w http.ResponseWriter, err error, resp ingestResponse) {
+	st := status.Convert(err)
+	code := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.FailedPrecondition:
+		code = http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		code = http.StatusTooManyRequests
+	case codes.Unavailable:
+		code = http.StatusServiceUnavailable
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(ri.GetRetryDelay().AsDuration().Seconds()))))
+		}
+	}
+	resp.Error = st.Message()
+	writeIngestJSON(w, code, resp)
+}
+
+func writeIngestJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
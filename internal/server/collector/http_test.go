@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/server/store/memory"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newTestService는 메모리 store에 저장하는 Service다. 테스트가 끝나면 닫는다.
+func newTestService(t *testing.T, cfg Config) (*Service, store.Store) {
+	t.Helper()
+	st := memory.New(1000)
+	s := New(st, agents.NewRegistry(time.Minute), cfg)
+	t.Cleanup(s.Close)
+	return s, st
+}
+
+// postIngest는 body를 Content-Type contentType으로 s에 POST하고 응답을 반환한다.
+func postIngest(t *testing.T, s *Service, contentType, body string) (*httptest.ResponseRecorder, ingestResponse) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/ingest", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("X-Nefi-Node-Name", "node-1")
+	w := httptest.NewRecorder()
+	s.HTTPHandler().ServeHTTP(w, r)
+	var resp ingestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response %q: %v", w.Body.String(), err)
+	}
+	return w, resp
+}
+
+func TestServeIngest(t *testing.T) {
+	const (
+		one = `{"events":[{"timestamp_ns":"1000","pid":1}]}`
+		two = `{"events":[{"timestamp_ns":"1000","pid":2},{"timestamp_ns":"1001","pid":3}]}`
+	)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+		batches     int
+		received    uint64
+		errContains string
+	}{
+		{"JSON", "application/json", two, http.StatusOK, 1, 2, ""},
+		{"JSON with charset", "application/json; charset=utf-8", one, http.StatusOK, 1, 1, ""},
+		{"JSON holds one batch", "application/json", one + "\n" + one, http.StatusBadRequest, 0, 0, "batch 1"},
+		{"NDJSON", "application/x-ndjson", one + "\n\n  \n" + two + "\n", http.StatusOK, 2, 3, ""},
+		{"JSONL", "application/jsonl", one, http.StatusOK, 1, 1, ""},
+		{"NDJSON stops at a bad line", "application/x-ndjson", one + "\n{bad\n" + two, http.StatusBadRequest, 1, 1, "batch 2"},
+		{"NDJSON stops at a rejected batch", "application/x-ndjson", one + "\n" + `{"schema_version":99}` + "\n" + two, http.StatusBadRequest, 1, 1, "schema 99"},
+		{"text/plain", "text/plain", one, http.StatusUnsupportedMediaType, 0, 0, "Content-Type"},
+		{"no Content-Type", "", one, http.StatusUnsupportedMediaType, 0, 0, "Content-Type"},
+	}
+	for _, tt := range tests {
+		s, _ := newTestService(t, Config{})
+		w, resp := postIngest(t, s, tt.contentType, tt.body)
+		if w.Code != tt.want || resp.Batches != tt.batches || resp.Received != tt.received || !strings.Contains(resp.Error, tt.errContains) {
+			t.Errorf("%s: %d %+v, want %d with %d batches, %d events and an error containing %q",
+				tt.name, w.Code, resp, tt.want, tt.batches, tt.received, tt.errContains)
+		}
+	}
+}
+
+func TestServeIngestStores(t *testing.T) {
+	s, st := newTestService(t, Config{})
+	if w, resp := postIngest(t, s, "application/x-ndjson", `{"events":[{"timestamp_ns":"1000","pid":7,"fd":3}]}`); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %+v", w.Code, resp)
+	}
+	s.Close() // 큐에 남은 batch를 저장한다
+	events := st.Recent(10)
+	if len(events) != 1 || events[0].GetNodeName() != "node-1" || events[0].GetConnId() != "node-1/7/3" {
+		t.Errorf("stored %v, want one event from node-1 (x-nefi-node-name header)", events)
+	}
+}
+
+func TestServeIngestMethod(t *testing.T) {
+	s, _ := newTestService(t, Config{})
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		r := httptest.NewRequest(method, "/api/v1/ingest", nil)
+		w := httptest.NewRecorder()
+		s.HTTPHandler().ServeHTTP(w, r)
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+			t.Errorf("%s: %d Allow=%q, want 405 Allow=POST", method, w.Code, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestServeIngestBodyLimit(t *testing.T) {
+	pad := strings.Repeat(" ", 1<<20)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+		batches     int
+	}{
+		{"JSON", "application/json", `{"events":[]}` + strings.Repeat(pad, maxIngestBody>>20+1), http.StatusRequestEntityTooLarge, 0},
+		// 공백 줄은 건너뛰므로 첫 batch만 수락한 뒤 body 한도에 걸린다.
+		{"NDJSON", "application/x-ndjson", `{}` + strings.Repeat("\n"+pad, maxIngestBody>>20+1), http.StatusRequestEntityTooLarge, 1},
+		{"NDJSON line at the limit", "application/x-ndjson", `{}` + strings.Repeat(" ", maxIngestBody-2), http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		s, _ := newTestService(t, Config{})
+		w, resp := postIngest(t, s, tt.contentType, tt.body)
+		if w.Code != tt.want || resp.Batches != tt.batches {
+			t.Errorf("%s: %d %+v, want %d after %d batches", tt.name, w.Code, resp, tt.want, tt.batches)
+		}
+	}
+}
+
+func TestServeIngestQuota(t *testing.T) {
+	s, _ := newTestService(t, Config{NodeEventRate: 1, NodeEventBurst: 2})
+	body := `{"events":[{"timestamp_ns":"1"},{"timestamp_ns":"2"}]}` + "\n" + `{"events":[{"timestamp_ns":"3"}]}`
+	w, resp := postIngest(t, s, "application/x-ndjson", body)
+	if w.Code != http.StatusTooManyRequests || resp.Batches != 1 || resp.Received != 2 {
+		t.Errorf("%d %+v, want 429 after 1 batch", w.Code, resp)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "1" {
+		t.Errorf("Retry-After = %q, want 1", ra)
+	}
+}
+
+func TestWriteIngestError(t *testing.T) {
+	retry := func(c codes.Code, d time.Duration) error {
+		st, _ := status.New(c, "rejected").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+		return st.Err()
+	}
+	tests := []struct {
+		err        error
+		want       int
+		retryAfter string
+	}{
+		{status.Error(codes.InvalidArgument, "bad"), http.StatusBadRequest, ""},
+		{status.Error(codes.FailedPrecondition, "old agent"), http.StatusPreconditionFailed, ""},
+		{retry(codes.ResourceExhausted, 1200*time.Millisecond), http.StatusTooManyRequests, "2"},
+		{retry(codes.Unavailable, 5*time.Second), http.StatusServiceUnavailable, "5"},
+		{status.Error(codes.Internal, "boom"), http.StatusInternalServerError, ""},
+		{status.Error(codes.PermissionDenied, "no"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeIngestError(w, tt.err, ingestResponse{Batches: 3})
+		var resp ingestResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != tt.want || w.Header().Get("Retry-After") != tt.retryAfter || resp.Batches != 3 || resp.Error != status.Convert(tt.err).Message() {
+			t.Errorf("%v: %d Retry-After=%q %+v, want %d Retry-After=%q",
+				status.Code(tt.err), w.Code, w.Header().Get("Retry-After"), resp, tt.want, tt.retryAfter)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// peerIdentity는 mTLS로 검증된 client 인증서에서 agent 신원을 읽는다.
+// 우선순위: URI SAN (SPIFFE ID 등) → 첫 DNS SAN → subject CN.
+// 평문 연결이거나 server가 client 인증서를 검증하지 않았으면 빈 문자열이다.
+func peerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	leaf := info.State.VerifiedChains[0][0]
+	switch {
+	case len(leaf.URIs) > 0:
+		return leaf.URIs[0].String()
+	case len(leaf.DNSNames) > 0:
+		return leaf.DNSNames[0]
+	default:
+		return leaf.Subject.CommonName
+	}
+}
+
+// peerTenant는 mTLS로 검증된 client 인증서 subject의 첫 O(organization)를 tenant로 읽는다.
+// 인증서를 검증하지 않았거나 O가 없으면 빈 문자열이다 (agent가 보낸 x-nefi-tenant를 쓴다).
+func peerTenant(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	if org := info.State.VerifiedChains[0][0].Subject.Organization; len(org) > 0 {
+		return org[0]
+	}
+	return ""
+}
+
+// identityLog는 연결 로그에 덧붙일 신원과 tenant 표기다 (둘 다 없으면 빈 문자열).
+func identityLog(info agents.Info) string {
+	s := ""
+	if info.Identity != "" {
+		s += " identity=" + info.Identity
+	}
+	if info.Tenant != "" {
+		s += " tenant=" + info.Tenant
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/enrich"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultQueueBatches는 worker 하나의 큐에 쌓을 수 있는 batch 수 기본값이다.
+const defaultQueueBatches = 256
+
+// ingestJob은 worker가 저장할 batch 하나다.
+type ingestJob struct {
+	batch    *nefiv1.EventBatch
+	src      enrich.Source // 보낸 agent (ingest 참고)
+	schema   int           // batch가 인코딩된 스키마 (batchSchema)
+	dedupKey string        // dedupKey 결과 (빈 문자열 = 중복 검사 안 함)
+	enqueued time.Time
+}
+
+// pipeline은 수신(Recv)과 저장 사이의 bounded 큐와 worker pool이다.
+//
+// 저장(보강, 검증, store.Add와 구독자 fan-out)을 Recv 고루틴에서 하면 저장이 느려질 때
+// 그 스트림의 수신이 멈추고, HTTP/2 flow control을 통해 같은 연결의 agent 전송까지 막힌다.
+// Recv는 batch를 큐에 넣기만 하고 worker가 저장한다.
+//
+// 큐는 worker마다 따로 있고 batch는 노드 키로 worker를 고른다. 요청과 응답 이벤트의 짝짓기
+// (connTracker)는 같은 노드의 이벤트가 보낸 순서대로 처리된다고 가정하므로, 한 노드의 batch를
+// 여러 worker가 동시에 처리하면 안 된다. 큐가 가득 차면 batch를 거부한다 (submit).
+type pipeline struct {
+	queues  []chan ingestJob
+	process func(ingestJob)
+	wg      sync.WaitGroup
+
+	queued   atomic.Int64  // 큐에 있는 이벤트 수
+	rejected atomic.Uint64 // 큐가 가득 차 거부한 이벤트 수
+
+	mu     sync.RWMutex // closed와 큐 close를 submit의 send와 직렬화한다
+	closed bool
+}
+
+// newPipeline은 workers개의 worker를 시작한다. 큐 하나에는 batch를 queueBatches개까지 쌓는다.
+// 0 이하의 값은 기본값(GOMAXPROCS, defaultQueueBatches)을 쓴다.
+func newPipeline(workers, queueBatches int, process func(ingestJob)) *pipeline {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if queueBatches <= 0 {
+		queueBatches = defaultQueueBatches
+	}
+	p := &pipeline{queues: make([]chan ingestJob, workers), process: process}
+	for i := range p.queues {
+		q := make(chan ingestJob, queueBatches)
+		p.queues[i] = q
+		p.wg.Add(1)
+		go p.work(q)
+	}
+	return p
+}
+
+func (p *pipeline) work(q <-chan ingestJob) {
+	defer p.wg.Done()
+	for j := range q {
+		p.process(j)
+		p.queued.Add(-int64(len(j.batch.GetEvents())))
+	}
+}
+
+// submit은 node에서 온 j를 node의 worker 큐에 넣는다. 기다리지 않으며, 큐가 가득 차면
+// ResourceExhausted와 RetryInfo로 거부한다 — agent는 ack받지 못한 batch를 안내받은 시간 뒤에
+// 다시 보낸다.
+func (p *pipeline) submit(node string, j ingestJob) error {
+	n := len(j.batch.GetEvents())
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return status.Error(codes.Unavailable, "collector is shutting down")
+	}
+	p.queued.Add(int64(n))
+	j.enqueued = time.Now()
+	select {
+	case p.queues[p.shard(node)] <- j:
+		return nil
+	default:
+		p.queued.Add(-int64(n))
+		p.rejected.Add(uint64(n))
+		return throttleError(minRetryDelay, "ingest-queue", "server ingestion queue is full")
+	}
+}
+
+// shard는 node의 batch를 처리할 worker 번호다.
+func (p *pipeline) shard(node string) int {
+	h := fnv.New32a()
+	h.Write([]byte(node))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// depth는 큐에 있는 batch 수다.
+func (p *pipeline) depth() int {
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// capacity는 모든 큐에 쌓을 수 있는 batch 수다.
+func (p *pipeline) capacity() int {
+	return len(p.queues) * cap(p.queues[0])
+}
+
+// close는 새 batch를 더 받지 않고, 큐에 남은 batch를 모두 저장한 뒤 반환한다.
+func (p *pipeline) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testJob은 이벤트 n개짜리 batch의 job이다. batch의 Seq로 job을 구분한다.
+func testJob(seq uint64, n int) ingestJob {
+	return ingestJob{batch: &nefiv1.EventBatch{Seq: seq, Events: make([]*nefiv1.TraceEvent, n)}}
+}
+
+func TestPipelineShard(t *testing.T) {
+	p := newPipeline(8, 1, func(ingestJob) {})
+	defer p.close()
+	other := newPipeline(8, 1, func(ingestJob) {})
+	defer other.close()
+
+	used := make(map[int]bool)
+	for i := range 200 {
+		node := fmt.Sprintf("node-%d", i)
+		w := p.shard(node)
+		if w != p.shard(node) || w != other.shard(node) {
+			t.Fatalf("%s moved between workers", node)
+		}
+		used[w] = true
+	}
+	if len(used) != 8 {
+		t.Errorf("200 nodes used %d of 8 workers", len(used))
+	}
+}
+
+func TestPipelineKeepsNodeOrder(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string][]uint64)
+	p := newPipeline(4, 1000, func(j ingestJob) {
+		mu.Lock()
+		seen[j.src.Node] = append(seen[j.src.Node], j.batch.GetSeq())
+		mu.Unlock()
+	})
+	for seq := range uint64(100) {
+		for _, node := range []string{"a", "b", "c", "d", "e"} {
+			j := testJob(seq, 1)
+			j.src.Node = node
+			if err := p.submit(node, j); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	p.close()
+	for node, seqs := range seen {
+		for i, seq := range seqs {
+			if seq != uint64(i) {
+				t.Fatalf("node %s processed out of order: %v", node, seqs)
+			}
+		}
+	}
+}
+
+func TestPipelineQueueFull(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	p := newPipeline(1, 1, func(ingestJob) {
+		started <- struct{}{}
+		<-release
+	})
+	defer p.close()
+
+	if err := p.submit("a", testJob(1, 3)); err != nil {
+		t.Fatal(err)
+	}
+	<-started // worker가 첫 batch를 처리 중이다
+	if err := p.submit("a", testJob(2, 5)); err != nil {
+		t.Fatal(err)
+	}
+	err := p.submit("b", testJob(3, 7)) // 큐(1)가 찼다
+	if status.Code(err) != codes.ResourceExhausted || quotaSubject(t, err) != "ingest-queue" {
+		t.Errorf("submit to a full queue: %v, want ResourceExhausted for ingest-queue", err)
+	}
+	if q, r := p.queued.Load(), p.rejected.Load(); q != 8 || r != 7 {
+		t.Errorf("queued=%d rejected=%d, want 8 and 7", q, r)
+	}
+	if d, c := p.depth(), p.capacity(); d != 1 || c != 1 {
+		t.Errorf("depth=%d capacity=%d, want 1 and 1", d, c)
+	}
+	close(release)
+	<-started
+}
+
+func TestPipelineCloseDrains(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var processed []uint64
+	p := newPipeline(2, 10, func(j ingestJob) {
+		<-release
+		mu.Lock()
+		processed = append(processed, j.batch.GetSeq())
+		mu.Unlock()
+	})
+	for seq := range uint64(10) {
+		if err := p.submit(fmt.Sprintf("node-%d", seq%3), testJob(seq, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		p.close()
+		close(closed)
+	}()
+	for { // close가 큐를 닫은 뒤에 worker를 풀어, 큐에 남은 batch를 저장하는지 본다
+		p.mu.RLock()
+		done := p.closed
+		p.mu.RUnlock()
+		if done {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+	<-closed
+	if len(processed) != 10 || p.queued.Load() != 0 {
+		t.Errorf("close returned after processing %d of 10 batches (queued %d)", len(processed), p.queued.Load())
+	}
+	if err := p.submit("node-0", testJob(10, 1)); status.Code(err) != codes.Unavailable {
+		t.Errorf("submit after close: %v, want Unavailable", err)
+	}
+	p.close() // 두 번 닫아도 된다
+}
@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// nodeIdleTTL이 지나도록 이벤트가 없는 노드의 bucket은 버린다 (노드 교체로 map이 커지지 않게).
+const nodeIdleTTL = 10 * time.Minute
+
+// quota는 수집 이벤트 속도를 노드별, 전체로 제한한다.
+//
+// 노드 하나가 폭주(루프 도는 health check, 잘못된 sidecar 설정 등)하면 store와
+// 집계를 다른 노드와 나눠 쓰므로 클러스터 전체 조회가 느려진다. token bucket으로 노드별과
+// 전체 초당 이벤트 수를 제한하고, 초과한 batch는 저장하지 않고 ResourceExhausted와
+// RetryInfo로 거부한다. agent는 ack받지 못한 batch를 안내받은 시간 뒤에 다시 보낸다.
+type quota struct {
+	global    *rate.Limiter // nil이면 전체 제한 없음
+	nodeRate  rate.Limit    // 0이면 노드별 제한 없음
+	nodeBurst int
+
+	throttledNode   atomic.Uint64 // 노드별 제한으로 거부한 이벤트 수
+	throttledGlobal atomic.Uint64 // 전체 제한으로 거부한 이벤트 수
+
+	mu        sync.Mutex
+	nodes     map[string]*nodeBucket
+	lastPrune time.Time
+
+	now func() time.Time // 테스트에서 교체
+}
+
+type nodeBucket struct {
+	lim      *rate.Limiter
+	lastUsed time.Time
+}
+
+func newQuota(nodeRate float64, nodeBurst int, globalRate float64, globalBurst int) *quota {
+	q := &quota{nodes: make(map[string]*nodeBucket), lastPrune: time.Now(), now: time.Now}
+	if nodeRate > 0 {
+		q.nodeRate = rate.Limit(nodeRate)
+		q.nodeBurst = max(nodeBurst, 1)
+	}
+	if globalRate > 0 {
+		q.global = rate.NewLimiter(rate.Limit(globalRate), max(globalBurst, 1))
+	}
+	return q
+}
+
+// take는 node에서 온 이벤트 n개를 수락할지 판단한다. 거부 시 agent에 반환할 에러를 돌려준다.
+// burst보다 큰 batch는 burst만큼 토큰을 쓴다 — 그렇지 않으면 같은 batch를 재전송해도
+// 영원히 수락되지 않는다.
+func (q *quota) take(node string, n int) error {
+	if n == 0 || (q.nodeRate == 0 && q.global == nil) {
+		return nil
+	}
+	now := q.now()
+	var nodeRes *rate.Reservation
+	if q.nodeRate > 0 {
+		lim := q.bucket(node, now)
+		nodeRes = lim.ReserveN(now, min(n, lim.Burst()))
+		if d := nodeRes.DelayFrom(now); d > 0 {
+			nodeRes.CancelAt(now)
+			q.throttledNode.Add(uint64(n))
+			return throttleError(d, "node:"+node,
+				fmt.Sprintf("node %s exceeds its ingestion limit of %.0f events/s", node, float64(q.nodeRate)))
+		}
+	}
+	if q.global != nil {
+		r := q.global.ReserveN(now, min(n, q.global.Burst()))
+		if d := r.DelayFrom(now); d > 0 {
+			r.CancelAt(now)
+			if nodeRes != nil {
+				nodeRes.CancelAt(now)
+			}
+			q.throttledGlobal.Add(uint64(n))
+			return throttleError(d, "global",
+				fmt.Sprintf("server exceeds its ingestion limit of %.0f events/s", float64(q.global.Limit())))
+		}
+	}
+	return nil
+}
+
+// bucket은 node의 token bucket을 반환하고, 가끔 오래 쓰지 않은 bucket을 정리한다.
+func (q *quota) bucket(node string, now time.Time) *rate.Limiter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if now.Sub(q.lastPrune) > nodeIdleTTL {
+		for k, b := range q.nodes {
+			if now.Sub(b.lastUsed) > nodeIdleTTL {
+				delete(q.nodes, k)
+			}
+		}
+		q.lastPrune = now
+	}
+	b, ok := q.nodes[node]
+	if !ok {
+		b = &nodeBucket{lim: rate.NewLimiter(q.nodeRate, q.nodeBurst)}
+		q.nodes[node] = b
+	}
+	b.lastUsed = now
+	return b.lim
+}
+
+// throttleError는 수집 속도 제한 거부 에러다. agent는 RetryInfo의 시간만큼 기다려 재시도하고,
+// QuotaFailure의 subject로 어느 제한에 걸렸는지 로그로 남길 수 있다.
+func throttleError(delay time.Duration, subject, desc string) error {
+	delay = max(delay, minRetryDelay)
+	st := status.New(codes.ResourceExhausted, desc)
+	detailed, err := st.WithDetails(
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: desc}}},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// quotaKey는 속도 제한을 적용하는 노드 키다. 노드 이름이 없으면 호출마다 바뀌는
+// peer 포트 대신 호스트로 구분한다.
+func quotaKey(info agents.Info) string {
+	node := info.Addr
+	if info.NodeName != "" {
+		node = info.NodeName
+	} else if host, _, err := net.SplitHostPort(info.Addr); err == nil {
+		node = host
+	}
+	if info.Tenant != "" {
+		return info.Tenant + "/" + node
+	}
+	return node
+}
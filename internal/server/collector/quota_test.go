@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// frozenQuota는 시계를 멈춘 quota다. 테스트는 *now를 옮겨 시간을 흘린다.
+func frozenQuota(nodeRate float64, nodeBurst int, globalRate float64, globalBurst int) (*quota, *time.Time) {
+	now := time.Now()
+	q := newQuota(nodeRate, nodeBurst, globalRate, globalBurst)
+	q.now = func() time.Time { return now }
+	return q, &now
+}
+
+func TestQuotaTake(t *testing.T) {
+	type take struct {
+		node    string
+		n       int
+		advance time.Duration // take 전에 흘릴 시간
+		want    string        // "" = 수락, 아니면 QuotaFailure subject
+	}
+	tests := []struct {
+		name                 string
+		nodeRate, globalRate float64
+		nodeBurst, globBurst int
+		takes                []take
+	}{
+		{"unlimited", 0, 0, 0, 0, []take{{"a", 1 << 20, 0, ""}}},
+		{"batch larger than burst", 10, 0, 5, 0, []take{
+			{"a", 100, 0, ""}, // burst만큼만 쓰므로 재전송이 언젠가 수락된다
+			{"a", 1, 0, "node:a"},
+			{"b", 5, 0, ""}, // 노드마다 bucket이 따로다
+			{"a", 5, 500 * time.Millisecond, ""},
+		}},
+		{"global limit", 0, 10, 0, 8, []take{
+			{"a", 5, 0, ""},
+			{"b", 5, 0, "global"},
+			{"b", 3, 0, ""},
+		}},
+		{"empty batch", 1, 0, 1, 0, []take{{"a", 1, 0, ""}, {"a", 0, 0, ""}}},
+	}
+	for _, tt := range tests {
+		q, now := frozenQuota(tt.nodeRate, tt.nodeBurst, tt.globalRate, tt.globBurst)
+		for i, tk := range tt.takes {
+			*now = now.Add(tk.advance)
+			err := q.take(tk.node, tk.n)
+			if got := quotaSubject(t, err); got != tk.want {
+				t.Errorf("%s: take #%d (%s, %d) rejected by %q, want %q", tt.name, i, tk.node, tk.n, got, tk.want)
+			}
+		}
+	}
+}
+
+func TestQuotaGlobalRejectReturnsNodeTokens(t *testing.T) {
+	q, now := frozenQuota(100, 10, 100, 5)
+	if err := q.take("b", 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := quotaSubject(t, q.take("a", 5)); got != "global" {
+		t.Fatalf("rejected by %q, want global", got)
+	}
+	if tokens := q.nodes["a"].lim.TokensAt(*now); tokens != 10 {
+		t.Errorf("node a has %v tokens after the global rejection, want all 10 back", tokens)
+	}
+	if n, g := q.throttledNode.Load(), q.throttledGlobal.Load(); n != 0 || g != 5 {
+		t.Errorf("throttled node=%d global=%d, want 0 and 5", n, g)
+	}
+}
+
+func TestQuotaPrunesIdleBuckets(t *testing.T) {
+	q, now := frozenQuota(100, 10, 0, 0)
+	q.lastPrune = *now
+	q.take("a", 1)
+	*now = now.Add(nodeIdleTTL / 2)
+	q.take("b", 1)
+
+	*now = now.Add(nodeIdleTTL/2 + time.Second) // a만 nodeIdleTTL 넘게 쉬었다
+	q.take("c", 1)
+	if _, ok := q.nodes["a"]; ok {
+		t.Error("idle bucket a was not pruned")
+	}
+	if _, ok := q.nodes["b"]; !ok {
+		t.Error("bucket b was pruned before nodeIdleTTL")
+	}
+
+	*now = now.Add(time.Minute) // 정리는 nodeIdleTTL에 한 번만 돈다
+	q.nodes["stale"] = &nodeBucket{lastUsed: now.Add(-2 * nodeIdleTTL)}
+	q.take("c", 1)
+	if _, ok := q.nodes["stale"]; !ok {
+		t.Error("buckets pruned again within nodeIdleTTL")
+	}
+}
+
+func TestThrottleErrorDetails(t *testing.T) {
+	q, _ := frozenQuota(1, 1, 0, 0)
+	q.take("a", 1)
+	st := status.Convert(q.take("a", 1))
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want ResourceExhausted", st.Code())
+	}
+	var retry *errdetails.RetryInfo
+	var failure *errdetails.QuotaFailure
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.RetryInfo:
+			retry = d
+		case *errdetails.QuotaFailure:
+			failure = d
+		}
+	}
+	// 1 event/s bucket은 1초 뒤에 토큰이 찬다. 더 짧아도 minRetryDelay보다 빨리 재시도시키지 않는다.
+	if retry == nil || retry.GetRetryDelay().AsDuration() < minRetryDelay {
+		t.Errorf("RetryInfo = %v, want a delay of at least %v", retry, minRetryDelay)
+	}
+	if failure == nil || len(failure.GetViolations()) != 1 || failure.GetViolations()[0].GetSubject() != "node:a" {
+		t.Errorf("QuotaFailure = %v, want one violation for node:a", failure)
+	}
+
+	short := status.Convert(throttleError(time.Millisecond, "global", "over"))
+	for _, d := range short.Details() {
+		if r, ok := d.(*errdetails.RetryInfo); ok && r.GetRetryDelay().AsDuration() != minRetryDelay {
+			t.Errorf("1ms delay advertised as %v, want %v", r.GetRetryDelay().AsDuration(), minRetryDelay)
+		}
+	}
+}
+
+func TestQuotaKey(t *testing.T) {
+	tests := []struct {
+		info agents.Info
+		want string
+	}{
+		{agents.Info{NodeName: "node-1", Addr: "10.0.0.1:5000"}, "node-1"},
+		{agents.Info{Addr: "10.0.0.1:5000"}, "10.0.0.1"}, // 재연결마다 바뀌는 포트는 뺀다
+		{agents.Info{Addr: "[fd00::1]:5000"}, "fd00::1"},
+		{agents.Info{Addr: "bufconn"}, "bufconn"},
+		{agents.Info{Tenant: "shop", NodeName: "node-1"}, "shop/node-1"},
+		{agents.Info{Tenant: "shop", Addr: "10.0.0.1:5000"}, "shop/10.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := quotaKey(tt.info); got != tt.want {
+			t.Errorf("quotaKey(%+v) = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+}
+
+// quotaSubject는 take의 거부 에러가 가리키는 QuotaFailure subject다 ("" = 수락).
+func quotaSubject(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	for _, d := range status.Convert(err).Details() {
+		if f, ok := d.(*errdetails.QuotaFailure); ok && len(f.GetViolations()) > 0 {
+			return f.GetViolations()[0].GetSubject()
+		}
+	}
+	t.Fatalf("rejection without QuotaFailure: %v", err)
+	return ""
+}
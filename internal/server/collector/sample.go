@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/aggregator"
+	"github.com/gihongjo/nefi/internal/server/enrich"
+)
+
+// 연결 이벤트 샘플링 키 (Config.ConnSampleBy, --conn-sample-by).
+const (
+	SampleByService = "service" // tenant, cluster, namespace, workload
+	SampleByNode    = "node"    // tenant, 노드
+)
+
+// ValidSampleBy는 --conn-sample-by 값이 올바른지 검사한다.
+func ValidSampleBy(by string) error {
+	switch by {
+	case "", SampleByService, SampleByNode:
+		return nil
+	}
+	return fmt.Errorf("unknown sampling key %q (want %s or %s)", by, SampleByService, SampleByNode)
+}
+
+// sampleIdleTTL마다 지난 초에 이벤트가 없던 키를 버린다.
+const sampleIdleTTL = time.Minute
+
+// connSampler는 저장할 연결 관측 이벤트를 서비스 또는 노드별 초당 상한으로 확률 샘플링한다.
+//
+// 연결 관측은 payload 없는 주기적 재관측이라 트래픽 폭주(재시도 폭풍, connection churn)
+// 때 가장 먼저 폭증해 store를 채운다. 키의 초당 이벤트 수(현재 초와 직전 초 중 큰 값)가
+// 상한 이하면 모두 저장하고, 넘으면 상한/초당 수의 확률로 저장해 키마다 초당 약 상한만큼
+// 남긴다. 빠진 이벤트도 store.Publish로 집계에 넘기므로 서비스별 연결 수는 줄지 않는다.
+// 수집 속도 제한(quota)과 달리 agent에 거부하지 않는다 — 재전송해도 같은 결과이기 때문이다.
+type connSampler struct {
+	limit float64 // 키별 초당 저장 상한, 0이면 샘플링하지 않는다
+	by    string
+
+	dropped atomic.Uint64 // 샘플링으로 저장하지 않은 연결 이벤트 수
+
+	mu        sync.Mutex
+	windows   map[string]*sampleWindow
+	lastPrune time.Time
+
+	now    func() time.Time // 테스트에서 교체
+	random func() float64   // [0, 1) 난수, 테스트에서 교체
+}
+
+type sampleWindow struct {
+	sec  int64
+	n    int // 현재 초의 이벤트 수
+	prev int // 직전 초의 이벤트 수
+}
+
+func newConnSampler(limit float64, by string) *connSampler {
+	if by == "" {
+		by = SampleByService
+	}
+	return &connSampler{
+		limit:     limit,
+		by:        by,
+		windows:   make(map[string]*sampleWindow),
+		lastPrune: time.Now(),
+		now:       time.Now,
+		random:    rand.Float64,
+	}
+}
+
+// keep은 event를 저장할지 판단한다. 연결 관측이 아닌 이벤트는 항상 저장한다.
+func (c *connSampler) keep(event *nefiv1.TraceEvent, src enrich.Source) bool {
+	if c.limit <= 0 || !event.GetConnection() {
+		return true
+	}
+	now := c.now()
+	sec := now.Unix()
+	key := c.key(event, src)
+
+	c.mu.Lock()
+	if now.Sub(c.lastPrune) > sampleIdleTTL {
+		for k, w := range c.windows {
+			if w.sec < sec-1 {
+				delete(c.windows, k)
+			}
+		}
+		c.lastPrune = now
+	}
+	w, ok := c.windows[key]
+	if !ok {
+		w = &sampleWindow{sec: sec}
+		c.windows[key] = w
+	}
+	if w.sec != sec {
+		w.prev = 0
+		if w.sec == sec-1 {
+			w.prev = w.n
+		}
+		w.sec, w.n = sec, 0
+	}
+	w.n++
+	observed := float64(max(w.n, w.prev))
+	c.mu.Unlock()
+
+	if observed <= c.limit || c.random() < c.limit/observed {
+		return true
+	}
+	c.dropped.Add(1)
+	return false
+}
+
+// key는 event의 샘플링 키다.
+func (c *connSampler) key(event *nefiv1.TraceEvent, src enrich.Source) string {
+	if c.by == SampleByNode {
+		node := event.GetNodeName()
+		if node == "" {
+			node = src.Node
+		}
+		return event.GetTenant() + "/" + node
+	}
+	return event.GetTenant() + "/" + event.GetCluster() + "/" + event.GetNamespace() + "/" + aggregator.EventWorkload(event)
+}
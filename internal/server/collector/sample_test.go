@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/enrich"
+)
+
+func TestConnSamplerRate(t *testing.T) {
+	const (
+		limit  = 100
+		perSec = 1000
+	)
+	now := time.Unix(1_000_000, 0)
+	c := newConnSampler(limit, SampleByNode)
+	c.now = func() time.Time { return now }
+	c.random = rand.New(rand.NewPCG(1, 2)).Float64
+
+	conn := &nefiv1.TraceEvent{Connection: true, NodeName: "node-1"}
+	other := &nefiv1.TraceEvent{Connection: true, NodeName: "node-2"}
+	data := &nefiv1.TraceEvent{NodeName: "node-1"}
+	var total, dataKept, otherKept int
+	for sec := range 10 {
+		kept := 0
+		for i := range perSec {
+			now = time.Unix(1_000_000+int64(sec), int64(i)*int64(time.Second/perSec))
+			if c.keep(conn, enrich.Source{}) {
+				kept++
+			}
+			if c.keep(data, enrich.Source{}) {
+				dataKept++
+			}
+			if i%10 == 0 && c.keep(other, enrich.Source{}) { // 초당 100개: 상한 이하
+				otherKept++
+			}
+		}
+		total += kept
+		// 첫 초는 관측 수가 늘어나는 동안 상한보다 많이 남긴다 (약 limit·(1+ln(perSec/limit))).
+		// 그 뒤로는 직전 초의 수를 기준으로 초당 약 limit개만 남긴다.
+		if sec > 0 && (kept < limit*8/10 || kept > limit*12/10) {
+			t.Errorf("second %d: kept %d connection events, want about %d", sec, kept, limit)
+		}
+	}
+	if dataKept != 10*perSec {
+		t.Errorf("kept %d of %d non-connection events, want all", dataKept, 10*perSec)
+	}
+	if otherKept != 10*perSec/10 {
+		t.Errorf("kept %d of %d events from a key under the limit, want all", otherKept, 10*perSec/10)
+	}
+	if got := c.dropped.Load(); got != uint64(10*perSec-total) {
+		t.Errorf("dropped = %d, want %d", got, 10*perSec-total)
+	}
+}
+
+func TestConnSamplerDisabled(t *testing.T) {
+	c := newConnSampler(0, "")
+	c.random = func() float64 { t.Fatal("sampler drew a random number with sampling disabled"); return 0 }
+	for range 1000 {
+		if !c.keep(&nefiv1.TraceEvent{Connection: true}, enrich.Source{}) {
+			t.Fatal("connection event dropped with sampling disabled")
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streams는 열린 agent 스트림의 마지막 수신 시각을 추적하고, idleTimeout 동안 아무것도
+// 보내지 않은 스트림을 닫는다.
+//
+// 죽은 TCP 연결은 gRPC keepalive가 끊지만, 연결은 살아 있고 agent만 멈춘 경우(캡처 루프가
+// 막힌 agent, 종료 중 멈춘 프로세스)의 스트림은 그대로 남아 registry 항목, 수신 고루틴과
+// 버퍼를 붙잡는다. idle로 닫힌 스트림의 agent가 살아 있다면 재연결해 ack받지 못한 batch를
+// 다시 보내므로 잃는 이벤트는 없다. agent는 보낼 이벤트가 없으면 batch도 보내지 않으므로,
+// 조용한 노드의 스트림도 idleTimeout마다 재연결한다 — 값은 이를 감안해 넉넉히 잡는다.
+type streams struct {
+	idleTimeout time.Duration // 0이면 닫지 않는다
+	stallAfter  time.Duration // 이보다 오래 조용한 스트림은 stalled로 센다
+
+	idleClosed atomic.Uint64 // idle로 닫은 스트림 수
+
+	mu   sync.Mutex
+	open map[*trackedStream]struct{}
+}
+
+type trackedStream struct {
+	last atomic.Int64 // 마지막 수신 시각 (UnixNano)
+}
+
+func newStreams(idleTimeout, stallAfter time.Duration) *streams {
+	return &streams{idleTimeout: idleTimeout, stallAfter: stallAfter, open: make(map[*trackedStream]struct{})}
+}
+
+// add는 스트림을 등록한다. 스트림이 끝나면 remove해야 한다.
+func (s *streams) add() *trackedStream {
+	ts := &trackedStream{}
+	ts.last.Store(time.Now().UnixNano())
+	s.mu.Lock()
+	s.open[ts] = struct{}{}
+	s.mu.Unlock()
+	return ts
+}
+
+func (s *streams) remove(ts *trackedStream) {
+	s.mu.Lock()
+	delete(s.open, ts)
+	s.mu.Unlock()
+}
+
+// counts는 열린 스트림을 최근 수신이 있는 것(active)과 stallAfter보다 오래 조용한 것(stalled)으로 센다.
+func (s *streams) counts() (active, stalled int) {
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ts := range s.open {
+		if time.Duration(now-ts.last.Load()) > s.stallAfter {
+			stalled++
+		} else {
+			active++
+		}
+	}
+	return active, stalled
+}
+
+type received[T any] struct {
+	msg T
+	err error
+}
+
+// streamReader는 스트림의 Recv를 idle 감시와 함께 감싼다. idleTimeout이 있으면 Recv를 별도
+// 고루틴에서 돌리고, handler는 메시지나 idle 만료를 기다린다 — 메시지 처리와 ack는 모두
+// handler 고루틴에 남는다. idle로 handler가 먼저 끝나면 gRPC가 스트림 context를 취소하므로
+// 수신 고루틴의 Recv도 에러로 끝난다.
+type streamReader[T any] struct {
+	s     *streams
+	ts    *trackedStream
+	recv  func() (T, error)
+	ch    chan received[T] // idle 감시 중일 때만
+	timer *time.Timer
+}
+
+// newStreamReader는 recv(stream.Recv)를 읽는 reader를 만들고 스트림을 등록한다.
+// 스트림이 끝나면 close해야 한다.
+func newStreamReader[T any](ctx context.Context, s *streams, recv func() (T, error)) *streamReader[T] {
+	r := &streamReader[T]{s: s, ts: s.add(), recv: recv}
+	if s.idleTimeout <= 0 {
+		return r
+	}
+	r.ch = make(chan received[T])
+	r.timer = time.NewTimer(s.idleTimeout)
+	go func() {
+		for {
+			msg, err := recv()
+			select {
+			case r.ch <- received[T]{msg, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// Recv는 다음 메시지를 반환한다. idleTimeout 동안 메시지가 없으면 Unavailable 에러다.
+func (r *streamReader[T]) Recv() (T, error) {
+	if r.ch == nil {
+		msg, err := r.recv()
+		r.ts.last.Store(time.Now().UnixNano())
+		return msg, err
+	}
+	r.timer.Reset(r.s.idleTimeout)
+	select {
+	case m := <-r.ch:
+		r.ts.last.Store(time.Now().UnixNano())
+		return m.msg, m.err
+	case <-r.timer.C:
+		r.s.idleClosed.Add(1)
+		var zero T
+		return zero, status.Errorf(codes.Unavailable, "no batch received for %s; closing the stalled stream", r.s.idleTimeout)
+	}
+}
+
+func (r *streamReader[T]) close() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.s.remove(r.ts)
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gihongjo/nefi/internal/metrics"
+	"github.com/gihongjo/nefi/internal/version"
+)
+
+// rpcKind는 batch/이벤트가 들어온 RPC다 (nefi_collector_*_received_total의 rpc label).
+// POST /api/v1/ingest는 SendBatch로 센다.
+type rpcKind int
+
+const (
+	rpcSendEvents rpcKind = iota
+	rpcStreamBatches
+	rpcSendBatch
+	numRPCKinds
+)
+
+var rpcKindNames = [numRPCKinds]string{"SendEvents", "StreamBatches", "SendBatch"}
+
+// latencyBuckets는 batch 처리 시간 histogram의 bucket 상한(초)이다.
+var latencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// ingestStats는 수신부터 저장까지의 수집 경로 지표다.
+// 검증, 속도 제한, 중복 제거, 큐 포화로 버린 수는 각 구성 요소가 따로 센다.
+type ingestStats struct {
+	batches [numRPCKinds]atomic.Uint64 // 받은 batch 수 (SendEvents는 이벤트 하나가 batch 하나)
+	events  [numRPCKinds]atomic.Uint64 // 받은 이벤트 수 (거부 전)
+
+	enrichDropped atomic.Uint64                        // 보강 stage가 버린 이벤트 수
+	upgraded      [version.SchemaVersion]atomic.Uint64 // 구버전 스키마에서 올린 이벤트 수 (인코딩 스키마별)
+
+	queueWait *metrics.HistogramValue // 큐에 넣은 뒤 worker가 꺼낼 때까지
+	store     *metrics.HistogramValue // worker가 batch를 보강, 검증, 저장하는 데 걸린 시간
+}
+
+func newIngestStats() *ingestStats {
+	return &ingestStats{
+		queueWait: metrics.NewHistogram(latencyBuckets...),
+		store:     metrics.NewHistogram(latencyBuckets...),
+	}
+}
+
+// received는 rpc로 n개 이벤트짜리 batch 하나를 받았음을 기록한다.
+func (st *ingestStats) received(rpc rpcKind, n int) {
+	st.batches[rpc].Add(1)
+	st.events[rpc].Add(uint64(n))
+}
+
+// processed는 enqueued에 큐에 넣은 batch를 started부터 지금까지 처리했음을 기록한다.
+func (st *ingestStats) processed(enqueued, started time.Time) {
+	st.queueWait.Observe(started.Sub(enqueued).Seconds())
+	st.store.Observe(time.Since(started).Seconds())
+}
+
+func (st *ingestStats) register(reg *metrics.Registry) {
+	perRPC := func(v *[numRPCKinds]atomic.Uint64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, numRPCKinds)
+			for r := range numRPCKinds {
+				samples = append(samples, metrics.Sample{Labels: metrics.Labels{"rpc": rpcKindNames[r]}, Value: float64(v[r].Load())})
+			}
+			return samples
+		}
+	}
+	reg.Register(metrics.Family{
+		Name:    "nefi_collector_batches_received_total",
+		Help:    "Batches received from agents and producers, by RPC (HTTP ingestion counts as SendBatch).",
+		Kind:    metrics.Counter,
+		Collect: perRPC(&st.batches),
+	})
+	reg.Register(metrics.Family{
+		Name:    "nefi_collector_events_received_total",
+		Help:    "Events received from agents and producers before validation, rate limiting and deduplication, by RPC.",
+		Kind:    metrics.Counter,
+		Collect: perRPC(&st.events),
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_events_enricher_dropped_total",
+		Help: "Events dropped by a server enrichment stage.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(st.enrichDropped.Load())}}
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_events_upgraded_total",
+		Help: "Events from agents on an older schema that the server upgraded to the current schema, by the schema they were encoded with.",
+		Kind: metrics.Counter,
+		Collect: func() []metrics.Sample {
+			samples := make([]metrics.Sample, 0, len(st.upgraded))
+			for v := version.MinAgentSchemaVersion; v < len(st.upgraded); v++ {
+				samples = append(samples, metrics.Sample{Labels: metrics.Labels{"schema": strconv.Itoa(v)}, Value: float64(st.upgraded[v].Load())})
+			}
+			return samples
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_batch_queue_wait_seconds",
+		Help: "Time a received batch waited in the ingestion queue before a worker picked it up.",
+		Kind: metrics.Histogram,
+		Collect: func() []metrics.Sample {
+			return st.queueWait.Samples(nil)
+		},
+	})
+	reg.Register(metrics.Family{
+		Name: "nefi_collector_batch_store_seconds",
+		Help: "Time an ingestion worker spent enriching, validating and storing one batch.",
+		Kind: metrics.Histogram,
+		Collect: func() []metrics.Sample {
+			return st.store.Samples(nil)
+		},
+	})
+}
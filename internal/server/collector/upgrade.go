@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"strconv"
+	"strings"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"github.com/gihongjo/nefi/internal/server/aggregator"
+	"github.com/gihongjo/nefi/internal/version"
+)
+
+// upgrades[v]는 스키마 v로 인코딩된 이벤트를 v+1의 모양으로 올린다 (nil = 바꿀 것 없음).
+// 스키마 v agent의 이벤트는 upgrades[v]부터 upgrades[SchemaVersion-1]까지 차례로 거친다.
+//
+// protobuf는 모르는 필드를 무시하고 없는 필드를 0 값으로 읽으므로 오래된 batch도 디코드는
+// 된다. 여기서는 그 0 값 중 server가 채울 수 있는 것을 채워, 구버전 agent의 이벤트도
+// topology, 연결 조회, 집계에서 현재 agent의 이벤트와 같게 보이게 한다. 필드 이름만 바꾸는
+// 변경은 번호가 같아 wire 형식이 같고, 번호를 바꾸는 변경은 옛 번호를 reserved로 두고
+// 그 값을 옮기는 단계를 여기에 추가한다.
+//
+// SchemaVersion을 올릴 때 새 단계를 추가한다. 단계는 stream 메타데이터의 노드 이름(node)만
+// 참고하며, 이미 채워진 필드는 바꾸지 않는다 — 같은 batch를 다시 올려도 결과가 같다.
+var upgrades = [version.SchemaVersion]func(node string, te *nefiv1.TraceEvent){
+	1: upgradeV1,
+	2: upgradeV2,
+}
+
+// upgradeV1: 스키마 1 agent는 노드 이름을 stream 메타데이터로만 보내고 이벤트마다 싣지 않으며,
+// 소켓 식별자(conn_id)도 보내지 않는다.
+func upgradeV1(node string, te *nefiv1.TraceEvent) {
+	if te.NodeName == "" {
+		te.NodeName = node
+	}
+	// agent grpc.ConnID와 같은 "<node>/<pid>/<fd>" 형식이다.
+	if te.ConnId == "" && te.NodeName != "" && te.Fd != 0 {
+		te.ConnId = te.NodeName + "/" + strconv.FormatUint(uint64(te.Pid), 10) + "/" + strconv.FormatUint(uint64(te.Fd), 10)
+	}
+}
+
+// upgradeV2: 스키마 2 agent는 빌드에 따라 workload(ownerReferences 해석)와 remote_kind를
+// 채우지 않는다. workload는 pod 이름에서 추출하고, remote_kind는 해석된 remote 필드로 정한다.
+func upgradeV2(_ string, te *nefiv1.TraceEvent) {
+	if te.Workload == "" && te.PodName != "" {
+		te.Workload = aggregator.WorkloadName(te.PodName)
+	}
+	if te.RemoteWorkload == "" && te.RemotePod != "" {
+		te.RemoteWorkload = aggregator.WorkloadName(te.RemotePod)
+	}
+	if te.RemoteKind == "" {
+		switch {
+		case te.RemoteExternal:
+			te.RemoteKind = model.RemoteKindExternal
+		case te.RemotePod != "":
+			te.RemoteKind = model.RemoteKindPod
+		case te.RemoteService != "":
+			te.RemoteKind = model.RemoteKindService
+		case strings.HasPrefix(te.RemoteName, "node/"):
+			te.RemoteKind = model.RemoteKindNode
+		}
+	}
+}
+
+// batchSchema는 batch가 인코딩된 스키마다. StreamBatches agent는 합의된 스키마를 batch에
+// 싣고, 그 전의 agent와 producer는 stream 메타데이터로만 보고한다. 둘 다 없으면 최초
+// 스키마(1)로 간주한다 (version.CheckAgent와 같다).
+func batchSchema(batch *nefiv1.EventBatch, info agents.Info) int {
+	if v := int(batch.GetSchemaVersion()); v > 0 {
+		return v
+	}
+	if info.SchemaVersion > 0 {
+		return info.SchemaVersion
+	}
+	return 1
+}
+
+// upgrade는 스키마 schema로 인코딩된 batch의 이벤트를 현재 스키마로 올린다.
+func (s *Service) upgrade(batch *nefiv1.EventBatch, schema int, node string) {
+	if schema >= version.SchemaVersion || schema < 1 {
+		return
+	}
+	for _, te := range batch.GetEvents() {
+		for v := schema; v < version.SchemaVersion; v++ {
+			if fn := upgrades[v]; fn != nil {
+				fn(node, te)
+			}
+		}
+	}
+	s.stats.upgraded[schema].Add(uint64(len(batch.GetEvents())))
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/agents"
+	"github.com/gihongjo/nefi/internal/version"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// readBatch는 testdata/upgrade의 protojson EventBatch를 읽는다.
+func readBatch(t *testing.T, name string) *nefiv1.EventBatch {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "upgrade", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := &nefiv1.EventBatch{}
+	if err := protojson.Unmarshal(data, batch); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return batch
+}
+
+// TestUpgradeFixtures는 지원하는 구버전 스키마마다 그 agent가 보내던 batch(schemaN.json)를
+// 현재 스키마로 올린 결과가 schemaN.want.json과 같은지 검사한다. MinAgentSchemaVersion이나
+// SchemaVersion을 바꾸면 fixture도 함께 추가해야 한다.
+func TestUpgradeFixtures(t *testing.T) {
+	for v := version.MinAgentSchemaVersion; v < version.SchemaVersion; v++ {
+		s := &Service{stats: &ingestStats{}}
+		batch := readBatch(t, fmt.Sprintf("schema%d.json", v))
+		want := readBatch(t, fmt.Sprintf("schema%d.want.json", v))
+
+		s.upgrade(batch, v, "node-1")
+		if !proto.Equal(batch, want) {
+			t.Errorf("schema %d:\ngot  %v\nwant %v", v, batch, want)
+		}
+		if n := s.stats.upgraded[v].Load(); n != uint64(len(batch.GetEvents())) {
+			t.Errorf("schema %d: upgraded = %d, want %d", v, n, len(batch.GetEvents()))
+		}
+		s.upgrade(batch, v, "node-1") // 이미 올린 batch를 다시 올려도 같다
+		if !proto.Equal(batch, want) {
+			t.Errorf("schema %d: second upgrade changed the batch:\n%v", v, batch)
+		}
+	}
+
+	current := readBatch(t, "schema1.json")
+	(&Service{stats: &ingestStats{}}).upgrade(current, version.SchemaVersion, "node-1")
+	if !proto.Equal(current, readBatch(t, "schema1.json")) {
+		t.Error("a batch on the current schema was changed")
+	}
+}
+
+func TestBatchSchema(t *testing.T) {
+	tests := []struct {
+		batch uint32
+		info  int
+		want  int
+	}{
+		{3, 2, 3}, // StreamBatches: batch의 스키마가 우선한다
+		{0, 2, 2},
+		{0, 0, 1},
+	}
+	for _, tt := range tests {
+		got := batchSchema(&nefiv1.EventBatch{SchemaVersion: tt.batch}, agents.Info{SchemaVersion: tt.info})
+		if got != tt.want {
+			t.Errorf("batchSchema(batch=%d, stream=%d) = %d, want %d", tt.batch, tt.info, got, tt.want)
+		}
+	}
+}
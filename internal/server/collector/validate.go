@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"slices"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// 검증 한도. 정상 agent가 만들 수 없는 값만 거른다.
+const (
+	// maxTimestampSkew는 batch의 중앙값에서 이만큼 떨어진 타임스탬프를 버리는 기준이다.
+	// agent의 타임스탬프는 노드의 CLOCK_MONOTONIC(bpf_ktime_get_ns)이라 server 시계와
+	// 비교할 수 없으므로, 같은 batch의 다른 이벤트와 비교한다.
+	maxTimestampSkew = time.Hour
+	maxMsgSize       = 1 << 30 // 관측한 메시지 크기(바이트)의 상한
+	maxPathLen       = 1024    // 넘는 http_path는 자른다 (집계 key와 index가 커지지 않게)
+	maxMethodLen     = 16
+	maxPayloadLen    = 4 * model.MaxMsgSize // agent는 MaxMsgSize까지만 캡처한다
+	maxLatency       = time.Hour            // 넘는 latency는 잘못 짝지은 요청/응답으로 보고 지운다
+)
+
+// rejectReason은 이벤트를 버린 이유다 (nefi_collector_events_rejected_total의 reason label).
+type rejectReason int
+
+const (
+	rejectNone rejectReason = iota
+	rejectTimestamp
+	rejectEndpoint
+	rejectSize
+	numRejectReasons
+)
+
+var rejectReasonNames = [numRejectReasons]string{"", "timestamp", "endpoint", "size"}
+
+// clampField는 잘라내거나 지운 필드다 (nefi_collector_events_clamped_total의 field label).
+type clampField int
+
+const (
+	clampPath clampField = iota
+	clampPayload
+	clampLatency
+	numClampFields
+)
+
+var clampFieldNames = [numClampFields]string{"http_path", "payload", "latency"}
+
+// validator는 저장 전에 명백히 잘못된 이벤트를 버리거나 값을 한도 안으로 자른다.
+// 오래된 agent 버그나 외부 producer의 잘못된 값이 index와 대시보드(집계 key,
+// 토폴로지, latency 분포)를 오염시키지 않게 한다.
+type validator struct {
+	rejected [numRejectReasons]atomic.Uint64
+	clamped  [numClampFields]atomic.Uint64
+}
+
+// check는 ev를 저장할 수 있는지 판단하고, 저장할 수 있으면 한도를 넘는 값을 자른다.
+// ref는 같은 batch의 타임스탬프 중앙값이다 (0 = 비교하지 않음).
+func (v *validator) check(ev *nefiv1.TraceEvent, ref uint64) bool {
+	if r := reject(ev, ref); r != rejectNone {
+		v.rejected[r].Add(1)
+		return false
+	}
+	if len(ev.HttpPath) > maxPathLen {
+		ev.HttpPath = truncateUTF8(ev.HttpPath, maxPathLen)
+		v.clamped[clampPath].Add(1)
+	}
+	if len(ev.Payload) > maxPayloadLen {
+		ev.Payload = ev.Payload[:maxPayloadLen]
+		v.clamped[clampPayload].Add(1)
+	}
+	if ev.LatencyNs > uint64(maxLatency) {
+		ev.LatencyNs = 0
+		v.clamped[clampLatency].Add(1)
+	}
+	return true
+}
+
+func reject(ev *nefiv1.TraceEvent, ref uint64) rejectReason {
+	if ev.TimestampNs == 0 {
+		return rejectTimestamp
+	}
+	if ref > 0 {
+		skew := ev.TimestampNs - ref
+		if ev.TimestampNs < ref {
+			skew = ref - ev.TimestampNs
+		}
+		if skew > uint64(maxTimestampSkew) {
+			return rejectTimestamp
+		}
+	}
+	if ev.HttpStatus != 0 && (ev.HttpStatus < 100 || ev.HttpStatus > 599) {
+		return rejectEndpoint
+	}
+	if ev.HttpMethod != "" && (ev.HttpPath == "" || len(ev.HttpMethod) > maxMethodLen) {
+		return rejectEndpoint
+	}
+	if ev.MsgSize > maxMsgSize {
+		return rejectSize
+	}
+	return rejectNone
+}
+
+// medianTimestamp는 events 타임스탬프의 중앙값이다 (0인 값 제외, 없으면 0).
+// 값 하나가 크게 틀려도 기준이 흔들리지 않도록 평균 대신 중앙값을 쓴다.
+func medianTimestamp(events []*nefiv1.TraceEvent) uint64 {
+	ts := make([]uint64, 0, len(events))
+	for _, ev := range events {
+		if ev.TimestampNs != 0 {
+			ts = append(ts, ev.TimestampNs)
+		}
+	}
+	if len(ts) == 0 {
+		return 0
+	}
+	slices.Sort(ts)
+	return ts[len(ts)/2]
+}
+
+// truncateUTF8는 s를 n바이트 이하로 자르되 멀티바이트 문자를 가르지 않는다.
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
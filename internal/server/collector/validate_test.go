@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+func TestReject(t *testing.T) {
+	const ref = uint64(100 * time.Hour)
+	skew := uint64(maxTimestampSkew)
+	tests := []struct {
+		name string
+		ev   *nefiv1.TraceEvent
+		ref  uint64
+		want rejectReason
+	}{
+		{"ok", &nefiv1.TraceEvent{TimestampNs: ref}, ref, rejectNone},
+		{"no timestamp", &nefiv1.TraceEvent{}, ref, rejectTimestamp},
+		{"skew after median", &nefiv1.TraceEvent{TimestampNs: ref + skew + 1}, ref, rejectTimestamp},
+		{"skew before median", &nefiv1.TraceEvent{TimestampNs: ref - skew - 1}, ref, rejectTimestamp},
+		{"skew at the limit after", &nefiv1.TraceEvent{TimestampNs: ref + skew}, ref, rejectNone},
+		{"skew at the limit before", &nefiv1.TraceEvent{TimestampNs: ref - skew}, ref, rejectNone},
+		{"no median", &nefiv1.TraceEvent{TimestampNs: 1}, 0, rejectNone},
+		{"status 100", &nefiv1.TraceEvent{TimestampNs: ref, HttpStatus: 100}, ref, rejectNone},
+		{"status 599", &nefiv1.TraceEvent{TimestampNs: ref, HttpStatus: 599}, ref, rejectNone},
+		{"status 99", &nefiv1.TraceEvent{TimestampNs: ref, HttpStatus: 99}, ref, rejectEndpoint},
+		{"status 600", &nefiv1.TraceEvent{TimestampNs: ref, HttpStatus: 600}, ref, rejectEndpoint},
+		{"negative status", &nefiv1.TraceEvent{TimestampNs: ref, HttpStatus: -1}, ref, rejectEndpoint},
+		{"method and path", &nefiv1.TraceEvent{TimestampNs: ref, HttpMethod: "GET", HttpPath: "/"}, ref, rejectNone},
+		{"method without path", &nefiv1.TraceEvent{TimestampNs: ref, HttpMethod: "GET"}, ref, rejectEndpoint},
+		{"long method", &nefiv1.TraceEvent{TimestampNs: ref, HttpMethod: strings.Repeat("A", maxMethodLen+1), HttpPath: "/"}, ref, rejectEndpoint},
+		{"path without method", &nefiv1.TraceEvent{TimestampNs: ref, HttpPath: "/"}, ref, rejectNone},
+		{"huge message", &nefiv1.TraceEvent{TimestampNs: ref, MsgSize: maxMsgSize + 1}, ref, rejectSize},
+	}
+	for _, tt := range tests {
+		if got := reject(tt.ev, tt.ref); got != tt.want {
+			t.Errorf("%s: reject = %q, want %q", tt.name, rejectReasonNames[got], rejectReasonNames[tt.want])
+		}
+	}
+}
+
+func TestCheckClamps(t *testing.T) {
+	var v validator
+	ev := &nefiv1.TraceEvent{
+		TimestampNs: 1,
+		HttpMethod:  "GET",
+		HttpPath:    "/" + strings.Repeat("a", maxPathLen),
+		Payload:     make([]byte, maxPayloadLen+1),
+		LatencyNs:   uint64(maxLatency) + 1,
+	}
+	if !v.check(ev, 0) {
+		t.Fatal("event with oversized fields rejected, want clamped")
+	}
+	if len(ev.HttpPath) != maxPathLen || len(ev.Payload) != maxPayloadLen || ev.LatencyNs != 0 {
+		t.Errorf("clamped to path=%d payload=%d latency=%d, want %d, %d, 0",
+			len(ev.HttpPath), len(ev.Payload), ev.LatencyNs, maxPathLen, maxPayloadLen)
+	}
+	for f := range numClampFields {
+		if n := v.clamped[f].Load(); n != 1 {
+			t.Errorf("clamped[%s] = %d, want 1", clampFieldNames[f], n)
+		}
+	}
+
+	ok := &nefiv1.TraceEvent{TimestampNs: 1, HttpPath: "/x", Payload: make([]byte, maxPayloadLen), LatencyNs: uint64(maxLatency)}
+	if !v.check(ok, 0) || ok.HttpPath != "/x" || len(ok.Payload) != maxPayloadLen || ok.LatencyNs != uint64(maxLatency) {
+		t.Errorf("event within limits changed: %+v", ok)
+	}
+	if v.check(&nefiv1.TraceEvent{}, 0) || v.rejected[rejectTimestamp].Load() != 1 {
+		t.Error("rejection not counted")
+	}
+}
+
+func TestMedianTimestamp(t *testing.T) {
+	tests := []struct {
+		ts   []uint64
+		want uint64
+	}{
+		{nil, 0},
+		{[]uint64{0, 0}, 0},
+		{[]uint64{5}, 5},
+		{[]uint64{30, 10, 20}, 20},
+		{[]uint64{10, 0, 1 << 62, 20, 30}, 30}, // 0은 빼고, 크게 틀린 값 하나는 기준을 흔들지 않는다
+	}
+	for _, tt := range tests {
+		events := make([]*nefiv1.TraceEvent, len(tt.ts))
+		for i, ts := range tt.ts {
+			events[i] = &nefiv1.TraceEvent{TimestampNs: ts}
+		}
+		if got := medianTimestamp(events); got != tt.want {
+			t.Errorf("medianTimestamp(%v) = %d, want %d", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abcdef", 3, "abc"},
+		{"ab한글", 3, "ab"},  // 한(3바이트)의 첫 바이트에서 자르지 않는다
+		{"ab한글", 4, "ab"},  // 한의 중간
+		{"ab한글", 5, "ab한"}, // 한 바로 뒤
+		{"한", 1, ""},
+		{"a😀b", 4, "a"},
+	}
+	for _, tt := range tests {
+		got := truncateUTF8(tt.s, tt.n)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package demo는 클러스터 없이 UI/API를 시연하기 위한 합성 트래픽 생성기다.
+//
+// 가상의 서비스 몇 개(frontend → api-gateway → orders/users/catalog → payments/inventory)가
+// 서로 HTTP를 호출하는 것처럼 TraceEvent를 만들어, 실제 agent와 똑같이
+// server 자신의 gRPC collector(SendEvents)로 스트리밍한다.
+// 따라서 HTTP 파싱, 요청/응답 매칭, 집계, topology, WebSocket 전파가 모두 실제 경로를 탄다.
+//
+// 주기적으로 서비스 하나를 골라 일정 시간 동안 에러율과 레이턴시를 올리는
+// 장애(incident)를 주입한다.
+package demo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
+	"github.com/gihongjo/nefi/internal/version"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	namespace        = "demo"
+	nodeName         = "demo-node"
+	incidentEvery    = 3 * time.Minute
+	incidentDuration = 45 * time.Second
+	retryInterval    = 2 * time.Second
+)
+
+// service는 가상 서비스 하나다.
+type service struct {
+	name      string
+	pod       string        // 고정 pod 이름 (workload 이름 추출이 가능한 형식)
+	ip        uint32        // 가상 pod IP
+	port      uint16        // listen 포트
+	latency   time.Duration // 정상 시 평균 처리 시간 (자기 자신만)
+	errorRate float64       // 정상 시 5xx 비율
+	paths     []string
+	calls     []string // 요청 처리 중 호출하는 하위 서비스
+}
+
+// topology는 데모 서비스 그래프다. frontend가 진입점이다.
+var topology = []*service{
+	{name: "frontend", latency: 4 * time.Millisecond, errorRate: 0.002,
+		paths: []string{"/", "/products", "/cart"}, calls: []string{"api-gateway"}},
+	{name: "api-gateway", latency: 2 * time.Millisecond, errorRate: 0.001,
+		paths: []string{"/api/orders", "/api/users/me", "/api/catalog"}, calls: []string{"orders", "users", "catalog"}},
+	{name: "orders", latency: 12 * time.Millisecond, errorRate: 0.01,
+		paths: []string{"/orders", "/orders/42"}, calls: []string{"payments", "inventory"}},
+	{name: "users", latency: 6 * time.Millisecond, errorRate: 0.003,
+		paths: []string{"/users/me"}},
+	{name: "catalog", latency: 9 * time.Millisecond, errorRate: 0.004,
+		paths: []string{"/items", "/items/7"}, calls: []string{"inventory"}},
+	{name: "payments", latency: 35 * time.Millisecond, errorRate: 0.02,
+		paths: []string{"/charge"}},
+	{name: "inventory", latency: 8 * time.Millisecond, errorRate: 0.005,
+		paths: []string{"/stock"}},
+}
+
+// Config는 생성기 설정이다.
+type Config struct {
+	// Rate는 초당 진입점(frontend) 요청 수다. 하위 호출은 그래프를 따라 추가로 생성된다.
+	Rate float64
+}
+
+// Run은 ctx가 취소될 때까지 addr의 collector로 합성 이벤트를 스트리밍한다.
+// 스트림이 끊기면 잠시 후 다시 연결한다.
+func Run(ctx context.Context, addr string, cfg Config) {
+	if cfg.Rate <= 0 {
+		cfg.Rate = 5
+	}
+	g := newGenerator()
+	for {
+		err := g.stream(ctx, addr, cfg.Rate)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[demo] stream error: %v — retrying in %v", err, retryInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+type generator struct {
+	byName   map[string]*service
+	nextFD   uint32
+	incident string    // 장애 중인 서비스 이름 ("" = 없음)
+	until    time.Time // incident 종료 시각
+	nextAt   time.Time // 다음 incident 시작 시각
+}
+
+func newGenerator() *generator {
+	g := &generator{byName: make(map[string]*service), nextFD: 10, nextAt: time.Now().Add(incidentEvery / 3)}
+	for i, s := range topology {
+		s.pod = fmt.Sprintf("%s-7d9f8b6c5-%05d", s.name, 10000+i*7919)
+		s.ip = 10<<24 | 244<<16 | 1<<8 | uint32(10+i)
+		s.port = 8080
+		g.byName[s.name] = s
+	}
+	return g
+}
+
+func (g *generator) stream(ctx context.Context, addr string, rate float64) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	info := version.Get()
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(
+		version.MDNodeName, nodeName,
+		version.MDVersion, info.Version,
+		version.MDGitCommit, info.GitCommit,
+		version.MDBuildDate, info.BuildDate,
+		version.MDSchemaVersion, strconv.Itoa(info.SchemaVersion),
+	))
+	st, err := nefiv1.NewNefiCollectorClient(conn).SendEvents(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("[demo] generating synthetic traffic: %d services, %.1f req/s at the edge", len(topology), rate)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			st.CloseAndRecv() //nolint:errcheck
+			return ctx.Err()
+		case now := <-ticker.C:
+			g.updateIncident(now)
+			var events []*nefiv1.TraceEvent
+			g.call(nil, g.byName["frontend"], now, &events)
+			for _, ev := range events {
+				if err := st.Send(ev); err != nil {
+					_, err = st.CloseAndRecv()
+					return err
+				}
+			}
+		}
+	}
+}
+
+// updateIncident는 incident를 시작/종료한다.
+func (g *generator) updateIncident(now time.Time) {
+	if g.incident != "" && now.After(g.until) {
+		log.Printf("[demo] incident resolved: %s", g.incident)
+		g.incident = ""
+		g.nextAt = now.Add(incidentEvery)
+	}
+	if g.incident == "" && now.After(g.nextAt) {
+		// 진입점은 제외하고 하위 서비스 중 하나를 고른다 (cascading failure 시연).
+		s := topology[1+rand.IntN(len(topology)-1)]
+		g.incident = s.name
+		g.until = now.Add(incidentDuration)
+		log.Printf("[demo] incident: %s degraded for %v", s.name, incidentDuration)
+	}
+}
+
+// call은 caller → callee 요청 하나를 시뮬레이션하고, 하위 호출을 재귀적으로 생성한다.
+// 반환값은 callee의 응답 status와 총 소요 시간이다.
+// caller가 nil이면 클러스터 외부(인터넷) 클라이언트의 요청으로, 이벤트는 만들지 않는다.
+func (g *generator) call(caller, callee *service, start time.Time, events *[]*nefiv1.TraceEvent) (int, time.Duration) {
+	latency := jitter(callee.latency)
+	errorRate := callee.errorRate
+	if callee.name == g.incident {
+		latency *= 6
+		errorRate = 0.3
+	}
+
+	status := 200
+	if rand.Float64() < errorRate {
+		status = 503
+	}
+	// 하위 호출 — 하나라도 실패하면 502로 전파한다.
+	elapsed := latency
+	if status == 200 {
+		for _, name := range callee.calls {
+			sub := g.byName[name]
+			subStatus, d := g.call(callee, sub, start.Add(elapsed), events)
+			elapsed += d
+			if subStatus >= 500 {
+				status = 502
+				break
+			}
+		}
+	}
+
+	if caller != nil {
+		g.nextFD++
+		path := callee.paths[rand.IntN(len(callee.paths))]
+		method := "GET"
+		if callee.name == "payments" || path == "/orders" {
+			method = "POST"
+		}
+		req := g.event(caller, callee, start, 0, 1, fmt.Sprintf("%s %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\n\r\n", method, path, callee.name, caller.name))
+		res := g.event(caller, callee, start.Add(elapsed), 1, 2, fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}", status, statusText(status)))
+		*events = append(*events, req, res)
+	}
+	return status, elapsed
+}
+
+// event는 caller pod에서 관측한 클라이언트 측 이벤트를 만든다 (agent와 같은 필드 구성).
+func (g *generator) event(caller, callee *service, ts time.Time, direction, msgType uint32, payload string) *nefiv1.TraceEvent {
+	return &nefiv1.TraceEvent{
+		TimestampNs: uint64(ts.UnixNano()),
+		Pid:         1000 + uint32(len(caller.name)),
+		Fd:          g.nextFD,
+		MsgSize:     uint32(len(payload)),
+		Direction:   direction,
+		Protocol:    1, // HTTP
+		MsgType:     msgType,
+		Comm:        caller.name,
+		NodeName:    nodeName,
+		Namespace:   namespace,
+		PodName:     caller.pod,
+		RemoteIp:    callee.ip,
+		RemotePort:  uint32(callee.port),
+		RemoteNs:    namespace,
+		RemotePod:   callee.pod,
+		RemoteKind:  model.RemoteKindPod,
+		Payload:     []byte(payload),
+		ConnId:      fmt.Sprintf("%s/%d/%d", nodeName, 1000+len(caller.name), g.nextFD),
+	}
+}
+
+// jitter는 d를 [0.5d, 1.5d) 범위로 흔든다.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int64N(int64(d)))
+}
+
+func statusText(code int) string {
+	switch code {
+	case 200:
+		return "OK"
+	case 502:
+		return "Bad Gateway"
+	default:
+		return "Service Unavailable"
+	}
+}
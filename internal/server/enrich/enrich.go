@@ -0,0 +1,133 @@
+// Package enrich는 collector가 저장 직전의 이벤트에 server 쪽 메타데이터를 붙이는
+// 순서 있는 stage chain이다.
+//
+// agent의 enrich 패키지와 같은 구조지만, 노드 로컬 정보(pod, cgroup) 대신 모든 agent와
+// producer에 공통인 정보를 다룬다. 순서는 --enrichers flag로 정하며 기본값은
+// "cluster,external,geoip,route"이다:
+//
+//	cluster  — cluster가 빈 이벤트에 보낸 agent의 클러스터(handshake) 또는 --default-cluster-name 부여
+//	external — 해석되지 않은 remote(HTTP 수집, 구버전 agent)에 CIDR/클라우드 대역 이름 부여
+//	geoip    — external remote에 국가 label 부여 (CIDR→국가 파일)
+//	route    — HTTP 이벤트에 ID를 템플릿으로 바꾼 path(http_route) 부여 (--route-rules로 규칙 추가)
+//
+// 설정되지 않은 stage(예: --geoip-cidrs 없이 geoip)는 chain에서 빠진다.
+// stage가 false를 반환하면 이벤트는 버려지고 뒤 stage는 실행되지 않는다.
+//
+// 조직별 메타데이터(팀, 비용 센터, 서비스 소유자 등)는 Enricher를 구현해
+// collector.Config.Enrichers 뒤에 붙인다.
+package enrich
+
+import (
+	"fmt"
+	"strings"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// Stage 이름 (--enrichers 값).
+const (
+	StageCluster  = "cluster"
+	StageExternal = "external"
+	StageGeoIP    = "geoip"
+	StageRoute    = "route"
+)
+
+// DefaultStages는 --enrichers 기본값이다.
+const DefaultStages = StageCluster + "," + StageExternal + "," + StageGeoIP + "," + StageRoute
+
+var knownStages = []string{StageCluster, StageExternal, StageGeoIP, StageRoute}
+
+// Source는 이벤트를 보낸 agent 또는 producer다. 메타데이터는 스트림/호출 단위로 받은 값이다.
+type Source struct {
+	Node     string // x-nefi-node-name
+	Cluster  string // x-nefi-cluster (agent --cluster-name)
+	Identity string // mTLS client 인증서에서 읽은 신원 (평문이면 빈 문자열)
+	Tenant   string // 인증서의 O 또는 x-nefi-tenant (collector가 이벤트의 tenant로 기록)
+}
+
+// Enricher는 server 보강 stage 하나다. 여러 worker 고루틴에서 동시에 호출된다.
+type Enricher interface {
+	// Name은 로그와 /configz에 표시되는 stage 이름이다.
+	Name() string
+	// Enrich는 src가 보낸 te를 보강한다. false를 반환하면 이벤트를 버린다.
+	Enrich(src Source, te *nefiv1.TraceEvent) bool
+}
+
+// Func는 함수를 Enricher로 쓰기 위한 어댑터다.
+func Func(name string, fn func(src Source, te *nefiv1.TraceEvent) bool) Enricher {
+	return funcEnricher{name: name, fn: fn}
+}
+
+type funcEnricher struct {
+	name string
+	fn   func(src Source, te *nefiv1.TraceEvent) bool
+}
+
+func (f funcEnricher) Name() string { return f.name }
+
+func (f funcEnricher) Enrich(src Source, te *nefiv1.TraceEvent) bool {
+	return f.fn(src, te)
+}
+
+// Pipeline은 stage를 순서대로 실행한다.
+type Pipeline struct {
+	stages []Enricher
+}
+
+// New는 stages를 주어진 순서로 실행하는 Pipeline을 만든다. nil stage는 무시한다.
+func New(stages ...Enricher) *Pipeline {
+	p := &Pipeline{}
+	for _, s := range stages {
+		if s != nil {
+			p.stages = append(p.stages, s)
+		}
+	}
+	return p
+}
+
+// Enrich는 모든 stage를 실행한다. 어떤 stage가 이벤트를 버리면 false다.
+func (p *Pipeline) Enrich(src Source, te *nefiv1.TraceEvent) bool {
+	for _, s := range p.stages {
+		if !s.Enrich(src, te) {
+			return false
+		}
+	}
+	return true
+}
+
+// Names는 활성 stage 이름을 실행 순서대로 반환한다.
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// ParseStages는 콤마로 구분된 stage 목록을 검증한다. 중복과 알 수 없는 이름은 에러다.
+func ParseStages(s string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(f))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, k := range knownStages {
+			if name == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown enricher %q (want one of %s)", name, strings.Join(knownStages, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("enricher %q listed twice", name)
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package enrich
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+// route stage가 path 단계를 바꿀 때 쓰는 이름.
+const (
+	RouteID   = "{id}"
+	RouteUUID = "{uuid}"
+	RouteHex  = "{hex}"
+)
+
+// minHexID는 hex ID로 볼 단계의 최소 길이다. MongoDB ObjectID(24), SHA-1(40) 같은 ID는
+// 잡고, "cafe", "add" 같은 짧은 단어는 그대로 둔다.
+const minHexID = 16
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// RouteRule은 --route-rules 파일의 규칙 하나다. query string을 뗀 path에서 Pattern에 맞는
+// 부분을 Replace로 바꾼다 (regexp.ReplaceAllString — Replace에 $1 같은 참조를 쓸 수 있다).
+type RouteRule struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// LoadRouteRules는 "<regexp> <replacement>" 줄로 된 규칙 파일을 읽는다. 빈 줄과 #로 시작하는
+// 줄은 건너뛴다. 규칙은 파일 순서대로 적용한다. 예:
+//
+//	^/api/users/[^/]+/avatar$  /api/users/{user}/avatar
+//	^/files/.*                 /files/{path}
+func LoadRouteRules(path string) ([]RouteRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []RouteRule
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"<regexp> <replacement>\"", path, n)
+		}
+		re, err := regexp.Compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		rules = append(rules, RouteRule{Pattern: re, Replace: fields[1]})
+	}
+	return rules, sc.Err()
+}
+
+// Route는 HTTP 이벤트의 http_route에 ID를 템플릿으로 바꾼 path를 넣는다 (NormalizePath).
+// /users/12345, /users/67890 같은 path를 하나의 엔드포인트로 묶어 집계 키의 수를 제한한다.
+// http_path는 그대로 둔다. 이미 http_route가 있으면(HTTP producer가 보낸 값) 바꾸지 않는다.
+func Route(rules []RouteRule) Enricher {
+	return routeEnricher{rules: rules}
+}
+
+type routeEnricher struct{ rules []RouteRule }
+
+func (routeEnricher) Name() string { return StageRoute }
+
+func (r routeEnricher) Enrich(_ Source, te *nefiv1.TraceEvent) bool {
+	if te.HttpPath != "" && te.HttpRoute == "" {
+		te.HttpRoute = NormalizePath(te.HttpPath, r.rules)
+	}
+	return true
+}
+
+// NormalizePath는 path에서 query string과 fragment를 떼고, rules를 순서대로 적용한 뒤,
+// 숫자만으로 된 단계를 RouteID로, UUID 단계를 RouteUUID로, 숫자가 섞인 minHexID자 이상의
+// hex 단계를 RouteHex로 바꾼다.
+func NormalizePath(path string, rules []RouteRule) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	for _, rule := range rules {
+		path = rule.Pattern.ReplaceAllString(path, rule.Replace)
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		switch {
+		case numeric(seg):
+			segments[i] = RouteID
+		case uuidPattern.MatchString(seg):
+			segments[i] = RouteUUID
+		case hexID(seg):
+			segments[i] = RouteHex
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func numeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hexID는 s가 숫자를 하나 이상 포함한 minHexID자 이상의 hex 문자열인지 여부다.
+func hexID(s string) bool {
+	if len(s) < minHexID {
+		return false
+	}
+	digit := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+		default:
+			return false
+		}
+	}
+	return digit
+}
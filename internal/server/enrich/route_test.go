@@ -0,0 +1,45 @@
+package enrich_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/gihongjo/nefi/internal/server/enrich"
+)
+
+func TestNormalizePath(t *testing.T) {
+	rules := []enrich.RouteRule{
+		{Pattern: regexp.MustCompile(`^/files/.*`), Replace: "/files/{path}"},
+		{Pattern: regexp.MustCompile(`^/users/[^/]+/avatar$`), Replace: "/users/{user}/avatar"},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "/"},
+		{"/api/users", "/api/users"},
+		{"/api/users/12345", "/api/users/{id}"},
+		{"/api/users/12345/orders/67", "/api/users/{id}/orders/{id}"},
+		{"/api/users/123?full=1", "/api/users/{id}"},
+		{"/api/users/123#top", "/api/users/{id}"},
+		{"/api/users/?page=2", "/api/users/"},
+		{"/api/users/123/", "/api/users/{id}/"},
+		{"/orders/550e8400-e29b-41d4-a716-446655440000", "/orders/{uuid}"},
+		{"/orders/550E8400-E29B-41D4-A716-446655440000/items", "/orders/{uuid}/items"},
+		{"/orders/550e8400e29b41d4a716446655440000", "/orders/{hex}"},
+		{"/objects/507f1f77bcf86cd799439011", "/objects/{hex}"},
+		{"/commits/da39a3ee5e6b4b0d3255bfef95601890afd80709/files", "/commits/{hex}/files"},
+		{"/api/v2/feed", "/api/v2/feed"},
+		{"/tags/deadbeefdeadbeef", "/tags/deadbeefdeadbeef"}, // 숫자가 없는 단어
+		{"/tags/cafe1234", "/tags/cafe1234"},                 // minHexID보다 짧다
+		{"/files/a/b/c.txt", "/files/{path}"},
+		{"/users/alice/avatar", "/users/{user}/avatar"},
+		{"/users/alice/avatar?size=64", "/users/{user}/avatar"},
+		{"/users/42/avatar/", "/users/{id}/avatar/"}, // 규칙은 $로 끝나므로 맞지 않는다
+	}
+	for _, tt := range tests {
+		if got := enrich.NormalizePath(tt.path, rules); got != tt.want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package enrich
+
+import (
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/agent/netclass"
+	"github.com/gihongjo/nefi/internal/model"
+)
+
+// GeoLabelCountry는 geoip stage가 external remote에 붙이는 label 키다 (agent geoip stage와 같다).
+const GeoLabelCountry = "nefi.io/geo-country"
+
+// Cluster는 cluster가 빈 이벤트에 보낸 agent의 클러스터 이름을, 그것도 없으면 def를 넣는다.
+// agent는 --cluster-name을 이벤트마다 싣지만, 구버전 agent와 HTTP producer는 비워 보낸다.
+func Cluster(def string) Enricher {
+	return clusterEnricher{def: def}
+}
+
+type clusterEnricher struct{ def string }
+
+func (clusterEnricher) Name() string { return StageCluster }
+
+func (c clusterEnricher) Enrich(src Source, te *nefiv1.TraceEvent) bool {
+	if te.Cluster == "" {
+		te.Cluster = src.Cluster
+	}
+	if te.Cluster == "" {
+		te.Cluster = c.def
+	}
+	return true
+}
+
+// External은 해석되지 않은 remote를 external로 표시하고 CIDR 매핑 > 클라우드 대역 >
+// private-network/internet 순으로 이름을 붙인다. agent가 이미 external로 표시했지만
+// "internet"/"private-network"처럼 일반 이름만 붙인 remote도 server의 매핑으로 다시 이름을
+// 붙인다 — 클라우드 대역 파일을 노드마다 배포하지 않고 server 한 곳에서 관리할 수 있다.
+func External(c *netclass.Classifier) Enricher {
+	if c == nil {
+		return nil
+	}
+	return externalEnricher{c: c}
+}
+
+type externalEnricher struct{ c *netclass.Classifier }
+
+func (externalEnricher) Name() string { return StageExternal }
+
+func (e externalEnricher) Enrich(_ Source, te *nefiv1.TraceEvent) bool {
+	switch {
+	case unresolved(te):
+		te.RemoteExternal = true
+		te.RemoteKind = model.RemoteKindExternal
+		te.RemoteName = e.c.Classify(te.RemoteIp)
+	case te.RemoteExternal && (te.RemoteName == netclass.NameInternet || te.RemoteName == netclass.NamePrivateNetwork):
+		te.RemoteName = e.c.Classify(te.RemoteIp)
+	}
+	return true
+}
+
+// GeoIP는 external remote에 CIDR→국가 매핑의 국가 label을 붙인다.
+func GeoIP(geo *netclass.Classifier) Enricher {
+	if geo == nil {
+		return nil
+	}
+	return geoEnricher{geo: geo}
+}
+
+type geoEnricher struct{ geo *netclass.Classifier }
+
+func (geoEnricher) Name() string { return StageGeoIP }
+
+func (g geoEnricher) Enrich(_ Source, te *nefiv1.TraceEvent) bool {
+	if !te.RemoteExternal || te.RemoteLabels[GeoLabelCountry] != "" {
+		return true
+	}
+	if country, ok := g.geo.Mapped(te.RemoteIp); ok {
+		labels := make(map[string]string, len(te.RemoteLabels)+1)
+		for k, v := range te.RemoteLabels {
+			labels[k] = v
+		}
+		labels[GeoLabelCountry] = country
+		te.RemoteLabels = labels
+	}
+	return true
+}
+
+// unresolved는 remote IP가 있지만 agent가 이름을 붙이지 않은 이벤트다.
+// mesh의 app↔sidecar 구간은 remote가 자기 pod이므로 해석 대상이 아니다.
+func unresolved(te *nefiv1.TraceEvent) bool {
+	return te.RemoteIp != 0 && te.RemotePod == "" && te.RemoteName == "" && te.MeshHop != model.MeshHopLocal
+}
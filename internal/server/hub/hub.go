@@ -3,10 +3,13 @@
 // 메시지 타입 (type 필드로 구분):
 //   {"type":"event", ...}  — raw 캡처 이벤트 (실시간)
 //   {"type":"stats", "window_sec":60, "endpoints":[...]}  — 1초마다 슬라이딩 윈도우 집계
+//   {"type":"agents", "agents":[...], "load":{...}}  — 5초마다 agent registry (/api/v1/agents와 같은 내용)
 //
 // WebSocket 엔드포인트: GET /ws
-//   - 연결 시 최근 100개 이벤트를 먼저 전송 (히스토리)
-//   - 이후 실시간 이벤트 + 매 1초 통계 스트리밍
+//   - 연결 시 최근 100개 이벤트와 현재 agent 목록을 먼저 전송 (히스토리)
+//   - 이후 실시간 이벤트 + 매 1초 통계 + 매 5초 agent 목록 스트리밍
+//   - 클라이언트는 연결 시 X-Nefi-Tenant 헤더(격리하지 않으면 브라우저는 ?tenant=)의 tenant 데이터만 받는다.
+//     격리 모드에서 tenant가 없으면 업그레이드 전에 403으로 거부한다 (tenant 패키지).
 package hub
 
 import (
@@ -19,8 +22,10 @@ import (
 	"github.com/gorilla/websocket"
 
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/agents"
 	"github.com/gihongjo/nefi/internal/server/aggregator"
 	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/server/tenant"
 )
 
 const (
@@ -29,6 +34,7 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512
+	agentsPeriod   = 5 * time.Second
 )
 
 var upgrader = websocket.Upgrader{
@@ -37,27 +43,46 @@ var upgrader = websocket.Upgrader{
 
 // WsEvent는 raw 이벤트 WebSocket 메시지다. Type은 항상 "event".
 type WsEvent struct {
-	Type            string `json:"type"` // "event"
-	TimestampNs     uint64 `json:"ts"`
-	PID             uint32 `json:"pid"`
-	FD              uint32 `json:"fd"`
-	MsgSize         uint32 `json:"msg_size"`
-	Direction       uint32 `json:"direction"`   // 0=send, 1=recv
-	Protocol        uint32 `json:"protocol"`
-	MsgType         uint32 `json:"msg_type"`
-	Comm            string `json:"comm"`
-	Namespace       string `json:"namespace,omitempty"`
-	PodName         string `json:"pod_name,omitempty"`
-	NodeName        string `json:"node_name,omitempty"`
-	RemoteIP        uint32 `json:"remote_ip,omitempty"`
-	RemotePort      uint32 `json:"remote_port,omitempty"`
-	RemoteNs        string `json:"remote_ns,omitempty"`
-	RemotePod       string `json:"remote_pod,omitempty"`
-	Payload         string `json:"payload,omitempty"` // printable ASCII
-	HttpMethod      string `json:"http_method,omitempty"`
-	HttpPath        string `json:"http_path,omitempty"`
-	HttpStatus      int32  `json:"http_status,omitempty"`
-	HttpContentType string `json:"http_content_type,omitempty"`
+	Type            string            `json:"type"` // "event"
+	Tenant          string            `json:"tenant,omitempty"`
+	TimestampNs     uint64            `json:"ts"`
+	PID             uint32            `json:"pid"`
+	FD              uint32            `json:"fd"`
+	MsgSize         uint32            `json:"msg_size"`
+	Direction       uint32            `json:"direction"` // 0=send, 1=recv
+	Protocol        uint32            `json:"protocol"`
+	MsgType         uint32            `json:"msg_type"`
+	Comm            string            `json:"comm"`
+	Cluster         string            `json:"cluster,omitempty"`
+	Namespace       string            `json:"namespace,omitempty"`
+	PodName         string            `json:"pod_name,omitempty"`
+	Workload        string            `json:"workload,omitempty"`
+	NodeName        string            `json:"node_name,omitempty"`
+	NodeZone        string            `json:"node_zone,omitempty"`
+	NodeRegion      string            `json:"node_region,omitempty"`
+	RemoteIP        uint32            `json:"remote_ip,omitempty"`
+	RemotePort      uint32            `json:"remote_port,omitempty"`
+	RemoteNs        string            `json:"remote_ns,omitempty"`
+	RemotePod       string            `json:"remote_pod,omitempty"`
+	RemoteWorkload  string            `json:"remote_workload,omitempty"`
+	RemoteService   string            `json:"remote_service,omitempty"`
+	RemoteHostname  string            `json:"remote_hostname,omitempty"`
+	RemoteExternal  bool              `json:"remote_external,omitempty"`
+	RemoteName      string            `json:"remote_name,omitempty"`
+	RemoteKind      string            `json:"remote_kind,omitempty"`
+	Policy          string            `json:"policy,omitempty"`
+	MeshHop         string            `json:"mesh_hop,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	RemoteLabels    map[string]string `json:"remote_labels,omitempty"`
+	Connection      bool              `json:"connection,omitempty"`
+	ConnID          string            `json:"conn_id,omitempty"`
+	AgentIdentity   string            `json:"agent_identity,omitempty"`
+	Payload         string            `json:"payload,omitempty"` // printable ASCII
+	HttpMethod      string            `json:"http_method,omitempty"`
+	HttpPath        string            `json:"http_path,omitempty"`
+	HttpRoute       string            `json:"http_route,omitempty"`
+	HttpStatus      int32             `json:"http_status,omitempty"`
+	HttpContentType string            `json:"http_content_type,omitempty"`
 }
 
 // WsStats는 슬라이딩 윈도우 집계 결과 WebSocket 메시지다. Type은 항상 "stats".
@@ -67,10 +92,19 @@ type WsStats struct {
 	Endpoints []aggregator.EndpointStat `json:"endpoints"`
 }
 
+// WsAgents는 agent registry WebSocket 메시지다. Type은 항상 "agents".
+type WsAgents struct {
+	Type   string           `json:"type"` // "agents"
+	Agents []agents.Agent   `json:"agents"`
+	Load   agents.FleetLoad `json:"load"`
+}
+
 // Hub는 Store와 Aggregator를 구독하고 WebSocket 클라이언트에게 이벤트/통계를 broadcast한다.
 type Hub struct {
 	store   store.Store
 	agg     *aggregator.Aggregator
+	agents  *agents.Registry
+	tenants tenant.Policy
 	sub     <-chan *nefiv1.TraceEvent
 	aggSub  <-chan []aggregator.EndpointStat
 	clients map[*client]struct{}
@@ -79,15 +113,19 @@ type Hub struct {
 }
 
 type client struct {
-	conn *websocket.Conn
-	send chan []byte
+	conn  *websocket.Conn
+	send  chan []byte
+	scope tenant.Scope // 연결 시 정한 tenant 범위
 }
 
-// New는 Hub를 생성하고 Store/Aggregator 구독을 시작한다.
-func New(s store.Store, agg *aggregator.Aggregator) *Hub {
+// New는 Hub를 생성하고 Store/Aggregator 구독을 시작한다. reg의 agent 목록은
+// agentsPeriod마다 보낸다. 클라이언트의 tenant 범위는 tenants로 정한다.
+func New(s store.Store, agg *aggregator.Aggregator, reg *agents.Registry, tenants tenant.Policy) *Hub {
 	h := &Hub{
 		store:   s,
 		agg:     agg,
+		agents:  reg,
+		tenants: tenants,
 		sub:     s.Subscribe(),
 		aggSub:  agg.Subscribe(),
 		clients: make(map[*client]struct{}),
@@ -100,23 +138,31 @@ func New(s store.Store, agg *aggregator.Aggregator) *Hub {
 // ServeHTTP는 WebSocket 업그레이드 핸들러다.
 // GET /ws 로 마운트하면 된다.
 func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scope, err := h.tenants.ResolveWebSocket(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[hub] upgrade error: %v", err)
 		return
 	}
 
-	c := &client{conn: conn, send: make(chan []byte, 256)}
+	c := &client{conn: conn, send: make(chan []byte, 256), scope: scope}
 	h.mu.Lock()
 	h.clients[c] = struct{}{}
 	h.mu.Unlock()
 
 	// 히스토리 먼저 전송
-	for _, ev := range h.store.Recent(historySize) {
+	for _, ev := range h.history(scope) {
 		if data, err := marshalEvent(ev); err == nil {
 			c.send <- data
 		}
 	}
+	if data, err := h.marshalAgents(scope); err == nil {
+		c.send <- data
+	}
 
 	go c.writePump()
 	c.readPump(func() {
@@ -136,10 +182,14 @@ func (h *Hub) Close() {
 
 // run은 Store 이벤트와 Aggregator 통계를 받아 모든 클라이언트에게 전송한다.
 func (h *Hub) run() {
+	ticker := time.NewTicker(agentsPeriod)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-h.done:
 			return
+		case <-ticker.C:
+			h.broadcast(h.marshalAgents)
 		case ev, ok := <-h.sub:
 			if !ok {
 				return
@@ -148,23 +198,37 @@ func (h *Hub) run() {
 			if err != nil {
 				continue
 			}
-			h.broadcast(data)
+			h.broadcast(func(s tenant.Scope) ([]byte, error) {
+				if !s.Allows(ev.Tenant) {
+					return nil, nil
+				}
+				return data, nil
+			})
 		case stats, ok := <-h.aggSub:
 			if !ok {
 				return
 			}
-			data, err := marshalStats(stats)
-			if err != nil {
-				continue
-			}
-			h.broadcast(data)
+			h.broadcast(func(s tenant.Scope) ([]byte, error) {
+				return marshalStats(statsIn(stats, s))
+			})
 		}
 	}
 }
 
-func (h *Hub) broadcast(data []byte) {
+// broadcast는 클라이언트마다 그 범위의 메시지를 보낸다. 메시지는 범위마다 한 번만 만든다.
+// msg가 nil을 반환하면 그 범위의 클라이언트에는 보내지 않는다.
+func (h *Hub) broadcast(msg func(tenant.Scope) ([]byte, error)) {
+	byScope := make(map[tenant.Scope][]byte)
 	h.mu.Lock()
 	for c := range h.clients {
+		data, ok := byScope[c.scope]
+		if !ok {
+			data, _ = msg(c.scope)
+			byScope[c.scope] = data
+		}
+		if data == nil {
+			continue
+		}
 		select {
 		case c.send <- data:
 		default:
@@ -174,6 +238,28 @@ func (h *Hub) broadcast(data []byte) {
 	h.mu.Unlock()
 }
 
+// history는 새 클라이언트에 먼저 보낼 s 범위의 최근 이벤트다.
+func (h *Hub) history(s tenant.Scope) []*nefiv1.TraceEvent {
+	if s.All {
+		return h.store.Recent(historySize)
+	}
+	return store.RecentMatching(h.store, historySize, func(ev *nefiv1.TraceEvent) bool { return s.Allows(ev.Tenant) })
+}
+
+// statsIn은 s 범위의 엔드포인트 집계만 반환한다.
+func statsIn(stats []aggregator.EndpointStat, s tenant.Scope) []aggregator.EndpointStat {
+	if s.All {
+		return stats
+	}
+	out := make([]aggregator.EndpointStat, 0, len(stats))
+	for _, st := range stats {
+		if s.Allows(st.Tenant) {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
 func (c *client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
@@ -226,9 +312,28 @@ func marshalStats(stats []aggregator.EndpointStat) ([]byte, error) {
 	})
 }
 
+func (h *Hub) marshalAgents(s tenant.Scope) ([]byte, error) {
+	list := h.agents.List()
+	if !s.All {
+		scoped := make([]agents.Agent, 0, len(list))
+		for _, a := range list {
+			if s.Allows(a.Tenant) {
+				scoped = append(scoped, a)
+			}
+		}
+		list = scoped
+	}
+	return json.Marshal(WsAgents{
+		Type:   "agents",
+		Agents: list,
+		Load:   agents.LoadOf(list),
+	})
+}
+
 func marshalEvent(ev *nefiv1.TraceEvent) ([]byte, error) {
 	ws := WsEvent{
 		Type:        "event",
+		Tenant:      ev.Tenant,
 		TimestampNs: ev.TimestampNs,
 		PID:         ev.Pid,
 		FD:          ev.Fd,
@@ -237,16 +342,34 @@ func marshalEvent(ev *nefiv1.TraceEvent) ([]byte, error) {
 		Protocol:    ev.Protocol,
 		MsgType:     ev.MsgType,
 		Comm:        ev.Comm,
+		Cluster:     ev.Cluster,
 		Namespace:   ev.Namespace,
 		PodName:     ev.PodName,
+		Workload:    ev.Workload,
 		NodeName:    ev.NodeName,
+		NodeZone:    ev.NodeZone,
+		NodeRegion:  ev.NodeRegion,
 		RemoteIP:    ev.RemoteIp,
 		RemotePort:  ev.RemotePort,
 		RemoteNs:    ev.RemoteNs,
 		RemotePod:       ev.RemotePod,
+		RemoteWorkload:  ev.RemoteWorkload,
+		RemoteService:   ev.RemoteService,
+		RemoteHostname:  ev.RemoteHostname,
+		RemoteExternal:  ev.RemoteExternal,
+		RemoteName:      ev.RemoteName,
+		RemoteKind:      ev.RemoteKind,
+		Policy:          ev.Policy,
+		MeshHop:         ev.MeshHop,
+		Labels:          ev.Labels,
+		RemoteLabels:    ev.RemoteLabels,
+		Connection:      ev.Connection,
+		ConnID:          ev.ConnId,
+		AgentIdentity:   ev.AgentIdentity,
 		Payload:         toPrintable(ev.Payload),
 		HttpMethod:      ev.HttpMethod,
 		HttpPath:        ev.HttpPath,
+		HttpRoute:       ev.HttpRoute,
 		HttpStatus:      ev.HttpStatus,
 		HttpContentType: ev.HttpContentType,
 	}
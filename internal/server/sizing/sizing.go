@@ -0,0 +1,83 @@
+// Package sizing은 관측된 이벤트량으로부터 저장소/server 용량을 추정한다.
+//
+// 입력은 노드(agent)별 실측 events/sec와 평균 이벤트 크기이며,
+// 목표 노드 수로 선형 확장해 일일 데이터량, 보존 기간 동안의 저장 용량,
+// 인메모리 ring buffer가 담을 수 있는 시간을 계산한다.
+// 영구 저장소를 켜기 전에 필요한 용량을 가늠하는 용도이며 정밀한 예측은 아니다.
+package sizing
+
+import "math"
+
+const secondsPerDay = 86400
+
+// NodeRate는 노드 하나의 실측 이벤트 속도다.
+type NodeRate struct {
+	Node         string  `json:"node"`
+	EventsPerSec float64 `json:"events_per_sec"`
+}
+
+// Input은 추정에 필요한 실측값과 가정이다.
+type Input struct {
+	Nodes         []NodeRate // 실측 노드별 속도
+	AvgEventBytes float64    // 직렬화된 이벤트 평균 크기
+	TargetNodes   int        // 추정 대상 노드 수 (0이면 실측 노드 수)
+	RetentionDays float64    // 보존 기간
+	Replicas      int        // 저장소 복제본 수 (primary 제외)
+	Overhead      float64    // 인덱스/메타데이터 등 저장 오버헤드 배수 (예: 1.3)
+	RingCapacity  int        // server 인메모리 ring buffer 크기 (이벤트 수)
+}
+
+// Report는 추정 결과다.
+type Report struct {
+	ObservedNodes         int        `json:"observed_nodes"`
+	ObservedEventsPerSec  float64    `json:"observed_events_per_sec"`
+	AvgEventsPerSecNode   float64    `json:"avg_events_per_sec_per_node"`
+	PeakEventsPerSecNode  float64    `json:"peak_events_per_sec_per_node"`
+	AvgEventBytes         float64    `json:"avg_event_bytes"`
+	TargetNodes           int        `json:"target_nodes"`
+	ProjectedEventsPerSec float64    `json:"projected_events_per_sec"`
+	IngestBytesPerSec     float64    `json:"ingest_bytes_per_sec"` // agent → server 네트워크 대역폭 (대략)
+	EventsPerDay          float64    `json:"events_per_day"`
+	BytesPerDay           float64    `json:"bytes_per_day"` // 복제/오버헤드 제외 원본 크기
+	RetentionDays         float64    `json:"retention_days"`
+	StorageBytes          float64    `json:"storage_bytes"`     // 보존 기간 × (1+replicas) × overhead
+	RingCoverageSec       float64    `json:"ring_coverage_sec"` // 현재 ring buffer가 담는 최근 시간 (0 = 이벤트 없음)
+	Nodes                 []NodeRate `json:"nodes"`
+}
+
+// Project는 in으로부터 용량 추정치를 계산한다.
+func Project(in Input) Report {
+	r := Report{
+		ObservedNodes: len(in.Nodes),
+		AvgEventBytes: in.AvgEventBytes,
+		TargetNodes:   in.TargetNodes,
+		RetentionDays: in.RetentionDays,
+		Nodes:         in.Nodes,
+	}
+	for _, n := range in.Nodes {
+		r.ObservedEventsPerSec += n.EventsPerSec
+		r.PeakEventsPerSecNode = math.Max(r.PeakEventsPerSecNode, n.EventsPerSec)
+	}
+	if r.ObservedNodes > 0 {
+		r.AvgEventsPerSecNode = r.ObservedEventsPerSec / float64(r.ObservedNodes)
+	}
+	if r.TargetNodes <= 0 {
+		r.TargetNodes = r.ObservedNodes
+	}
+
+	r.ProjectedEventsPerSec = r.AvgEventsPerSecNode * float64(r.TargetNodes)
+	r.IngestBytesPerSec = r.ProjectedEventsPerSec * in.AvgEventBytes
+	r.EventsPerDay = r.ProjectedEventsPerSec * secondsPerDay
+	r.BytesPerDay = r.IngestBytesPerSec * secondsPerDay
+
+	overhead := in.Overhead
+	if overhead < 1 {
+		overhead = 1
+	}
+	r.StorageBytes = r.BytesPerDay * in.RetentionDays * float64(1+in.Replicas) * overhead
+
+	if r.ProjectedEventsPerSec > 0 {
+		r.RingCoverageSec = float64(in.RingCapacity) / r.ProjectedEventsPerSec
+	}
+	return r
+}
@@ -0,0 +1,338 @@
+// Package embedded는 외부 의존성 없이 로컬 디스크에 이벤트를 보관하는 Store 구현이다
+// (nefi-server --storage=embedded).
+//
+// 동작 방식:
+//   - 조회와 구독은 인메모리 ring buffer(memory.Store)가 그대로 처리한다
+//   - Add: ring buffer에 저장한 이벤트를 <dir>/events.binpb에 길이가 앞에 붙은 protobuf로 덧붙임
+//   - 시작 시 파일의 마지막 capacity개 이벤트를 ring buffer로 복원 (재시작해도 최근 이벤트와
+//     topology가 남는다 — kind/minikube 데모, CI e2e에서 server pod가 재시작되는 경우)
+//   - 파일의 이벤트가 capacity의 compactFactor배를 넘으면 ring buffer 내용으로 다시 써서 크기를 제한
+//   - 쓰기는 버퍼링해 flushInterval마다 fsync한다. 비정상 종료 시 마지막 flushInterval 이내의
+//     이벤트는 잃을 수 있고, 파일 끝의 잘린 레코드는 복원 시 버린다
+//   - 파일 쓰기가 실패하면 다음 flushInterval마다 ring buffer 내용으로 파일을 다시 써서 복구한다
+//
+// 실시간 집계(aggregator)는 복원하지 않는다. 한 디렉터리는 server 하나만 열어야 한다.
+package embedded
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/store/memory"
+)
+
+const (
+	fileName      = "events.binpb"
+	flushInterval = time.Second
+	compactFactor = 2
+	maxRecord     = 16 << 20 // 이벤트 하나의 최대 크기 (이보다 크면 손상된 파일로 본다)
+)
+
+// Store는 디스크에 덧붙여 쓰는 memory.Store다.
+//
+// Add는 레코드를 메모리 버퍼(buf)에 모으기만 하고, 파일 쓰기·fsync·compaction은 flushLoop가
+// s.mu 밖에서 한다 — 느린 디스크가 수집 경로의 Add를 막지 않는다.
+type Store struct {
+	*memory.Store
+	path string
+
+	ioMu sync.Mutex // 파일 쓰기(flush, compaction)를 하나씩 실행한다. mu보다 먼저 잡는다
+	f    *os.File   // nil = 덧붙일 파일이 없다 (쓰기 실패 후 다음 compaction에서 다시 연다)
+
+	mu      sync.Mutex
+	buf     []byte // 파일에 아직 쓰지 않은 레코드
+	written int    // 파일과 buf에 있는 이벤트 수
+	broken  bool   // 쓰기가 실패해 buf에 모으지 않는다 — 다음 flush에서 ring buffer로 파일을 다시 쓴다
+	err     error  // 마지막 쓰기 에러 (Health)
+	closed  bool
+
+	compact chan struct{} // 파일이 compactFactor배를 넘음 (버퍼 1)
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Open은 dir의 이벤트 파일을 열어(없으면 만든다) 마지막 capacity개를 복원한 Store를 반환한다.
+// ttl은 memory.NewTTL과 같다 — 복원한 이벤트는 복원 시각부터 센다.
+func Open(dir string, capacity int, ttl time.Duration) (*Store, error) {
+	if dir == "" {
+		return nil, errors.New("embedded storage needs a data directory")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		Store:   memory.NewTTL(capacity, ttl),
+		path:    filepath.Join(dir, fileName),
+		compact: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	n, err := s.restore()
+	if err != nil {
+		return nil, fmt.Errorf("restore %s: %w", s.path, err)
+	}
+	if s.f, err = openAppend(s.path); err != nil {
+		return nil, err
+	}
+	s.written = n
+	if n > 0 {
+		log.Printf("[store] restored %d events from %s", min(n, s.Capacity()), s.path)
+	}
+	if n > compactFactor*s.Capacity() {
+		s.ioMu.Lock()
+		s.compactLocked()
+		s.ioMu.Unlock()
+	}
+	s.wg.Add(1)
+	go s.flushLoop()
+	return s, nil
+}
+
+// restore는 파일의 이벤트를 ring buffer에 넣고 읽은 이벤트 수를 반환한다. 끝이 잘린
+// 레코드가 있으면 그 앞까지 파일을 잘라 다음 쓰기가 이어지게 한다.
+func (s *Store) restore() (int, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := &countingReader{Reader: bufio.NewReader(f)}
+	opts := protodelim.UnmarshalOptions{MaxSize: maxRecord}
+	n := 0
+	var good int64
+	for {
+		ev := &nefiv1.TraceEvent{}
+		err := opts.UnmarshalFrom(r, ev)
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			log.Printf("[store] %s: dropping unreadable tail after %d events: %v", s.path, n, err)
+			return n, os.Truncate(s.path, good)
+		}
+		s.Load(ev)
+		n++
+		good = r.n
+	}
+}
+
+// countingReader는 protodelim이 읽은 바이트 수를 센다 (마지막 온전한 레코드의 끝 위치).
+type countingReader struct {
+	*bufio.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+}
+
+// Add는 이벤트를 ring buffer에 저장하고 파일에 덧붙일 버퍼에 넣는다. 파일 쓰기에 실패해도
+// ring buffer 저장과 구독자 전파는 계속하며, 에러는 Health로 보고한다.
+func (s *Store) Add(event *nefiv1.TraceEvent) {
+	rec, err := proto.Marshal(event)
+
+	s.mu.Lock()
+	s.Store.Add(event)
+	if s.closed || s.broken {
+		s.mu.Unlock()
+		return
+	}
+	if err != nil {
+		s.err = err
+		s.mu.Unlock()
+		return
+	}
+	s.buf = protowire.AppendBytes(s.buf, rec) // protodelim 형식 (길이 varint + 레코드)
+	s.written++
+	full := s.written > compactFactor*s.Capacity()
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.compact <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// compactLocked는 파일을 ring buffer 내용으로 다시 쓰고 새 파일에 이어서 덧붙인다. 쓰기에
+// 실패한 뒤 파일을 복구하는 데도 쓴다. s.ioMu를 잡은 상태에서 호출해야 한다.
+func (s *Store) compactLocked() {
+	// ring buffer의 스냅샷과 버퍼 비우기를 한 번에 한다 — 이후의 Add는 다시 쓴 파일 뒤에 붙는다.
+	s.mu.Lock()
+	events := s.Recent(s.Capacity())
+	s.buf = nil
+	s.written = len(events)
+	s.broken = false
+	s.mu.Unlock()
+
+	tmp := s.path + ".tmp"
+	err := writeFile(tmp, events)
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		s.failLocked(fmt.Errorf("compact: %w", err))
+		return
+	}
+	if s.f != nil {
+		s.f.Close()
+	}
+	if s.f, err = openAppend(s.path); err != nil {
+		s.failLocked(err)
+		return
+	}
+	s.mu.Lock()
+	s.err = nil
+	s.mu.Unlock()
+}
+
+func writeFile(path string, events []*nefiv1.TraceEvent) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 256<<10)
+	for _, ev := range events {
+		if _, err := protodelim.MarshalTo(w, ev); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Store) flushLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.ioMu.Lock()
+			s.flushLocked()
+			s.ioMu.Unlock()
+		case <-s.compact:
+			s.ioMu.Lock()
+			s.compactLocked()
+			s.ioMu.Unlock()
+		}
+	}
+}
+
+// flushLocked는 버퍼를 파일에 쓰고 fsync한다. 앞선 쓰기가 실패했으면 대신 ring buffer로
+// 파일을 다시 쓴다. s.ioMu를 잡은 상태에서 호출해야 한다.
+func (s *Store) flushLocked() {
+	s.mu.Lock()
+	if s.broken {
+		s.mu.Unlock()
+		s.compactLocked()
+		return
+	}
+	data := s.buf
+	s.buf = nil
+	s.mu.Unlock()
+
+	_, err := s.f.Write(data)
+	if err == nil {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		s.failLocked(err)
+		return
+	}
+	s.mu.Lock()
+	s.err = nil
+	s.mu.Unlock()
+}
+
+// failLocked는 쓰기 에러를 기록하고 파일을 닫는다. 파일 끝에 쓰다 만 레코드가 있을 수 있으므로
+// 다음 flush는 덧붙이지 않고 ring buffer로 파일을 다시 쓴다. 같은 에러가 이어지면 처음 한 번만
+// 로그를 남긴다. s.ioMu를 잡은 상태에서 호출해야 한다.
+func (s *Store) failLocked(err error) {
+	if s.f != nil {
+		s.f.Close()
+		s.f = nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		log.Printf("[store] write %s failed, new events are kept in memory only until the file is rewritten: %v", s.path, err)
+	}
+	s.err = err
+	s.broken = true
+	s.buf = nil
+}
+
+// Health는 마지막 파일 쓰기 에러다 (store.HealthReporter). 다음 flush가 파일을 다시 쓰는 데
+// 성공하면 nil로 돌아온다.
+func (s *Store) Health() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return fmt.Errorf("embedded storage: %w", s.err)
+	}
+	return nil
+}
+
+// Close는 남은 이벤트를 파일에 쓰고 닫은 뒤 모든 구독 채널을 닫는다.
+func (s *Store) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	s.wg.Wait()
+	s.ioMu.Lock()
+	s.flushLocked()
+	if s.f != nil {
+		s.f.Close()
+		s.f = nil
+	}
+	s.ioMu.Unlock()
+	s.Store.Close()
+}
@@ -0,0 +1,120 @@
+package embedded
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+func event(ts uint64) *nefiv1.TraceEvent {
+	return &nefiv1.TraceEvent{TimestampNs: ts}
+}
+
+func timestamps(events []*nefiv1.TraceEvent) []uint64 {
+	ts := make([]uint64, len(events))
+	for i, ev := range events {
+		ts[i] = ev.GetTimestampNs()
+	}
+	return ts
+}
+
+// flush는 flushLoop의 한 주기를 실행한다.
+func flush(s *Store) {
+	s.ioMu.Lock()
+	s.flushLocked()
+	s.ioMu.Unlock()
+}
+
+// closeWithin은 Close가 d 안에 끝나지 않으면 테스트를 실패시킨다.
+func closeWithin(t *testing.T, s *Store, d time.Duration) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(d):
+		t.Fatal("Close did not return")
+	}
+}
+
+func TestRestoreAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ts := uint64(1); ts <= 8; ts++ {
+		s.Add(event(ts)) // 7번째 Add에서 compaction
+	}
+	closeWithin(t, s, 5*time.Second)
+
+	s, err = Open(dir, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := timestamps(s.Recent(10)); len(got) != 3 || got[0] != 6 || got[2] != 8 {
+		t.Errorf("restored %v, want [6 7 8]", got)
+	}
+}
+
+func TestWriteFailureRecovers(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(event(1))
+	s.f.Close() // 디스크 에러 흉내: 다음 쓰기가 실패한다
+	s.Add(event(2))
+	flush(s)
+	if s.Health() == nil {
+		t.Fatal("Health = nil after a failed write")
+	}
+
+	s.Add(event(3))
+	flush(s) // 파일을 ring buffer로 다시 쓴다
+	if err := s.Health(); err != nil {
+		t.Fatalf("Health = %v after the file was rewritten", err)
+	}
+	s.Add(event(4))
+	closeWithin(t, s, 5*time.Second)
+
+	s, err = Open(dir, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := timestamps(s.Recent(10)); len(got) != 4 || got[0] != 1 || got[3] != 4 {
+		t.Errorf("restored %v, want [1 2 3 4]", got)
+	}
+}
+
+func TestCloseAfterFailedRewrite(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 임시 파일 자리에 비어 있지 않은 디렉터리를 두어 다시 쓰기도 실패하게 한다.
+	if err := os.MkdirAll(s.path+".tmp/x", 0o750); err != nil {
+		t.Fatal(err)
+	}
+	s.Add(event(1))
+	s.f.Close()
+	flush(s)
+	flush(s)
+	if s.Health() == nil {
+		t.Fatal("Health = nil after the rewrite failed")
+	}
+	s.Add(event(2))
+	if got := timestamps(s.Recent(10)); len(got) != 2 {
+		t.Errorf("Recent = %v, want both events kept in memory", got)
+	}
+	closeWithin(t, s, 5*time.Second)
+}
@@ -3,13 +3,21 @@
 // 동작 방식:
 //   - Add: ring buffer에 이벤트 저장 + 모든 구독자 채널에 비블로킹 전송
 //   - ring buffer가 가득 차면 가장 오래된 이벤트를 덮어씀
+//   - TTL이 있으면 저장한 지 TTL이 지난 이벤트는 조회에서 빠지고 다음 Add 때 버퍼에서 지움
 //   - 구독자 채널이 느리면 이벤트를 drop (backpressure 없음)
+//   - Publish: 저장 없이 구독자에게만 전송 (샘플링으로 저장하지 않는 이벤트)
+//   - 프로토콜(이벤트 타입)별로 기록 건수/바이트/거부 건수를 누적한다
 package memory
 
 import (
+	"sort"
 	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/model"
 )
 
 const subscriberChanSize = 256
@@ -18,44 +26,147 @@ const subscriberChanSize = 256
 type Store struct {
 	mu          sync.RWMutex
 	ring        []*nefiv1.TraceEvent
+	added       []int64 // ring과 같은 위치의 저장 시각 (UnixNano, TTL이 있을 때만)
 	capacity    int
-	head        int // 다음 쓰기 위치 (항상 0 ≤ head < capacity)
-	count       int // 저장된 이벤트 수 (최대 capacity)
+	ttl         time.Duration    // 0이면 만료 없음
+	now         func() time.Time // 테스트에서 교체
+	head        int              // 다음 쓰기 위치 (항상 0 ≤ head < capacity)
+	count       int              // 저장된 이벤트 수 (최대 capacity)
+	total       uint64           // 지금까지 저장한 이벤트 수 = 가장 최근 이벤트의 위치
 	closed      bool
 	subscribers map[chan *nefiv1.TraceEvent]struct{}
+	writes      map[string]*WriteStat // 프로토콜 이름 → 누적 기록 통계
+}
+
+// WriteStat은 이벤트 타입 하나에 대한 누적 기록 통계다.
+// 용량 이상 징후(특정 타입 폭증)를 추적하는 데 사용된다.
+type WriteStat struct {
+	EventType string `json:"event_type"` // 프로토콜 이름 (HTTP, MySQL, ...)
+	Written   uint64 `json:"written"`    // 저장된 이벤트 수
+	Bytes     uint64 `json:"bytes"`      // 저장된 이벤트의 직렬화 크기 합 (bytes)
+	Rejected  uint64 `json:"rejected"`   // 저장이 거부된 이벤트 수 (Close 이후 등)
 }
 
 // New는 주어진 capacity의 인메모리 Store를 반환한다.
 func New(capacity int) *Store {
+	return NewTTL(capacity, 0)
+}
+
+// NewTTL은 저장한 지 ttl이 지난 이벤트를 버리는 Store를 반환한다 (0 = 만료 없음).
+// capacity와 ttl 중 먼저 닿는 쪽이 보관 범위를 정한다.
+func NewTTL(capacity int, ttl time.Duration) *Store {
 	if capacity <= 0 {
 		capacity = 1000
 	}
-	return &Store{
+	s := &Store{
 		ring:        make([]*nefiv1.TraceEvent, capacity),
 		capacity:    capacity,
+		ttl:         max(ttl, 0),
+		now:         time.Now,
 		subscribers: make(map[chan *nefiv1.TraceEvent]struct{}),
+		writes:      make(map[string]*WriteStat),
+	}
+	if s.ttl > 0 {
+		s.added = make([]int64, capacity)
 	}
+	return s
 }
 
 // Add는 이벤트를 ring buffer에 저장하고 구독자에게 전파한다.
 func (s *Store) Add(event *nefiv1.TraceEvent) {
+	size := uint64(proto.Size(event)) // 직렬화 크기 계산은 lock 밖에서 한다
 	s.mu.Lock()
+	ws := s.writeStat(event)
 	if s.closed {
+		ws.Rejected++
 		s.mu.Unlock()
 		return
 	}
+	ws.Written++
+	ws.Bytes += size
+	s.insert(event)
+	// 구독자 목록 복사 후 뮤텍스 해제 (채널 send 중 데드락 방지)
+	subs := s.subscriberList()
+	s.mu.Unlock()
+	send(subs, event)
+}
+
+// Load는 이벤트를 ring buffer에만 넣는다 — 기록 통계를 세지 않고 구독자에게도 보내지 않는다.
+// 영속 backend가 시작 시 디스크의 이벤트를 복원하는 데 쓴다. TTL은 복원한 시각부터 센다.
+func (s *Store) Load(event *nefiv1.TraceEvent) {
+	s.mu.Lock()
+	s.insert(event)
+	s.mu.Unlock()
+}
+
+// insert는 이벤트를 head에 쓰고 만료된 이벤트를 지운다. s.mu를 잡은 상태에서 호출해야 한다.
+func (s *Store) insert(event *nefiv1.TraceEvent) {
+	if s.ttl > 0 {
+		s.evict()
+		s.added[s.head] = s.now().UnixNano()
+	}
 	s.ring[s.head] = event
 	s.head = (s.head + 1) % s.capacity
 	if s.count < s.capacity {
 		s.count++
 	}
-	// 구독자 목록 복사 후 뮤텍스 해제 (채널 send 중 데드락 방지)
+	s.total++
+}
+
+// evict는 가장 오래된 것부터 만료된 이벤트를 버퍼에서 지운다 (GC가 회수하도록 참조도 끊는다).
+// s.mu를 잡은 상태에서 호출해야 한다.
+func (s *Store) evict() {
+	expired := s.expired()
+	oldest := s.oldest()
+	for i := 0; i < expired; i++ {
+		s.ring[(oldest+i)%s.capacity] = nil
+	}
+	s.count -= expired
+}
+
+// expired는 가장 오래된 쪽에서부터 만료된 이벤트 수다. s.mu를 잡은 상태에서 호출해야 한다.
+func (s *Store) expired() int {
+	if s.ttl <= 0 {
+		return 0
+	}
+	cutoff := s.now().Add(-s.ttl).UnixNano()
+	oldest := s.oldest()
+	n := 0
+	for n < s.count && s.added[(oldest+n)%s.capacity] <= cutoff {
+		n++
+	}
+	return n
+}
+
+// oldest는 가장 오래된 이벤트의 위치다. s.mu를 잡은 상태에서 호출해야 한다.
+func (s *Store) oldest() int {
+	// head는 다음 쓰기 위치 → (head - count + capacity) % capacity 가 가장 오래된 위치
+	return ((s.head - s.count) + s.capacity) % s.capacity
+}
+
+// Publish는 이벤트를 저장하지 않고 구독자에게만 전파한다. 저장 전에 샘플링으로 빠진
+// 이벤트도 실시간 집계에는 세기 위해 쓴다.
+func (s *Store) Publish(event *nefiv1.TraceEvent) {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return
+	}
+	subs := s.subscriberList()
+	s.mu.RUnlock()
+	send(subs, event)
+}
+
+// subscriberList는 구독자 채널의 복사본이다. s.mu를 잡은 상태에서 호출해야 한다.
+func (s *Store) subscriberList() []chan *nefiv1.TraceEvent {
 	subs := make([]chan *nefiv1.TraceEvent, 0, len(s.subscribers))
 	for ch := range s.subscribers {
 		subs = append(subs, ch)
 	}
-	s.mu.Unlock()
+	return subs
+}
 
+func send(subs []chan *nefiv1.TraceEvent, event *nefiv1.TraceEvent) {
 	for _, ch := range subs {
 		select {
 		case ch <- event:
@@ -65,6 +176,32 @@ func (s *Store) Add(event *nefiv1.TraceEvent) {
 	}
 }
 
+// writeStat은 이벤트 타입의 통계 항목을 반환한다. s.mu를 잡은 상태에서 호출해야 한다.
+func (s *Store) writeStat(event *nefiv1.TraceEvent) *WriteStat {
+	name := model.Protocol(event.GetProtocol()).String()
+	if event.GetConnection() {
+		name = "connection"
+	}
+	ws, ok := s.writes[name]
+	if !ok {
+		ws = &WriteStat{EventType: name}
+		s.writes[name] = ws
+	}
+	return ws
+}
+
+// WriteStats는 이벤트 타입별 누적 기록 통계를 이름 순으로 반환한다.
+func (s *Store) WriteStats() []WriteStat {
+	s.mu.RLock()
+	result := make([]WriteStat, 0, len(s.writes))
+	for _, ws := range s.writes {
+		result = append(result, *ws)
+	}
+	s.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].EventType < result[j].EventType })
+	return result
+}
+
 // Subscribe는 새 이벤트 구독 채널을 반환한다.
 func (s *Store) Subscribe() <-chan *nefiv1.TraceEvent {
 	ch := make(chan *nefiv1.TraceEvent, subscriberChanSize)
@@ -87,29 +224,62 @@ func (s *Store) Unsubscribe(ch <-chan *nefiv1.TraceEvent) {
 	s.mu.Unlock()
 }
 
-// Recent는 최근 n개 이벤트를 오래된 것부터 반환한다.
+// Recent는 만료되지 않은 최근 n개 이벤트를 오래된 것부터 반환한다.
 func (s *Store) Recent(n int) []*nefiv1.TraceEvent {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if n <= 0 || s.count == 0 {
+	// 새 Add가 없어 아직 지우지 못한 만료 이벤트는 건너뛴다.
+	count := s.count - s.expired()
+	if n <= 0 || count == 0 {
 		return []*nefiv1.TraceEvent{}
 	}
-	if n > s.count {
-		n = s.count
+	if n > count {
+		n = count
 	}
 
 	result := make([]*nefiv1.TraceEvent, n)
-	// head는 다음 쓰기 위치 → (head - count + capacity) % capacity 가 가장 오래된 위치
-	start := ((s.head - s.count) + s.capacity) % s.capacity
+	start := s.oldest()
 	for i := 0; i < n; i++ {
-		// n개 중 최신 n개: (count - n)개 건너뜀
+		// 최신 n개: 앞의 (s.count - n)개 건너뜀
 		idx := (start + (s.count - n) + i) % s.capacity
 		result[i] = s.ring[idx]
 	}
 	return result
 }
 
+// Before는 위치가 before보다 앞선 만료되지 않은 이벤트 중 최근 n개를 오래된 것부터
+// 반환한다 (before = 0이면 가장 최근 이벤트까지). 위치는 저장한 순서대로 1부터 매기는
+// 번호이고, first는 반환한 첫 이벤트의 위치다 — 그 이전 페이지는 Before(first, n)로 읽는다.
+// 남은 이벤트가 없으면 빈 슬라이스와 0이다.
+func (s *Store) Before(before uint64, n int) (events []*nefiv1.TraceEvent, first uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := uint64(s.count - s.expired())
+	end := s.total // 반환할 마지막 위치
+	if before != 0 && before-1 < end {
+		end = before - 1
+	}
+	oldest := s.total - count + 1 // 남아 있는 가장 오래된 위치
+	if n <= 0 || count == 0 || end < oldest {
+		return []*nefiv1.TraceEvent{}, 0
+	}
+	first = max(oldest, end-min(uint64(n), end)+1)
+	events = make([]*nefiv1.TraceEvent, end-first+1)
+	for i := range events {
+		// 위치 p의 이벤트는 head에서 (total - p + 1)칸 앞에 있다.
+		back := int(s.total - (first + uint64(i)) + 1)
+		events[i] = s.ring[(s.head-back+s.capacity)%s.capacity]
+	}
+	return events, first
+}
+
+// Capacity는 ring buffer 크기(보관 가능한 최대 이벤트 수)다.
+func (s *Store) Capacity() int {
+	return s.capacity
+}
+
 // Close는 모든 구독 채널을 닫는다.
 func (s *Store) Close() {
 	s.mu.Lock()
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+)
+
+func event(ts uint64) *nefiv1.TraceEvent {
+	return &nefiv1.TraceEvent{TimestampNs: ts}
+}
+
+func timestamps(events []*nefiv1.TraceEvent) []uint64 {
+	ts := make([]uint64, len(events))
+	for i, ev := range events {
+		ts[i] = ev.GetTimestampNs()
+	}
+	return ts
+}
+
+func equal(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRecentWrapsAround(t *testing.T) {
+	s := New(3)
+	for ts := uint64(1); ts <= 5; ts++ {
+		s.Add(event(ts))
+	}
+	if got := timestamps(s.Recent(10)); !equal(got, []uint64{3, 4, 5}) {
+		t.Errorf("Recent(10) = %v, want [3 4 5]", got)
+	}
+	if got := timestamps(s.Recent(2)); !equal(got, []uint64{4, 5}) {
+		t.Errorf("Recent(2) = %v, want [4 5]", got)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewTTL(4, time.Minute)
+	s.now = func() time.Time { return now }
+
+	s.Add(event(1))
+	now = now.Add(30 * time.Second)
+	s.Add(event(2))
+	s.Add(event(3))
+
+	now = now.Add(40 * time.Second) // 1은 70초, 2와 3은 40초 전에 저장됨
+	if got := timestamps(s.Recent(10)); !equal(got, []uint64{2, 3}) {
+		t.Errorf("Recent after the first event expired = %v, want [2 3]", got)
+	}
+
+	s.Add(event(4))
+	if s.count != 3 || s.ring[0] != nil {
+		t.Errorf("Add left the expired event in the buffer: count %d, ring[0] %v", s.count, s.ring[0])
+	}
+
+	now = now.Add(time.Hour)
+	if got := s.Recent(10); len(got) != 0 {
+		t.Errorf("Recent after every event expired = %v, want none", timestamps(got))
+	}
+	s.Add(event(5))
+	if got := timestamps(s.Recent(10)); !equal(got, []uint64{5}) {
+		t.Errorf("Recent = %v, want [5]", got)
+	}
+}
+
+func TestSubscribeAndClose(t *testing.T) {
+	s := New(2)
+	ch := s.Subscribe()
+	s.Add(event(1))
+	s.Publish(event(2))
+	for _, want := range []uint64{1, 2} {
+		if got := (<-ch).GetTimestampNs(); got != want {
+			t.Errorf("subscriber got %d, want %d", got, want)
+		}
+	}
+	if got := timestamps(s.Recent(10)); !equal(got, []uint64{1}) {
+		t.Errorf("Recent = %v, want [1] (Publish must not store)", got)
+	}
+
+	s.Close()
+	if _, ok := <-ch; ok {
+		t.Error("subscriber channel still open after Close")
+	}
+	s.Add(event(3))
+	if ws := s.WriteStats(); len(ws) != 1 || ws[0].Written != 1 || ws[0].Rejected != 1 {
+		t.Errorf("WriteStats = %+v, want 1 written and 1 rejected", ws)
+	}
+}
+
+func TestBeforePages(t *testing.T) {
+	s := New(4)
+	for ts := uint64(1); ts <= 6; ts++ {
+		s.Add(event(ts)) // 위치 = ts, 3~6이 남음
+	}
+	events, first := s.Before(0, 3)
+	if got := timestamps(events); !equal(got, []uint64{4, 5, 6}) || first != 4 {
+		t.Fatalf("Before(0, 3) = %v, %d; want [4 5 6], 4", got, first)
+	}
+	events, first = s.Before(first, 3)
+	if got := timestamps(events); !equal(got, []uint64{3}) || first != 3 {
+		t.Fatalf("Before(4, 3) = %v, %d; want [3], 3 (older events were overwritten)", got, first)
+	}
+	if events, first = s.Before(first, 3); len(events) != 0 || first != 0 {
+		t.Errorf("Before(3, 3) = %v, %d; want none", timestamps(events), first)
+	}
+	if got := timestamps(s.Recent(2)); !equal(got, []uint64{5, 6}) {
+		t.Errorf("Recent(2) = %v, want [5 6]", got)
+	}
+}
@@ -2,20 +2,141 @@
 package store
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
 	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/metrics"
+	"github.com/gihongjo/nefi/internal/server/store/embedded"
 	"github.com/gihongjo/nefi/internal/server/store/memory"
 )
 
+// WriteStat은 이벤트 타입별 누적 기록 통계다.
+type WriteStat = memory.WriteStat
+
 // Store는 이벤트 저장소 인터페이스다.
 type Store interface {
 	Add(event *nefiv1.TraceEvent)
+	// Publish는 이벤트를 저장하지 않고 구독자(aggregator, WebSocket)에게만 전파한다.
+	Publish(event *nefiv1.TraceEvent)
 	Subscribe() <-chan *nefiv1.TraceEvent
 	Unsubscribe(ch <-chan *nefiv1.TraceEvent)
 	Recent(n int) []*nefiv1.TraceEvent
+	// Before는 저장 순서 위치가 before보다 앞선 이벤트 중 최근 n개와 첫 이벤트의 위치다
+	// (before = 0이면 가장 최근부터). 페이지를 거슬러 읽는 데 쓴다 (memory.Store.Before).
+	Before(before uint64, n int) (events []*nefiv1.TraceEvent, first uint64)
+	WriteStats() []WriteStat
+	Capacity() int
 	Close()
 }
 
+// scanPage는 RecentMatching이 Before로 한 번에 읽는 이벤트 수다.
+const scanPage = 1000
+
+// RecentMatching은 s의 이벤트 중 keep을 통과하는 최근 n개를 오래된 것부터 반환한다.
+// 최신 쪽부터 scanPage개씩 거슬러 읽고 n개를 찾으면 멈추므로, ring buffer 전체를 한 번에
+// 복사하지 않는다.
+func RecentMatching(s Store, n int, keep func(*nefiv1.TraceEvent) bool) []*nefiv1.TraceEvent {
+	out := make([]*nefiv1.TraceEvent, 0, min(n, scanPage)) // 최신 것부터
+	var before uint64
+	for len(out) < n {
+		chunk, first := s.Before(before, scanPage)
+		if len(chunk) == 0 {
+			break
+		}
+		for i := len(chunk) - 1; i >= 0 && len(out) < n; i-- {
+			if keep(chunk[i]) {
+				out = append(out, chunk[i])
+			}
+		}
+		before = first
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// HealthReporter는 읽기가 부분적으로 실패할 수 있는 backend(원격 저장소 등)가 구현한다.
+// Health가 nil이 아니면 API는 해당 데이터 소스를 degraded로 표시하고
+// 가능한 나머지 데이터로 응답한다. 인메모리 store는 구현하지 않는다(항상 정상).
+// embedded store는 디스크 쓰기 실패를 보고한다.
+type HealthReporter interface {
+	Health() error
+}
+
+// 저장소 backend 종류 (nefi-server --storage).
+const (
+	BackendMemory   = "memory"   // 프로세스 내 ring buffer (재시작 시 사라짐)
+	BackendEmbedded = "embedded" // ring buffer + 로컬 디스크 파일 (재시작 시 복원)
+)
+
+// Shared는 backend를 다른 프로세스가 쓰는 동안 여러 프로세스가 함께 읽을 수 있는지 여부다.
+// 지금은 없다: memory는 프로세스 안에만 있고, embedded는 쓰는 프로세스가 버퍼에 모은 뒤
+// 덧붙이며 Open과 flush 중에 파일을 compaction으로 다시 쓴다.
+func Shared(backend string) bool {
+	return false
+}
+
 // New는 인메모리 Store를 반환한다.
 func New(capacity int) Store {
 	return memory.New(capacity)
 }
+
+// Config는 저장소 설정이다.
+type Config struct {
+	Backend  string        // BackendMemory(기본) 또는 BackendEmbedded
+	Capacity int           // ring buffer 크기 (보관할 최대 이벤트 수)
+	TTL      time.Duration // 저장한 지 이만큼 지난 이벤트를 버린다 (0 = capacity로만 제한)
+	DataDir  string        // BackendEmbedded의 데이터 디렉터리
+}
+
+// Open은 cfg.Backend에 맞는 Store를 반환한다.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		return memory.NewTTL(cfg.Capacity, cfg.TTL), nil
+	case BackendEmbedded:
+		s, err := embedded.Open(cfg.DataDir, cfg.Capacity, cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("embedded storage: %w", err)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (want %q or %q)", cfg.Backend, BackendMemory, BackendEmbedded)
+	}
+}
+
+// RegisterMetrics는 이벤트 타입별 기록 통계를 reg에 등록한다.
+func RegisterMetrics(reg *metrics.Registry, s Store) {
+	collect := func(value func(WriteStat) uint64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			stats := s.WriteStats()
+			samples := make([]metrics.Sample, 0, len(stats))
+			for _, ws := range stats {
+				samples = append(samples, metrics.Sample{
+					Labels: metrics.Labels{"event_type": ws.EventType},
+					Value:  float64(value(ws)),
+				})
+			}
+			return samples
+		}
+	}
+	reg.Register(metrics.Family{
+		Name:    "nefi_store_written_events_total",
+		Help:    "Events written to the store, by event type.",
+		Kind:    metrics.Counter,
+		Collect: collect(func(ws WriteStat) uint64 { return ws.Written }),
+	})
+	reg.Register(metrics.Family{
+		Name:    "nefi_store_written_bytes_total",
+		Help:    "Serialized bytes written to the store, by event type.",
+		Kind:    metrics.Counter,
+		Collect: collect(func(ws WriteStat) uint64 { return ws.Bytes }),
+	})
+	reg.Register(metrics.Family{
+		Name:    "nefi_store_rejected_events_total",
+		Help:    "Events rejected by the store, by event type.",
+		Kind:    metrics.Counter,
+		Collect: collect(func(ws WriteStat) uint64 { return ws.Rejected }),
+	})
+}
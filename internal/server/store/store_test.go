@@ -0,0 +1,38 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+
+	nefiv1 "github.com/gihongjo/nefi/gen/go/nefi/v1"
+	"github.com/gihongjo/nefi/internal/server/store"
+	"github.com/gihongjo/nefi/internal/server/store/memory"
+)
+
+func timestamps(events []*nefiv1.TraceEvent) []uint64 {
+	ts := make([]uint64, len(events))
+	for i, ev := range events {
+		ts[i] = ev.GetTimestampNs()
+	}
+	return ts
+}
+
+func TestRecentMatching(t *testing.T) {
+	s := memory.New(2500)
+	for ts := uint64(1); ts <= 3000; ts++ {
+		s.Add(&nefiv1.TraceEvent{TimestampNs: ts}) // 501~3000이 남음
+	}
+	odd := func(ev *nefiv1.TraceEvent) bool { return ev.GetTimestampNs()%2 == 1 }
+
+	// 1200개를 찾으려면 Before 페이지 여러 개를 거슬러 읽어야 한다.
+	got := timestamps(store.RecentMatching(s, 1200, odd))
+	if len(got) != 1200 || got[0] != 601 || got[len(got)-1] != 2999 {
+		t.Fatalf("RecentMatching(1200) = %d events %v..%v, want 601..2999", len(got), got[:1], got[len(got)-1:])
+	}
+	if got := timestamps(store.RecentMatching(s, 3, odd)); !slices.Equal(got, []uint64{2995, 2997, 2999}) {
+		t.Errorf("RecentMatching(3) = %v, want [2995 2997 2999]", got)
+	}
+	if got := store.RecentMatching(s, 5000, odd); len(got) != 1250 || got[0].GetTimestampNs() != 501 {
+		t.Errorf("RecentMatching(5000) = %d events, want all 1250 retained odd events", len(got))
+	}
+}
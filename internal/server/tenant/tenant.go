@@ -0,0 +1,99 @@
+// Package tenant는 한 nefi-server를 여러 팀이 나눠 쓸 때 조회 요청의 tenant 범위를 정한다.
+//
+// 이벤트의 tenant는 collector가 수집 시 정한다 (TraceEvent.tenant: mTLS client 인증서의
+// O, 없으면 x-nefi-tenant 메타데이터/헤더). 조회 쪽(REST API, WebSocket)은 요청의
+// X-Nefi-Tenant 헤더로 범위를 정하고 그 tenant의 이벤트, 집계, agent만 반환한다.
+//
+// nefi-server는 사용자를 인증하지 않는다. 격리(Policy.Isolation)를 켜면 앞단의 인증
+// 프록시가 로그인한 사용자의 tenant를 X-Nefi-Tenant에 설정해야 하며(client가 보낸 값은
+// 덮어쓴다), 헤더가 없는 요청은 거부한다. Operator tenant는 모든 tenant를 조회하고
+// fleet 전체에 영향을 주는 관리 엔드포인트(agent 설정, 저장소 통계 등)를 쓸 수 있다.
+package tenant
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Header는 조회 요청의 tenant 헤더다. 수집 메타데이터 x-nefi-tenant와 이름이 같다.
+const Header = "X-Nefi-Tenant"
+
+// QueryParam은 헤더를 설정할 수 없는 client(브라우저 WebSocket)가 쓰는 query 파라미터다.
+// 격리하지 않을 때 WebSocket 요청에서만 읽는다 (ResolveWebSocket).
+const QueryParam = "tenant"
+
+// ErrMissing은 격리 모드에서 tenant 없이 온 요청의 에러다.
+var ErrMissing = errors.New("tenant isolation is enabled: request has no " + Header + " header")
+
+// ErrOperatorOnly는 operator가 아닌 tenant가 fleet 전체 엔드포인트를 호출한 에러다.
+var ErrOperatorOnly = errors.New("this endpoint affects every tenant and requires the operator tenant")
+
+// Policy는 조회 요청의 tenant 정책이다. 0 값은 격리하지 않는다.
+type Policy struct {
+	// Isolation이 true면 모든 조회 요청에 tenant가 있어야 하고, 요청은 그 tenant의 데이터만 본다.
+	Isolation bool
+	// Operator는 모든 tenant를 조회할 수 있는 tenant 이름이다 (빈 문자열 = 없음).
+	Operator string
+}
+
+// Scope는 요청 하나가 볼 수 있는 범위다.
+type Scope struct {
+	Tenant string // All이 false일 때 볼 수 있는 tenant
+	All    bool   // 모든 tenant (격리 없음 또는 operator)
+}
+
+// Allows는 tenant t의 데이터가 범위 안인지 여부다.
+func (s Scope) Allows(t string) bool {
+	return s.All || t == s.Tenant
+}
+
+// Resolve는 r의 X-Nefi-Tenant 헤더로 범위를 정한다. 격리하지 않으면 헤더가 없는 요청은 모든
+// tenant를, 있는 요청은 그 tenant만 본다(필터). 격리 모드에서는 헤더가 없으면 ErrMissing이다.
+func (p Policy) Resolve(r *http.Request) (Scope, error) {
+	return p.scope(strings.TrimSpace(r.Header.Get(Header)))
+}
+
+// ResolveWebSocket은 WebSocket 업그레이드 요청의 범위를 정한다. 브라우저 WebSocket은 헤더를
+// 설정할 수 없으므로 격리하지 않을 때는 헤더 대신 QueryParam도 받는다. 격리 모드에서는
+// client가 고른 값으로 operator 범위를 얻지 못하도록 프록시가 설정한 헤더만 믿는다.
+func (p Policy) ResolveWebSocket(r *http.Request) (Scope, error) {
+	t := strings.TrimSpace(r.Header.Get(Header))
+	if t == "" && !p.Isolation {
+		t = strings.TrimSpace(r.URL.Query().Get(QueryParam))
+	}
+	return p.scope(t)
+}
+
+func (p Policy) scope(t string) (Scope, error) {
+	switch {
+	case t == "" && p.Isolation:
+		return Scope{}, ErrMissing
+	case t == "", p.Operator != "" && t == p.Operator:
+		return Scope{All: true}, nil
+	}
+	return Scope{Tenant: t}, nil
+}
+
+// Fleet는 s가 fleet 전체 엔드포인트를 쓸 수 있는지 검사한다. 격리하지 않으면 누구나 쓸 수 있다.
+func (p Policy) Fleet(s Scope) error {
+	if p.Isolation && !s.All {
+		return ErrOperatorOnly
+	}
+	return nil
+}
+
+// OperatorOnly는 격리 모드에서 operator tenant만 h를 호출할 수 있게 감싼다.
+func (p Policy) OperatorOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := p.Resolve(r)
+		if err == nil {
+			err = p.Fleet(s)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,77 @@
+package tenant
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	open := Policy{Operator: "ops"}
+	isolated := Policy{Isolation: true, Operator: "ops"}
+	tests := []struct {
+		name    string
+		policy  Policy
+		header  string
+		query   string
+		ws      bool // ResolveWebSocket
+		want    Scope
+		wantErr error
+	}{
+		{"no tenant", open, "", "", false, Scope{All: true}, nil},
+		{"header", open, "shop", "", false, Scope{Tenant: "shop"}, nil},
+		{"operator header", open, "ops", "", false, Scope{All: true}, nil},
+		{"query ignored outside WebSocket", open, "", "shop", false, Scope{All: true}, nil},
+		{"WebSocket query", open, "", "shop", true, Scope{Tenant: "shop"}, nil},
+		{"WebSocket header wins over query", open, "shop", "ops", true, Scope{Tenant: "shop"}, nil},
+		{"isolation without header", isolated, "", "", false, Scope{}, ErrMissing},
+		{"isolation header", isolated, " shop ", "", false, Scope{Tenant: "shop"}, nil},
+		{"isolation operator header", isolated, "ops", "", false, Scope{All: true}, nil},
+		{"isolation operator query", isolated, "", "ops", false, Scope{}, ErrMissing},
+		{"isolation WebSocket operator query", isolated, "", "ops", true, Scope{}, ErrMissing},
+		{"isolation WebSocket query under a tenant header", isolated, "shop", "ops", true, Scope{Tenant: "shop"}, nil},
+		{"no operator configured", Policy{}, "ops", "", false, Scope{Tenant: "ops"}, nil},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/ws?"+QueryParam+"="+tt.query, nil)
+		if tt.header != "" {
+			r.Header.Set(Header, tt.header)
+		}
+		resolve := tt.policy.Resolve
+		if tt.ws {
+			resolve = tt.policy.ResolveWebSocket
+		}
+		got, err := resolve(r)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOperatorOnly(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		policy Policy
+		header string
+		query  string
+		want   int
+	}{
+		{Policy{}, "", "", http.StatusOK},
+		{Policy{}, "shop", "", http.StatusOK},
+		{Policy{Isolation: true, Operator: "ops"}, "ops", "", http.StatusOK},
+		{Policy{Isolation: true, Operator: "ops"}, "shop", "", http.StatusForbidden},
+		{Policy{Isolation: true, Operator: "ops"}, "", "ops", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/metrics/services?"+QueryParam+"="+tt.query, nil)
+		if tt.header != "" {
+			r.Header.Set(Header, tt.header)
+		}
+		w := httptest.NewRecorder()
+		tt.policy.OperatorOnly(ok).ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%+v header=%q query=%q: status %d, want %d", tt.policy, tt.header, tt.query, w.Code, tt.want)
+		}
+	}
+}
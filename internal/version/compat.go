@@ -0,0 +1,99 @@
+package version
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MinAgentSchemaVersion은 server가 해석할 수 있는 가장 오래된 agent 스키마 버전이다.
+// 이보다 오래된 agent는 스트림 시작 시 거부된다. server는 두 스키마 전(N-2)까지 받으므로,
+// 수천 노드의 agent를 server와 같은 시점에 업그레이드하지 않아도 된다. collector는 그 사이의
+// 스키마를 현재 스키마로 올리는 단계를 갖고 있어야 한다.
+const MinAgentSchemaVersion = SchemaVersion - 2
+
+// ViolationAgentSchema는 호환성 거부 시 PreconditionFailure violation 타입이다.
+const ViolationAgentSchema = "AGENT_SCHEMA_VERSION"
+
+// PreNegotiationSchemaVersion은 Negotiate RPC가 없는 server의 스키마 버전이다.
+// agent는 Negotiate가 Unimplemented면 이 버전과 SendEvents 스트림으로 낮춰 보낸다.
+const PreNegotiationSchemaVersion = 2
+
+// 전송 기능. agent와 server가 Negotiate로 모두 지원하는 것만 쓴다.
+const (
+	FeatureBatchAck = "batch_ack" // StreamBatches: seq를 붙인 batch 전송과 ack
+	FeatureGzip     = "gzip"      // gRPC gzip 메시지 압축
+	FeatureDict     = "dict"      // EventBatch 문자열 사전 인코딩 (internal/batchdict)
+)
+
+// ServerFeatures는 이 server가 지원하는 전송 기능이다.
+var ServerFeatures = []string{FeatureBatchAck, FeatureGzip, FeatureDict}
+
+// Negotiate returns the schema an agent supporting up to agentSchema should
+// encode with — the older of the agent's and this server's — and the
+// features of want this server supports. ok is false when the agent is
+// older than MinAgentSchemaVersion and cannot be accepted.
+func Negotiate(agentSchema int, want []string) (schema int, features []string, ok bool) {
+	schema = min(agentSchema, SchemaVersion)
+	if schema < MinAgentSchemaVersion {
+		return schema, nil, false
+	}
+	for _, f := range want {
+		if slices.Contains(ServerFeatures, f) {
+			features = append(features, f)
+		}
+	}
+	return schema, features, true
+}
+
+// Compat은 agent와 server 간 호환성 판정 결과다.
+type Compat int
+
+const (
+	Compatible   Compat = iota // 동일 스키마
+	Deprecated                 // 동작하지만 곧 지원 중단 — 업그레이드 권장
+	Incompatible               // 스트림 거부
+)
+
+func (c Compat) String() string {
+	switch c {
+	case Compatible:
+		return "compatible"
+	case Deprecated:
+		return "deprecated"
+	default:
+		return "incompatible"
+	}
+}
+
+// CheckAgent는 agent가 보고한 스키마 버전을 이 server 기준으로 판정하고,
+// 호환되지 않거나 지원 중단 예정이면 운영자를 위한 안내 문구를 함께 반환한다.
+//
+// 판정 규칙 (rolling upgrade는 server → agent 순서):
+//
+//	agent > server           → Incompatible (server를 먼저 업그레이드해야 함)
+//	agent < MinAgentSchema   → Incompatible (agent 업그레이드 필요)
+//	min ≤ agent < server     → Deprecated
+//	agent == server          → Compatible
+//
+// 버전 메타데이터를 보내지 않는 agent(schema 0)는 최초 스키마(1)로 간주한다.
+// Negotiate를 거친 agent는 합의된 스키마를 보고하므로 server보다 새롭지 않다.
+func CheckAgent(agentSchema int) (Compat, string) {
+	if agentSchema == 0 {
+		agentSchema = 1
+	}
+	switch {
+	case agentSchema > SchemaVersion:
+		return Incompatible, fmt.Sprintf(
+			"agent schema %d is newer than server schema %d: upgrade nefi-server before rolling out agents",
+			agentSchema, SchemaVersion)
+	case agentSchema < MinAgentSchemaVersion:
+		return Incompatible, fmt.Sprintf(
+			"agent schema %d is no longer supported (minimum %d): upgrade nefi-agent",
+			agentSchema, MinAgentSchemaVersion)
+	case agentSchema < SchemaVersion:
+		return Deprecated, fmt.Sprintf(
+			"agent schema %d is older than server schema %d: upgrade nefi-agent before support is dropped",
+			agentSchema, SchemaVersion)
+	}
+	return Compatible, ""
+}
@@ -0,0 +1,57 @@
+package version
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckAgent(t *testing.T) {
+	tests := []struct {
+		agent int
+		want  Compat
+	}{
+		{SchemaVersion, Compatible},
+		{SchemaVersion + 1, Incompatible}, // server를 먼저 올려야 한다
+		{SchemaVersion - 1, Deprecated},
+		{MinAgentSchemaVersion, Deprecated},
+		{0, Deprecated}, // 메타데이터가 없는 agent = 스키마 1
+	}
+	for _, tt := range tests {
+		got, guidance := CheckAgent(tt.agent)
+		if got != tt.want {
+			t.Errorf("CheckAgent(%d) = %v, want %v", tt.agent, got, tt.want)
+		}
+		if (guidance == "") != (got == Compatible) {
+			t.Errorf("CheckAgent(%d) = %v with guidance %q", tt.agent, got, guidance)
+		}
+	}
+	// 최소 스키마가 1인 동안은 0(= 1)보다 오래된 버전이 없다.
+	if v := MinAgentSchemaVersion - 1; v > 0 {
+		if got, _ := CheckAgent(v); got != Incompatible {
+			t.Errorf("CheckAgent(%d) = %v, want incompatible", v, got)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		agent    int
+		want     []string
+		schema   int
+		features []string
+		ok       bool
+	}{
+		{SchemaVersion, []string{FeatureBatchAck, FeatureGzip}, SchemaVersion, []string{FeatureBatchAck, FeatureGzip}, true},
+		{SchemaVersion + 5, []string{FeatureDict}, SchemaVersion, []string{FeatureDict}, true}, // 새 agent는 server 스키마로 낮춘다
+		{MinAgentSchemaVersion, nil, MinAgentSchemaVersion, nil, true},
+		{SchemaVersion, []string{"zstd", FeatureGzip}, SchemaVersion, []string{FeatureGzip}, true},
+		{MinAgentSchemaVersion - 1, []string{FeatureGzip}, MinAgentSchemaVersion - 1, nil, false},
+	}
+	for _, tt := range tests {
+		schema, features, ok := Negotiate(tt.agent, tt.want)
+		if schema != tt.schema || !slices.Equal(features, tt.features) || ok != tt.ok {
+			t.Errorf("Negotiate(%d, %v) = %d, %v, %v; want %d, %v, %v",
+				tt.agent, tt.want, schema, features, ok, tt.schema, tt.features, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package version은 빌드 시 주입되는 버전 정보를 제공한다.
+//
+// 값은 -ldflags로 주입한다:
+//
+//	go build -ldflags "-X github.com/gihongjo/nefi/internal/version.Version=v0.3.0 \
+//	  -X github.com/gihongjo/nefi/internal/version.GitCommit=$(git rev-parse --short HEAD) \
+//	  -X github.com/gihongjo/nefi/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import "runtime"
+
+// ldflags로 덮어쓰는 값. 주입되지 않으면 로컬 빌드로 간주한다.
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)
+
+// SchemaVersion은 proto/nefi/v1 스키마 버전이다.
+// TraceEvent 필드가 추가/변경될 때마다 올린다.
+//
+//	2: Negotiate 이전의 마지막 스키마 (SendEvents 스트림)
+//	3: Negotiate, EventBatch.seq/schema_version (StreamBatches)
+const SchemaVersion = 3
+
+// Info는 바이너리 하나의 버전 정보다.
+type Info struct {
+	Version       string `json:"version"`
+	GitCommit     string `json:"git_commit"`
+	BuildDate     string `json:"build_date"`
+	SchemaVersion int    `json:"schema_version"`
+	GoVersion     string `json:"go_version"`
+}
+
+// Get은 현재 바이너리의 버전 정보를 반환한다.
+func Get() Info {
+	return Info{
+		Version:       Version,
+		GitCommit:     GitCommit,
+		BuildDate:     BuildDate,
+		SchemaVersion: SchemaVersion,
+		GoVersion:     runtime.Version(),
+	}
+}
+
+// agent가 gRPC 스트림을 열 때 보내는 메타데이터 키.
+// server는 이를 읽어 agent registry에 기록한다.
+const (
+	MDNodeName      = "x-nefi-node-name"
+	MDVersion       = "x-nefi-version"
+	MDGitCommit     = "x-nefi-git-commit"
+	MDBuildDate     = "x-nefi-build-date"
+	MDSchemaVersion = "x-nefi-schema-version"
+
+	// handshake: agent 실행 환경과 수집 능력 (fleet coverage 조회용)
+	MDKernelVersion = "x-nefi-kernel-version"
+	MDProbes        = "x-nefi-probes"      // 활성 수집 방식, 쉼표 구분 (예: "tracepoints,ssl_uprobes")
+	MDNodeLabels    = "x-nefi-node-labels" // 노드 topology label, "key=value" 쉼표 구분
+	MDCluster       = "x-nefi-cluster"     // agent --cluster-name (단일 클러스터 배포는 비어 있다)
+	MDTenant        = "x-nefi-tenant"      // agent --tenant (mTLS 인증서의 O가 있으면 server는 그것을 쓴다)
+)